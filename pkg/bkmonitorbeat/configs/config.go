@@ -96,31 +96,32 @@ type Config struct {
 	MustHostIDExist    bool   `config:"must_host_id_exist"`
 	DisableNetLink     bool   `config:"disable_netlink"`
 
-	TCPTask            *TCPTaskMetaConfig     `config:"tcp_task"`
-	HeartBeat          *HeartBeatConfig       `config:"heart_beat"`
-	GatherUpBeat       *GatherUpBeatConfig    `config:"gather_up_beat"`
-	UDPTask            *UDPTaskMetaConfig     `config:"udp_task"`
-	HTTPTask           *HTTPTaskMetaConfig    `config:"http_task"`
-	ScriptTask         *ScriptTaskMetaConfig  `config:"script_task"`
-	PingTask           *PingTaskMetaConfig    `config:"ping_task"`
-	MetricTask         *MetricBeatMetaConfig  `config:"metricbeat_task"`
-	KeywordTask        *KeywordTaskMetaConfig `config:"keyword_task"`
-	TrapTask           *TrapMetaConfig        `config:"trap_task"`
-	StaticTask         *StaticTaskMetaConfig  `config:"static_task"`
-	BaseReportTask     *BasereportConfig      `config:"basereport_task"`
-	ExceptionBeatTask  *ExceptionBeatConfig   `config:"exceptionbeat_task"`
-	KubeeventTask      *KubeEventConfig       `config:"kubeevent_task"`
-	ProcessBeatTask    *ProcessbeatConfig     `config:"processbeat_task"`
-	ProcConfTask       *ProcConfig            `config:"procconf_task"`
-	ProcCustomTask     *ProcCustomConfig      `config:"proccustom_task"`
-	ProcSyncTask       *ProcSyncConfig        `config:"procsync_task"`
-	ProcStatusTask     *ProcStatusConfig      `config:"procstatus_task"`
-	LoginLogTask       *LoginLogConfig        `config:"loginlog_task"`
-	ProcSnapshotTask   *ProcSnapshotConfig    `config:"procsnapshot_task"`
-	ProcBinTask        *ProcBinConfig         `config:"procbin_task"`
-	SocketSnapshotTask *SocketSnapshotConfig  `config:"socketsnapshot_task"`
-	ShellHistoryTask   *ShellHistoryConfig    `config:"shellhistory_task"`
-	RpmPackageTask     *RpmPackageConfig      `config:"rpmpackage_task"`
+	TCPTask            *TCPTaskMetaConfig        `config:"tcp_task"`
+	HeartBeat          *HeartBeatConfig          `config:"heart_beat"`
+	GatherUpBeat       *GatherUpBeatConfig       `config:"gather_up_beat"`
+	UDPTask            *UDPTaskMetaConfig        `config:"udp_task"`
+	HTTPTask           *HTTPTaskMetaConfig       `config:"http_task"`
+	ScriptTask         *ScriptTaskMetaConfig     `config:"script_task"`
+	PingTask           *PingTaskMetaConfig       `config:"ping_task"`
+	TracerouteTask     *TracerouteTaskMetaConfig `config:"traceroute_task"`
+	MetricTask         *MetricBeatMetaConfig     `config:"metricbeat_task"`
+	KeywordTask        *KeywordTaskMetaConfig    `config:"keyword_task"`
+	TrapTask           *TrapMetaConfig           `config:"trap_task"`
+	StaticTask         *StaticTaskMetaConfig     `config:"static_task"`
+	BaseReportTask     *BasereportConfig         `config:"basereport_task"`
+	ExceptionBeatTask  *ExceptionBeatConfig      `config:"exceptionbeat_task"`
+	KubeeventTask      *KubeEventConfig          `config:"kubeevent_task"`
+	ProcessBeatTask    *ProcessbeatConfig        `config:"processbeat_task"`
+	ProcConfTask       *ProcConfig               `config:"procconf_task"`
+	ProcCustomTask     *ProcCustomConfig         `config:"proccustom_task"`
+	ProcSyncTask       *ProcSyncConfig           `config:"procsync_task"`
+	ProcStatusTask     *ProcStatusConfig         `config:"procstatus_task"`
+	LoginLogTask       *LoginLogConfig           `config:"loginlog_task"`
+	ProcSnapshotTask   *ProcSnapshotConfig       `config:"procsnapshot_task"`
+	ProcBinTask        *ProcBinConfig            `config:"procbin_task"`
+	SocketSnapshotTask *SocketSnapshotConfig     `config:"socketsnapshot_task"`
+	ShellHistoryTask   *ShellHistoryConfig       `config:"shellhistory_task"`
+	RpmPackageTask     *RpmPackageConfig         `config:"rpmpackage_task"`
 }
 
 // NewConfig : new config struct
@@ -140,6 +141,7 @@ func NewConfig() *Config {
 	config.HTTPTask = NewHTTPTaskMetaConfig(config)
 	config.ScriptTask = NewScriptTaskMetaConfig(config)
 	config.PingTask = NewPingTaskMetaConfig(config)
+	config.TracerouteTask = NewTracerouteTaskMetaConfig(config)
 	config.MetricTask = NewMetricBeatMetaConfig(config)
 	config.KeywordTask = NewKeywordTaskMetaConfig(config)
 	config.TrapTask = NewTrapMetaConfig(config)
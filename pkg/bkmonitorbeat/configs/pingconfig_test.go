@@ -34,8 +34,8 @@ func (s *PingConfiSuite) TestConfigClean() {
 	taskConf := configs.NewPingTaskConfig()
 	taskConf.Targets = []*configs.Target{
 		{
-			"127.0.0.1",
-			"ip",
+			Target:     "127.0.0.1",
+			TargetType: "ip",
 		},
 	}
 	metaConf.Tasks = append(metaConf.Tasks, taskConf)
@@ -0,0 +1,186 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package configs
+
+import (
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/utils"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// config类型定义
+const (
+	ConfigTypeTraceroute = define.ModuleTraceroute
+)
+
+// 路径探测协议
+const (
+	TracerouteProtocolICMP = "icmp"
+	TracerouteProtocolUDP  = "udp"
+	TracerouteProtocolTCP  = "tcp"
+)
+
+const (
+	defaultTracerouteMaxHops = 30
+	defaultTracerouteQueries = 3
+	defaultTracerouteUDPPort = 33434
+	defaultTracerouteTCPPort = 80
+)
+
+// TracerouteTaskConfig :
+type TracerouteTaskConfig struct {
+	BaseTaskParam `config:"_,inline"`
+	TargetIPType  IPType `config:"target_ip_type"`
+	// 域名检测模式
+	DNSCheckMode CheckMode `config:"dns_check_mode"`
+	Targets      []*Target `config:"targets"`
+	// 探测协议 icmp/udp/tcp
+	Protocol string `config:"protocol"`
+	// udp 模式下为起始目的端口（每次探测递增），tcp 模式下为固定目的端口
+	Port       int    `config:"port"`
+	FirstHop   int    `config:"first_hop"`
+	MaxHops    int    `config:"max_hops"`
+	Queries    int    `config:"queries"`
+	MaxRTT     string `config:"max_rtt"`
+	PacketSize int    `config:"packet_size"`
+	// 非特权模式下无法监听原始 icmp 报文，只能判断目标是否可达，中间跳地址无法获取
+	NotPrivileged bool `config:"not_privileged"`
+	CustomReport  bool `config:"custom_report"`
+}
+
+// InitIdent :
+func (c *TracerouteTaskConfig) InitIdent() error {
+	return c.initIdent(c)
+}
+
+// Clean :
+func (c *TracerouteTaskConfig) Clean() error {
+	err := utils.CleanCompositeParamList(&c.BaseTaskParam)
+	if err != nil {
+		return err
+	}
+
+	switch c.Protocol {
+	case "":
+		c.Protocol = TracerouteProtocolICMP
+	case TracerouteProtocolICMP, TracerouteProtocolUDP, TracerouteProtocolTCP:
+	default:
+		return define.ErrWrongProtocol
+	}
+
+	if c.Port <= 0 {
+		if c.Protocol == TracerouteProtocolTCP {
+			c.Port = defaultTracerouteTCPPort
+		} else {
+			c.Port = defaultTracerouteUDPPort
+		}
+	}
+	if c.FirstHop <= 0 {
+		c.FirstHop = 1
+	}
+	if c.MaxHops <= 0 {
+		logger.Infof("max hops not configured,set %v by default", defaultTracerouteMaxHops)
+		c.MaxHops = defaultTracerouteMaxHops
+	}
+	if c.FirstHop > c.MaxHops {
+		c.FirstHop = c.MaxHops
+	}
+	if c.Queries <= 0 {
+		logger.Infof("queries not configured,set %v by default", defaultTracerouteQueries)
+		c.Queries = defaultTracerouteQueries
+	}
+	if c.MaxRTT == "" {
+		defaultMaxRTT := "1s"
+		logger.Infof("max rtt not configured,set %v by default", defaultMaxRTT)
+		c.MaxRTT = defaultMaxRTT
+	}
+	// 各跳并发探测，单个目标的耗时约为 max_rtt，超时时间不足时无法得到完整路径
+	// 未配置超时时间时由 CleanTask 补全
+	maxRTT, err := time.ParseDuration(c.MaxRTT)
+	if err != nil {
+		return err
+	}
+	if c.Timeout > 0 && c.Timeout < maxRTT {
+		return define.ErrTimeoutTooShort
+	}
+	if c.PacketSize < 8 {
+		c.PacketSize = 52
+	}
+
+	for _, target := range c.Targets {
+		if target.GetTargetType() != "ip" && target.GetTargetType() != "domain" {
+			return define.ErrWrongTargetType
+		}
+	}
+
+	if c.DNSCheckMode == "" {
+		// 未配置则使用默认模式
+		c.DNSCheckMode = DefaultDNSCheckMode
+	}
+
+	return nil
+}
+
+// GetType :
+func (c *TracerouteTaskConfig) GetType() string {
+	return ConfigTypeTraceroute
+}
+
+// NewTracerouteTaskConfig :
+func NewTracerouteTaskConfig() *TracerouteTaskConfig {
+	var conf TracerouteTaskConfig
+	conf.Timeout = define.DefaultTimeout
+	return &conf
+}
+
+// TracerouteTaskMetaConfig : associate task config
+type TracerouteTaskMetaConfig struct {
+	BaseTaskMetaParam `config:"_,inline"`
+
+	Tasks []*TracerouteTaskConfig `config:"tasks"`
+}
+
+// Clean :
+func (c *TracerouteTaskMetaConfig) Clean() error {
+	err := utils.CleanCompositeParamList(&c.BaseTaskMetaParam)
+	if err != nil {
+		return err
+	}
+	for _, task := range c.Tasks {
+		err = c.CleanTask(task)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTaskConfigList :
+func (c *TracerouteTaskMetaConfig) GetTaskConfigList() []define.TaskConfig {
+	tasks := make([]define.TaskConfig, len(c.Tasks))
+	for index, task := range c.Tasks {
+		tasks[index] = task
+	}
+	return tasks
+}
+
+// NewTracerouteTaskMetaConfig :
+func NewTracerouteTaskMetaConfig(root *Config) *TracerouteTaskMetaConfig {
+	config := &TracerouteTaskMetaConfig{
+		BaseTaskMetaParam: NewBaseTaskMetaParam(),
+	}
+	config.Tasks = make([]*TracerouteTaskConfig, 0)
+
+	root.TaskTypeMapping[ConfigTypeTraceroute] = config
+
+	return config
+}
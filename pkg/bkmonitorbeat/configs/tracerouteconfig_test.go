@@ -0,0 +1,70 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package configs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+)
+
+func TestTracerouteConfigClean(t *testing.T) {
+	metaConf := configs.NewTracerouteTaskMetaConfig(configs.NewConfig())
+	taskConf := configs.NewTracerouteTaskConfig()
+	taskConf.Targets = []*configs.Target{
+		{Target: "127.0.0.1", TargetType: "ip"},
+	}
+	metaConf.Tasks = append(metaConf.Tasks, taskConf)
+
+	assert.NoError(t, metaConf.Clean())
+	assert.Equal(t, define.DefaultPeriod, taskConf.Period)
+	assert.Equal(t, define.DefaultTimeout, taskConf.Timeout)
+	assert.Equal(t, configs.TracerouteProtocolICMP, taskConf.Protocol)
+	assert.Equal(t, 1, taskConf.FirstHop)
+	assert.Equal(t, 30, taskConf.MaxHops)
+	assert.Equal(t, 3, taskConf.Queries)
+	assert.Equal(t, "1s", taskConf.MaxRTT)
+	assert.Equal(t, configs.DefaultDNSCheckMode, taskConf.DNSCheckMode)
+}
+
+func TestTracerouteConfigPort(t *testing.T) {
+	tcpConf := configs.NewTracerouteTaskConfig()
+	tcpConf.Protocol = configs.TracerouteProtocolTCP
+	assert.NoError(t, tcpConf.Clean())
+	assert.Equal(t, 80, tcpConf.Port)
+
+	udpConf := configs.NewTracerouteTaskConfig()
+	udpConf.Protocol = configs.TracerouteProtocolUDP
+	assert.NoError(t, udpConf.Clean())
+	assert.Equal(t, 33434, udpConf.Port)
+}
+
+func TestTracerouteConfigInvalid(t *testing.T) {
+	taskConf := configs.NewTracerouteTaskConfig()
+	taskConf.Protocol = "sctp"
+	assert.Equal(t, define.ErrWrongProtocol, taskConf.Clean())
+
+	taskConf = configs.NewTracerouteTaskConfig()
+	taskConf.Targets = []*configs.Target{
+		{Target: "127.0.0.1", TargetType: "host"},
+	}
+	assert.Equal(t, define.ErrWrongTargetType, taskConf.Clean())
+
+	taskConf = configs.NewTracerouteTaskConfig()
+	taskConf.MaxRTT = "5s"
+	assert.Equal(t, define.ErrTimeoutTooShort, taskConf.Clean())
+
+	taskConf = configs.NewTracerouteTaskConfig()
+	taskConf.MaxRTT = "abc"
+	assert.Error(t, taskConf.Clean())
+}
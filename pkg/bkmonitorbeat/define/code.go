@@ -57,6 +57,9 @@ var (
 	CodeConnFailed          = newNamedCode(1000, "ConnFailed")
 	CodeConnRefused         = newNamedCode(2501, "ConnRefused")
 	CodeInvalidPromFormat   = newNamedCode(2502, "InvalidPromFormat")
+	CodeInvalidJSONFormat   = newNamedCode(2503, "InvalidJSONFormat")
+	CodeSampleLimitExceeded = newNamedCode(2504, "SampleLimitExceeded")
+	CodeLabelLimitExceeded  = newNamedCode(2505, "LabelLimitExceeded")
 	CodeScriptRunFailed     = newNamedCode(2301, "ScriptRunFailed")
 	CodeScriptNoOutput      = newNamedCode(2303, "ScriptNoOutput")
 	CodeScriptTimeout       = newNamedCode(2304, "ScriptRunTimeout")
@@ -36,6 +36,8 @@ var ErrCodeMap = map[error]int{
 	ErrNoVersion:       123,
 	ErrTypeConvert:     131,
 	ErrWrongTargetType: 138,
+	ErrWrongProtocol:   139,
+	ErrTimeoutTooShort: 140,
 }
 
 var (
@@ -44,6 +46,8 @@ var (
 	ErrType            = errors.New("type error")
 	ErrTypeConvert     = errors.New("get error when try to convert type")
 	ErrWrongTargetType = errors.New("wrong target type") // 目标类型不是ip也不是domain
+	ErrWrongProtocol   = errors.New("wrong protocol")    // 探测协议不在支持范围内
+	ErrTimeoutTooShort = errors.New("timeout too short") // 任务超时时间小于单次探测所需时间
 	ErrNoChildPath     = errors.New("bkmonitorbeat.include not configured")
 	ErrGetChildTasks   = errors.New("get child tasks error")
 	ErrUnpackCfg       = errors.New("unpack cfg error")
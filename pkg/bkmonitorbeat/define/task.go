@@ -22,6 +22,7 @@ const (
 	ModuleHTTP            = "http"
 	ModuleMetricbeat      = "metricbeat"
 	ModulePing            = "ping"
+	ModuleTraceroute      = "traceroute"
 	ModuleScript          = "script"
 	ModuleTCP             = "tcp"
 	ModuleUDP             = "udp"
@@ -19,6 +19,7 @@ require (
 	github.com/gosnmp/gosnmp v1.32.0
 	github.com/hpcloud/tail v1.0.0
 	github.com/influxdata/telegraf v0.10.2-0.20190611181903-c9d8f7b008f6
+	github.com/jmespath/go-jmespath v0.4.0
 	github.com/magiconair/properties v1.8.1
 	github.com/mattn/go-shellwords v1.0.12
 	github.com/mdlayher/netlink v1.4.1
@@ -38,6 +39,7 @@ require (
 	github.com/xeipuuv/gojsonschema v1.2.0
 	github.com/yumaojun03/dmidecode v0.1.4
 	github.com/yusufpapurcu/wmi v1.2.3
+	golang.org/x/net v0.24.0
 	golang.org/x/sync v0.7.0
 	golang.org/x/sys v0.19.0
 	golang.org/x/text v0.14.0
@@ -124,7 +126,6 @@ require (
 	go.uber.org/multierr v1.11.0 // indirect
 	go.uber.org/zap v1.24.0 // indirect
 	golang.org/x/crypto v0.22.0 // indirect
-	golang.org/x/net v0.24.0 // indirect
 	google.golang.org/genproto v0.0.0-20230306155012-7f2fa6fef1f4 // indirect
 	google.golang.org/grpc v1.55.0 // indirect
 	google.golang.org/protobuf v1.33.0 // indirect
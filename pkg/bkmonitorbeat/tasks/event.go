@@ -269,6 +269,31 @@ func NewPingEvent(task define.TaskConfig) *PingEvent {
 	}
 }
 
+// TracerouteEvent
+type TracerouteEvent struct {
+	StandardEvent
+}
+
+// IgnoreCMDBLevel
+func (e *TracerouteEvent) IgnoreCMDBLevel() bool { return true }
+
+func (e *TracerouteEvent) GetType() string {
+	return define.ModuleTraceroute
+}
+
+func (e *TracerouteEvent) AsMapStr() common.MapStr {
+	mapStr := e.StandardEvent.AsMapStr()
+	mapStr["bk_biz_id"] = e.BizID
+	return mapStr
+}
+
+// NewTracerouteEvent
+func NewTracerouteEvent(task define.TaskConfig) *TracerouteEvent {
+	return &TracerouteEvent{
+		StandardEvent: *NewStandardEvent(task),
+	}
+}
+
 // CustomMetricEvent metricbeat转自定义时序上报
 type CustomMetricEvent struct {
 	*MetricEvent
@@ -474,6 +499,28 @@ func NewCustomEventBySimpleEvent(e *SimpleEvent) *CustomEvent {
 
 // NewCustomEventByPingEvent 通过PingEvent创建自定义事件
 func NewCustomEventByPingEvent(events ...*PingEvent) *CustomEvent {
+	standardEvents := make([]*StandardEvent, 0, len(events))
+	for _, e := range events {
+		standardEvents = append(standardEvents, &e.StandardEvent)
+	}
+
+	event := events[0]
+	return newCustomEventByStandardEvents(event.GetType(), event.IgnoreCMDBLevel(), standardEvents)
+}
+
+// NewCustomEventByTracerouteEvent 通过TracerouteEvent创建自定义事件
+func NewCustomEventByTracerouteEvent(events ...*TracerouteEvent) *CustomEvent {
+	standardEvents := make([]*StandardEvent, 0, len(events))
+	for _, e := range events {
+		standardEvents = append(standardEvents, &e.StandardEvent)
+	}
+
+	event := events[0]
+	return newCustomEventByStandardEvents(event.GetType(), event.IgnoreCMDBLevel(), standardEvents)
+}
+
+// newCustomEventByStandardEvents 将StandardEvent转换为自定义时序事件
+func newCustomEventByStandardEvents(t string, ignoreCmdbLevel bool, events []*StandardEvent) *CustomEvent {
 	var data []map[string]interface{}
 	for _, e := range events {
 		ts := e.Time.Unix()
@@ -516,7 +563,7 @@ func NewCustomEventByPingEvent(events ...*PingEvent) *CustomEvent {
 		"timestamp": event.Time.Unix(),
 	}
 
-	return NewCustomEvent(event.GetType(), customEvent, event.IgnoreCMDBLevel(), event.Labels)
+	return NewCustomEvent(t, customEvent, ignoreCmdbLevel, event.Labels)
 }
 
 // GetType 获取事件类型
@@ -12,6 +12,8 @@ package metricbeat
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/elastic/beats/libbeat/common"
@@ -20,6 +22,7 @@ import (
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
 )
 
 func TestMetricBeatGatherRun(t *testing.T) {
@@ -54,3 +57,50 @@ dataid: 1573267`)
 	}
 	assert.Equal(t, num, 2)
 }
+
+func TestMetricBeatGatherRunJSONAPI(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"queues": [{"name": "default", "length": 10}]}`))
+	}))
+	defer svr.Close()
+
+	globalConfig := configs.NewConfig()
+	globalConfig.GatherUpBeat.DataID = 10001
+	taskConf := configs.NewMetricBeatConfig()
+	taskConf.CustomReport = true
+	buf := []byte(fmt.Sprintf(`module: jsonapi
+metricsets: ["collector"]
+enabled: true
+hosts: ["%s/status"]
+namespace: jsonapi_test
+metrics:
+  - name: queue_length
+    path: queues
+    value: length
+    labels:
+      queue: name
+dataid: 1573267`, svr.URL))
+	ucfgConfig, err := yaml.NewConfig(buf)
+	assert.NoError(t, err)
+	taskConf.Module = (*common.Config)(ucfgConfig)
+
+	gather := New(globalConfig, taskConf)
+	e := make(chan define.Event, 100)
+	gather.Run(context.Background(), e)
+	gather.Wait()
+	close(e)
+
+	var found bool
+	for ev := range e {
+		if _, ok := ev.(*tasks.CustomMetricEvent); !ok {
+			continue
+		}
+		for _, item := range ev.AsMapStr()["data"].([]map[string]interface{}) {
+			if _, ok := item["metrics"].(map[string]interface{})["queue_length"]; ok {
+				assert.Equal(t, "default", item["dimension"].(map[string]string)["queue"])
+				found = true
+			}
+		}
+	}
+	assert.True(t, found)
+}
@@ -14,5 +14,6 @@
 package include
 
 import (
+	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/metricbeat/module/jsonapi/collector"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/metricbeat/module/prometheus/collector"
 )
@@ -0,0 +1,213 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package collector
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/mb/parse"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
+	promcollector "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/metricbeat/module/prometheus/collector"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	defaultScheme = "http"
+	defaultPath   = "/"
+
+	// namespace 与 prometheus 模块保持一致，使得数据沿用相同的拆分及自定义上报链路
+	namespace = "prometheus.collector"
+)
+
+var hostParser = parse.URLHostParserBuilder{
+	DefaultScheme: defaultScheme,
+	DefaultPath:   defaultPath,
+	PathConfigKey: "path",
+}.Build()
+
+func init() {
+	mb.Registry.MustAddMetricSet("jsonapi", "collector", New,
+		mb.WithHostParser(hostParser),
+		mb.WithNamespace(namespace),
+	)
+}
+
+// MetricSet 拉取 json 接口并按规则转换为指标
+type MetricSet struct {
+	mb.BaseMetricSet
+	httpClient             *promcollector.HTTPClient
+	namespace              string
+	rules                  []*compiledRule
+	disableCustomTimestamp bool
+}
+
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := struct {
+		Namespace              string       `config:"namespace" validate:"required"`
+		Metrics                []MetricRule `config:"metrics"`
+		DisableCustomTimestamp bool         `config:"disable_custom_timestamp"`
+	}{}
+
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		logger.Errorf("unpack failed, error: %s", err)
+		return nil, err
+	}
+	logger.Infof("base.metric.set config: %+v", config)
+
+	if len(config.Metrics) == 0 {
+		return nil, errors.New("jsonapi metric rules not configured")
+	}
+
+	rules, err := compileRules(config.Metrics)
+	if err != nil {
+		logger.Errorf("compile metric rules failed: %v", err)
+		return nil, err
+	}
+
+	// 复用 prometheus 模块的 http 客户端，TLS / 认证 / 代理等配置保持一致
+	httpClient, err := promcollector.NewHTTPClient(base)
+	if err != nil {
+		logger.Errorf("failed to create HTTP client: %v", err)
+		return nil, err
+	}
+	httpClient.SetHeader("Accept", "application/json")
+
+	return &MetricSet{
+		BaseMetricSet:          base,
+		httpClient:             httpClient,
+		namespace:              config.Namespace,
+		rules:                  rules,
+		disableCustomTimestamp: config.DisableCustomTimestamp,
+	}, nil
+}
+
+func (m *MetricSet) logkvs() []define.LogKV {
+	return []define.LogKV{
+		{K: "uri", V: m.HostData().SanitizedURI},
+	}
+}
+
+// asEvents 将自监控指标文本转换为事件
+func (m *MetricSet) asEvents(line string, ts int64) []common.MapStr {
+	tsHandler, _ := tasks.GetTimestampHandler("s")
+	promEvent, err := tasks.NewPromEventFast(line, ts, 24*time.Hour, tsHandler)
+	if err != nil {
+		logger.Warnf("failed to parse inner metric(%s): %v", line, err)
+		return nil
+	}
+
+	event := common.MapStr{
+		"key":    promEvent.Key,
+		"labels": promEvent.Labels,
+		"value":  promEvent.Value,
+	}
+	if !m.disableCustomTimestamp {
+		event["timestamp"] = promEvent.TS
+	}
+	return []common.MapStr{event}
+}
+
+// produceEvents 按规则从 json 文档中提取指标事件
+func (m *MetricSet) produceEvents(doc interface{}, ts int64) ([]common.MapStr, error) {
+	events := make([]common.MapStr, 0)
+	var errs []error
+	for _, rule := range m.rules {
+		samples, err := rule.extract(doc)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, s := range samples {
+			labels := common.MapStr{}
+			for k, v := range s.labels {
+				labels[k] = v
+			}
+
+			event := common.MapStr{
+				"key":    s.name,
+				"labels": labels,
+				"value":  s.value,
+			}
+			if !m.disableCustomTimestamp {
+				event["timestamp"] = ts
+			}
+			events = append(events, event)
+		}
+	}
+
+	if len(errs) > 0 {
+		return events, errors.Errorf("%d metric rules failed, first error: %v", len(errs), errs[0])
+	}
+	return events, nil
+}
+
+// Fetch 采集逻辑入口
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	summary := common.MapStr{"namespace": m.namespace}
+	startTime := time.Now()
+	ts := startTime.Unix()
+
+	markUp := func(code define.NamedCode, events []common.MapStr) {
+		events = append(events, m.asEvents(promcollector.CodeUp(code, m.logkvs()), ts)...)
+		events = append(events, m.asEvents(promcollector.CodeHandleDuration(time.Since(startTime).Seconds(), m.logkvs()), ts)...)
+		summary["metrics"] = events
+	}
+
+	rsp, err := m.httpClient.FetchResponse()
+	if err != nil {
+		markUp(define.CodeConnRefused, nil)
+		err = errors.Wrap(err, "request failed")
+		logger.Error(err)
+		return summary, err
+	}
+	defer rsp.Body.Close()
+
+	logger.Infof("http request: host=%s, take=%v", m.Host(), time.Since(startTime))
+
+	body, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		markUp(define.CodeResponseFailed, nil)
+		err = errors.Wrap(err, "read response failed")
+		logger.Error(err)
+		return summary, err
+	}
+
+	events := m.asEvents(promcollector.CodeScrapeSize(len(body), m.logkvs()), ts)
+	events = append(events, m.asEvents(promcollector.CodeScrapeDuration(time.Since(startTime).Seconds(), m.logkvs()), ts)...)
+
+	var doc interface{}
+	if err = json.Unmarshal(body, &doc); err != nil {
+		markUp(define.CodeInvalidJSONFormat, events)
+		err = errors.Wrap(err, "decode json failed")
+		logger.Error(err)
+		return summary, err
+	}
+
+	metrics, err := m.produceEvents(doc, ts)
+	events = append(events, metrics...)
+	events = append(events, m.asEvents(promcollector.CodeScrapeLine(len(metrics), m.logkvs()), ts)...)
+	if err != nil {
+		// 部分规则失败时仍上报已提取的指标
+		logger.Warnf("failed to produce events: %v", err)
+		markUp(define.CodeInvalidJSONFormat, events)
+		return summary, nil
+	}
+
+	markUp(define.CodeOK, events)
+	return summary, nil
+}
@@ -0,0 +1,83 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+)
+
+func newTestMetricSet(t *testing.T, url string) *MetricSet {
+	config := map[string]interface{}{
+		"module":       "jsonapi",
+		"metricsets":   []string{"collector"},
+		"hosts":        []string{url},
+		"namespace":    "jsonapi_test",
+		"bearer_token": "token",
+		"metrics": []map[string]interface{}{
+			{
+				"name":   "queue_length",
+				"path":   "queues",
+				"value":  "length",
+				"labels": map[string]string{"queue": "name"},
+			},
+		},
+	}
+	return mbtest.NewEventFetcher(t, config).(*MetricSet)
+}
+
+func eventsByKey(summary common.MapStr) map[string]common.MapStr {
+	ret := make(map[string]common.MapStr)
+	for _, event := range summary["metrics"].([]common.MapStr) {
+		ret[event["key"].(string)] = event
+	}
+	return ret
+}
+
+func TestFetch(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(testDocument))
+	}))
+	defer svr.Close()
+
+	ms := newTestMetricSet(t, svr.URL)
+	summary, err := ms.Fetch()
+	assert.NoError(t, err)
+	assert.Equal(t, "jsonapi_test", summary["namespace"])
+
+	events := eventsByKey(summary)
+	assert.Equal(t, common.MapStr{"queue": "slow"}, events["queue_length"]["labels"])
+	assert.Equal(t, float64(1), events[define.NameMetricBeatUp]["value"])
+	assert.Equal(t, "0", events[define.NameMetricBeatUp]["labels"].(common.MapStr)["code"])
+	assert.Equal(t, float64(2), events[define.NameMetricBeatScrapeLine]["value"])
+}
+
+func TestFetchInvalidJSON(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("not json"))
+	}))
+	defer svr.Close()
+
+	ms := newTestMetricSet(t, svr.URL)
+	summary, err := ms.Fetch()
+	assert.Error(t, err)
+
+	events := eventsByKey(summary)
+	assert.Equal(t, "2503", events[define.NameMetricBeatUp]["labels"].(common.MapStr)["code"])
+}
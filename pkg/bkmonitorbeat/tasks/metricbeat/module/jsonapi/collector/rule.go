@@ -0,0 +1,187 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package collector
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/pkg/errors"
+)
+
+// MetricRule 指标提取规则
+//
+// Path 从响应根节点选取样本对象，结果为数组时每个元素都会生成一个样本
+// Value 从样本对象中提取数值，为空时样本对象本身即为数值
+// Labels 为维度名到 JMESPath 表达式的映射，ConstLabels 为固定维度
+type MetricRule struct {
+	Name        string            `config:"name" validate:"required"`
+	Path        string            `config:"path"`
+	Value       string            `config:"value"`
+	Labels      map[string]string `config:"labels"`
+	ConstLabels map[string]string `config:"const_labels"`
+}
+
+type compiledRule struct {
+	name        string
+	path        *jmespath.JMESPath
+	value       *jmespath.JMESPath
+	labels      map[string]*jmespath.JMESPath
+	constLabels map[string]string
+}
+
+// sample 提取出来的单个样本
+type sample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+func compileExpr(expr string) (*jmespath.JMESPath, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return jmespath.Compile(expr)
+}
+
+func compileRules(rules []MetricRule) ([]*compiledRule, error) {
+	compiled := make([]*compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, errors.New("metric rule name is required")
+		}
+
+		cr := &compiledRule{
+			name:        rule.Name,
+			labels:      make(map[string]*jmespath.JMESPath),
+			constLabels: rule.ConstLabels,
+		}
+
+		var err error
+		if cr.path, err = compileExpr(rule.Path); err != nil {
+			return nil, errors.Wrapf(err, "compile path of metric(%s) failed", rule.Name)
+		}
+		if cr.value, err = compileExpr(rule.Value); err != nil {
+			return nil, errors.Wrapf(err, "compile value of metric(%s) failed", rule.Name)
+		}
+		for name, expr := range rule.Labels {
+			jp, err := compileExpr(expr)
+			if err != nil {
+				return nil, errors.Wrapf(err, "compile label(%s) of metric(%s) failed", name, rule.Name)
+			}
+			if jp != nil {
+				cr.labels[name] = jp
+			}
+		}
+		compiled = append(compiled, cr)
+	}
+	return compiled, nil
+}
+
+// extract 按规则从 json 文档中提取样本，无法转换为数值的样本会被忽略
+func (r *compiledRule) extract(doc interface{}) ([]sample, error) {
+	selected := doc
+	if r.path != nil {
+		var err error
+		if selected, err = r.path.Search(doc); err != nil {
+			return nil, errors.Wrapf(err, "search path of metric(%s) failed", r.name)
+		}
+	}
+
+	items, ok := selected.([]interface{})
+	if !ok {
+		items = []interface{}{selected}
+	}
+
+	samples := make([]sample, 0, len(items))
+	for _, item := range items {
+		raw := item
+		if r.value != nil {
+			var err error
+			if raw, err = r.value.Search(item); err != nil {
+				return nil, errors.Wrapf(err, "search value of metric(%s) failed", r.name)
+			}
+		}
+
+		value, ok := toFloat(raw)
+		if !ok {
+			continue
+		}
+
+		labels := make(map[string]string, len(r.labels)+len(r.constLabels))
+		for k, v := range r.constLabels {
+			labels[k] = v
+		}
+		for name, jp := range r.labels {
+			lv, err := jp.Search(item)
+			if err != nil {
+				return nil, errors.Wrapf(err, "search label(%s) of metric(%s) failed", name, r.name)
+			}
+			if s, ok := toString(lv); ok {
+				labels[name] = s
+			}
+		}
+
+		samples = append(samples, sample{name: r.name, labels: labels, value: value})
+	}
+	return samples, nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	var f float64
+	switch val := v.(type) {
+	case float64:
+		f = val
+	case json.Number:
+		n, err := val.Float64()
+		if err != nil {
+			return 0, false
+		}
+		f = n
+	case bool:
+		if val {
+			f = 1
+		}
+	case string:
+		n, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return 0, false
+		}
+		f = n
+	default:
+		return 0, false
+	}
+
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+func toString(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return val, true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(val), true
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return "", false
+		}
+		return string(b), true
+	}
+}
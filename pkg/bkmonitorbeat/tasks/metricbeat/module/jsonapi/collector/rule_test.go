@@ -0,0 +1,65 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package collector
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDocument = `{
+  "status": "ok",
+  "uptime": "3600",
+  "healthy": true,
+  "queues": [
+    {"name": "default", "length": 10, "consumers": {"active": 2}},
+    {"name": "slow", "length": 3, "consumers": {"active": null}},
+    {"name": "broken", "length": "NaN"}
+  ]
+}`
+
+func TestExtractRules(t *testing.T) {
+	var doc interface{}
+	assert.NoError(t, json.Unmarshal([]byte(testDocument), &doc))
+
+	rules, err := compileRules([]MetricRule{
+		{Name: "uptime_seconds", Path: "uptime"},
+		{Name: "healthy", Path: "healthy", ConstLabels: map[string]string{"source": "appliance"}},
+		{Name: "queue_length", Path: "queues", Value: "length", Labels: map[string]string{"queue": "name"}},
+		{Name: "queue_consumers", Path: "queues[*]", Value: "consumers.active", Labels: map[string]string{"queue": "name"}},
+	})
+	assert.NoError(t, err)
+
+	var samples []sample
+	for _, rule := range rules {
+		s, err := rule.extract(doc)
+		assert.NoError(t, err)
+		samples = append(samples, s...)
+	}
+
+	expected := []sample{
+		{name: "uptime_seconds", labels: map[string]string{}, value: 3600},
+		{name: "healthy", labels: map[string]string{"source": "appliance"}, value: 1},
+		{name: "queue_length", labels: map[string]string{"queue": "default"}, value: 10},
+		{name: "queue_length", labels: map[string]string{"queue": "slow"}, value: 3},
+		{name: "queue_consumers", labels: map[string]string{"queue": "default"}, value: 2},
+	}
+	assert.Equal(t, expected, samples)
+}
+
+func TestCompileRulesFailed(t *testing.T) {
+	_, err := compileRules([]MetricRule{{Name: "m", Path: "queues[?"}})
+	assert.Error(t, err)
+
+	_, err = compileRules([]MetricRule{{Path: "queues"}})
+	assert.Error(t, err)
+}
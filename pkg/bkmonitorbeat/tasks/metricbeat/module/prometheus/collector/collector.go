@@ -65,6 +65,8 @@ type MetricSet struct {
 	workers                int
 	disableCustomTimestamp bool
 	normalizeMetricName    bool
+	sampleLimit            int
+	labelLimit             int
 	remoteRelabelCache     []*relabel.Config
 	MetricRelabelRemote    string
 	MetricRelabelConfigs   []*relabel.Config
@@ -83,6 +85,8 @@ func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
 		Workers                    int               `config:"workers"`
 		DisableCustomTimestamp     bool              `config:"disable_custom_timestamp"`
 		NormalizeMetricName        bool              `config:"normalize_metric_name"`
+		SampleLimit                int               `config:"sample_limit"`
+		LabelLimit                 int               `config:"label_limit"`
 	}{}
 
 	if err := base.Module().UnpackConfig(&config); err != nil {
@@ -149,6 +153,8 @@ func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
 		MetricRelabelConfigs:   relabels,
 		disableCustomTimestamp: config.DisableCustomTimestamp,
 		normalizeMetricName:    config.NormalizeMetricName,
+		sampleLimit:            config.SampleLimit,
+		labelLimit:             config.LabelLimit,
 		workers:                config.Workers,
 	}, nil
 }
@@ -284,20 +290,18 @@ func (m *MetricSet) getEventsFromReader(metricsReader io.ReadCloser, cleanup fun
 
 	// 补充 up 指标文本
 	var total atomic.Int64
-	markUp := func(failed bool, t0 time.Time) {
-		// 需要减去自监控指标
-		events := m.asEvents(CodeScrapeLine(int(total.Load()-2), m.logkvs()), milliTs)
-		if failed {
-			events = append(events, m.asEvents(CodeUp(define.CodeInvalidPromFormat, m.logkvs()), milliTs)...)
-		} else {
-			events = append(events, m.asEvents(CodeUp(define.CodeOK, m.logkvs()), milliTs)...)
-		}
+	markUp := func(code define.NamedCode, lines int, t0 time.Time) {
+		events := m.asEvents(CodeScrapeLine(lines, m.logkvs()), milliTs)
+		events = append(events, m.asEvents(CodeUp(code, m.logkvs()), milliTs)...)
 		events = append(events, m.asEvents(CodeHandleDuration(time.Since(t0).Seconds(), m.logkvs()), milliTs)...)
 		for i := 0; i < len(events); i++ {
 			eventChan <- events[i]
 		}
 	}
 
+	// 采集限制 超限时丢弃本次采集的样本
+	limiter := newScrapeLimiter(m.sampleLimit, m.labelLimit)
+
 	// 消费指标文本并生成事件
 	var produceErr atomic.Bool
 	consume := func() {
@@ -309,6 +313,10 @@ func (m *MetricSet) getEventsFromReader(metricsReader io.ReadCloser, cleanup fun
 				continue
 			}
 			for j := 0; j < len(events); j++ {
+				if limiter != nil && !IsInnerMetric(keyFunc(events[j])) {
+					limiter.Add(events[j])
+					continue
+				}
 				eventChan <- events[j]
 				total.Add(1)
 			}
@@ -330,8 +338,27 @@ func (m *MetricSet) getEventsFromReader(metricsReader io.ReadCloser, cleanup fun
 		}
 		wg.Wait()
 
+		code := define.CodeOK
+		if produceErr.Load() {
+			code = define.CodeInvalidPromFormat
+		}
+
+		// 需要减去自监控指标
+		lines := int(total.Load() - 2)
+		if limiter != nil {
+			events, exceeded := limiter.Result()
+			if exceeded != nil {
+				code = *exceeded
+				logger.Warnf("scrape %s exceeded, uri=%s, samples=%d", code.Name(), m.HostData().SanitizedURI, limiter.Samples())
+			}
+			for i := 0; i < len(events); i++ {
+				eventChan <- events[i]
+			}
+			lines += limiter.Samples()
+		}
+
 		if up {
-			markUp(produceErr.Load(), start) // 一次采集只上报一次状态
+			markUp(code, lines, start) // 一次采集只上报一次状态
 		}
 	}()
 	return eventChan
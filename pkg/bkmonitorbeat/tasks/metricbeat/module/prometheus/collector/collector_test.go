@@ -79,3 +79,53 @@ metric3:foo:bar{label1="value3"} 12
 		index++
 	}
 }
+
+func TestGetEventsWithScrapeLimit(t *testing.T) {
+	lines := `
+metric1{label1="value1"} 10
+metric2{label1="value2",label2="value2"} 11
+metric3{label1="value3"} 12
+`
+	collect := func(mb *MetricSet) (map[string]int, string) {
+		keys := make(map[string]int)
+		var codeName string
+		for msg := range mb.getEventsFromReader(io.NopCloser(bytes.NewBufferString(lines)), func() {}, true) {
+			key := msg["key"].(string)
+			keys[key]++
+			if key == "bkm_metricbeat_endpoint_up" {
+				codeName = msg["labels"].(common.MapStr)["code_name"].(string)
+			}
+		}
+		return keys, codeName
+	}
+
+	t.Run("NotExceeded", func(t *testing.T) {
+		keys, codeName := collect(&MetricSet{sampleLimit: 3, labelLimit: 3})
+		assert.Equal(t, "Ok", codeName)
+		assert.Equal(t, 1, keys["metric1"])
+		assert.Equal(t, 1, keys["metric2"])
+		assert.Equal(t, 1, keys["metric3"])
+	})
+
+	t.Run("SampleLimitExceeded", func(t *testing.T) {
+		keys, codeName := collect(&MetricSet{sampleLimit: 2})
+		assert.Equal(t, "SampleLimitExceeded", codeName)
+		assert.Equal(t, 0, keys["metric1"])
+		assert.Equal(t, 0, keys["metric3"])
+	})
+
+	t.Run("LabelLimitExceeded", func(t *testing.T) {
+		keys, codeName := collect(&MetricSet{sampleLimit: 3, labelLimit: 2})
+		assert.Equal(t, "LabelLimitExceeded", codeName)
+		assert.Equal(t, 0, keys["metric1"])
+		assert.Equal(t, 0, keys["metric3"])
+	})
+
+	t.Run("LabelLimitExceededWithoutSampleLimit", func(t *testing.T) {
+		keys, codeName := collect(&MetricSet{labelLimit: 2})
+		assert.Equal(t, "LabelLimitExceeded", codeName)
+		assert.Equal(t, 0, keys["metric1"])
+		assert.Equal(t, 0, keys["metric2"])
+		assert.Equal(t, 0, keys["metric3"])
+	})
+}
@@ -123,6 +123,11 @@ func NewHTTPClient(base mb.BaseMetricSet) (*HTTPClient, error) {
 	}, nil
 }
 
+// SetHeader 覆盖请求头，供复用该客户端的其他模块调整 Accept 等头部
+func (cli *HTTPClient) SetHeader(key, value string) {
+	cli.headers[key] = value
+}
+
 func (cli *HTTPClient) FetchResponse() (*http.Response, error) {
 	u, err := url.Parse(cli.base.HostData().SanitizedURI)
 	if err != nil {
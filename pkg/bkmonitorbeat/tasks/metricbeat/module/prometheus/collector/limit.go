@@ -0,0 +1,92 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package collector
+
+import (
+	"sync"
+
+	"github.com/elastic/beats/libbeat/common"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+)
+
+// scrapeLimiter 单次采集的样本数及维度数限制
+// 与 prometheus 行为保持一致 任一限制超限时丢弃本次采集的全部样本 仅通过 up 指标说明原因
+// 因此需要先缓存本次采集的样本 超限后立即释放缓存
+type scrapeLimiter struct {
+	sampleLimit int
+	labelLimit  int
+
+	mut      sync.Mutex
+	samples  int
+	events   []common.MapStr
+	exceeded *define.NamedCode
+}
+
+func newScrapeLimiter(sampleLimit, labelLimit int) *scrapeLimiter {
+	if sampleLimit <= 0 && labelLimit <= 0 {
+		return nil
+	}
+	return &scrapeLimiter{
+		sampleLimit: sampleLimit,
+		labelLimit:  labelLimit,
+	}
+}
+
+func countLabels(event common.MapStr) int {
+	lbs, ok := event["labels"].(common.MapStr)
+	if !ok {
+		return 0
+	}
+	return len(lbs)
+}
+
+// Add 缓存样本 超限后的样本均会被丢弃
+func (l *scrapeLimiter) Add(event common.MapStr) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	l.samples++
+	if l.exceeded != nil {
+		return
+	}
+
+	// 维度数包含指标名 与 prometheus 计算方式保持一致
+	if l.labelLimit > 0 && countLabels(event)+1 > l.labelLimit {
+		l.exceed(define.CodeLabelLimitExceeded)
+		return
+	}
+	if l.sampleLimit > 0 && l.samples > l.sampleLimit {
+		l.exceed(define.CodeSampleLimitExceeded)
+		return
+	}
+	l.events = append(l.events, event)
+}
+
+func (l *scrapeLimiter) exceed(code define.NamedCode) {
+	l.exceeded = &code
+	l.events = nil
+}
+
+// Samples 返回本次采集的样本总数（包含被丢弃的样本）
+func (l *scrapeLimiter) Samples() int {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	return l.samples
+}
+
+// Result 返回缓存的样本 超限时返回对应的状态码
+func (l *scrapeLimiter) Result() ([]common.MapStr, *define.NamedCode) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	return l.events, l.exceeded
+}
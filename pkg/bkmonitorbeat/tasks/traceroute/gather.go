@@ -0,0 +1,288 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceroute
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	// unknownHop 无响应跳的地址占位
+	unknownHop = "*"
+	// customReportBatchSize 自定义上报单批次数据条数
+	customReportBatchSize = 512
+)
+
+// traceResult : 单个目标ip的探测结果
+type traceResult struct {
+	target *configs.Target
+	ip     string
+	hops   []*Hop
+}
+
+// Gather :
+type Gather struct {
+	tasks.BaseTask
+
+	// 记录上一次探测的路径，用于判断路径是否发生变化
+	pathLock sync.Mutex
+	paths    map[string]string
+}
+
+// Run :
+func (g *Gather) Run(ctx context.Context, e chan<- define.Event) {
+	taskConf := g.TaskConfig.(*configs.TracerouteTaskConfig)
+	// 预处理
+	g.PreRun(ctx)
+	defer g.PostRun(ctx)
+
+	// 配置超时context
+	subCtx, cancel := context.WithTimeout(ctx, taskConf.Timeout)
+	defer cancel()
+
+	maxRTT, err := time.ParseDuration(taskConf.MaxRTT)
+	if err != nil {
+		logger.Errorf("parse max rtt failed, error:%s", err)
+		tasks.SendFailEvent(taskConf.GetDataID(), e)
+		return
+	}
+
+	if len(taskConf.Targets) == 0 {
+		// 目标为空则直接返回空
+		logger.Debugf("traceroute targetList is empty")
+		return
+	}
+
+	tracer := NewTracer(TracerOption{
+		Protocol:   taskConf.Protocol,
+		Port:       taskConf.Port,
+		FirstHop:   taskConf.FirstHop,
+		MaxHops:    taskConf.MaxHops,
+		Queries:    taskConf.Queries,
+		MaxRtt:     maxRTT,
+		Size:       taskConf.PacketSize,
+		Privileged: !taskConf.NotPrivileged,
+	})
+
+	// 各目标ip并发探测
+	var (
+		wg           sync.WaitGroup
+		mut          sync.Mutex
+		results      []*traceResult
+		listenFailed bool
+	)
+	for _, target := range taskConf.Targets {
+		ips := resolveTarget(subCtx, taskConf, target)
+		for _, ip := range ips {
+			wg.Add(1)
+			go func(target *configs.Target, ip net.IP) {
+				defer wg.Done()
+
+				hops, err := tracer.Trace(subCtx, ip)
+				if errors.Is(err, ErrListen) {
+					logger.Errorf("traceroute target(%s) ip(%s) failed, error:%v", target.GetTarget(), ip, err)
+					mut.Lock()
+					listenFailed = true
+					mut.Unlock()
+					return
+				}
+				if err != nil {
+					logger.Warnf("traceroute target(%s) ip(%s) not finished, error:%v", target.GetTarget(), ip, err)
+				}
+				if len(hops) == 0 {
+					return
+				}
+
+				mut.Lock()
+				results = append(results, &traceResult{target: target, ip: ip.String(), hops: hops})
+				mut.Unlock()
+			}(target, ip)
+		}
+	}
+	wg.Wait()
+
+	// 监听失败时无法获得任何探测结果，需要上报失败事件
+	if listenFailed {
+		tasks.SendFailEvent(taskConf.GetDataID(), e)
+	}
+
+	// 数据处理
+	events := make([]*tasks.TracerouteEvent, 0)
+	flush := func(force bool) {
+		if len(events) == 0 || (!force && len(events) < customReportBatchSize) {
+			return
+		}
+		e <- tasks.NewCustomEventByTracerouteEvent(events...)
+		events = make([]*tasks.TracerouteEvent, 0)
+	}
+	emit := func(event *tasks.TracerouteEvent) {
+		// 如果需要使用自定义上报，则将事件转换为自定义事件，并分批上报
+		if !taskConf.CustomReport {
+			e <- event
+			return
+		}
+		events = append(events, event)
+		flush(false)
+	}
+
+	for _, result := range results {
+		for _, event := range g.toEvents(taskConf, result) {
+			emit(event)
+		}
+	}
+	flush(true)
+
+	// 任务结束
+	logger.Infof("traceroute task(%d) get %v result", taskConf.TaskID, len(results))
+}
+
+// toEvents : 将探测结果转换为逐跳事件及目标汇总事件
+func (g *Gather) toEvents(taskConf *configs.TracerouteTaskConfig, result *traceResult) []*tasks.TracerouteEvent {
+	now := time.Now()
+
+	// 解析域名时，resolved_ip为解析后的ip
+	resolvedIP := ""
+	if result.target.GetTargetType() == "domain" {
+		resolvedIP = result.ip
+	}
+
+	newEvent := func() *tasks.TracerouteEvent {
+		event := tasks.NewTracerouteEvent(taskConf)
+		event.Time = now
+		event.DataID = taskConf.GetDataID()
+		event.Dimensions = map[string]string{
+			"target":      result.target.GetTarget(),
+			"target_type": result.target.GetTargetType(),
+			"error_code":  "0",
+			"bk_biz_id":   strconv.Itoa(int(taskConf.GetBizID())),
+			"resolved_ip": resolvedIP,
+			"protocol":    taskConf.Protocol,
+		}
+		return event
+	}
+
+	events := make([]*tasks.TracerouteEvent, 0, len(result.hops)+1)
+	addrs := make([]string, 0, len(result.hops))
+	for _, hop := range result.hops {
+		addr := hop.Addr
+		if addr == "" {
+			addr = unknownHop
+		}
+		addrs = append(addrs, addr)
+
+		lossPercent, minRtt, maxRtt, avgRtt := hop.Stats()
+		event := newEvent()
+		event.Dimensions["hop"] = strconv.Itoa(hop.TTL)
+		event.Dimensions["hop_ip"] = addr
+		event.Metrics = map[string]interface{}{
+			"hop_available":    1 - lossPercent,
+			"hop_loss_percent": lossPercent,
+			"hop_max_rtt":      maxRtt,
+			"hop_min_rtt":      minRtt,
+			"hop_avg_rtt":      avgRtt,
+		}
+		events = append(events, event)
+	}
+
+	// 目标汇总，以最后一跳的结果作为目标的可达情况
+	last := result.hops[len(result.hops)-1]
+	reached, lossPercent, avgRtt := 0, 1.0, 0.0
+	if last.Reached {
+		reached = 1
+		lossPercent, _, _, avgRtt = last.Stats()
+	}
+
+	path := strings.Join(addrs, ",")
+	previousPath, changed := g.updatePath(result.target.GetTarget()+"|"+result.ip, path)
+	pathChanged := 0
+	if changed {
+		pathChanged = 1
+		logger.Infof("traceroute target(%s) ip(%s) path changed from [%s] to [%s]", result.target.GetTarget(), result.ip, previousPath, path)
+	}
+
+	event := newEvent()
+	event.Dimensions["path"] = path
+	event.Dimensions["previous_path"] = previousPath
+	event.Metrics = map[string]interface{}{
+		"available":    1 - lossPercent,
+		"loss_percent": lossPercent,
+		"avg_rtt":      avgRtt,
+		"reached":      reached,
+		"hop_count":    len(result.hops),
+		"path_changed": pathChanged,
+	}
+	events = append(events, event)
+
+	// 将target的labels合并到event的dimensions中
+	for _, event := range events {
+		for k, v := range result.target.Labels {
+			if _, ok := event.Dimensions[k]; !ok {
+				event.Dimensions[k] = v
+			}
+		}
+	}
+	return events
+}
+
+// updatePath : 记录最新路径，返回上一次的路径及路径是否发生变化
+func (g *Gather) updatePath(key, path string) (string, bool) {
+	g.pathLock.Lock()
+	defer g.pathLock.Unlock()
+
+	previous, ok := g.paths[key]
+	g.paths[key] = path
+	return previous, ok && previous != path
+}
+
+// resolveTarget : 解析目标ip
+func resolveTarget(ctx context.Context, taskConf *configs.TracerouteTaskConfig, target *configs.Target) []net.IP {
+	if target.GetTargetType() != "domain" {
+		ip := net.ParseIP(target.GetTarget())
+		if ip == nil {
+			logger.Errorf("invalid traceroute target ip:%s", target.GetTarget())
+			return nil
+		}
+		return []net.IP{ip}
+	}
+
+	ips, err := tasks.LookupIP(ctx, taskConf.TargetIPType, target.GetTarget())
+	if err != nil {
+		logger.Errorf("lookup domain ip failed, domain:%s, error:%v", target.GetTarget(), err)
+		return nil
+	}
+	// 如果是单个模式，只取第一个ip
+	if taskConf.DNSCheckMode == configs.CheckModeSingle && len(ips) > 0 {
+		ips = ips[:1]
+	}
+	return ips
+}
+
+// New :
+func New(globalConfig define.Config, taskConfig define.TaskConfig) define.Task {
+	gather := &Gather{
+		paths: make(map[string]string),
+	}
+	gather.GlobalConfig = globalConfig
+	gather.TaskConfig = taskConfig
+	gather.Init()
+
+	return gather
+}
@@ -0,0 +1,125 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceroute
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
+)
+
+func newTestGather() (*Gather, *configs.TracerouteTaskConfig) {
+	globalConf := configs.NewConfig()
+	globalConf.HeartBeat.GlobalDataID = 1000
+	taskConf := configs.NewTracerouteTaskConfig()
+	taskConf.Targets = []*configs.Target{
+		{Target: "127.0.0.1", TargetType: "ip", Labels: map[string]string{"env": "test"}},
+	}
+	taskConf.MaxHops = 3
+	taskConf.Queries = 1
+	taskConf.MaxRTT = "200ms"
+	taskConf.Timeout = 3 * time.Second
+	taskConf.NotPrivileged = true
+	_ = taskConf.Clean()
+
+	return New(globalConf, taskConf).(*Gather), taskConf
+}
+
+func TestGatherToEvents(t *testing.T) {
+	gather, taskConf := newTestGather()
+
+	result := &traceResult{
+		target: taskConf.Targets[0],
+		ip:     "127.0.0.1",
+		hops: []*Hop{
+			{TTL: 1, Addr: "10.0.0.1", RTTs: []time.Duration{time.Millisecond}},
+			{TTL: 2, RTTs: []time.Duration{-1}},
+			{TTL: 3, Addr: "127.0.0.1", RTTs: []time.Duration{2 * time.Millisecond}, Reached: true},
+		},
+	}
+
+	events := gather.toEvents(taskConf, result)
+	assert.Len(t, events, 4)
+	assert.Equal(t, "*", events[1].Dimensions["hop_ip"])
+	assert.Equal(t, 1.0, events[1].Metrics["hop_loss_percent"])
+	assert.Equal(t, "test", events[0].Dimensions["env"])
+
+	summary := events[3]
+	assert.Equal(t, "10.0.0.1,*,127.0.0.1", summary.Dimensions["path"])
+	assert.Equal(t, 1, summary.Metrics["reached"])
+	assert.Equal(t, 3, summary.Metrics["hop_count"])
+	assert.Equal(t, 0, summary.Metrics["path_changed"])
+
+	// 路径发生变化
+	result.hops[0].Addr = "10.0.0.2"
+	events = gather.toEvents(taskConf, result)
+	summary = events[3]
+	assert.Equal(t, 1, summary.Metrics["path_changed"])
+	assert.Equal(t, "10.0.0.1,*,127.0.0.1", summary.Dimensions["previous_path"])
+
+	// 路径保持不变
+	events = gather.toEvents(taskConf, result)
+	assert.Equal(t, 0, events[3].Metrics["path_changed"])
+}
+
+func TestGatherRun(t *testing.T) {
+	gather, taskConf := newTestGather()
+	// 非特权 udp 模式无需监听 icmp，本机目标返回端口不可达即认为到达
+	taskConf.Protocol = configs.TracerouteProtocolUDP
+	taskConf.Port = 33434
+	taskConf.CustomReport = true
+
+	e := make(chan define.Event, 10)
+	gather.Run(context.Background(), e)
+	gather.Wait()
+	close(e)
+
+	events := make([]define.Event, 0)
+	for ev := range e {
+		events = append(events, ev)
+	}
+	assert.Len(t, events, 1)
+
+	custom, ok := events[0].(*tasks.CustomEvent)
+	assert.True(t, ok)
+	assert.Equal(t, define.ModuleTraceroute, custom.GetType())
+	// 到达目标的第一跳及目标汇总
+	assert.Len(t, custom.AsMapStr()["data"], 2)
+}
+
+func TestGatherRunListenFailed(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("privileged user can listen icmp")
+	}
+
+	gather, taskConf := newTestGather()
+	// 非特权模式下无法监听原始 icmp，以特权模式运行时监听失败
+	taskConf.NotPrivileged = false
+
+	e := make(chan define.Event, 10)
+	gather.Run(context.Background(), e)
+	gather.Wait()
+	close(e)
+
+	events := make([]define.Event, 0)
+	for ev := range e {
+		events = append(events, ev)
+	}
+	assert.Len(t, events, 1)
+	_, ok := events[0].(*tasks.StatusEvent)
+	assert.True(t, ok)
+}
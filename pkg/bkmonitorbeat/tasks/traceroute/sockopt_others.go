@@ -0,0 +1,47 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build !windows
+
+package traceroute
+
+import (
+	"syscall"
+)
+
+// setSocketTTL : 在建立连接前设置 socket 的 ttl
+func setSocketTTL(fd uintptr, isIPv6 bool, ttl int) error {
+	if isIPv6 {
+		return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
+	}
+	return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+}
+
+// bindSocket : 绑定随机源端口并返回该端口
+func bindSocket(fd uintptr, isIPv6 bool) (int, error) {
+	var addr syscall.Sockaddr = &syscall.SockaddrInet4{}
+	if isIPv6 {
+		addr = &syscall.SockaddrInet6{}
+	}
+	if err := syscall.Bind(int(fd), addr); err != nil {
+		return 0, err
+	}
+
+	bound, err := syscall.Getsockname(int(fd))
+	if err != nil {
+		return 0, err
+	}
+	switch obj := bound.(type) {
+	case *syscall.SockaddrInet4:
+		return obj.Port, nil
+	case *syscall.SockaddrInet6:
+		return obj.Port, nil
+	}
+	return 0, syscall.EAFNOSUPPORT
+}
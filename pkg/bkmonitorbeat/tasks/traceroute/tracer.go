@@ -0,0 +1,644 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceroute
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	// protocolIPv4ICMP is IANA ICMP IPv4
+	protocolIPv4ICMP = 1
+	// protocolIPv6ICMP is IANA ICMP IPv6
+	protocolIPv6ICMP = 58
+	// protocolTCP is IANA TCP
+	protocolTCP = 6
+	// protocolUDP is IANA UDP
+	protocolUDP = 17
+	// readDeadline is ICMP read deadline
+	readDeadline = 10 * time.Millisecond
+	// udpPortRange 为 udp 模式下目的端口的递增范围
+	udpPortRange = 1024
+)
+
+// ErrListen : icmp 监听失败，通常为权限不足
+var ErrListen = errors.New("listen icmp failed")
+
+// Hop : 单跳探测结果
+type Hop struct {
+	TTL     int
+	Addr    string          // 响应地址，为空表示该跳无响应
+	RTTs    []time.Duration // 每次探测的时延，-1 表示丢包
+	Reached bool            // 是否已经到达目标
+}
+
+// Stats : 丢包率及时延统计，时延单位为毫秒
+func (h *Hop) Stats() (lossPercent, minRtt, maxRtt, avgRtt float64) {
+	if len(h.RTTs) == 0 {
+		return 1, 0, 0, 0
+	}
+
+	lossCount, rttTotal := 0, 0.0
+	for _, rtt := range h.RTTs {
+		if rtt < 0 {
+			lossCount++
+			continue
+		}
+
+		ms := rtt.Seconds() * 1000
+		rttTotal += ms
+		if ms > maxRtt {
+			maxRtt = ms
+		}
+		if ms < minRtt || minRtt == 0 {
+			minRtt = ms
+		}
+	}
+
+	lossPercent = float64(lossCount) / float64(len(h.RTTs))
+	if lossCount < len(h.RTTs) {
+		avgRtt = rttTotal / float64(len(h.RTTs)-lossCount)
+	}
+	return lossPercent, minRtt, maxRtt, avgRtt
+}
+
+// TracerOption : 路径探测参数
+type TracerOption struct {
+	Protocol   string        // 探测协议 icmp/udp/tcp
+	Port       int           // 目的端口，icmp 模式下无效
+	FirstHop   int           // 起始 ttl
+	MaxHops    int           // 最大 ttl
+	Queries    int           // 每跳探测次数
+	MaxRtt     time.Duration // 单次探测最大等待时间
+	Size       int           // 探测包负载大小
+	Privileged bool          // 是否特权模式，非特权模式下无法获取中间跳地址
+}
+
+// Tracer : 路径探测器
+type Tracer struct {
+	opt TracerOption
+
+	id  int
+	seq uint32
+}
+
+// probeResult : 单次探测结果
+type probeResult struct {
+	addr    net.IP
+	rtt     time.Duration
+	reached bool
+}
+
+// matcher : 判断收到的 icmp 报文是否为当前探测的响应，返回是否匹配及是否到达目标
+type matcher func(peer net.IP, msg *icmp.Message) (bool, bool)
+
+// icmpReply : 分发给探测的 icmp 响应
+type icmpReply struct {
+	peer    net.IP
+	reached bool
+	at      time.Time
+}
+
+// waiter : 等待 icmp 响应的单次探测
+type waiter struct {
+	session *session
+	match   matcher
+	reply   chan *icmpReply
+}
+
+// close : 停止等待
+func (w *waiter) close() {
+	if w == nil {
+		return
+	}
+	w.session.lock.Lock()
+	delete(w.session.waiters, w)
+	w.session.lock.Unlock()
+}
+
+// session : 单次 Trace 内各探测共享的 icmp 监听，由 serve 统一读取并分发响应
+type session struct {
+	listener *icmp.PacketConn
+	proto    int
+
+	// 设置 ttl 与发送需要原子完成，避免并发探测互相覆盖 ttl
+	writeLock sync.Mutex
+
+	lock    sync.Mutex
+	waiters map[*waiter]struct{}
+}
+
+// newSession :
+func newSession(listener *icmp.PacketConn, isIPv6 bool) *session {
+	proto := protocolIPv4ICMP
+	if isIPv6 {
+		proto = protocolIPv6ICMP
+	}
+	return &session{
+		listener: listener,
+		proto:    proto,
+		waiters:  make(map[*waiter]struct{}),
+	}
+}
+
+// watch : 注册等待，需要在发送探测前调用，session 为空时返回 nil
+func (s *session) watch(match matcher) *waiter {
+	if s == nil {
+		return nil
+	}
+	w := &waiter{session: s, match: match, reply: make(chan *icmpReply, 1)}
+	s.lock.Lock()
+	s.waiters[w] = struct{}{}
+	s.lock.Unlock()
+	return w
+}
+
+// dispatch : 将响应交给第一个匹配的探测
+func (s *session) dispatch(peer net.IP, msg *icmp.Message, at time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for w := range s.waiters {
+		matched, reached := w.match(peer, msg)
+		if !matched {
+			continue
+		}
+		delete(s.waiters, w)
+		w.reply <- &icmpReply{peer: peer, reached: reached, at: at}
+		return
+	}
+}
+
+// serve : 持续读取 icmp 报文直到 ctx 结束或监听关闭
+func (s *session) serve(ctx context.Context) {
+	buf := make([]byte, 1500)
+	for ctx.Err() == nil {
+		if err := s.listener.SetReadDeadline(time.Now().Add(readDeadline)); err != nil {
+			return
+		}
+
+		n, peer, err := s.listener.ReadFrom(buf)
+		at := time.Now()
+		if err != nil {
+			var netErr *net.OpError
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return
+		}
+
+		msg, err := icmp.ParseMessage(s.proto, buf[:n])
+		if err != nil {
+			continue
+		}
+		peerIP := addrIP(peer)
+		if peerIP == nil {
+			continue
+		}
+		s.dispatch(peerIP, msg, at)
+	}
+}
+
+// NewTracer : 创建路径探测器
+func NewTracer(opt TracerOption) *Tracer {
+	return &Tracer{
+		opt: opt,
+		id:  rand.Intn(0xffff),
+	}
+}
+
+// Trace : 对单个ip进行路径探测，各跳并发探测，耗时约为单次探测最大等待时间
+// 监听失败时返回 ErrListen，超时或取消时未响应的探测按丢包处理
+func (t *Tracer) Trace(ctx context.Context, ip net.IP) ([]*Hop, error) {
+	isIPv6 := ip.To4() == nil
+
+	// icmp 模式总是需要监听，udp/tcp 模式仅在特权模式下监听原始 icmp 差错报文
+	var s *session
+	if t.opt.Protocol == configs.TracerouteProtocolICMP || t.opt.Privileged {
+		conn, err := t.listen(isIPv6)
+		if err != nil {
+			return nil, errors.Wrap(ErrListen, err.Error())
+		}
+		s = newSession(conn, isIPv6)
+
+		serveCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.serve(serveCtx)
+		}()
+		defer func() {
+			cancel()
+			<-done
+			_ = conn.Close()
+		}()
+	}
+
+	hops := make([]*Hop, 0, t.opt.MaxHops-t.opt.FirstHop+1)
+	for ttl := t.opt.FirstHop; ttl <= t.opt.MaxHops; ttl++ {
+		hops = append(hops, &Hop{TTL: ttl, RTTs: make([]time.Duration, t.opt.Queries)})
+	}
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+	)
+	for _, hop := range hops {
+		for i := 0; i < t.opt.Queries; i++ {
+			wg.Add(1)
+			go func(hop *Hop, i int) {
+				defer wg.Done()
+				res := t.probe(ctx, s, ip, hop.TTL)
+
+				lock.Lock()
+				defer lock.Unlock()
+				if res == nil {
+					hop.RTTs[i] = -1
+					return
+				}
+				hop.RTTs[i] = res.rtt
+				if hop.Addr == "" && res.addr != nil {
+					hop.Addr = res.addr.String()
+				}
+				if res.reached {
+					hop.Reached = true
+				}
+			}(hop, i)
+		}
+	}
+	wg.Wait()
+
+	// 到达目标之后的跳没有意义
+	for i, hop := range hops {
+		if hop.Reached {
+			hops = hops[:i+1]
+			break
+		}
+	}
+	return hops, ctx.Err()
+}
+
+// listen : 启动icmp监听
+func (t *Tracer) listen(isIPv6 bool) (*icmp.PacketConn, error) {
+	network, address := "ip4:icmp", "0.0.0.0"
+	if !t.opt.Privileged {
+		network = "udp4"
+	}
+	if isIPv6 {
+		network, address = "ip6:ipv6-icmp", "::"
+		if !t.opt.Privileged {
+			network = "udp6"
+		}
+	}
+	return icmp.ListenPacket(network, address)
+}
+
+// probe : 发送单次探测，无响应时返回nil
+func (t *Tracer) probe(ctx context.Context, s *session, ip net.IP, ttl int) *probeResult {
+	seq := int(atomic.AddUint32(&t.seq, 1) & 0xffff)
+
+	var (
+		res *probeResult
+		err error
+	)
+	switch t.opt.Protocol {
+	case configs.TracerouteProtocolUDP:
+		res, err = t.probeUDP(ctx, s, ip, ttl, seq)
+	case configs.TracerouteProtocolTCP:
+		res, err = t.probeTCP(ctx, s, ip, ttl)
+	default:
+		res, err = t.probeICMP(ctx, s, ip, ttl, seq)
+	}
+	if err != nil {
+		logger.Debugf("traceroute probe failed, ip: %s, ttl: %d, error: %v", ip, ttl, err)
+		return nil
+	}
+	return res
+}
+
+// probeICMP : 发送 icmp echo 探测
+func (t *Tracer) probeICMP(ctx context.Context, s *session, ip net.IP, ttl, seq int) (*probeResult, error) {
+	isIPv6 := ip.To4() == nil
+
+	var icmpType icmp.Type = ipv4.ICMPTypeEcho
+	if isIPv6 {
+		icmpType = ipv6.ICMPTypeEchoRequest
+	}
+
+	payload := make([]byte, t.opt.Size)
+	if len(payload) >= 8 {
+		binary.BigEndian.PutUint64(payload, uint64(time.Now().UnixNano()))
+	}
+	msg, err := (&icmp.Message{
+		Type: icmpType,
+		Code: 0,
+		Body: &icmp.Echo{ID: t.id, Seq: seq, Data: payload},
+	}).Marshal(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var dst net.Addr = &net.IPAddr{IP: ip}
+	if !t.opt.Privileged {
+		dst = &net.UDPAddr{IP: ip}
+	}
+
+	w := s.watch(func(peer net.IP, msg *icmp.Message) (bool, bool) {
+		switch body := msg.Body.(type) {
+		case *icmp.Echo:
+			if msg.Type != ipv4.ICMPTypeEchoReply && msg.Type != ipv6.ICMPTypeEchoReply {
+				return false, false
+			}
+			// 非特权模式下 echo id 会被内核改写
+			if body.Seq != seq || (t.opt.Privileged && body.ID != t.id) {
+				return false, false
+			}
+			return true, true
+		case *icmp.TimeExceeded:
+			return t.matchQuotedEcho(body.Data, isIPv6, ip, seq), false
+		case *icmp.DstUnreach:
+			return t.matchQuotedEcho(body.Data, isIPv6, ip, seq), peer.Equal(ip)
+		}
+		return false, false
+	})
+	defer w.close()
+
+	start, err := s.send(msg, dst, isIPv6, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return t.wait(ctx, w, start, nil)
+}
+
+// send : 以指定 ttl 通过监听发送报文，返回发送时间
+func (s *session) send(msg []byte, dst net.Addr, isIPv6 bool, ttl int) (time.Time, error) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	var err error
+	if isIPv6 {
+		err = s.listener.IPv6PacketConn().SetHopLimit(ttl)
+	} else {
+		err = s.listener.IPv4PacketConn().SetTTL(ttl)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	start := time.Now()
+	_, err = s.listener.WriteTo(msg, dst)
+	return start, err
+}
+
+// probeUDP : 发送 udp 探测，目标返回端口不可达即认为到达
+func (t *Tracer) probeUDP(ctx context.Context, s *session, ip net.IP, ttl, seq int) (*probeResult, error) {
+	isIPv6 := ip.To4() == nil
+
+	port := t.opt.Port + seq%udpPortRange
+	if port > 0xffff {
+		port = t.opt.Port
+	}
+
+	network := "udp4"
+	if isIPv6 {
+		network = "udp6"
+	}
+	conn, err := net.DialUDP(network, nil, &net.UDPAddr{IP: ip, Port: port})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if isIPv6 {
+		err = ipv6.NewConn(conn).SetHopLimit(ttl)
+	} else {
+		err = ipv4.NewConn(conn).SetTTL(ttl)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w := s.watch(func(peer net.IP, msg *icmp.Message) (bool, bool) {
+		switch body := msg.Body.(type) {
+		case *icmp.TimeExceeded:
+			return matchQuotedPort(body.Data, isIPv6, ip, protocolUDP, 0, port), false
+		case *icmp.DstUnreach:
+			return matchQuotedPort(body.Data, isIPv6, ip, protocolUDP, 0, port), peer.Equal(ip)
+		}
+		return false, false
+	})
+	defer w.close()
+
+	start := time.Now()
+	if _, err = conn.Write(make([]byte, t.opt.Size)); err != nil {
+		return nil, err
+	}
+
+	// 已连接的 udp socket 收到端口不可达时读取会返回 ECONNREFUSED，非特权模式下据此判断是否到达
+	sockResult := make(chan *probeResult, 1)
+	go func() {
+		_ = conn.SetReadDeadline(start.Add(t.opt.MaxRtt))
+		_, err := conn.Read(make([]byte, 64))
+		if err == nil || errors.Is(err, syscall.ECONNREFUSED) {
+			sockResult <- &probeResult{addr: ip, rtt: time.Since(start), reached: true}
+			return
+		}
+		sockResult <- nil
+	}()
+
+	return t.wait(ctx, w, start, sockResult)
+}
+
+// probeTCP : 发送 tcp syn 探测，连接建立或被拒绝即认为到达
+func (t *Tracer) probeTCP(ctx context.Context, s *session, ip net.IP, ttl int) (*probeResult, error) {
+	isIPv6 := ip.To4() == nil
+
+	dialCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 各跳并发探测时目的端口相同，需要在连接前绑定源端口，用于区分差错报文对应的探测
+	registered := make(chan *waiter, 1)
+	dialer := &net.Dialer{
+		Timeout: t.opt.MaxRtt,
+		Control: func(network, address string, c syscall.RawConn) error {
+			var (
+				port    int
+				sockErr error
+			)
+			err := c.Control(func(fd uintptr) {
+				if sockErr = setSocketTTL(fd, isIPv6, ttl); sockErr != nil {
+					return
+				}
+				port, sockErr = bindSocket(fd, isIPv6)
+			})
+			if err != nil {
+				return err
+			}
+			if sockErr != nil {
+				return sockErr
+			}
+			registered <- s.watch(func(peer net.IP, msg *icmp.Message) (bool, bool) {
+				switch body := msg.Body.(type) {
+				case *icmp.TimeExceeded:
+					return matchQuotedPort(body.Data, isIPv6, ip, protocolTCP, port, t.opt.Port), false
+				case *icmp.DstUnreach:
+					return matchQuotedPort(body.Data, isIPv6, ip, protocolTCP, port, t.opt.Port), peer.Equal(ip)
+				}
+				return false, false
+			})
+			return nil
+		},
+	}
+
+	start := time.Now()
+	sockResult := make(chan *probeResult, 1)
+	go func() {
+		conn, err := dialer.DialContext(dialCtx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(t.opt.Port)))
+		if err == nil {
+			_ = conn.Close()
+		}
+		if err == nil || errors.Is(err, syscall.ECONNREFUSED) {
+			sockResult <- &probeResult{addr: ip, rtt: time.Since(start), reached: true}
+			return
+		}
+		sockResult <- nil
+	}()
+
+	// 等待源端口绑定完成后再开始等待差错报文
+	var w *waiter
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case w = <-registered:
+	case res := <-sockResult:
+		select {
+		case w = <-registered:
+			w.close()
+		default:
+		}
+		return res, nil
+	}
+	defer w.close()
+
+	return t.wait(ctx, w, start, sockResult)
+}
+
+// wait : 等待探测响应，sockResult 为 socket 层的探测结果，w 为空时仅等待 socket 结果
+func (t *Tracer) wait(ctx context.Context, w *waiter, start time.Time, sockResult <-chan *probeResult) (*probeResult, error) {
+	timer := time.NewTimer(time.Until(start.Add(t.opt.MaxRtt)))
+	defer timer.Stop()
+
+	var replies <-chan *icmpReply
+	if w != nil {
+		replies = w.reply
+	}
+
+	for {
+		if replies == nil && sockResult == nil {
+			return nil, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case res := <-sockResult:
+			if res != nil {
+				return res, nil
+			}
+			// socket 层已经结束但未到达目标，继续等待 icmp 差错报文
+			sockResult = nil
+		case reply := <-replies:
+			return &probeResult{addr: reply.peer, rtt: reply.at.Sub(start), reached: reply.reached}, nil
+		case <-timer.C:
+			return nil, nil
+		}
+	}
+}
+
+// matchQuotedEcho : 判断差错报文中携带的原始报文是否为本次发送的 echo 请求
+func (t *Tracer) matchQuotedEcho(data []byte, isIPv6 bool, ip net.IP, seq int) bool {
+	proto, dst, payload, ok := parseQuoted(data, isIPv6)
+	if !ok || !dst.Equal(ip) || len(payload) < 8 {
+		return false
+	}
+
+	if isIPv6 {
+		if proto != protocolIPv6ICMP || payload[0] != byte(ipv6.ICMPTypeEchoRequest) {
+			return false
+		}
+	} else {
+		if proto != protocolIPv4ICMP || payload[0] != byte(ipv4.ICMPTypeEcho) {
+			return false
+		}
+	}
+
+	if int(binary.BigEndian.Uint16(payload[6:8])) != seq {
+		return false
+	}
+	return !t.opt.Privileged || int(binary.BigEndian.Uint16(payload[4:6])) == t.id
+}
+
+// matchQuotedPort : 判断差错报文中携带的原始报文是否为发往指定目的端口的 tcp/udp 报文，srcPort 为 0 时不校验源端口
+func matchQuotedPort(data []byte, isIPv6 bool, ip net.IP, protocol, srcPort, dstPort int) bool {
+	proto, dst, payload, ok := parseQuoted(data, isIPv6)
+	if !ok || proto != protocol || !dst.Equal(ip) || len(payload) < 4 {
+		return false
+	}
+	if srcPort != 0 && int(binary.BigEndian.Uint16(payload[0:2])) != srcPort {
+		return false
+	}
+	return int(binary.BigEndian.Uint16(payload[2:4])) == dstPort
+}
+
+// parseQuoted : 解析 icmp 差错报文中携带的原始 ip 报文，返回协议号、目的地址及传输层头部
+func parseQuoted(data []byte, isIPv6 bool) (int, net.IP, []byte, bool) {
+	if isIPv6 {
+		if len(data) < ipv6.HeaderLen {
+			return 0, nil, nil, false
+		}
+		return int(data[6]), net.IP(data[24:40]), data[ipv6.HeaderLen:], true
+	}
+
+	if len(data) < ipv4.HeaderLen {
+		return 0, nil, nil, false
+	}
+	headerLen := int(data[0]&0x0f) << 2
+	if headerLen < ipv4.HeaderLen || len(data) < headerLen {
+		return 0, nil, nil, false
+	}
+	return int(data[9]), net.IP(data[16:20]), data[headerLen:], true
+}
+
+// addrIP : 获取地址中的ip
+func addrIP(addr net.Addr) net.IP {
+	switch obj := addr.(type) {
+	case *net.IPAddr:
+		return obj.IP
+	case *net.UDPAddr:
+		return obj.IP
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceroute
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+func TestHopStats(t *testing.T) {
+	hop := &Hop{RTTs: []time.Duration{10 * time.Millisecond, -1, 30 * time.Millisecond, -1}}
+	lossPercent, minRtt, maxRtt, avgRtt := hop.Stats()
+	assert.Equal(t, 0.5, lossPercent)
+	assert.Equal(t, 10.0, minRtt)
+	assert.Equal(t, 30.0, maxRtt)
+	assert.Equal(t, 20.0, avgRtt)
+
+	hop = &Hop{RTTs: []time.Duration{-1, -1}}
+	lossPercent, _, _, avgRtt = hop.Stats()
+	assert.Equal(t, 1.0, lossPercent)
+	assert.Equal(t, 0.0, avgRtt)
+}
+
+func TestMatchQuotedPort(t *testing.T) {
+	ip := net.ParseIP("10.0.0.1").To4()
+
+	// 20 字节 ipv4 头部 + 8 字节 udp 头部
+	data := make([]byte, 28)
+	data[0] = 0x45
+	data[9] = protocolUDP
+	copy(data[16:20], ip)
+	binary.BigEndian.PutUint16(data[20:22], 50000)
+	binary.BigEndian.PutUint16(data[22:24], 33435)
+
+	assert.True(t, matchQuotedPort(data, false, ip, protocolUDP, 0, 33435))
+	assert.False(t, matchQuotedPort(data, false, ip, protocolUDP, 0, 33436))
+	assert.False(t, matchQuotedPort(data, false, ip, protocolTCP, 0, 33435))
+	assert.False(t, matchQuotedPort(data, false, net.ParseIP("10.0.0.2"), protocolUDP, 0, 33435))
+	assert.False(t, matchQuotedPort(data[:10], false, ip, protocolUDP, 0, 33435))
+
+	// 校验源端口
+	assert.True(t, matchQuotedPort(data, false, ip, protocolUDP, 50000, 33435))
+	assert.False(t, matchQuotedPort(data, false, ip, protocolUDP, 50001, 33435))
+}
+
+func TestMatchQuotedEcho(t *testing.T) {
+	ip := net.ParseIP("::1")
+	tracer := NewTracer(TracerOption{Privileged: true})
+
+	// 40 字节 ipv6 头部 + 8 字节 icmp 头部
+	data := make([]byte, 48)
+	data[6] = protocolIPv6ICMP
+	copy(data[24:40], ip)
+	data[40] = 128
+	binary.BigEndian.PutUint16(data[44:46], uint16(tracer.id))
+	binary.BigEndian.PutUint16(data[46:48], 7)
+
+	assert.True(t, tracer.matchQuotedEcho(data, true, ip, 7))
+	assert.False(t, tracer.matchQuotedEcho(data, true, ip, 8))
+
+	binary.BigEndian.PutUint16(data[44:46], uint16(tracer.id+1))
+	assert.False(t, tracer.matchQuotedEcho(data, true, ip, 7))
+}
+
+func TestTraceLocalhost(t *testing.T) {
+	for _, protocol := range []string{configs.TracerouteProtocolICMP, configs.TracerouteProtocolUDP} {
+		tracer := NewTracer(TracerOption{
+			Protocol: protocol,
+			Port:     33434,
+			FirstHop: 1,
+			MaxHops:  3,
+			Queries:  2,
+			MaxRtt:   200 * time.Millisecond,
+			Size:     52,
+		})
+
+		hops, err := tracer.Trace(context.Background(), net.ParseIP("127.0.0.1"))
+		if err != nil {
+			t.Skipf("traceroute not permitted in current environment: %v", err)
+		}
+
+		assert.Len(t, hops, 1, protocol)
+		assert.True(t, hops[0].Reached, protocol)
+		assert.Equal(t, "127.0.0.1", hops[0].Addr, protocol)
+	}
+}
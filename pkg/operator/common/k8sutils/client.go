@@ -22,6 +22,7 @@ import (
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/metadata"
@@ -74,6 +75,15 @@ func NewPromClient(host string, tlsConfig *rest.TLSClientConfig) (promversioned.
 	return promversioned.NewForConfig(cfg)
 }
 
+// NewDynamicClient 操作 prometheus-operator 客户端尚未支持的 CRD（如 ScrapeConfig）
+func NewDynamicClient(host string, tlsConfig *rest.TLSClientConfig) (dynamic.Interface, error) {
+	cfg, err := k8sutil.NewClusterConfig(host, tlsConfig.Insecure, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(cfg)
+}
+
 // NewBKClient 操作 DataID CRD
 func NewBKClient(host string, tlsConfig *rest.TLSClientConfig) (bkversioned.Interface, error) {
 	cfg, err := k8sutil.NewClusterConfig(host, tlsConfig.Insecure, tlsConfig)
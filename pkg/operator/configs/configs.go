@@ -125,6 +125,41 @@ type PromSli struct {
 	Scrape        PromSliScrape `yaml:"prometheus"`
 }
 
+// PromRule PrometheusRule 转换配置 转换结果推送至 metadata 并同时写入 ConfigMap 备查
+type PromRule struct {
+	Namespace         string           `yaml:"namespace"`
+	ConfigMapName     string           `yaml:"configmap_name"`
+	ConfigMapMaxBytes int              `yaml:"configmap_max_bytes"`
+	DefaultInterval   string           `yaml:"default_interval"`
+	Metadata          PromRuleMetadata `yaml:"metadata"`
+}
+
+// PromRuleMetadata metadata 接口配置 api_url 为空时不推送
+type PromRuleMetadata struct {
+	APIURL    string `yaml:"api_url"`
+	AppCode   string `yaml:"app_code"`
+	AppSecret string `yaml:"app_secret"`
+	Timeout   string `yaml:"timeout"`
+}
+
+func setupPromRule(c *Config) {
+	if c.PromRule.Namespace == "" {
+		c.PromRule.Namespace = c.MonitorNamespace
+	}
+	if c.PromRule.ConfigMapName == "" {
+		c.PromRule.ConfigMapName = "bkmonitor-operator-prometheus-rules"
+	}
+	if c.PromRule.ConfigMapMaxBytes <= 0 {
+		c.PromRule.ConfigMapMaxBytes = 900 * 1024 // ConfigMap 上限为 1MiB 预留部分空间给元数据
+	}
+	if c.PromRule.DefaultInterval == "" {
+		c.PromRule.DefaultInterval = "1m" // 与 prometheus 默认 evaluation_interval 保持一致
+	}
+	if c.PromRule.Metadata.Timeout == "" {
+		c.PromRule.Metadata.Timeout = "30s"
+	}
+}
+
 // PromSliScrape prometheus 抓取目标配置
 type PromSliScrape struct {
 	Global    map[string]interface{} `yaml:"global"`
@@ -179,9 +214,18 @@ type Config struct {
 	// EnablePodMonitor 是否启用 podmonitor
 	EnablePodMonitor bool `yaml:"enable_pod_monitor"`
 
+	// EnableProbe 是否启用 probe
+	EnableProbe bool `yaml:"enable_probe"`
+
+	// EnableScrapeConfig 是否启用 scrapeconfig（monitoring.coreos.com/v1alpha1）
+	EnableScrapeConfig bool `yaml:"enable_scrape_config"`
+
 	// EnablePromRule 是否启用 promrules 自监控专用
 	EnablePromRule bool `yaml:"enable_prometheus_rule"`
 
+	// EnablePromRuleTranslate 是否将 PrometheusRule 转换为 bkmonitor 预计算规则及告警策略
+	EnablePromRuleTranslate bool `yaml:"enable_prometheus_rule_translate"`
+
 	// EnableStatefulSetWorker 是否启用 statefulset worker 调度
 	EnableStatefulSetWorker bool `yaml:"enable_statefulset_worker"`
 
@@ -227,6 +271,7 @@ type Config struct {
 	Event       Event        `yaml:"event"`
 	Logger      Logger       `yaml:"logger"`
 	PromSli     PromSli      `yaml:"sli"`
+	PromRule    PromRule     `yaml:"prom_rule"`
 	MetaEnv     env.Metadata `yaml:"meta_env"`
 	PromSDKinds PromSDKinds  `yaml:"prom_sd_kinds"`
 
@@ -316,6 +361,7 @@ func (c *Config) setup() {
 		setupHTTP,
 		setupStatefulSetWorker,
 		setupVCluster,
+		setupPromRule,
 	}
 
 	for _, fn := range funcs {
@@ -18,6 +18,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/elastic/beats/libbeat/common/transport/tlscommon"
@@ -75,6 +76,11 @@ type CommonOptions struct {
 	MatchSelector          map[string]string
 	DropSelector           map[string]string
 	LabelJoinMatcher       *feature.LabelJoinMatcherSpec
+
+	// 采集限制 为 0 表示不限制 sample/label 限制由 bkmonitorbeat 执行
+	SampleLimit uint64
+	LabelLimit  uint64
+	TargetLimit uint64
 }
 
 type BaseDiscover struct {
@@ -93,6 +99,9 @@ type BaseDiscover struct {
 	// 任务配置文件信息 通过 source 进行分组 使用 hash 进行唯一校验
 	childConfigMut    sync.RWMutex
 	childConfigGroups map[string]map[uint64]*ChildConfig // map[targetGroup.Source]map[hash]*ChildConfig
+
+	// 采集目标数量是否处于超限状态 仅在状态变化时输出日志
+	targetLimitExceededState atomic.Bool
 }
 
 func NewBaseDiscover(ctx context.Context, checkFn define.CheckFunc, opts *CommonOptions) *BaseDiscover {
@@ -239,6 +248,13 @@ func (d *BaseDiscover) makeMetricTarget(lbls, origLabels labels.Labels, namespac
 		metricTarget.Params[key] = append(metricTarget.Params[key], params[key]...)
 	}
 
+	// __param_<name> 标签会作为请求参数 与 prometheus 语义保持一致（如 probe 的 target 参数）
+	for _, label := range lbls {
+		if strings.HasPrefix(label.Name, model.ParamLabelPrefix) {
+			metricTarget.Params.Set(strings.TrimPrefix(label.Name, model.ParamLabelPrefix), label.Value)
+		}
+	}
+
 	if d.helper.AccessBasicAuth != nil {
 		username, password, err := d.helper.AccessBasicAuth()
 		if err != nil {
@@ -296,15 +312,62 @@ func (d *BaseDiscover) makeMetricTarget(lbls, origLabels labels.Labels, namespac
 	metricTarget.RelabelIndex = d.opts.RelabelIndex
 	metricTarget.NormalizeMetricName = d.opts.NormalizeMetricName
 	metricTarget.LabelJoinMatcher = d.opts.LabelJoinMatcher
+	metricTarget.SampleLimit = d.opts.SampleLimit
+	metricTarget.LabelLimit = d.opts.LabelLimit
 
 	return metricTarget, nil
 }
 
+// TargetLimitStatus 采集目标数量限制状态
+type TargetLimitStatus struct {
+	Targets int    `json:"targets"`
+	Limit   uint64 `json:"limit"`
+}
+
+// Exceeded 采集目标数量是否超限
+func (s TargetLimitStatus) Exceeded() bool {
+	return s.Limit > 0 && uint64(s.Targets) > s.Limit
+}
+
+func (d *BaseDiscover) TargetLimitStatus() TargetLimitStatus {
+	d.childConfigMut.RLock()
+	defer d.childConfigMut.RUnlock()
+
+	return d.targetLimitStatus()
+}
+
+func (d *BaseDiscover) targetLimitStatus() TargetLimitStatus {
+	var n int
+	for _, group := range d.childConfigGroups {
+		n += len(group)
+	}
+	return TargetLimitStatus{Targets: n, Limit: d.opts.TargetLimit}
+}
+
+// targetLimitExceeded 与 prometheus 行为保持一致 采集目标数量超限时不下发任何采集任务
+func (d *BaseDiscover) targetLimitExceeded() bool {
+	status := d.targetLimitStatus()
+	exceeded := status.Exceeded()
+	if d.targetLimitExceededState.Swap(exceeded) == exceeded {
+		return exceeded
+	}
+
+	if exceeded {
+		logger.Warnf("%s targets count %d exceeded target limit %d", d.Name(), status.Targets, status.Limit)
+	} else {
+		logger.Infof("%s targets count %d back within target limit %d", d.Name(), status.Targets, status.Limit)
+	}
+	return exceeded
+}
+
 func (d *BaseDiscover) StatefulSetChildConfigs() []*ChildConfig {
 	d.childConfigMut.RLock()
 	defer d.childConfigMut.RUnlock()
 
 	cfgs := make([]*ChildConfig, 0)
+	if d.targetLimitExceeded() {
+		return cfgs
+	}
 	for _, group := range d.childConfigGroups {
 		for _, cfg := range group {
 			if cfg.TaskType == tasks.TaskTypeStatefulSet {
@@ -320,6 +383,9 @@ func (d *BaseDiscover) DaemonSetChildConfigs() []*ChildConfig {
 	defer d.childConfigMut.RUnlock()
 
 	cfgs := make([]*ChildConfig, 0)
+	if d.targetLimitExceeded() {
+		return cfgs
+	}
 	for _, group := range d.childConfigGroups {
 		for _, cfg := range group {
 			if cfg.TaskType == tasks.TaskTypeDaemonSet {
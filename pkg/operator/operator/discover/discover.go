@@ -65,4 +65,7 @@ type Discover interface {
 
 	// StatefulSetChildConfigs 获取 statafulset 类型子配置信息
 	StatefulSetChildConfigs() []*ChildConfig
+
+	// TargetLimitStatus 获取采集目标数量限制状态
+	TargetLimitStatus() TargetLimitStatus
 }
@@ -12,8 +12,11 @@ package discover
 import (
 	"testing"
 
+	promconfig "github.com/prometheus/common/config"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/tasks"
 )
 
 func TestForwardAddressCorrectly(t *testing.T) {
@@ -139,3 +142,36 @@ func TestMatchSelector(t *testing.T) {
 		})
 	}
 }
+
+func TestTargetLimitStatus(t *testing.T) {
+	d := &BaseDiscover{
+		opts: &CommonOptions{TargetLimit: 1},
+		childConfigGroups: map[string]map[uint64]*ChildConfig{
+			"source1": {1: {TaskType: tasks.TaskTypeStatefulSet}, 2: {TaskType: tasks.TaskTypeDaemonSet}},
+		},
+	}
+	assert.True(t, d.TargetLimitStatus().Exceeded())
+	assert.Len(t, d.StatefulSetChildConfigs(), 0)
+	assert.Len(t, d.DaemonSetChildConfigs(), 0)
+	assert.True(t, d.targetLimitExceededState.Load())
+
+	d.opts.TargetLimit = 0
+	assert.False(t, d.TargetLimitStatus().Exceeded())
+	assert.Len(t, d.StatefulSetChildConfigs(), 1)
+	assert.Len(t, d.DaemonSetChildConfigs(), 1)
+	assert.False(t, d.targetLimitExceededState.Load())
+}
+
+func TestHashConfigWithSecret(t *testing.T) {
+	newConfig := func(password string) *promconfig.HTTPClientConfig {
+		return &promconfig.HTTPClientConfig{
+			BasicAuth: &promconfig.BasicAuth{
+				Username: "admin",
+				Password: promconfig.Secret(password),
+			},
+		}
+	}
+
+	assert.Equal(t, HashConfig(newConfig("foo")), HashConfig(newConfig("foo")))
+	assert.NotEqual(t, HashConfig(newConfig("foo")), HashConfig(newConfig("bar")))
+}
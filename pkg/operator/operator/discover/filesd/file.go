@@ -0,0 +1,227 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package filesd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	promconfig "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	promfile "github.com/prometheus/prometheus/discovery/file"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/shareddiscovery"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	TypeFileSd = "filesd"
+
+	labelFilepath = model.MetaLabelPrefix + "filepath"
+
+	defaultRefreshInterval = 5 * time.Minute
+)
+
+type Options struct {
+	*discover.CommonOptions
+
+	// SDConfig files 中的每一项格式为 $namespace/$configmap/$key 其中 $key 支持通配符
+	SDConfig         *promfile.SDConfig
+	Client           kubernetes.Interface
+	HTTPClientConfig promconfig.HTTPClientConfig
+}
+
+type Discover struct {
+	*discover.BaseDiscover
+
+	opts *Options
+}
+
+var _ discover.Discover = (*Discover)(nil)
+
+func New(ctx context.Context, checkFn define.CheckFunc, opts *Options) *Discover {
+	d := &Discover{
+		BaseDiscover: discover.NewBaseDiscover(ctx, checkFn, opts.CommonOptions),
+		opts:         opts,
+	}
+
+	d.SetUK(fmt.Sprintf("%s:%s:%d", d.Type(), opts.Name, discover.HashConfig(opts.SDConfig)))
+	d.SetHelper(discover.NewHTTPClientConfigHelper(opts.HTTPClientConfig))
+	return d
+}
+
+func (d *Discover) Type() string {
+	return TypeFileSd
+}
+
+func (d *Discover) Reload() error {
+	d.Stop()
+	return d.Start()
+}
+
+func (d *Discover) Start() error {
+	d.PreStart()
+
+	err := shareddiscovery.Register(d.UK(), func() (*shareddiscovery.SharedDiscovery, error) {
+		interval := time.Duration(d.opts.SDConfig.RefreshInterval)
+		if interval <= 0 {
+			interval = defaultRefreshInterval
+		}
+		discovery := &configMapDiscovery{
+			client:   d.opts.Client,
+			files:    d.opts.SDConfig.Files,
+			interval: interval,
+			sources:  make(map[string]map[string]struct{}),
+		}
+		return shareddiscovery.New(d.UK(), discovery), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	go d.LoopHandle()
+	return nil
+}
+
+// configMapDiscovery 从 ConfigMap 中读取 file_sd 格式的 targetgroups
+type configMapDiscovery struct {
+	client   kubernetes.Interface
+	files    []string
+	interval time.Duration
+	sources  map[string]map[string]struct{} // map[file]map[source]
+}
+
+func (cd *configMapDiscovery) Run(ctx context.Context, ch chan<- []*targetgroup.Group) {
+	ticker := time.NewTicker(cd.interval)
+	defer ticker.Stop()
+
+	cd.refresh(ctx, ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cd.refresh(ctx, ch)
+		}
+	}
+}
+
+func (cd *configMapDiscovery) refresh(ctx context.Context, ch chan<- []*targetgroup.Group) {
+	var tgs []*targetgroup.Group
+	for _, file := range cd.files {
+		groups, err := cd.readConfigMap(ctx, file)
+		if err != nil {
+			// 读取失败时保留上一轮的目标 避免 apiserver 抖动导致目标被删除
+			logger.Errorf("filesd read configmap '%s' failed: %v", file, err)
+			continue
+		}
+
+		current := make(map[string]struct{})
+		for _, tg := range groups {
+			current[tg.Source] = struct{}{}
+		}
+		// 已经消失的 source 需要下发空 targetgroup 通知删除
+		for source := range cd.sources[file] {
+			if _, ok := current[source]; !ok {
+				groups = append(groups, &targetgroup.Group{Source: source})
+			}
+		}
+		cd.sources[file] = current
+		tgs = append(tgs, groups...)
+	}
+
+	if len(tgs) == 0 {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case ch <- tgs:
+	}
+}
+
+// readConfigMap 读取 $namespace/$configmap/$key 匹配的所有 targetgroups
+func (cd *configMapDiscovery) readConfigMap(ctx context.Context, file string) ([]*targetgroup.Group, error) {
+	parts := strings.SplitN(file, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, errors.Errorf("invalid file '%s', want $namespace/$configmap/$key", file)
+	}
+	namespace, name, pattern := parts[0], parts[1], parts[2]
+
+	cm, err := cd.client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(cm.Data))
+	for key := range cm.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var ret []*targetgroup.Group
+	for _, key := range keys {
+		matched, err := filepath.Match(pattern, key)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+
+		groups, err := parseTargetGroups(key, []byte(cm.Data[key]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse key '%s'", key)
+		}
+		for i, tg := range groups {
+			tg.Source = fmt.Sprintf("configmap/%s/%s:%s:%d", namespace, name, key, i)
+			if tg.Labels == nil {
+				tg.Labels = model.LabelSet{}
+			}
+			tg.Labels[labelFilepath] = model.LabelValue(fmt.Sprintf("%s/%s/%s", namespace, name, key))
+		}
+		ret = append(ret, groups...)
+	}
+	return ret, nil
+}
+
+func parseTargetGroups(key string, content []byte) ([]*targetgroup.Group, error) {
+	var tgs []*targetgroup.Group
+	switch ext := filepath.Ext(key); strings.ToLower(ext) {
+	case ".json":
+		if err := json.Unmarshal(content, &tgs); err != nil {
+			return nil, err
+		}
+	case ".yml", ".yaml":
+		if err := yaml.UnmarshalStrict(content, &tgs); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unsupported file extension '%s'", ext)
+	}
+
+	for _, tg := range tgs {
+		if tg == nil {
+			return nil, errors.New("nil target group item found")
+		}
+	}
+	return tgs, nil
+}
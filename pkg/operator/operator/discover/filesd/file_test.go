@@ -0,0 +1,79 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package filesd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapDiscovery(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "targets"},
+		Data: map[string]string{
+			"a.json":    `[{"targets": ["10.0.0.1:9100"], "labels": {"env": "prod"}}]`,
+			"b.yaml":    "- targets: ['10.0.0.2:9100', '10.0.0.3:9100']\n",
+			"c.txt":     "ignored",
+			"d.json":    `[{"targets": ["10.0.0.4:9100"]}]`,
+			"readme.md": "ignored",
+		},
+	}
+	client := fake.NewSimpleClientset(cm)
+
+	cd := &configMapDiscovery{
+		client:   client,
+		files:    []string{"monitoring/targets/*.json", "monitoring/targets/b.yaml", "monitoring/notfound/*.json"},
+		interval: time.Minute,
+		sources:  make(map[string]map[string]struct{}),
+	}
+
+	ctx := context.Background()
+	ch := make(chan []*targetgroup.Group, 1)
+	cd.refresh(ctx, ch)
+	tgs := <-ch
+	assert.Len(t, tgs, 3)
+
+	assert.Equal(t, "configmap/monitoring/targets:a.json:0", tgs[0].Source)
+	assert.Equal(t, "prod", string(tgs[0].Labels["env"]))
+	assert.Equal(t, "monitoring/targets/a.json", string(tgs[0].Labels[labelFilepath]))
+	assert.Equal(t, "configmap/monitoring/targets:d.json:0", tgs[1].Source)
+	assert.Equal(t, "configmap/monitoring/targets:b.yaml:0", tgs[2].Source)
+	assert.Len(t, tgs[2].Targets, 2)
+
+	// 删除 d.json 后需要下发空 targetgroup
+	delete(cm.Data, "d.json")
+	_, err := client.CoreV1().ConfigMaps("monitoring").Update(ctx, cm, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	cd.refresh(ctx, ch)
+	tgs = <-ch
+	assert.Len(t, tgs, 3)
+	assert.Equal(t, "configmap/monitoring/targets:d.json:0", tgs[1].Source)
+	assert.Empty(t, tgs[1].Targets)
+}
+
+func TestParseTargetGroups(t *testing.T) {
+	_, err := parseTargetGroups("a.txt", []byte("foo"))
+	assert.Error(t, err)
+
+	_, err = parseTargetGroups("a.yaml", []byte("- targets: ['a:1']\n  unknown: 1\n"))
+	assert.Error(t, err)
+
+	tgs, err := parseTargetGroups("a.YML", []byte("- targets: ['a:1']\n"))
+	assert.NoError(t, err)
+	assert.Len(t, tgs, 1)
+}
@@ -0,0 +1,59 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package discover
+
+import (
+	"github.com/elastic/beats/libbeat/common/transport/tlscommon"
+	"github.com/mitchellh/hashstructure"
+	promconfig "github.com/prometheus/common/config"
+)
+
+// NewHTTPClientConfigHelper 根据 HTTPClientConfig 生成 Helper 适用于非 kubernetes 类型的 discover
+func NewHTTPClientConfigHelper(cfg promconfig.HTTPClientConfig) Helper {
+	return Helper{
+		AccessBasicAuth: func() (string, string, error) {
+			auth := cfg.BasicAuth
+			if auth != nil {
+				return auth.Username, string(auth.Password), nil
+			}
+			return "", "", nil
+		},
+		AccessBearerToken: func() (string, error) {
+			auth := cfg.Authorization
+			if auth == nil || auth.Type != "Bearer" {
+				return "", nil
+			}
+			return string(auth.Credentials), nil
+		},
+		AccessTlsConfig: func() (*tlscommon.Config, error) {
+			tls := cfg.TLSConfig
+			if len(tls.CAFile) == 0 && len(tls.KeyFile) == 0 && len(tls.CertFile) == 0 {
+				return nil, nil
+			}
+
+			tlsConfig := &tlscommon.Config{
+				CAs: []string{tls.CAFile},
+			}
+			tlsConfig.Certificate.Certificate = tls.CertFile
+			tlsConfig.Certificate.Key = tls.KeyFile
+			return tlsConfig, nil
+		},
+	}
+}
+
+// HashConfig 计算 sdconfig 哈希 配置变更时 shareddiscovery 需要使用新的 uk
+// 直接对结构体字段求哈希 yaml 序列化会将 Secret 掩码为 <secret> 导致密钥变更无法感知
+func HashConfig(cfg interface{}) uint64 {
+	h, err := hashstructure.Hash(cfg, nil)
+	if err != nil {
+		return 0
+	}
+	return h
+}
@@ -32,7 +32,8 @@ import (
 )
 
 const (
-	TypePod = "pod"
+	TypePod     = "pod"
+	TypeIngress = "ingress"
 )
 
 func TypeEndpoints(endpointslice bool) string {
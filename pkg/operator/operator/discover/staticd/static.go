@@ -0,0 +1,119 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package staticd
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/pkg/errors"
+	promconfig "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/shareddiscovery"
+)
+
+const (
+	TypeStatic = "static"
+)
+
+type Options struct {
+	*discover.CommonOptions
+
+	// TargetGroups 静态目标 targetgroup.Source 建议使用 $kind/$namespace/$name 格式 以便解析出 namespace
+	TargetGroups     []*targetgroup.Group
+	HTTPClientConfig promconfig.HTTPClientConfig
+}
+
+type Discover struct {
+	*discover.BaseDiscover
+
+	opts *Options
+}
+
+var _ discover.Discover = (*Discover)(nil)
+
+func New(ctx context.Context, checkFn define.CheckFunc, opts *Options) *Discover {
+	d := &Discover{
+		BaseDiscover: discover.NewBaseDiscover(ctx, checkFn, opts.CommonOptions),
+		opts:         opts,
+	}
+
+	// 静态目标变更时需要生成新的 shareddiscovery 实例 因此 uk 需包含目标内容
+	d.SetUK(fmt.Sprintf("%s:%s:%d", d.Type(), opts.Name, hashTargetGroups(opts.TargetGroups)))
+	d.SetHelper(discover.NewHTTPClientConfigHelper(opts.HTTPClientConfig))
+	return d
+}
+
+func (d *Discover) Type() string {
+	return TypeStatic
+}
+
+func (d *Discover) Reload() error {
+	d.Stop()
+	return d.Start()
+}
+
+func (d *Discover) Start() error {
+	d.PreStart()
+
+	err := shareddiscovery.Register(d.UK(), func() (*shareddiscovery.SharedDiscovery, error) {
+		if len(d.opts.TargetGroups) == 0 {
+			return nil, errors.Errorf("%s: empty target groups", d.Type())
+		}
+		return shareddiscovery.New(d.UK(), staticDiscovery(d.opts.TargetGroups)), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	go d.LoopHandle()
+	return nil
+}
+
+// staticDiscovery 下发一次静态 targetgroups 后阻塞至退出
+// prometheus 自带的 staticDiscoverer 发送完成后会关闭 channel 不适用于 shareddiscovery
+type staticDiscovery []*targetgroup.Group
+
+func (sd staticDiscovery) Run(ctx context.Context, ch chan<- []*targetgroup.Group) {
+	select {
+	case <-ctx.Done():
+		return
+	case ch <- sd:
+	}
+	<-ctx.Done()
+}
+
+func hashTargetGroups(tgs []*targetgroup.Group) uint64 {
+	h := fnv.New64a()
+	for _, tg := range tgs {
+		if tg == nil {
+			continue
+		}
+		h.Write([]byte(tg.Source))
+		for _, lbs := range append([]model.LabelSet{tg.Labels}, tg.Targets...) {
+			names := make([]string, 0, len(lbs))
+			for name := range lbs {
+				names = append(names, string(name))
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				h.Write([]byte(name))
+				h.Write([]byte(lbs[model.LabelName(name)]))
+			}
+		}
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,64 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package staticd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticDiscoveryRun(t *testing.T) {
+	tgs := []*targetgroup.Group{
+		{
+			Source:  "probe/default/test",
+			Targets: []model.LabelSet{{model.AddressLabel: "localhost:9090"}},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan []*targetgroup.Group)
+	done := make(chan struct{})
+	go func() {
+		staticDiscovery(tgs).Run(ctx, ch)
+		close(done)
+	}()
+
+	assert.Equal(t, tgs, <-ch)
+
+	// 下发后不会关闭 channel 直至退出
+	select {
+	case <-done:
+		t.Fatal("static discovery exited before context canceled")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	<-done
+}
+
+func TestHashTargetGroups(t *testing.T) {
+	newTgs := func(addr string) []*targetgroup.Group {
+		return []*targetgroup.Group{
+			{
+				Source:  "probe/default/test",
+				Labels:  model.LabelSet{"namespace": "default", "env": "prod"},
+				Targets: []model.LabelSet{{model.AddressLabel: model.LabelValue(addr)}},
+			},
+		}
+	}
+
+	assert.Equal(t, hashTargetGroups(newTgs("localhost:9090")), hashTargetGroups(newTgs("localhost:9090")))
+	assert.NotEqual(t, hashTargetGroups(newTgs("localhost:9090")), hashTargetGroups(newTgs("localhost:9091")))
+}
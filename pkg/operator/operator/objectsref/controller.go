@@ -13,6 +13,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"sync"
 	"time"
 
@@ -68,6 +69,8 @@ func (oid ObjectID) String() string {
 }
 
 type Objects struct {
+	changeHook
+
 	kind string
 	mut  sync.Mutex
 	objs map[string]Object
@@ -92,14 +95,21 @@ func (o *Objects) Set(obj Object) {
 	o.mut.Lock()
 	defer o.mut.Unlock()
 
+	prev, ok := o.objs[obj.ID.String()]
 	o.objs[obj.ID.String()] = obj
+	if !ok || !reflect.DeepEqual(prev, obj) {
+		o.changed()
+	}
 }
 
 func (o *Objects) Del(oid ObjectID) {
 	o.mut.Lock()
 	defer o.mut.Unlock()
 
-	delete(o.objs, oid.String())
+	if _, ok := o.objs[oid.String()]; ok {
+		delete(o.objs, oid.String())
+		o.changed()
+	}
 }
 
 func (o *Objects) GetByNodeName(nodeName string) []Object {
@@ -239,6 +249,9 @@ type ObjectsController struct {
 	endpointsObjs       *EndpointsMap
 	ingressObjs         *IngressMap
 	bkLogConfigObjs     *BkLogConfigMap
+
+	topo       *topoRecorder
+	topoSignal chan struct{}
 }
 
 func NewController(ctx context.Context, client kubernetes.Interface, mClient metadata.Interface, bkClient bkversioned.Interface) (*ObjectsController, error) {
@@ -247,6 +260,7 @@ func NewController(ctx context.Context, client kubernetes.Interface, mClient met
 		client: client,
 		ctx:    ctx,
 		cancel: cancel,
+		topo:   newTopoRecorder(),
 	}
 
 	var err error
@@ -333,6 +347,9 @@ func NewController(ctx context.Context, client kubernetes.Interface, mClient met
 
 	go controller.recordMetrics()
 
+	controller.watchTopologyChanges()
+	go controller.loopRefreshTopology()
+
 	return controller, nil
 }
 
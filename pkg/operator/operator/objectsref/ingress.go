@@ -11,6 +11,7 @@ package objectsref
 
 import (
 	"context"
+	"reflect"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -33,6 +34,8 @@ type ingressEntity struct {
 type ingressEntities map[string]ingressEntity
 
 type IngressMap struct {
+	changeHook
+
 	mut       sync.Mutex
 	ingresses map[string]ingressEntities
 }
@@ -58,6 +61,10 @@ func (m *IngressMap) Set(ingress ingressEntity) {
 		m.ingresses[ingress.namespace] = make(ingressEntities)
 	}
 
+	prev, ok := m.ingresses[ingress.namespace][ingress.name]
+	if !ok || !reflect.DeepEqual(prev, ingress) {
+		m.changed()
+	}
 	m.ingresses[ingress.namespace][ingress.name] = ingress
 }
 
@@ -66,7 +73,10 @@ func (m *IngressMap) Del(namespace, name string) {
 	defer m.mut.Unlock()
 
 	if objs, ok := m.ingresses[namespace]; ok {
-		delete(objs, name)
+		if _, exist := objs[name]; exist {
+			delete(objs, name)
+			m.changed()
+		}
 	}
 }
 
@@ -84,6 +94,15 @@ func (m *IngressMap) Range(namespace string, visitFunc func(name string, ingress
 	}
 }
 
+func (m *IngressMap) rangeIngresses(visitFunc func(namespace string, ingresses ingressEntities)) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	for k, v := range m.ingresses {
+		visitFunc(k, v)
+	}
+}
+
 func newIngressObjects(ctx context.Context, sharedInformer informers.SharedInformerFactory, resources map[GVRK]struct{}) (*IngressMap, error) {
 	if _, ok := resources[GVRK{
 		Group:    "networking.k8s.io",
@@ -24,6 +24,8 @@ import (
 )
 
 type NodeMap struct {
+	changeHook
+
 	mut         sync.Mutex
 	nodes       map[string]*corev1.Node
 	ips         map[string][]string
@@ -120,11 +122,16 @@ func (n *NodeMap) Set(node *corev1.Node) error {
 		return errors.New("empty node name")
 	}
 
+	_, exist := n.nodes[node.Name]
 	n.nodes[node.Name] = node
 	priorityIP, address, err := k8sutils.GetNodeAddress(*node)
 	if err != nil {
 		return err
 	}
+	// 拓扑仅关注节点及其 IP 忽略心跳等状态更新
+	if !exist || n.priorityIPs[node.Name] != priorityIP {
+		n.changed()
+	}
 	n.priorityIPs[node.Name] = priorityIP
 
 	lst := make([]string, 0)
@@ -139,6 +146,9 @@ func (n *NodeMap) Del(nodeName string) {
 	n.mut.Lock()
 	defer n.mut.Unlock()
 
+	if _, ok := n.nodes[nodeName]; ok {
+		n.changed()
+	}
 	delete(n.nodes, nodeName)
 	delete(n.ips, nodeName)
 }
@@ -11,6 +11,7 @@ package objectsref
 
 import (
 	"context"
+	"reflect"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -35,6 +36,8 @@ type serviceEntity struct {
 type serviceEntities map[string]serviceEntity
 
 type ServiceMap struct {
+	changeHook
+
 	mut      sync.Mutex
 	services map[string]serviceEntities
 }
@@ -76,7 +79,7 @@ func (m *ServiceMap) Set(service *corev1.Service) {
 		return dst
 	}
 
-	m.services[service.Namespace][service.Name] = serviceEntity{
+	entity := serviceEntity{
 		name:            service.Name,
 		namespace:       service.Namespace,
 		kind:            string(service.Spec.Type),
@@ -85,6 +88,12 @@ func (m *ServiceMap) Set(service *corev1.Service) {
 		externalName:    service.Spec.ExternalName,
 		selector:        service.Spec.Selector,
 	}
+	// 拓扑仅关注服务类型及 selector
+	prev, ok := m.services[service.Namespace][service.Name]
+	if !ok || prev.kind != entity.kind || !reflect.DeepEqual(prev.selector, entity.selector) {
+		m.changed()
+	}
+	m.services[service.Namespace][service.Name] = entity
 }
 
 func (m *ServiceMap) Del(service *corev1.Service) {
@@ -92,7 +101,10 @@ func (m *ServiceMap) Del(service *corev1.Service) {
 	defer m.mut.Unlock()
 
 	if objs, ok := m.services[service.Namespace]; ok {
-		delete(objs, service.Name)
+		if _, exist := objs[service.Name]; exist {
+			delete(objs, service.Name)
+			m.changed()
+		}
 	}
 }
 
@@ -0,0 +1,541 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package objectsref
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	topoEdgeOwns      = "owns"      // workload -> workload/pod
+	topoEdgeSchedules = "schedules" // node -> pod
+	topoEdgeSelects   = "selects"   // service -> pod
+	topoEdgeRoutes    = "routes"    // ingress -> service
+
+	TopoEventAdded    = "added"
+	TopoEventUpdated  = "updated"
+	TopoEventDeleted  = "deleted"
+	TopoEventBookmark = "bookmark"
+
+	topoBookmarkInterval   = 30 * time.Second
+	topoMinRefreshInterval = time.Second // 合并短时间内的连续变更 限制拓扑重建频率
+	topoMaxEvents          = 8192
+	topoSubscriberQueue    = 1024
+)
+
+// ErrTopoVersionExpired 请求的版本已不在事件缓存中 客户端需要重新获取全量拓扑
+var ErrTopoVersionExpired = errors.New("topology version expired")
+
+// TopoNode 拓扑图节点
+type TopoNode struct {
+	ID        string            `json:"id"`
+	Kind      string            `json:"kind"`
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name"`
+	Attrs     map[string]string `json:"attrs,omitempty"`
+}
+
+// TopoEdge 拓扑图边 Namespace 为边所属命名空间（集群级别资源之间的边为空）
+type TopoEdge struct {
+	Kind      string `json:"kind"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func (e TopoEdge) ID() string {
+	return e.Kind + "|" + e.From + "|" + e.To
+}
+
+// TopoGraph 拓扑图快照 Version 为快照对应的最新事件版本 可用于增量 watch
+type TopoGraph struct {
+	Version   uint64     `json:"version"`
+	Timestamp int64      `json:"timestamp"`
+	Nodes     []TopoNode `json:"nodes"`
+	Edges     []TopoEdge `json:"edges"`
+}
+
+// TopoEvent 拓扑变更事件
+type TopoEvent struct {
+	Version   uint64    `json:"version"`
+	Timestamp int64     `json:"timestamp"`
+	Type      string    `json:"type"`
+	Node      *TopoNode `json:"node,omitempty"`
+	Edge      *TopoEdge `json:"edge,omitempty"`
+}
+
+func (e TopoEvent) match(namespace string) bool {
+	if namespace == "" || e.Type == TopoEventBookmark {
+		return true
+	}
+	if e.Node != nil {
+		return e.Node.Namespace == namespace
+	}
+	if e.Edge != nil {
+		return e.Edge.Namespace == namespace
+	}
+	return false
+}
+
+// changeHook 资源缓存变更回调 由 informer 事件处理函数触发 用于驱动拓扑更新
+type changeHook struct {
+	fn atomic.Pointer[func()]
+}
+
+// OnChange 注册变更回调
+func (h *changeHook) OnChange(fn func()) {
+	h.fn.Store(&fn)
+}
+
+func (h *changeHook) changed() {
+	if fn := h.fn.Load(); fn != nil {
+		(*fn)()
+	}
+}
+
+func topoNodeID(kind, namespace, name string) string {
+	if namespace == "" {
+		return kind + ":" + name
+	}
+	return kind + ":" + namespace + "/" + name
+}
+
+type topoBuilder struct {
+	nodes map[string]TopoNode
+	edges map[string]TopoEdge
+}
+
+func (b *topoBuilder) addNode(node TopoNode) string {
+	node.ID = topoNodeID(node.Kind, node.Namespace, node.Name)
+	b.nodes[node.ID] = node
+	return node.ID
+}
+
+func (b *topoBuilder) addEdge(edge TopoEdge) {
+	b.edges[edge.ID()] = edge
+}
+
+// buildTopology 根据当前缓存的资源构建全量拓扑
+func (oc *ObjectsController) buildTopology() (map[string]TopoNode, map[string]TopoEdge) {
+	b := &topoBuilder{
+		nodes: make(map[string]TopoNode),
+		edges: make(map[string]TopoEdge),
+	}
+
+	addrs := oc.nodeObjs.Addrs()
+	for _, node := range oc.nodeObjs.GetAll() {
+		attrs := map[string]string{}
+		if ip, ok := addrs[node.Name]; ok {
+			attrs["ip"] = ip
+		}
+		b.addNode(TopoNode{Kind: kindNode, Name: node.Name, Attrs: attrs})
+	}
+
+	addOwnerEdges := func(id string, obj Object) {
+		for _, ref := range obj.OwnerRefs {
+			b.addEdge(TopoEdge{
+				Kind:      topoEdgeOwns,
+				From:      topoNodeID(ref.Kind, obj.ID.Namespace, ref.Name),
+				To:        id,
+				Namespace: obj.ID.Namespace,
+			})
+		}
+	}
+
+	for kind, objs := range oc.objsMap() {
+		for _, obj := range objs.GetAll() {
+			id := b.addNode(TopoNode{Kind: kind, Namespace: obj.ID.Namespace, Name: obj.ID.Name})
+			addOwnerEdges(id, obj)
+		}
+	}
+
+	for _, pod := range oc.podObjs.GetAll() {
+		id := b.addNode(TopoNode{
+			Kind:      kindPod,
+			Namespace: pod.ID.Namespace,
+			Name:      pod.ID.Name,
+			Attrs: map[string]string{
+				"ip":   pod.PodIP,
+				"node": pod.NodeName,
+			},
+		})
+		addOwnerEdges(id, pod)
+		if pod.NodeName != "" {
+			b.addEdge(TopoEdge{
+				Kind:      topoEdgeSchedules,
+				From:      topoNodeID(kindNode, "", pod.NodeName),
+				To:        id,
+				Namespace: pod.ID.Namespace,
+			})
+		}
+	}
+
+	oc.serviceObjs.rangeServices(func(namespace string, services serviceEntities) {
+		var pods []Object
+		for _, svc := range services {
+			id := b.addNode(TopoNode{
+				Kind:      kindService,
+				Namespace: namespace,
+				Name:      svc.name,
+				Attrs:     map[string]string{"type": svc.kind},
+			})
+			if len(svc.selector) == 0 {
+				continue
+			}
+
+			if pods == nil {
+				pods = oc.podObjs.GetByNamespace(namespace)
+			}
+			for _, pod := range pods {
+				if !matchLabels(svc.selector, pod.Labels) {
+					continue
+				}
+				b.addEdge(TopoEdge{
+					Kind:      topoEdgeSelects,
+					From:      id,
+					To:        topoNodeID(kindPod, namespace, pod.ID.Name),
+					Namespace: namespace,
+				})
+			}
+		}
+	})
+
+	oc.ingressObjs.rangeIngresses(func(namespace string, ingresses ingressEntities) {
+		for _, ingress := range ingresses {
+			id := b.addNode(TopoNode{Kind: kindIngress, Namespace: namespace, Name: ingress.name})
+			for _, svc := range ingress.services {
+				b.addEdge(TopoEdge{
+					Kind:      topoEdgeRoutes,
+					From:      id,
+					To:        topoNodeID(kindService, namespace, svc),
+					Namespace: namespace,
+				})
+			}
+		}
+	})
+
+	// 移除端点不存在的边（如 owner 为未监听的自定义资源）
+	for id, edge := range b.edges {
+		_, fromOk := b.nodes[edge.From]
+		_, toOk := b.nodes[edge.To]
+		if !fromOk || !toOk {
+			delete(b.edges, id)
+		}
+	}
+	return b.nodes, b.edges
+}
+
+// topoRecorder 记录拓扑快照并通过对比生成变更事件
+type topoRecorder struct {
+	mut       sync.RWMutex
+	version   uint64
+	timestamp int64
+	nodes     map[string]TopoNode
+	edges     map[string]TopoEdge
+	events    []TopoEvent
+	subs      map[chan TopoEvent]struct{}
+}
+
+func newTopoRecorder() *topoRecorder {
+	return &topoRecorder{
+		nodes: make(map[string]TopoNode),
+		edges: make(map[string]TopoEdge),
+		subs:  make(map[chan TopoEvent]struct{}),
+	}
+}
+
+func (r *topoRecorder) update(nodes map[string]TopoNode, edges map[string]TopoEdge, ts int64) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	var events []TopoEvent
+	newEvent := func(typ string, node *TopoNode, edge *TopoEdge) {
+		r.version++
+		events = append(events, TopoEvent{
+			Version:   r.version,
+			Timestamp: ts,
+			Type:      typ,
+			Node:      node,
+			Edge:      edge,
+		})
+	}
+
+	// 先增加节点再增加边 删除顺序相反 保证消费方按序处理时边的端点总是存在
+	for _, id := range sortedKeys(nodes) {
+		node := nodes[id]
+		prev, ok := r.nodes[id]
+		if !ok {
+			newEvent(TopoEventAdded, &node, nil)
+		} else if !reflect.DeepEqual(prev, node) {
+			newEvent(TopoEventUpdated, &node, nil)
+		}
+	}
+	for _, id := range sortedKeys(edges) {
+		if _, ok := r.edges[id]; !ok {
+			edge := edges[id]
+			newEvent(TopoEventAdded, nil, &edge)
+		}
+	}
+	for _, id := range sortedKeys(r.edges) {
+		if _, ok := edges[id]; !ok {
+			edge := r.edges[id]
+			newEvent(TopoEventDeleted, nil, &edge)
+		}
+	}
+	for _, id := range sortedKeys(r.nodes) {
+		if _, ok := nodes[id]; !ok {
+			node := r.nodes[id]
+			newEvent(TopoEventDeleted, &node, nil)
+		}
+	}
+
+	r.nodes = nodes
+	r.edges = edges
+	r.timestamp = ts
+
+	r.events = append(r.events, events...)
+	if n := len(r.events) - topoMaxEvents; n > 0 {
+		r.events = append([]TopoEvent(nil), r.events[n:]...)
+	}
+
+	for ch := range r.subs {
+		for _, event := range events {
+			select {
+			case ch <- event:
+			default:
+				// 消费过慢的订阅者直接断开 由客户端重新同步
+				logger.Warnf("topology subscriber too slow, closed")
+				delete(r.subs, ch)
+				close(ch)
+			}
+			if _, ok := r.subs[ch]; !ok {
+				break
+			}
+		}
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (r *topoRecorder) graph(namespace string) TopoGraph {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	g := TopoGraph{
+		Version:   r.version,
+		Timestamp: r.timestamp,
+		Nodes:     make([]TopoNode, 0),
+		Edges:     make([]TopoEdge, 0),
+	}
+
+	// 集群级别节点（如 Node）只在被命名空间内的边引用时返回
+	refs := make(map[string]struct{})
+	for _, id := range sortedKeys(r.edges) {
+		edge := r.edges[id]
+		if namespace != "" && edge.Namespace != namespace {
+			continue
+		}
+		g.Edges = append(g.Edges, edge)
+		refs[edge.From] = struct{}{}
+		refs[edge.To] = struct{}{}
+	}
+	for _, id := range sortedKeys(r.nodes) {
+		node := r.nodes[id]
+		if namespace != "" && node.Namespace != namespace {
+			if _, ok := refs[id]; !ok || node.Namespace != "" {
+				continue
+			}
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+	return g
+}
+
+// eventsSince 返回版本号大于 since 的事件 调用方需持有锁
+func (r *topoRecorder) eventsSince(namespace string, since uint64) ([]TopoEvent, error) {
+	if since > r.version {
+		return nil, ErrTopoVersionExpired
+	}
+	if since < r.version && (len(r.events) == 0 || r.events[0].Version > since+1) {
+		return nil, ErrTopoVersionExpired
+	}
+
+	idx := sort.Search(len(r.events), func(i int) bool {
+		return r.events[i].Version > since
+	})
+	ret := make([]TopoEvent, 0)
+	for _, event := range r.events[idx:] {
+		if event.match(namespace) {
+			ret = append(ret, event)
+		}
+	}
+	return ret, nil
+}
+
+func (r *topoRecorder) Events(namespace string, since uint64) ([]TopoEvent, error) {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	return r.eventsSince(namespace, since)
+}
+
+// subscribe 返回 since 之后的历史事件及订阅时的版本号并订阅后续事件 二者之间不会丢失事件
+func (r *topoRecorder) subscribe(namespace string, since uint64) ([]TopoEvent, uint64, chan TopoEvent, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	events, err := r.eventsSince(namespace, since)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	ch := make(chan TopoEvent, topoSubscriberQueue)
+	r.subs[ch] = struct{}{}
+	return events, r.version, ch, nil
+}
+
+func (r *topoRecorder) unsubscribe(ch chan TopoEvent) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if _, ok := r.subs[ch]; ok {
+		delete(r.subs, ch)
+		close(ch)
+	}
+}
+
+func (oc *ObjectsController) refreshTopology() {
+	nodes, edges := oc.buildTopology()
+	oc.topo.update(nodes, edges, time.Now().Unix())
+}
+
+// notifyTopology 资源变更时通知重建拓扑 已有待处理通知时直接返回
+func (oc *ObjectsController) notifyTopology() {
+	select {
+	case oc.topoSignal <- struct{}{}:
+	default:
+	}
+}
+
+// watchTopologyChanges 注册拓扑相关资源的变更回调
+func (oc *ObjectsController) watchTopologyChanges() {
+	oc.topoSignal = make(chan struct{}, 1)
+
+	oc.podObjs.OnChange(oc.notifyTopology)
+	oc.nodeObjs.OnChange(oc.notifyTopology)
+	oc.serviceObjs.OnChange(oc.notifyTopology)
+	oc.ingressObjs.OnChange(oc.notifyTopology)
+	for _, objs := range oc.objsMap() {
+		objs.OnChange(oc.notifyTopology)
+	}
+}
+
+// loopRefreshTopology 由 informer 变更事件驱动拓扑更新 重建频率不超过 topoMinRefreshInterval
+func (oc *ObjectsController) loopRefreshTopology() {
+	oc.refreshTopology()
+	for {
+		select {
+		case <-oc.ctx.Done():
+			return
+
+		case <-oc.topoSignal:
+			oc.refreshTopology()
+		}
+
+		select {
+		case <-oc.ctx.Done():
+			return
+		case <-time.After(topoMinRefreshInterval):
+		}
+	}
+}
+
+// TopologyGraph 返回集群拓扑快照 namespace 为空时返回全部
+func (oc *ObjectsController) TopologyGraph(namespace string) TopoGraph {
+	return oc.topo.graph(namespace)
+}
+
+// TopologyEvents 返回版本号大于 since 的拓扑变更事件
+func (oc *ObjectsController) TopologyEvents(namespace string, since uint64) ([]TopoEvent, error) {
+	return oc.topo.Events(namespace, since)
+}
+
+// WatchTopology 持续推送版本号大于 since 的拓扑变更事件 直至 ctx 结束或 fn 返回错误
+// 历史事件推送后及无变更时按周期推送 bookmark 事件 用于保活及记录该订阅者已消费的最新版本
+func (oc *ObjectsController) WatchTopology(ctx context.Context, namespace string, since uint64, fn func(TopoEvent) error) error {
+	events, version, ch, err := oc.topo.subscribe(namespace, since)
+	if err != nil {
+		return err
+	}
+	defer oc.topo.unsubscribe(ch)
+
+	for _, event := range events {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	// bookmark 版本为该订阅者已消费的最后一个事件版本（包含被命名空间过滤的事件）
+	// 不能使用全局最新版本 否则客户端可能跳过仍在队列中尚未推送的事件
+	bookmark := func() error {
+		return fn(TopoEvent{
+			Version:   version,
+			Timestamp: time.Now().Unix(),
+			Type:      TopoEventBookmark,
+		})
+	}
+
+	// 历史事件推送完成后立即发送一次 bookmark 告知客户端当前版本
+	if err := bookmark(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(topoBookmarkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-ch:
+			if !ok {
+				return ErrTopoVersionExpired
+			}
+			version = event.Version
+			if !event.match(namespace) {
+				continue
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+
+		case <-ticker.C:
+			if err := bookmark(); err != nil {
+				return err
+			}
+		}
+	}
+}
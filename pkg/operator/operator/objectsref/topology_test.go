@@ -0,0 +1,287 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package objectsref
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestTopoController(t *testing.T) *ObjectsController {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	oc := &ObjectsController{
+		ctx:             ctx,
+		cancel:          cancel,
+		podObjs:         NewObjects(kindPod),
+		replicaSetObjs:  NewObjects(kindReplicaSet),
+		deploymentObjs:  NewObjects(kindDeployment),
+		daemonSetObjs:   NewObjects(kindDaemonSet),
+		statefulSetObjs: NewObjects(kindStatefulSet),
+		jobObjs:         NewObjects(kindJob),
+		cronJobObjs:     NewObjects(kindCronJob),
+		nodeObjs:        NewNodeMap(),
+		serviceObjs:     NewServiceMap(),
+		ingressObjs:     NewIngressMap(),
+		topo:            newTopoRecorder(),
+	}
+
+	assert.NoError(t, oc.nodeObjs.Set(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}},
+		},
+	}))
+	oc.deploymentObjs.Set(Object{ID: ObjectID{Namespace: "blueking", Name: "unify-query"}})
+	oc.replicaSetObjs.Set(Object{
+		ID:        ObjectID{Namespace: "blueking", Name: "unify-query-7d9f"},
+		OwnerRefs: []OwnerRef{{Kind: kindDeployment, Name: "unify-query"}},
+	})
+	oc.podObjs.Set(Object{
+		ID:        ObjectID{Namespace: "blueking", Name: "unify-query-7d9f-x1"},
+		OwnerRefs: []OwnerRef{{Kind: kindReplicaSet, Name: "unify-query-7d9f"}},
+		NodeName:  "node-1",
+		PodIP:     "192.168.0.1",
+		Labels:    map[string]string{"app": "unify-query"},
+	})
+	oc.podObjs.Set(Object{
+		ID:       ObjectID{Namespace: "default", Name: "nginx"},
+		NodeName: "node-1",
+		PodIP:    "192.168.0.2",
+		Labels:   map[string]string{"app": "nginx"},
+	})
+	oc.serviceObjs.Set(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "blueking", Name: "unify-query"},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: map[string]string{"app": "unify-query"},
+		},
+	})
+	oc.ingressObjs.Set(ingressEntity{namespace: "blueking", name: "unify-query", services: []string{"unify-query"}})
+	return oc
+}
+
+func TestBuildTopology(t *testing.T) {
+	oc := newTestTopoController(t)
+	nodes, edges := oc.buildTopology()
+
+	assert.Len(t, nodes, 7)
+	assert.Equal(t, map[string]string{"ip": "10.0.0.1"}, nodes["Node:node-1"].Attrs)
+	assert.Equal(t, map[string]string{"ip": "192.168.0.1", "node": "node-1"}, nodes["Pod:blueking/unify-query-7d9f-x1"].Attrs)
+
+	expected := []TopoEdge{
+		{Kind: topoEdgeOwns, From: "Deployment:blueking/unify-query", To: "ReplicaSet:blueking/unify-query-7d9f", Namespace: "blueking"},
+		{Kind: topoEdgeOwns, From: "ReplicaSet:blueking/unify-query-7d9f", To: "Pod:blueking/unify-query-7d9f-x1", Namespace: "blueking"},
+		{Kind: topoEdgeRoutes, From: "Ingress:blueking/unify-query", To: "Service:blueking/unify-query", Namespace: "blueking"},
+		{Kind: topoEdgeSchedules, From: "Node:node-1", To: "Pod:blueking/unify-query-7d9f-x1", Namespace: "blueking"},
+		{Kind: topoEdgeSchedules, From: "Node:node-1", To: "Pod:default/nginx", Namespace: "default"},
+		{Kind: topoEdgeSelects, From: "Service:blueking/unify-query", To: "Pod:blueking/unify-query-7d9f-x1", Namespace: "blueking"},
+	}
+	assert.Len(t, edges, len(expected))
+	for _, edge := range expected {
+		assert.Contains(t, edges, edge.ID())
+	}
+
+	// owner 不存在时不生成边
+	oc.deploymentObjs.Del(ObjectID{Namespace: "blueking", Name: "unify-query"})
+	_, edges = oc.buildTopology()
+	assert.NotContains(t, edges, expected[0].ID())
+}
+
+func TestTopologyGraphNamespace(t *testing.T) {
+	oc := newTestTopoController(t)
+	oc.refreshTopology()
+
+	g := oc.TopologyGraph("default")
+	assert.Len(t, g.Edges, 1)
+	ids := make([]string, 0)
+	for _, node := range g.Nodes {
+		ids = append(ids, node.ID)
+	}
+	assert.Equal(t, []string{"Node:node-1", "Pod:default/nginx"}, ids)
+
+	g = oc.TopologyGraph("")
+	assert.Len(t, g.Nodes, 7)
+	assert.Len(t, g.Edges, 6)
+	assert.Equal(t, uint64(13), g.Version)
+}
+
+func TestTopologyEvents(t *testing.T) {
+	oc := newTestTopoController(t)
+	oc.refreshTopology()
+	version := oc.TopologyGraph("").Version
+
+	events, err := oc.TopologyEvents("", version)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	// 无变更不产生事件
+	oc.refreshTopology()
+	assert.Equal(t, version, oc.TopologyGraph("").Version)
+
+	oc.podObjs.Del(ObjectID{Namespace: "default", Name: "nginx"})
+	oc.podObjs.Set(Object{
+		ID:        ObjectID{Namespace: "blueking", Name: "unify-query-7d9f-x1"},
+		OwnerRefs: []OwnerRef{{Kind: kindReplicaSet, Name: "unify-query-7d9f"}},
+		NodeName:  "node-1",
+		PodIP:     "192.168.0.3",
+		Labels:    map[string]string{"app": "unify-query"},
+	})
+	oc.refreshTopology()
+
+	events, err = oc.TopologyEvents("", version)
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+	assert.Equal(t, TopoEventUpdated, events[0].Type)
+	assert.Equal(t, "192.168.0.3", events[0].Node.Attrs["ip"])
+	assert.Equal(t, TopoEventDeleted, events[1].Type)
+	assert.Equal(t, topoEdgeSchedules, events[1].Edge.Kind)
+	assert.Equal(t, TopoEventDeleted, events[2].Type)
+	assert.Equal(t, "Pod:default/nginx", events[2].Node.ID)
+
+	events, err = oc.TopologyEvents("blueking", version)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	_, err = oc.TopologyEvents("", version+100)
+	assert.ErrorIs(t, err, ErrTopoVersionExpired)
+}
+
+func TestWatchTopology(t *testing.T) {
+	oc := newTestTopoController(t)
+	oc.refreshTopology()
+	version := oc.TopologyGraph("").Version
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan TopoEvent, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- oc.WatchTopology(ctx, "default", version, func(event TopoEvent) error {
+			ch <- event
+			return nil
+		})
+	}()
+
+	// 等待订阅完成后再产生变更
+	assert.Eventually(t, func() bool {
+		oc.topo.mut.RLock()
+		defer oc.topo.mut.RUnlock()
+		return len(oc.topo.subs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	oc.podObjs.Del(ObjectID{Namespace: "default", Name: "nginx"})
+	oc.refreshTopology()
+
+	event := <-ch
+	assert.Equal(t, TopoEventBookmark, event.Type)
+	assert.Equal(t, version, event.Version)
+
+	event = <-ch
+	assert.Equal(t, TopoEventDeleted, event.Type)
+	assert.Equal(t, topoEdgeSchedules, event.Edge.Kind)
+	event = <-ch
+	assert.Equal(t, "Pod:default/nginx", event.Node.ID)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestWatchTopologyBookmarkVersion(t *testing.T) {
+	oc := newTestTopoController(t)
+	oc.refreshTopology()
+	version := oc.TopologyGraph("").Version
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var events []TopoEvent
+	done := make(chan error, 1)
+	go func() {
+		done <- oc.WatchTopology(ctx, "", version-1, func(event TopoEvent) error {
+			events = append(events, event)
+			// 推送历史事件期间产生新变更 bookmark 不能跳过尚未推送的事件
+			if len(events) == 1 {
+				oc.podObjs.Del(ObjectID{Namespace: "default", Name: "nginx"})
+				oc.refreshTopology()
+			}
+			if len(events) == 4 {
+				cancel()
+			}
+			return nil
+		})
+	}()
+	assert.NoError(t, <-done)
+
+	assert.Len(t, events, 4)
+	assert.Equal(t, version, events[0].Version)
+	assert.Equal(t, TopoEventBookmark, events[1].Type)
+	assert.Equal(t, version, events[1].Version)
+	assert.Equal(t, version+1, events[2].Version)
+	assert.Equal(t, version+2, events[3].Version)
+}
+
+func TestTopologyDrivenByChanges(t *testing.T) {
+	oc := newTestTopoController(t)
+	oc.watchTopologyChanges()
+	go oc.loopRefreshTopology()
+
+	assert.Eventually(t, func() bool {
+		return len(oc.TopologyGraph("default").Nodes) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	oc.podObjs.Set(Object{
+		ID:       ObjectID{Namespace: "default", Name: "redis"},
+		NodeName: "node-1",
+		PodIP:    "192.168.0.3",
+	})
+	assert.Eventually(t, func() bool {
+		for _, node := range oc.TopologyGraph("default").Nodes {
+			if node.ID == "Pod:default/redis" {
+				return true
+			}
+		}
+		return false
+	}, 3*topoMinRefreshInterval, 10*time.Millisecond)
+}
+
+func TestTopologyChangeHook(t *testing.T) {
+	oc := newTestTopoController(t)
+	oc.watchTopologyChanges()
+
+	pod := Object{ID: ObjectID{Namespace: "default", Name: "nginx"}, NodeName: "node-1", PodIP: "192.168.0.2", Labels: map[string]string{"app": "nginx"}}
+	oc.podObjs.Set(pod)
+	assert.Len(t, oc.topoSignal, 0) // 内容未变化不触发
+
+	pod.PodIP = "192.168.0.4"
+	oc.podObjs.Set(pod)
+	assert.Len(t, oc.topoSignal, 1)
+	<-oc.topoSignal
+
+	// 仅状态变化的节点更新不触发
+	assert.NoError(t, oc.nodeObjs.Set(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Addresses:  []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}))
+	assert.Len(t, oc.topoSignal, 0)
+
+	oc.serviceObjs.Del(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "blueking", Name: "unify-query"}})
+	assert.Len(t, oc.topoSignal, 1)
+}
@@ -22,6 +22,9 @@ import (
 	promversioned "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned"
 	prominformers "github.com/prometheus-operator/prometheus-operator/pkg/informers"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/tools/cache"
@@ -34,6 +37,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/shareddiscovery"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/objectsref"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/promrule"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/promsli"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
 )
@@ -41,8 +45,14 @@ import (
 const (
 	monitorKindServiceMonitor = "ServiceMonitor"
 	monitorKindPodMonitor     = "PodMonitor"
+	monitorKindProbe          = "Probe"
+	monitorKindScrapeConfig   = "ScrapeConfig"
 	monitorKindHttpSd         = "HttpSd"
 	monitorKindKubernetesSd   = "KubernetesSd"
+	monitorKindFileSd         = "FileSd"
+	monitorKindDnsSd          = "DnsSd"
+	monitorKindConsulSd       = "ConsulSd"
+	monitorKindStaticConfig   = "StaticConfig"
 )
 
 var (
@@ -63,13 +73,17 @@ type Operator struct {
 	mdClient   metadata.Interface
 	promclient promversioned.Interface
 	bkclient   bkversioned.Interface
+	dynClient  dynamic.Interface
 	srv        *http.Server
 
 	serviceMonitorInformer *prominformers.ForResource
 	podMonitorInformer     *prominformers.ForResource
+	probeInformer          *prominformers.ForResource
+	scrapeConfigInformers  []informers.GenericInformer
 
-	promRuleInformer  *prominformers.ForResource
-	promsliController *promsli.Controller
+	promRuleInformer   *prominformers.ForResource
+	promsliController  *promsli.Controller
+	promruleController *promrule.Controller
 
 	statefulSetWorkerScaled time.Time
 	statefulSetWorker       int
@@ -122,6 +136,11 @@ func New(ctx context.Context, buildInfo BuildInfo) (*Operator, error) {
 		return nil, err
 	}
 
+	operator.dynClient, err = k8sutils.NewDynamicClient(apiHost, configs.G().GetTLS())
+	if err != nil {
+		return nil, err
+	}
+
 	operator.discovers = make(map[string]discover.Discover)
 	allNamespaces := map[string]struct{}{}
 	if len(configs.G().TargetNamespaces) == 0 {
@@ -188,7 +207,41 @@ func New(ctx context.Context, buildInfo BuildInfo) (*Operator, error) {
 		}
 	}
 
-	if configs.G().EnablePromRule {
+	if configs.G().EnableProbe {
+		operator.probeInformer, err = prominformers.NewInformersForResource(
+			prominformers.NewMonitoringInformerFactories(
+				allNamespaces,
+				denyTargetNamespaces,
+				operator.promclient,
+				define.ReSyncPeriod,
+				nil,
+			),
+			promv1.SchemeGroupVersion.WithResource(promv1.ProbeName),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "create Probe informer failed")
+		}
+	}
+
+	// ScrapeConfig CRD 可能未安装 此时跳过监听
+	if configs.G().EnableScrapeConfig {
+		if operator.scrapeConfigCRDExists() {
+			for namespace := range allNamespaces {
+				operator.scrapeConfigInformers = append(operator.scrapeConfigInformers, dynamicinformer.NewFilteredDynamicInformer(
+					operator.dynClient,
+					scrapeConfigGVR,
+					namespace,
+					define.ReSyncPeriod,
+					cache.Indexers{},
+					nil,
+				))
+			}
+		} else {
+			logger.Warnf("%s not found, skip watching ScrapeConfig", scrapeConfigGVR)
+		}
+	}
+
+	if configs.G().EnablePromRule || configs.G().EnablePromRuleTranslate {
 		operator.promRuleInformer, err = prominformers.NewInformersForResource(
 			prominformers.NewMonitoringInformerFactories(
 				map[string]struct{}{corev1.NamespaceAll: {}},
@@ -202,6 +255,8 @@ func New(ctx context.Context, buildInfo BuildInfo) (*Operator, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "create PrometheusRule informer failed")
 		}
+	}
+	if configs.G().EnablePromRule {
 		operator.promsliController = promsli.NewController(operator.ctx, operator.client, useEndpointslice)
 	}
 
@@ -212,6 +267,9 @@ func New(ctx context.Context, buildInfo BuildInfo) (*Operator, error) {
 
 	operator.recorder = newRecorder()
 	operator.dw = dataidwatcher.New(operator.ctx, operator.bkclient)
+	if configs.G().EnablePromRuleTranslate {
+		operator.promruleController = promrule.NewController(operator.ctx, operator.client, operator.dw.GetClusterInfo)
+	}
 	operator.mm = newMetricMonitor()
 	operator.statefulSetSecretMap = map[string]struct{}{}
 
@@ -366,7 +424,25 @@ func (c *Operator) Run() error {
 		c.podMonitorInformer.Start(c.ctx.Done())
 	}
 
-	if configs.G().EnablePromRule {
+	if configs.G().EnableProbe {
+		c.probeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.handleProbeAdd,
+			UpdateFunc: c.handleProbeUpdate,
+			DeleteFunc: c.handleProbeDelete,
+		})
+		c.probeInformer.Start(c.ctx.Done())
+	}
+
+	for _, inf := range c.scrapeConfigInformers {
+		inf.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.handleScrapeConfigAdd,
+			UpdateFunc: c.handleScrapeConfigUpdate,
+			DeleteFunc: c.handleScrapeConfigDelete,
+		})
+		go inf.Informer().Run(c.ctx.Done())
+	}
+
+	if configs.G().EnablePromRule || configs.G().EnablePromRuleTranslate {
 		c.promRuleInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 			AddFunc:    c.handlePrometheusRuleAdd,
 			UpdateFunc: c.handlePrometheusRuleUpdate,
@@ -421,6 +497,7 @@ func (c *Operator) waitForCacheSync(ctx context.Context) error {
 	}{
 		{"ServiceMonitor", c.serviceMonitorInformer},
 		{"PodMonitor", c.podMonitorInformer},
+		{"Probe", c.probeInformer},
 		{"PrometheusRule", c.promRuleInformer},
 	} {
 		// 跳过没有初始化的 informers
@@ -434,6 +511,12 @@ func (c *Operator) waitForCacheSync(ctx context.Context) error {
 		}
 	}
 
+	for _, inf := range c.scrapeConfigInformers {
+		if !k8sutils.WaitForNamedCacheSync(ctx, "ScrapeConfig", inf.Informer()) {
+			ok = false
+		}
+	}
+
 	if !ok {
 		return errors.New("failed to sync Monitor caches")
 	}
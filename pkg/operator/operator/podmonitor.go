@@ -202,6 +202,9 @@ func (c *Operator) createPodMonitorDiscovers(podMonitor *promv1.PodMonitor) []di
 				System:                 systemResource,
 				UrlValues:              endpoint.Params,
 				MetricRelabelConfigs:   metricRelabelings,
+				SampleLimit:            podMonitor.Spec.SampleLimit,
+				LabelLimit:             podMonitor.Spec.LabelLimit,
+				TargetLimit:            podMonitor.Spec.TargetLimit,
 			},
 			Client:            c.client,
 			Namespaces:        namespaces,
@@ -0,0 +1,351 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package operator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	promconfig "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/feature"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/k8sutils"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/kubernetesd"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/staticd"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	defaultProbePath   = "/probe"
+	defaultProbeScheme = "http"
+)
+
+func probeID(obj *promv1.Probe) string {
+	return fmt.Sprintf("%s/%s", obj.Namespace, obj.Name)
+}
+
+func (c *Operator) handleProbeAdd(obj interface{}) {
+	probe, ok := obj.(*promv1.Probe)
+	if !ok {
+		logger.Errorf("expected Probe type, got %T", obj)
+		return
+	}
+
+	// 新增的 probe 命中黑名单则流程终止
+	if ifRejectProbe(probe) {
+		logger.Infof("add action match blacklist rules, probe=%s", probeID(probe))
+		return
+	}
+
+	discovers := c.createProbeDiscovers(probe)
+	for _, dis := range discovers {
+		if err := c.addOrUpdateDiscover(dis); err != nil {
+			logger.Errorf("add or update probe discover %s failed: %s", dis, err)
+		}
+	}
+}
+
+func (c *Operator) handleProbeUpdate(oldObj interface{}, newObj interface{}) {
+	old, ok := oldObj.(*promv1.Probe)
+	if !ok {
+		logger.Errorf("expected Probe type, got %T", oldObj)
+		return
+	}
+	cur, ok := newObj.(*promv1.Probe)
+	if !ok {
+		logger.Errorf("expected Probe type, got %T", newObj)
+		return
+	}
+
+	if old.ResourceVersion == cur.ResourceVersion {
+		logger.Debugf("probe '%s' does not change", probeID(old))
+		return
+	}
+
+	// 对于更新的 probe 如果新的 spec 命中黑名单 则需要将原有的 probe 移除
+	if ifRejectProbe(cur) {
+		logger.Infof("update action match blacklist rules, probe=%s", probeID(cur))
+		for _, name := range c.getProbeDiscoversName(cur) {
+			c.deleteDiscoverByName(name)
+		}
+		return
+	}
+
+	for _, name := range c.getProbeDiscoversName(old) {
+		c.deleteDiscoverByName(name)
+	}
+	for _, dis := range c.createProbeDiscovers(cur) {
+		if err := c.addOrUpdateDiscover(dis); err != nil {
+			logger.Errorf("add or update probe discover %s failed: %s", dis, err)
+		}
+	}
+}
+
+func (c *Operator) handleProbeDelete(obj interface{}) {
+	probe, ok := obj.(*promv1.Probe)
+	if !ok {
+		logger.Errorf("expected Probe type, got %T", obj)
+		return
+	}
+
+	for _, name := range c.getProbeDiscoversName(probe) {
+		c.deleteDiscoverByName(name)
+	}
+}
+
+// getProbeDiscoversName 每个 probe 至多生成一个 discover（staticConfig 优先于 ingress）
+func (c *Operator) getProbeDiscoversName(probe *promv1.Probe) []string {
+	monitorMeta := define.MonitorMeta{
+		Name:      probe.Name,
+		Kind:      monitorKindProbe,
+		Namespace: probe.Namespace,
+	}
+	return []string{monitorMeta.ID()}
+}
+
+func (c *Operator) createProbeDiscovers(probe *promv1.Probe) []discover.Discover {
+	var discovers []discover.Discover
+
+	if probe.Spec.ProberSpec.URL == "" {
+		logger.Errorf("probe '%s' prober.url is required", probeID(probe))
+		return discovers
+	}
+	if err := probe.Spec.Targets.Validate(); err != nil {
+		logger.Errorf("probe '%s' invalid targets: %v", probeID(probe), err)
+		return discovers
+	}
+
+	systemResource := feature.IfSystemResource(probe.Annotations)
+	meta := define.MonitorMeta{
+		Name:      probe.Name,
+		Kind:      monitorKindProbe,
+		Namespace: probe.Namespace,
+	}
+	dataID, err := c.dw.MatchMetricDataID(meta, systemResource)
+	if err != nil {
+		logger.Errorf("probe(%+v) no dataid matched", meta)
+		return discovers
+	}
+	specLabels := dataID.Spec.Labels
+
+	path := probe.Spec.ProberSpec.Path
+	if path == "" {
+		path = defaultProbePath
+	}
+	scheme := probe.Spec.ProberSpec.Scheme
+	if scheme == "" {
+		scheme = defaultProbeScheme
+	}
+
+	var urlValues url.Values
+	if probe.Spec.Module != "" {
+		urlValues = url.Values{"module": []string{probe.Spec.Module}}
+	}
+
+	metricRelabelings := make([]yaml.MapSlice, 0)
+	for _, cfg := range probe.Spec.MetricRelabelConfigs {
+		metricRelabelings = append(metricRelabelings, generatePromv1RelabelConfig(cfg))
+	}
+
+	var relabels []yaml.MapSlice
+	staticConfig := probe.Spec.Targets.StaticConfig
+	if staticConfig != nil {
+		relabels = getProbeStaticRelabels(probe)
+	} else {
+		relabels = getProbeIngressRelabels(probe)
+	}
+	logger.Debugf("probe '%s' get relabels: %v", probeID(probe), relabels)
+
+	resultLabels, err := yamlToRelabels(relabels)
+	if err != nil {
+		logger.Errorf("failed to convert relabels, err: %s", err)
+		return discovers
+	}
+
+	logger.Infof("found new probe '%s'", probeID(probe))
+	commonOpts := &discover.CommonOptions{
+		MonitorMeta:          meta,
+		RelabelRule:          feature.RelabelRule(probe.Annotations),
+		RelabelIndex:         feature.RelabelIndex(probe.Annotations),
+		NormalizeMetricName:  feature.IfNormalizeMetricName(probe.Annotations),
+		AntiAffinity:         feature.IfAntiAffinity(probe.Annotations),
+		MatchSelector:        feature.MonitorMatchSelector(probe.Annotations),
+		DropSelector:         feature.MonitorDropSelector(probe.Annotations),
+		LabelJoinMatcher:     feature.LabelJoinMatcher(probe.Annotations),
+		Name:                 meta.ID(),
+		DataID:               dataID,
+		Relabels:             resultLabels,
+		Path:                 path,
+		Scheme:               scheme,
+		Period:               string(probe.Spec.Interval),
+		Timeout:              string(probe.Spec.ScrapeTimeout),
+		ProxyURL:             probe.Spec.ProberSpec.ProxyURL,
+		ExtraLabels:          specLabels,
+		System:               systemResource,
+		UrlValues:            urlValues,
+		MetricRelabelConfigs: metricRelabelings,
+		SampleLimit:          probe.Spec.SampleLimit,
+		LabelLimit:           probe.Spec.LabelLimit,
+		TargetLimit:          probe.Spec.TargetLimit,
+	}
+
+	var safeTlsConfig *promv1.SafeTLSConfig
+	if probe.Spec.TLSConfig != nil {
+		safeTlsConfig = probe.Spec.TLSConfig.SafeTLSConfig.DeepCopy()
+	}
+
+	// 静态目标
+	if staticConfig != nil {
+		httpClientConfig, err := c.resolveSafeHTTPClientConfig(probe.Namespace, probe.Spec.BasicAuth, &probe.Spec.BearerTokenSecret, safeTlsConfig)
+		if err != nil {
+			logger.Errorf("probe '%s' resolve http client config failed: %v", probeID(probe), err)
+			return discovers
+		}
+
+		dis := staticd.New(c.ctx, c.objectsController.NodeNameExists, &staticd.Options{
+			CommonOptions:    commonOpts,
+			TargetGroups:     probeStaticTargetGroups(probe),
+			HTTPClientConfig: httpClientConfig,
+		})
+		logger.Infof("create new probe static discover: %s", dis.Name())
+		return append(discovers, dis)
+	}
+
+	// ingress 目标
+	ingress := probe.Spec.Targets.Ingress
+	var namespaces []string
+	if ingress.NamespaceSelector.Any {
+		namespaces = []string{}
+	} else if len(ingress.NamespaceSelector.MatchNames) == 0 {
+		namespaces = []string{probe.Namespace}
+	} else {
+		namespaces = ingress.NamespaceSelector.MatchNames
+	}
+
+	var tlsConfig *promv1.TLSConfig
+	if safeTlsConfig != nil {
+		tlsConfig = &promv1.TLSConfig{SafeTLSConfig: *safeTlsConfig}
+	}
+
+	dis := kubernetesd.New(c.ctx, kubernetesd.TypeIngress, c.objectsController.NodeNameExists, &kubernetesd.Options{
+		CommonOptions:     commonOpts,
+		Client:            c.client,
+		Namespaces:        namespaces,
+		KubeConfig:        configs.G().KubeConfig,
+		BasicAuth:         probe.Spec.BasicAuth.DeepCopy(),
+		BearerTokenSecret: probe.Spec.BearerTokenSecret.DeepCopy(),
+		TLSConfig:         tlsConfig,
+		UseEndpointSlice:  useEndpointslice,
+	})
+	logger.Infof("create new probe ingress discover: %s", dis.Name())
+	return append(discovers, dis)
+}
+
+// probeStaticTargetGroups 生成静态目标 targetgroup source 遵循 $kind/$namespace/$name 格式
+func probeStaticTargetGroups(probe *promv1.Probe) []*targetgroup.Group {
+	staticConfig := probe.Spec.Targets.StaticConfig
+	tg := &targetgroup.Group{
+		Source: fmt.Sprintf("%s/%s/%s", strings.ToLower(monitorKindProbe), probe.Namespace, probe.Name),
+		Labels: model.LabelSet{"namespace": model.LabelValue(probe.Namespace)},
+	}
+	for k, v := range staticConfig.Labels {
+		tg.Labels[model.LabelName(k)] = model.LabelValue(v)
+	}
+	for _, target := range staticConfig.Targets {
+		tg.Targets = append(tg.Targets, model.LabelSet{model.AddressLabel: model.LabelValue(target)})
+	}
+	return []*targetgroup.Group{tg}
+}
+
+// resolveSafeHTTPClientConfig 从 secrets 中读取认证信息并转换为 HTTPClientConfig
+// 由于结果在 discover 创建时生成 secrets 变更需要等待监控资源更新后才会生效
+func (c *Operator) resolveSafeHTTPClientConfig(namespace string, basicAuth *promv1.BasicAuth, bearerTokenSecret *corev1.SecretKeySelector, tlsConfig *promv1.SafeTLSConfig) (promconfig.HTTPClientConfig, error) {
+	var cfg promconfig.HTTPClientConfig
+	secretClient := c.client.CoreV1().Secrets(namespace)
+
+	if basicAuth != nil && basicAuth.Username.Name != "" && basicAuth.Password.Name != "" {
+		username, err := k8sutils.GetSecretDataBySecretKeySelector(c.ctx, secretClient, basicAuth.Username)
+		if err != nil {
+			return cfg, err
+		}
+		password, err := k8sutils.GetSecretDataBySecretKeySelector(c.ctx, secretClient, basicAuth.Password)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.BasicAuth = &promconfig.BasicAuth{
+			Username: username,
+			Password: promconfig.Secret(password),
+		}
+	}
+
+	if bearerTokenSecret != nil && bearerTokenSecret.Name != "" && bearerTokenSecret.Key != "" {
+		token, err := k8sutils.GetSecretDataBySecretKeySelector(c.ctx, secretClient, *bearerTokenSecret)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Authorization = &promconfig.Authorization{
+			Type:        "Bearer",
+			Credentials: promconfig.Secret(token),
+		}
+	}
+
+	if tlsConfig == nil {
+		return cfg, nil
+	}
+
+	// 证书内容以 base64:// 形式传递给采集器
+	load := func(selector *corev1.SecretKeySelector) (string, error) {
+		if selector == nil {
+			return "", nil
+		}
+		s, err := k8sutils.GetSecretDataBySecretKeySelector(c.ctx, secretClient, *selector)
+		if err != nil {
+			return "", err
+		}
+		return "base64://" + base64.StdEncoding.EncodeToString([]byte(s)), nil
+	}
+
+	var err error
+	if cfg.TLSConfig.CAFile, err = load(tlsConfig.CA.Secret); err != nil {
+		return cfg, err
+	}
+	if cfg.TLSConfig.CertFile, err = load(tlsConfig.Cert.Secret); err != nil {
+		return cfg, err
+	}
+	if cfg.TLSConfig.KeyFile, err = load(tlsConfig.KeySecret); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func ifRejectProbe(monitor *promv1.Probe) bool {
+	if monitor == nil {
+		return false
+	}
+	for _, rule := range configs.G().MonitorBlacklistMatchRules {
+		if !rule.Validate() {
+			continue
+		}
+		if strings.ToUpper(rule.Kind) == strings.ToUpper(monitorKindProbe) && rule.Namespace == monitor.Namespace && rule.Name == monitor.Name {
+			return true
+		}
+	}
+	return false
+}
@@ -22,7 +22,12 @@ func (c *Operator) handlePrometheusRuleAdd(obj interface{}) {
 		return
 	}
 
-	c.promsliController.UpdatePrometheusRule(promRule)
+	if c.promsliController != nil {
+		c.promsliController.UpdatePrometheusRule(promRule)
+	}
+	if c.promruleController != nil {
+		c.promruleController.UpdatePrometheusRule(promRule)
+	}
 }
 
 func (c *Operator) handlePrometheusRuleUpdate(_ interface{}, obj interface{}) {
@@ -32,7 +37,12 @@ func (c *Operator) handlePrometheusRuleUpdate(_ interface{}, obj interface{}) {
 		return
 	}
 
-	c.promsliController.UpdatePrometheusRule(promRule)
+	if c.promsliController != nil {
+		c.promsliController.UpdatePrometheusRule(promRule)
+	}
+	if c.promruleController != nil {
+		c.promruleController.UpdatePrometheusRule(promRule)
+	}
 }
 
 func (c *Operator) handlePrometheusRuleDelete(obj interface{}) {
@@ -42,5 +52,10 @@ func (c *Operator) handlePrometheusRuleDelete(obj interface{}) {
 		return
 	}
 
-	c.promsliController.DeletePrometheusRule(promRule)
+	if c.promsliController != nil {
+		c.promsliController.DeletePrometheusRule(promRule)
+	}
+	if c.promruleController != nil {
+		c.promruleController.DeletePrometheusRule(promRule)
+	}
 }
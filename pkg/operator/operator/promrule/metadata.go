@@ -0,0 +1,316 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package promrule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	ruleTypePrometheus = "prometheus"
+
+	dataSourcePrometheus = "prometheus"
+	dataTypeTimeSeries   = "time_series"
+)
+
+// metadataRecordRule metadata 预计算规则 rule_config 为单条规则对应的 prometheus rule group 配置
+type metadataRecordRule struct {
+	ID         string `json:"id"`
+	Source     string `json:"source"`
+	RecordName string `json:"record_name"`
+	RuleType   string `json:"rule_type"`
+	RuleConfig string `json:"rule_config"`
+}
+
+type ruleGroups struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+type ruleGroup struct {
+	Name     string       `yaml:"name"`
+	Interval string       `yaml:"interval"`
+	Rules    []recordItem `yaml:"rules"`
+}
+
+type recordItem struct {
+	Record string            `yaml:"record"`
+	Expr   string            `yaml:"expr"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// metadataStrategy bkmonitor 告警策略 表达式有数据返回即触发 与 prometheus alert 语义一致
+type metadataStrategy struct {
+	ID          string            `json:"id"`
+	Source      string            `json:"source"`
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Items       []strategyItem    `json:"items"`
+	Detects     []strategyDetect  `json:"detects"`
+}
+
+type strategyItem struct {
+	Name         string                `json:"name"`
+	QueryConfigs []strategyQueryConfig `json:"query_configs"`
+}
+
+type strategyQueryConfig struct {
+	DataSourceLabel string `json:"data_source_label"`
+	DataTypeLabel   string `json:"data_type_label"`
+	PromQL          string `json:"promql"`
+	AggInterval     int    `json:"agg_interval"`
+}
+
+type strategyDetect struct {
+	Level          int                    `json:"level"`
+	TriggerConfig  strategyTriggerConfig  `json:"trigger_config"`
+	RecoveryConfig strategyRecoveryConfig `json:"recovery_config"`
+}
+
+type strategyTriggerConfig struct {
+	Count       int `json:"count"`
+	CheckWindow int `json:"check_window"`
+}
+
+type strategyRecoveryConfig struct {
+	CheckWindow int `json:"check_window"`
+}
+
+// syncRequest 全量同步请求 metadata 侧以集群为单位替换 operator 管理的规则 空列表即删除
+type syncRequest struct {
+	BcsClusterID string               `json:"bcs_cluster_id"`
+	BkBizID      string               `json:"bk_biz_id"`
+	RecordRules  []metadataRecordRule `json:"record_rules"`
+	Strategies   []metadataStrategy   `json:"strategies"`
+}
+
+type syncResponse struct {
+	Result  bool   `json:"result"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func toMetadataRecordRule(rule RecordRule) (metadataRecordRule, error) {
+	b, err := yaml.Marshal(ruleGroups{
+		Groups: []ruleGroup{{
+			Name:     rule.Group,
+			Interval: rule.Interval,
+			Rules: []recordItem{{
+				Record: rule.Record,
+				Expr:   rule.Expr,
+				Labels: rule.Labels,
+			}},
+		}},
+	})
+	if err != nil {
+		return metadataRecordRule{}, err
+	}
+
+	return metadataRecordRule{
+		ID:         rule.ID,
+		Source:     rule.Source,
+		RecordName: rule.Record,
+		RuleType:   ruleTypePrometheus,
+		RuleConfig: string(b),
+	}, nil
+}
+
+// triggerCount 持续 for 时长才告警 对应连续 for/interval+1 个周期命中
+func triggerCount(interval, forDuration string) int {
+	if forDuration == "" {
+		return 1
+	}
+	i, err := model.ParseDuration(interval)
+	if err != nil || i <= 0 {
+		return 1
+	}
+	f, err := model.ParseDuration(forDuration)
+	if err != nil {
+		return 1
+	}
+	return int(time.Duration(f)/time.Duration(i)) + 1
+}
+
+func toMetadataStrategy(strategy Strategy) metadataStrategy {
+	var aggInterval int
+	if d, err := model.ParseDuration(strategy.Interval); err == nil {
+		aggInterval = int(time.Duration(d).Seconds())
+	}
+	count := triggerCount(strategy.Interval, strategy.For)
+
+	return metadataStrategy{
+		ID:          strategy.ID,
+		Source:      strategy.Source,
+		Name:        strategy.Name,
+		Labels:      strategy.Labels,
+		Annotations: strategy.Annotations,
+		Items: []strategyItem{{
+			Name: strategy.Name,
+			QueryConfigs: []strategyQueryConfig{{
+				DataSourceLabel: dataSourcePrometheus,
+				DataTypeLabel:   dataTypeTimeSeries,
+				PromQL:          strategy.Expr,
+				AggInterval:     aggInterval,
+			}},
+		}},
+		Detects: []strategyDetect{{
+			Level: strategy.Level,
+			TriggerConfig: strategyTriggerConfig{
+				Count:       count,
+				CheckWindow: count,
+			},
+			RecoveryConfig: strategyRecoveryConfig{
+				CheckWindow: count,
+			},
+		}},
+	}
+}
+
+// newSyncRequest 按 PrometheusRule 标识排序生成请求 保证内容未变更时哈希稳定
+func newSyncRequest(info *define.ClusterInfo, results map[string]*Result) (*syncRequest, error) {
+	ids := make([]string, 0, len(results))
+	for id := range results {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	req := &syncRequest{
+		BcsClusterID: info.BcsClusterID,
+		BkBizID:      info.BizID,
+		RecordRules:  make([]metadataRecordRule, 0),
+		Strategies:   make([]metadataStrategy, 0),
+	}
+	for _, id := range ids {
+		for _, rule := range results[id].RecordRules {
+			r, err := toMetadataRecordRule(rule)
+			if err != nil {
+				return nil, errors.Wrapf(err, "translate record rule '%s' failed", rule.ID)
+			}
+			req.RecordRules = append(req.RecordRules, r)
+		}
+		for _, strategy := range results[id].Strategies {
+			req.Strategies = append(req.Strategies, toMetadataStrategy(strategy))
+		}
+	}
+	return req, nil
+}
+
+// metadataPusher 将转换结果推送至 metadata 仅在内容变更或上次推送失败时请求
+type metadataPusher struct {
+	cfg     configs.PromRuleMetadata
+	client  *http.Client
+	prev    uint64
+	pending bool
+}
+
+func newMetadataPusher(cfg configs.PromRuleMetadata) *metadataPusher {
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil || timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &metadataPusher{
+		cfg:    cfg,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Enabled 未配置 api_url 时不推送
+func (p *metadataPusher) Enabled() bool {
+	return p.cfg.APIURL != ""
+}
+
+// Pending 上次推送是否失败
+func (p *metadataPusher) Pending() bool {
+	return p.pending
+}
+
+// Sync 全量同步转换结果
+func (p *metadataPusher) Sync(ctx context.Context, info *define.ClusterInfo, results map[string]*Result) {
+	req, err := newSyncRequest(info, results)
+	if err != nil {
+		logger.Errorf("generate prometheus rules sync request failed: %v", err)
+		return
+	}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		logger.Errorf("marshal prometheus rules sync request failed: %v", err)
+		return
+	}
+
+	h := fnv.New64a()
+	h.Write(b)
+	sum := h.Sum64()
+	if sum == p.prev && !p.pending {
+		return
+	}
+
+	if err := p.post(ctx, b); err != nil {
+		p.pending = true
+		logger.Errorf("push prometheus rules to metadata failed: %v", err)
+		return
+	}
+
+	p.pending = false
+	p.prev = sum
+	logger.Infof("push prometheus rules to metadata, record_rules=%d, strategies=%d", len(req.RecordRules), len(req.Strategies))
+}
+
+func (p *metadataPusher) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.APIURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	auth, _ := json.Marshal(map[string]string{
+		"bk_app_code":   p.cfg.AppCode,
+		"bk_app_secret": p.cfg.AppSecret,
+	})
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Bkapi-Authorization", string(auth))
+
+	rsp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	buf, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return err
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d: %s", rsp.StatusCode, buf)
+	}
+
+	var ret syncResponse
+	if err := json.Unmarshal(buf, &ret); err != nil {
+		return errors.Wrap(err, "unmarshal response failed")
+	}
+	if !ret.Result {
+		return errors.Errorf("code=%d, message=%s", ret.Code, ret.Message)
+	}
+	return nil
+}
@@ -0,0 +1,468 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package promrule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/k8sutils"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/notifier"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	kindPrometheusRule = "PrometheusRule"
+
+	// 告警级别 与 bkmonitor 策略级别保持一致
+	levelFatal   = 1
+	levelWarning = 2
+	levelRemind  = 3
+)
+
+// RecordRule 预计算规则 对应 bkmonitor record-rule
+type RecordRule struct {
+	ID       string            `json:"id"`
+	Source   string            `json:"source"`
+	Group    string            `json:"group"`
+	Record   string            `json:"record"`
+	Expr     string            `json:"expr"`
+	Interval string            `json:"interval"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// Strategy 告警策略 对应 bkmonitor strategy
+type Strategy struct {
+	ID          string            `json:"id"`
+	Source      string            `json:"source"`
+	Group       string            `json:"group"`
+	Name        string            `json:"name"`
+	Expr        string            `json:"expr"`
+	Interval    string            `json:"interval"`
+	For         string            `json:"for,omitempty"`
+	Level       int               `json:"level"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// TranslateError 转换失败的规则
+type TranslateError struct {
+	ID     string `json:"id"`
+	Source string `json:"source"`
+	Group  string `json:"group"`
+	Rule   string `json:"rule"`
+	Error  string `json:"error"`
+}
+
+// Result 单个 PrometheusRule 的转换结果
+type Result struct {
+	RecordRules []RecordRule     `json:"record_rules"`
+	Strategies  []Strategy       `json:"strategies"`
+	Errors      []TranslateError `json:"errors,omitempty"`
+}
+
+// sourceID PrometheusRule 唯一标识 同名资源删除重建后 UID 不同 不会与旧规则冲突
+func sourceID(pr *promv1.PrometheusRule) string {
+	return fmt.Sprintf("%s/%s/%s", pr.Namespace, pr.Name, pr.UID)
+}
+
+// Translate 将 PrometheusRule 转换为预计算规则及告警策略 非法规则会记录在 Errors 中而不会中断转换
+func Translate(pr *promv1.PrometheusRule, defaultInterval string) *Result {
+	source := fmt.Sprintf("%s/%s/%s", kindPrometheusRule, pr.Namespace, pr.Name)
+	ret := &Result{}
+
+	for _, group := range pr.Spec.Groups {
+		interval := group.Interval
+		if interval == "" {
+			interval = defaultInterval
+		}
+
+		for idx, rule := range group.Rules {
+			id := fmt.Sprintf("%s/%s/%d", sourceID(pr), group.Name, idx)
+			expr := rule.Expr.String()
+
+			if err := validateRule(rule, interval); err != nil {
+				ret.Errors = append(ret.Errors, TranslateError{
+					ID:     id,
+					Source: source,
+					Group:  group.Name,
+					Rule:   rule.Record + rule.Alert,
+					Error:  err.Error(),
+				})
+				continue
+			}
+
+			if rule.Record != "" {
+				ret.RecordRules = append(ret.RecordRules, RecordRule{
+					ID:       id,
+					Source:   source,
+					Group:    group.Name,
+					Record:   rule.Record,
+					Expr:     expr,
+					Interval: interval,
+					Labels:   rule.Labels,
+				})
+				continue
+			}
+
+			ret.Strategies = append(ret.Strategies, Strategy{
+				ID:          id,
+				Source:      source,
+				Group:       group.Name,
+				Name:        rule.Alert,
+				Expr:        expr,
+				Interval:    interval,
+				For:         rule.For,
+				Level:       severityLevel(rule.Labels["severity"]),
+				Labels:      rule.Labels,
+				Annotations: rule.Annotations,
+			})
+		}
+	}
+	return ret
+}
+
+func validateRule(rule promv1.Rule, interval string) error {
+	if rule.Record != "" && rule.Alert != "" {
+		return errors.New("only one of 'record' and 'alert' must be set")
+	}
+	if rule.Record == "" && rule.Alert == "" {
+		return errors.New("one of 'record' or 'alert' must be set")
+	}
+	if rule.Record != "" && !model.IsValidMetricName(model.LabelValue(rule.Record)) {
+		return errors.Errorf("invalid recording rule name: %s", rule.Record)
+	}
+	if rule.Record != "" && rule.For != "" {
+		return errors.New("invalid field 'for' in recording rule")
+	}
+
+	if _, err := model.ParseDuration(interval); err != nil {
+		return errors.Wrap(err, "invalid interval")
+	}
+	if rule.For != "" {
+		if _, err := model.ParseDuration(rule.For); err != nil {
+			return errors.Wrap(err, "invalid for")
+		}
+	}
+
+	expr := rule.Expr.String()
+	if expr == "" {
+		return errors.New("empty expr")
+	}
+	if _, err := parser.ParseExpr(expr); err != nil {
+		return errors.Wrap(err, "invalid expr")
+	}
+	return nil
+}
+
+// severityLevel 将 kube-prometheus 惯用的 severity 标签映射为告警级别
+func severityLevel(severity string) int {
+	switch strings.ToLower(severity) {
+	case "critical", "error", "fatal":
+		return levelFatal
+	case "info", "none":
+		return levelRemind
+	default:
+		return levelWarning
+	}
+}
+
+type Controller struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	client      kubernetes.Interface
+	bus         *notifier.RateBus
+	clusterInfo func() (*define.ClusterInfo, error)
+
+	mut     sync.Mutex
+	results map[string]*Result
+
+	pusher      *metadataPusher
+	prevContent map[string]string
+}
+
+func NewController(ctx context.Context, client kubernetes.Interface, clusterInfo func() (*define.ClusterInfo, error)) *Controller {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &Controller{
+		ctx:         ctx,
+		cancel:      cancel,
+		client:      client,
+		bus:         notifier.NewDefaultRateBus(),
+		clusterInfo: clusterInfo,
+		results:     make(map[string]*Result),
+		pusher:      newMetadataPusher(configs.G().PromRule.Metadata),
+	}
+
+	go c.handle()
+	return c
+}
+
+func (c *Controller) handle() {
+	ticker := time.NewTicker(2 * time.Hour) // 兜底检查
+	defer ticker.Stop()
+
+	retry := time.NewTicker(time.Minute) // 推送失败重试 内容未变更时不会重复推送
+	defer retry.Stop()
+
+	fn := func() {
+		if err := c.CreateOrUpdateConfigMap(); err != nil {
+			logger.Errorf("failed to update prometheus rules translate configmap: %v", err)
+		}
+		c.syncMetadata()
+	}
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+
+		case <-c.bus.Subscribe(): // 信号收敛
+			fn()
+
+		case <-retry.C:
+			if c.pusher.Pending() {
+				c.syncMetadata()
+			}
+
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
+
+func (c *Controller) UpdatePrometheusRule(pr *promv1.PrometheusRule) {
+	result := Translate(pr, configs.G().PromRule.DefaultInterval)
+	for _, e := range result.Errors {
+		logger.Warnf("translate PrometheusRule rule '%s' failed: %s", e.ID, e.Error)
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.bus.Publish()
+	c.results[sourceID(pr)] = result
+}
+
+func (c *Controller) DeletePrometheusRule(pr *promv1.PrometheusRule) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.bus.Publish()
+	delete(c.results, sourceID(pr))
+}
+
+func (c *Controller) sortedIDs() []string {
+	ids := make([]string, 0, len(c.results))
+	for id := range c.results {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// RecordRules 返回所有预计算规则 可按 namespace 过滤
+func (c *Controller) RecordRules(namespace string) []RecordRule {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ret := make([]RecordRule, 0)
+	for _, id := range c.sortedIDs() {
+		for _, rule := range c.results[id].RecordRules {
+			if namespace != "" && !matchNamespace(rule.Source, namespace) {
+				continue
+			}
+			ret = append(ret, rule)
+		}
+	}
+	return ret
+}
+
+// Strategies 返回所有告警策略 可按 namespace 过滤
+func (c *Controller) Strategies(namespace string) []Strategy {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ret := make([]Strategy, 0)
+	for _, id := range c.sortedIDs() {
+		for _, strategy := range c.results[id].Strategies {
+			if namespace != "" && !matchNamespace(strategy.Source, namespace) {
+				continue
+			}
+			ret = append(ret, strategy)
+		}
+	}
+	return ret
+}
+
+// Errors 返回所有转换失败的规则
+func (c *Controller) Errors() []TranslateError {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ret := make([]TranslateError, 0)
+	for _, id := range c.sortedIDs() {
+		ret = append(ret, c.results[id].Errors...)
+	}
+	return ret
+}
+
+func matchNamespace(source, namespace string) bool {
+	parts := strings.Split(source, "/")
+	return len(parts) == 3 && parts[1] == namespace
+}
+
+// snapshot 复制当前转换结果 避免推送 metadata 时长时间持有锁
+func (c *Controller) snapshot() map[string]*Result {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ret := make(map[string]*Result, len(c.results))
+	for id, result := range c.results {
+		ret[id] = result
+	}
+	return ret
+}
+
+// syncMetadata 将转换结果推送至 metadata 生成预计算规则及告警策略
+func (c *Controller) syncMetadata() {
+	if !c.pusher.Enabled() {
+		return
+	}
+
+	info, err := c.clusterInfo()
+	if err != nil {
+		logger.Warnf("get cluster info failed, skip pushing prometheus rules: %v", err)
+		return
+	}
+	c.pusher.Sync(c.ctx, info, c.snapshot())
+}
+
+// contentKey ConfigMap data key 仅允许 [-._a-zA-Z0-9] 字符
+func contentKey(id string) string {
+	return strings.ReplaceAll(id, "/", "_") + ".json"
+}
+
+// GenerateContent 生成 ConfigMap 内容 每个 PrometheusRule 对应一个 json 文件
+func (c *Controller) GenerateContent() map[string]string {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	data := make(map[string]string)
+	for id, result := range c.results {
+		if len(result.RecordRules) == 0 && len(result.Strategies) == 0 {
+			continue
+		}
+
+		b, err := json.Marshal(result)
+		if err != nil {
+			logger.Errorf("marshal prometheus rule '%s' failed: %v", id, err)
+			continue
+		}
+		data[contentKey(id)] = string(b)
+	}
+	return data
+}
+
+// shardContent 按大小将内容切分为多个分片 保证单个 ConfigMap 不超过 maxBytes
+// 单个文件超过 maxBytes 时独占一个分片
+func shardContent(content map[string]string, maxBytes int) []map[string]string {
+	keys := make([]string, 0, len(content))
+	for k := range content {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var shards []map[string]string
+	var size int
+	for _, k := range keys {
+		n := len(k) + len(content[k])
+		if n > maxBytes {
+			logger.Warnf("prometheus rule translate content '%s' too large, size=%dB", k, n)
+		}
+
+		if len(shards) == 0 || size+n > maxBytes {
+			shards = append(shards, make(map[string]string))
+			size = 0
+		}
+		shards[len(shards)-1][k] = content[k]
+		size += n
+	}
+	return shards
+}
+
+func shardName(i int) string {
+	return fmt.Sprintf("%s-%d", configs.G().PromRule.ConfigMapName, i)
+}
+
+const labelTranslateShard = "prometheus-rule-translate"
+
+// CreateOrUpdateConfigMap 将转换结果分片写入 ConfigMap 多余的分片会被清理
+func (c *Controller) CreateOrUpdateConfigMap() error {
+	content := c.GenerateContent()
+	if reflect.DeepEqual(content, c.prevContent) {
+		logger.Info("no prometheus rule translate content changed, skipped")
+		return nil
+	}
+
+	cli := c.client.CoreV1().ConfigMaps(configs.G().PromRule.Namespace)
+	shards := shardContent(content, configs.G().PromRule.ConfigMapMaxBytes)
+	desired := make(map[string]struct{})
+	for i, data := range shards {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: shardName(i),
+				Labels: map[string]string{
+					"controller":        "bkm-operator",
+					labelTranslateShard: "true",
+				},
+			},
+			Data: data,
+		}
+		logger.Infof("create or update prometheus rules translate configmap '%s', count=%d", cm.Name, len(data))
+		if err := k8sutils.CreateOrUpdateConfigMap(c.ctx, cli, cm); err != nil {
+			return err
+		}
+		desired[cm.Name] = struct{}{}
+	}
+
+	cms, err := cli.List(c.ctx, metav1.ListOptions{LabelSelector: labelTranslateShard + "=true"})
+	if err != nil {
+		return err
+	}
+	for _, cm := range cms.Items {
+		if _, ok := desired[cm.Name]; ok {
+			continue
+		}
+		logger.Infof("delete stale prometheus rules translate configmap '%s'", cm.Name)
+		if err := cli.Delete(c.ctx, cm.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	c.prevContent = content
+	return nil
+}
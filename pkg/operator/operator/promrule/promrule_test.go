@@ -0,0 +1,205 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package promrule
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/configs"
+)
+
+func TestTranslate(t *testing.T) {
+	pr := &promv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "node-rules", UID: "8d3f"},
+		Spec: promv1.PrometheusRuleSpec{
+			Groups: []promv1.RuleGroup{
+				{
+					Name:     "node.rules",
+					Interval: "30s",
+					Rules: []promv1.Rule{
+						{
+							Record: "instance:node_cpu:rate5m",
+							Expr:   intstr.FromString(`sum by (instance) (rate(node_cpu_seconds_total{mode!="idle"}[5m]))`),
+						},
+						{
+							Alert:       "NodeDown",
+							Expr:        intstr.FromString(`up{job="node"} == 0`),
+							For:         "5m",
+							Labels:      map[string]string{"severity": "critical"},
+							Annotations: map[string]string{"summary": "node down"},
+						},
+					},
+				},
+				{
+					Name: "invalid.rules",
+					Rules: []promv1.Rule{
+						{Record: "invalid metric", Expr: intstr.FromString("up")},
+						{Alert: "BadExpr", Expr: intstr.FromString("sum(up")},
+						{Record: "foo", Alert: "foo", Expr: intstr.FromString("up")},
+						{Alert: "NoSeverity", Expr: intstr.FromString("up == 0")},
+					},
+				},
+			},
+		},
+	}
+
+	result := Translate(pr, "1m")
+	assert.Len(t, result.RecordRules, 1)
+	assert.Len(t, result.Strategies, 2)
+	assert.Len(t, result.Errors, 3)
+
+	record := result.RecordRules[0]
+	assert.Equal(t, "monitoring/node-rules/8d3f/node.rules/0", record.ID)
+	assert.Equal(t, "PrometheusRule/monitoring/node-rules", record.Source)
+	assert.Equal(t, "instance:node_cpu:rate5m", record.Record)
+	assert.Equal(t, "30s", record.Interval)
+
+	strategy := result.Strategies[0]
+	assert.Equal(t, "NodeDown", strategy.Name)
+	assert.Equal(t, "5m", strategy.For)
+	assert.Equal(t, levelFatal, strategy.Level)
+	assert.Equal(t, "node down", strategy.Annotations["summary"])
+
+	strategy = result.Strategies[1]
+	assert.Equal(t, "NoSeverity", strategy.Name)
+	assert.Equal(t, "1m", strategy.Interval)
+	assert.Equal(t, levelWarning, strategy.Level)
+
+	assert.Equal(t, "monitoring/node-rules/8d3f/invalid.rules/1", result.Errors[1].ID)
+}
+
+func TestTranslateRecreated(t *testing.T) {
+	newRule := func(uid string) *promv1.PrometheusRule {
+		return &promv1.PrometheusRule{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "node-rules", UID: types.UID(uid)},
+			Spec: promv1.PrometheusRuleSpec{
+				Groups: []promv1.RuleGroup{{
+					Name:  "node.rules",
+					Rules: []promv1.Rule{{Record: "foo", Expr: intstr.FromString("up")}},
+				}},
+			},
+		}
+	}
+
+	prev := Translate(newRule("uid-1"), "1m")
+	curr := Translate(newRule("uid-2"), "1m")
+	assert.NotEqual(t, prev.RecordRules[0].ID, curr.RecordRules[0].ID)
+}
+
+func TestShardContent(t *testing.T) {
+	content := map[string]string{
+		"a.json": strings.Repeat("a", 40),
+		"b.json": strings.Repeat("b", 40),
+		"c.json": strings.Repeat("c", 40),
+		"d.json": strings.Repeat("d", 200),
+	}
+
+	shards := shardContent(content, 100)
+	assert.Len(t, shards, 3)
+	assert.Len(t, shards[0], 2)
+	assert.Len(t, shards[1], 1)
+	assert.Contains(t, shards[2], "d.json")
+
+	assert.Len(t, shardContent(nil, 100), 0)
+}
+
+func TestTriggerCount(t *testing.T) {
+	assert.Equal(t, 1, triggerCount("1m", ""))
+	assert.Equal(t, 6, triggerCount("1m", "5m"))
+	assert.Equal(t, 3, triggerCount("30s", "1m"))
+	assert.Equal(t, 1, triggerCount("invalid", "5m"))
+}
+
+func TestMetadataPusher(t *testing.T) {
+	var requests []syncRequest
+	fail := false
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("X-Bkapi-Authorization"), `"bk_app_code":"bkmonitor"`)
+
+		var req syncRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+
+		if fail {
+			w.Write([]byte(`{"result":false,"code":500,"message":"internal error"}`))
+			return
+		}
+		w.Write([]byte(`{"result":true,"code":200}`))
+	}))
+	defer svr.Close()
+
+	pr := &promv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "node-rules", UID: "8d3f"},
+		Spec: promv1.PrometheusRuleSpec{
+			Groups: []promv1.RuleGroup{{
+				Name: "node.rules",
+				Rules: []promv1.Rule{
+					{Record: "instance:up:sum", Expr: intstr.FromString("sum by (instance) (up)")},
+					{Alert: "NodeDown", Expr: intstr.FromString("up == 0"), For: "5m", Labels: map[string]string{"severity": "critical"}},
+				},
+			}},
+		},
+	}
+	results := map[string]*Result{sourceID(pr): Translate(pr, "1m")}
+	info := &define.ClusterInfo{BcsClusterID: "BCS-K8S-00000", BizID: "2"}
+
+	p := newMetadataPusher(configs.PromRuleMetadata{APIURL: svr.URL, AppCode: "bkmonitor"})
+	assert.True(t, p.Enabled())
+
+	p.Sync(context.Background(), info, results)
+	assert.Len(t, requests, 1)
+	assert.False(t, p.Pending())
+
+	req := requests[0]
+	assert.Equal(t, "BCS-K8S-00000", req.BcsClusterID)
+	assert.Equal(t, "2", req.BkBizID)
+	assert.Len(t, req.RecordRules, 1)
+	assert.Equal(t, "instance:up:sum", req.RecordRules[0].RecordName)
+	assert.Contains(t, req.RecordRules[0].RuleConfig, "record: instance:up:sum")
+	assert.Len(t, req.Strategies, 1)
+	assert.Equal(t, "up == 0", req.Strategies[0].Items[0].QueryConfigs[0].PromQL)
+	assert.Equal(t, 60, req.Strategies[0].Items[0].QueryConfigs[0].AggInterval)
+	assert.Equal(t, levelFatal, req.Strategies[0].Detects[0].Level)
+	assert.Equal(t, 6, req.Strategies[0].Detects[0].TriggerConfig.Count)
+
+	// 内容未变更不重复推送
+	p.Sync(context.Background(), info, results)
+	assert.Len(t, requests, 1)
+
+	// 删除后推送空列表 失败时保留重试标记
+	fail = true
+	p.Sync(context.Background(), info, map[string]*Result{})
+	assert.Len(t, requests, 2)
+	assert.Len(t, requests[1].RecordRules, 0)
+	assert.True(t, p.Pending())
+
+	fail = false
+	p.Sync(context.Background(), info, map[string]*Result{})
+	assert.Len(t, requests, 3)
+	assert.False(t, p.Pending())
+}
+
+func TestMatchNamespace(t *testing.T) {
+	assert.True(t, matchNamespace("PrometheusRule/monitoring/node-rules", "monitoring"))
+	assert.False(t, matchNamespace("PrometheusRule/default/node-rules", "monitoring"))
+	assert.False(t, matchNamespace("invalid", "monitoring"))
+}
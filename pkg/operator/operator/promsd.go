@@ -18,6 +18,10 @@ import (
 	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/config"
+	promdiscovery "github.com/prometheus/prometheus/discovery"
+	promconsul "github.com/prometheus/prometheus/discovery/consul"
+	promdns "github.com/prometheus/prometheus/discovery/dns"
+	promfile "github.com/prometheus/prometheus/discovery/file"
 	promhttpsd "github.com/prometheus/prometheus/discovery/http"
 	promk8ssd "github.com/prometheus/prometheus/discovery/kubernetes"
 	"gopkg.in/yaml.v2"
@@ -26,8 +30,12 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/define"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/configs"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/consuld"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/dnsd"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/filesd"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/httpd"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/kubernetesd"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/staticd"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
 )
 
@@ -115,7 +123,8 @@ func castDuration(d model.Duration) string {
 	return d.String()
 }
 
-func (c *Operator) createHttpSdDiscover(scrapeConfig config.ScrapeConfig, sdConfig *promhttpsd.SDConfig, index int) (discover.Discover, error) {
+// newPromSdCommonOptions 根据 scrapeConfig 生成各类 sd discover 通用的配置
+func (c *Operator) newPromSdCommonOptions(scrapeConfig config.ScrapeConfig, kind string, index int) (*discover.CommonOptions, error) {
 	metricRelabelings := make([]yaml.MapSlice, 0)
 	if len(scrapeConfig.MetricRelabelConfigs) != 0 {
 		for _, cfg := range scrapeConfig.MetricRelabelConfigs {
@@ -126,7 +135,7 @@ func (c *Operator) createHttpSdDiscover(scrapeConfig config.ScrapeConfig, sdConf
 
 	monitorMeta := define.MonitorMeta{
 		Name:      scrapeConfig.JobName,
-		Kind:      monitorKindHttpSd,
+		Kind:      kind,
 		Namespace: "-", // 不标记 namespace
 		Index:     index,
 	}
@@ -150,25 +159,37 @@ func (c *Operator) createHttpSdDiscover(scrapeConfig config.ScrapeConfig, sdConf
 		bearerTokenFile = auth.CredentialsFile
 	}
 
+	return &discover.CommonOptions{
+		MonitorMeta:            monitorMeta,
+		Name:                   monitorMeta.ID(),
+		DataID:                 dataID,
+		Relabels:               scrapeConfig.RelabelConfigs,
+		Path:                   scrapeConfig.MetricsPath,
+		Scheme:                 scrapeConfig.Scheme,
+		BearerTokenFile:        bearerTokenFile,
+		ProxyURL:               proxyURL,
+		Period:                 castDuration(scrapeConfig.ScrapeInterval),
+		Timeout:                castDuration(scrapeConfig.ScrapeTimeout),
+		DisableCustomTimestamp: !ifHonorTimestamps(&scrapeConfig.HonorTimestamps),
+		UrlValues:              scrapeConfig.Params,
+		ExtraLabels:            specLabels,
+		MetricRelabelConfigs:   metricRelabelings,
+		SampleLimit:            uint64(scrapeConfig.SampleLimit),
+		LabelLimit:             uint64(scrapeConfig.LabelLimit),
+		TargetLimit:            uint64(scrapeConfig.TargetLimit),
+	}, nil
+}
+
+func (c *Operator) createHttpSdDiscover(scrapeConfig config.ScrapeConfig, sdConfig *promhttpsd.SDConfig, index int) (discover.Discover, error) {
+	commonOpts, err := c.newPromSdCommonOptions(scrapeConfig, monitorKindHttpSd, index)
+	if err != nil {
+		return nil, err
+	}
+
 	dis := httpd.New(c.ctx, c.objectsController.NodeNameExists, &httpd.Options{
-		CommonOptions: &discover.CommonOptions{
-			MonitorMeta:            monitorMeta,
-			Name:                   monitorMeta.ID(),
-			DataID:                 dataID,
-			Relabels:               scrapeConfig.RelabelConfigs,
-			Path:                   scrapeConfig.MetricsPath,
-			Scheme:                 scrapeConfig.Scheme,
-			BearerTokenFile:        bearerTokenFile,
-			ProxyURL:               proxyURL,
-			Period:                 castDuration(scrapeConfig.ScrapeInterval),
-			Timeout:                castDuration(scrapeConfig.ScrapeTimeout),
-			DisableCustomTimestamp: !ifHonorTimestamps(&scrapeConfig.HonorTimestamps),
-			UrlValues:              scrapeConfig.Params,
-			ExtraLabels:            specLabels,
-			MetricRelabelConfigs:   metricRelabelings,
-		},
+		CommonOptions:    commonOpts,
 		SDConfig:         sdConfig,
-		HTTPClientConfig: httpClientConfig,
+		HTTPClientConfig: scrapeConfig.HTTPClientConfig,
 	})
 	logger.Infof("create http_sd discover: %s", dis.Name())
 
@@ -176,40 +197,11 @@ func (c *Operator) createHttpSdDiscover(scrapeConfig config.ScrapeConfig, sdConf
 }
 
 func (c *Operator) createKubernetesSdDiscover(scrapeConfig config.ScrapeConfig, sdConfig *promk8ssd.SDConfig, index int) (discover.Discover, error) {
-	metricRelabelings := make([]yaml.MapSlice, 0)
-	if len(scrapeConfig.MetricRelabelConfigs) != 0 {
-		for _, cfg := range scrapeConfig.MetricRelabelConfigs {
-			relabeling := generatePromRelabelConfig(cfg)
-			metricRelabelings = append(metricRelabelings, relabeling)
-		}
-	}
-
-	monitorMeta := define.MonitorMeta{
-		Name:      scrapeConfig.JobName,
-		Kind:      monitorKindKubernetesSd,
-		Namespace: "-", // 不标记 namespace
-		Index:     index,
-	}
-	// 默认使用 custommetric dataid
-	dataID, err := c.dw.MatchMetricDataID(monitorMeta, false)
+	commonOpts, err := c.newPromSdCommonOptions(scrapeConfig, monitorKindKubernetesSd, index)
 	if err != nil {
 		return nil, err
 	}
 
-	specLabels := dataID.Spec.Labels
-	httpClientConfig := scrapeConfig.HTTPClientConfig
-
-	var proxyURL string
-	if httpClientConfig.ProxyURL.URL != nil {
-		proxyURL = httpClientConfig.ProxyURL.String()
-	}
-
-	var bearerTokenFile string
-	auth := httpClientConfig.Authorization
-	if auth != nil && auth.Type == "Bearer" {
-		bearerTokenFile = auth.CredentialsFile
-	}
-
 	var username, password string
 	basicAuth := scrapeConfig.HTTPClientConfig.BasicAuth
 	if basicAuth != nil {
@@ -218,25 +210,10 @@ func (c *Operator) createKubernetesSdDiscover(scrapeConfig config.ScrapeConfig,
 	}
 
 	dis := kubernetesd.New(c.ctx, string(sdConfig.Role), c.objectsController.NodeNameExists, &kubernetesd.Options{
-		CommonOptions: &discover.CommonOptions{
-			MonitorMeta:            monitorMeta,
-			Name:                   monitorMeta.ID(),
-			DataID:                 dataID,
-			Relabels:               scrapeConfig.RelabelConfigs,
-			Path:                   scrapeConfig.MetricsPath,
-			Scheme:                 scrapeConfig.Scheme,
-			BearerTokenFile:        bearerTokenFile,
-			ProxyURL:               proxyURL,
-			Period:                 castDuration(scrapeConfig.ScrapeInterval),
-			Timeout:                castDuration(scrapeConfig.ScrapeTimeout),
-			DisableCustomTimestamp: !ifHonorTimestamps(&scrapeConfig.HonorTimestamps),
-			UrlValues:              scrapeConfig.Params,
-			ExtraLabels:            specLabels,
-			MetricRelabelConfigs:   metricRelabelings,
-		},
-		KubeConfig: configs.G().KubeConfig,
-		Namespaces: sdConfig.NamespaceDiscovery.Names,
-		Client:     c.client,
+		CommonOptions: commonOpts,
+		KubeConfig:    configs.G().KubeConfig,
+		Namespaces:    sdConfig.NamespaceDiscovery.Names,
+		Client:        c.client,
 		BasicAuthRaw: kubernetesd.BasicAuthRaw{
 			Username: username,
 			Password: password,
@@ -253,6 +230,72 @@ func (c *Operator) createKubernetesSdDiscover(scrapeConfig config.ScrapeConfig,
 	return dis, nil
 }
 
+// createFileSdDiscover file_sd 的文件内容从 ConfigMap 中读取 files 格式为 $namespace/$configmap/$key
+func (c *Operator) createFileSdDiscover(scrapeConfig config.ScrapeConfig, sdConfig *promfile.SDConfig, index int) (discover.Discover, error) {
+	commonOpts, err := c.newPromSdCommonOptions(scrapeConfig, monitorKindFileSd, index)
+	if err != nil {
+		return nil, err
+	}
+
+	dis := filesd.New(c.ctx, c.objectsController.NodeNameExists, &filesd.Options{
+		CommonOptions:    commonOpts,
+		SDConfig:         sdConfig,
+		Client:           c.client,
+		HTTPClientConfig: scrapeConfig.HTTPClientConfig,
+	})
+	logger.Infof("create file_sd discover: %s", dis.Name())
+
+	return dis, nil
+}
+
+func (c *Operator) createDnsSdDiscover(scrapeConfig config.ScrapeConfig, sdConfig *promdns.SDConfig, index int) (discover.Discover, error) {
+	commonOpts, err := c.newPromSdCommonOptions(scrapeConfig, monitorKindDnsSd, index)
+	if err != nil {
+		return nil, err
+	}
+
+	dis := dnsd.New(c.ctx, c.objectsController.NodeNameExists, &dnsd.Options{
+		CommonOptions:    commonOpts,
+		SDConfig:         sdConfig,
+		HTTPClientConfig: scrapeConfig.HTTPClientConfig,
+	})
+	logger.Infof("create dns_sd discover: %s", dis.Name())
+
+	return dis, nil
+}
+
+func (c *Operator) createConsulSdDiscover(scrapeConfig config.ScrapeConfig, sdConfig *promconsul.SDConfig, index int) (discover.Discover, error) {
+	commonOpts, err := c.newPromSdCommonOptions(scrapeConfig, monitorKindConsulSd, index)
+	if err != nil {
+		return nil, err
+	}
+
+	dis := consuld.New(c.ctx, c.objectsController.NodeNameExists, &consuld.Options{
+		CommonOptions:    commonOpts,
+		SDConfig:         sdConfig,
+		HTTPClientConfig: scrapeConfig.HTTPClientConfig,
+	})
+	logger.Infof("create consul_sd discover: %s", dis.Name())
+
+	return dis, nil
+}
+
+func (c *Operator) createStaticConfigDiscover(scrapeConfig config.ScrapeConfig, staticConfig promdiscovery.StaticConfig, index int) (discover.Discover, error) {
+	commonOpts, err := c.newPromSdCommonOptions(scrapeConfig, monitorKindStaticConfig, index)
+	if err != nil {
+		return nil, err
+	}
+
+	dis := staticd.New(c.ctx, c.objectsController.NodeNameExists, &staticd.Options{
+		CommonOptions:    commonOpts,
+		TargetGroups:     staticConfig,
+		HTTPClientConfig: scrapeConfig.HTTPClientConfig,
+	})
+	logger.Infof("create static_config discover: %s", dis.Name())
+
+	return dis, nil
+}
+
 func (c *Operator) createPromScrapeConfigDiscovers() []discover.Discover {
 	scrapeConfigs, ok := c.getPromScrapeConfigs()
 	if !ok {
@@ -288,6 +331,54 @@ func (c *Operator) createPromScrapeConfigDiscovers() []discover.Discover {
 					continue
 				}
 				discovers = append(discovers, kubernetesSd)
+
+			case *promfile.SDConfig:
+				if !configs.G().PromSDKinds.Allow(monitorKindFileSd) {
+					continue
+				}
+
+				fileSd, err := c.createFileSdDiscover(scrapeConfig, obj, idx)
+				if err != nil {
+					logger.Errorf("failed to create file_sd discover: %v", err)
+					continue
+				}
+				discovers = append(discovers, fileSd)
+
+			case *promdns.SDConfig:
+				if !configs.G().PromSDKinds.Allow(monitorKindDnsSd) {
+					continue
+				}
+
+				dnsSd, err := c.createDnsSdDiscover(scrapeConfig, obj, idx)
+				if err != nil {
+					logger.Errorf("failed to create dns_sd discover: %v", err)
+					continue
+				}
+				discovers = append(discovers, dnsSd)
+
+			case *promconsul.SDConfig:
+				if !configs.G().PromSDKinds.Allow(monitorKindConsulSd) {
+					continue
+				}
+
+				consulSd, err := c.createConsulSdDiscover(scrapeConfig, obj, idx)
+				if err != nil {
+					logger.Errorf("failed to create consul_sd discover: %v", err)
+					continue
+				}
+				discovers = append(discovers, consulSd)
+
+			case promdiscovery.StaticConfig:
+				if !configs.G().PromSDKinds.Allow(monitorKindStaticConfig) {
+					continue
+				}
+
+				staticConfig, err := c.createStaticConfigDiscover(scrapeConfig, obj, idx)
+				if err != nil {
+					logger.Errorf("failed to create static_config discover: %v", err)
+					continue
+				}
+				discovers = append(discovers, staticConfig)
 			}
 		}
 	}
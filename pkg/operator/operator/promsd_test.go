@@ -12,6 +12,10 @@ package operator
 import (
 	"testing"
 
+	promdiscovery "github.com/prometheus/prometheus/discovery"
+	promconsul "github.com/prometheus/prometheus/discovery/consul"
+	promdns "github.com/prometheus/prometheus/discovery/dns"
+	promfile "github.com/prometheus/prometheus/discovery/file"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -78,3 +82,46 @@ func TestUnmarshalPromSdConfigs(t *testing.T) {
 		t.Logf("config.httpconfig: %+v", config.HTTPClientConfig)
 	}
 }
+
+const contentMoreSdConfigs = `
+- job_name: 'file'
+  file_sd_configs:
+  - files: ['monitoring/file-sd-targets/*.json']
+    refresh_interval: 1m
+- job_name: 'dns'
+  dns_sd_configs:
+  - names: ['_prometheus._tcp.example.com']
+- job_name: 'consul'
+  consul_sd_configs:
+  - server: 'consul.example.com:8500'
+    services: ['node-exporter']
+- job_name: 'static'
+  static_configs:
+  - targets: ['10.0.0.1:9100', '10.0.0.2:9100']
+    labels:
+      env: prod
+`
+
+func TestUnmarshalPromSdConfigsMoreKinds(t *testing.T) {
+	configs, err := unmarshalPromSdConfigs([]byte(contentMoreSdConfigs))
+	assert.NoError(t, err)
+	assert.Len(t, configs, 4)
+
+	fileSd, ok := configs[0].ServiceDiscoveryConfigs[0].(*promfile.SDConfig)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"monitoring/file-sd-targets/*.json"}, fileSd.Files)
+
+	dnsSd, ok := configs[1].ServiceDiscoveryConfigs[0].(*promdns.SDConfig)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"_prometheus._tcp.example.com"}, dnsSd.Names)
+
+	consulSd, ok := configs[2].ServiceDiscoveryConfigs[0].(*promconsul.SDConfig)
+	assert.True(t, ok)
+	assert.Equal(t, "consul.example.com:8500", consulSd.Server)
+
+	staticConfig, ok := configs[3].ServiceDiscoveryConfigs[0].(promdiscovery.StaticConfig)
+	assert.True(t, ok)
+	assert.Len(t, staticConfig, 1)
+	assert.Len(t, staticConfig[0].Targets, 2)
+	assert.Equal(t, "prod", string(staticConfig[0].Labels["env"]))
+}
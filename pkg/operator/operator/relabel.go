@@ -444,3 +444,138 @@ func getPodMonitorRelabels(m *promv1.PodMonitor, ep *promv1.PodMetricsEndpoint)
 
 	return relabelings
 }
+
+func probeJobName(m *promv1.Probe) string {
+	if m.Spec.JobName != "" {
+		return m.Spec.JobName
+	}
+	return fmt.Sprintf("%s/%s", m.GetNamespace(), m.GetName())
+}
+
+// probeTargetRelabels 将探测目标转换为 prober 请求参数 即 __address__ -> __param_target -> instance
+func probeTargetRelabels(m *promv1.Probe) []yaml.MapSlice {
+	return []yaml.MapSlice{
+		{
+			{Key: "source_labels", Value: []string{"__param_target"}},
+			{Key: "target_label", Value: "instance"},
+		},
+		{
+			{Key: "target_label", Value: "__address__"},
+			{Key: "replacement", Value: m.Spec.ProberSpec.URL},
+		},
+	}
+}
+
+func getProbeStaticRelabels(m *promv1.Probe) []yaml.MapSlice {
+	relabelings := initRelabelings()
+	relabelings = append(relabelings, yaml.MapSlice{
+		{Key: "target_label", Value: "job"},
+		{Key: "replacement", Value: probeJobName(m)},
+	})
+
+	staticConfig := m.Spec.Targets.StaticConfig
+	for _, c := range staticConfig.RelabelConfigs {
+		relabelings = append(relabelings, generatePromv1RelabelConfig(c))
+	}
+
+	relabelings = append(relabelings, yaml.MapSlice{
+		{Key: "source_labels", Value: []string{"__address__"}},
+		{Key: "target_label", Value: "__param_target"},
+	})
+	relabelings = append(relabelings, probeTargetRelabels(m)...)
+	return relabelings
+}
+
+func getProbeIngressRelabels(m *promv1.Probe) []yaml.MapSlice {
+	relabelings := initRelabelings()
+	ingress := m.Spec.Targets.Ingress
+
+	// Filter targets by ingresses selected by the monitor.
+	// Exact label matches.
+	var labelKeys []string
+	for k := range ingress.Selector.MatchLabels {
+		labelKeys = append(labelKeys, k)
+	}
+	sort.Strings(labelKeys)
+
+	for _, k := range labelKeys {
+		relabelings = append(relabelings, yaml.MapSlice{
+			{Key: "action", Value: "keep"},
+			{Key: "source_labels", Value: []string{"__meta_kubernetes_ingress_label_" + sanitizeLabelName(k)}},
+			{Key: "regex", Value: ingress.Selector.MatchLabels[k]},
+		})
+	}
+	// Set based label matching. We have to map the valid relations
+	// `In`, `NotIn`, `Exists`, and `DoesNotExist`, into relabeling rules.
+	for _, exp := range ingress.Selector.MatchExpressions {
+		switch exp.Operator {
+		case metav1.LabelSelectorOpIn:
+			relabelings = append(relabelings, yaml.MapSlice{
+				{Key: "action", Value: "keep"},
+				{Key: "source_labels", Value: []string{"__meta_kubernetes_ingress_label_" + sanitizeLabelName(exp.Key)}},
+				{Key: "regex", Value: strings.Join(exp.Values, "|")},
+			})
+		case metav1.LabelSelectorOpNotIn:
+			relabelings = append(relabelings, yaml.MapSlice{
+				{Key: "action", Value: "drop"},
+				{Key: "source_labels", Value: []string{"__meta_kubernetes_ingress_label_" + sanitizeLabelName(exp.Key)}},
+				{Key: "regex", Value: strings.Join(exp.Values, "|")},
+			})
+		case metav1.LabelSelectorOpExists:
+			relabelings = append(relabelings, yaml.MapSlice{
+				{Key: "action", Value: "keep"},
+				{Key: "source_labels", Value: []string{"__meta_kubernetes_ingress_labelpresent_" + sanitizeLabelName(exp.Key)}},
+				{Key: "regex", Value: "true"},
+			})
+		case metav1.LabelSelectorOpDoesNotExist:
+			relabelings = append(relabelings, yaml.MapSlice{
+				{Key: "action", Value: "drop"},
+				{Key: "source_labels", Value: []string{"__meta_kubernetes_ingress_labelpresent_" + sanitizeLabelName(exp.Key)}},
+				{Key: "regex", Value: "true"},
+			})
+		}
+	}
+
+	// Relabel namespace and ingress labels into proper labels.
+	// The original ingress address is available via the `__tmp_prometheus_ingress_address` label.
+	relabelings = append(relabelings, []yaml.MapSlice{
+		{
+			{Key: "source_labels", Value: []string{"__meta_kubernetes_ingress_scheme", "__address__", "__meta_kubernetes_ingress_path"}},
+			{Key: "separator", Value: ";"},
+			{Key: "regex", Value: "(.+);(.+);(.+)"},
+			{Key: "target_label", Value: "__tmp_prometheus_ingress_address"},
+			{Key: "replacement", Value: "${1}://${2}${3}"},
+			{Key: "action", Value: "replace"},
+		},
+		{
+			{Key: "source_labels", Value: []string{"__meta_kubernetes_namespace"}},
+			{Key: "target_label", Value: "namespace"},
+		},
+		{
+			{Key: "source_labels", Value: []string{"__meta_kubernetes_ingress_name"}},
+			{Key: "target_label", Value: "ingress"},
+		},
+		{
+			{Key: "target_label", Value: "job"},
+			{Key: "replacement", Value: probeJobName(m)},
+		},
+	}...)
+
+	for _, c := range ingress.RelabelConfigs {
+		relabelings = append(relabelings, generatePromv1RelabelConfig(c))
+	}
+
+	relabelings = append(relabelings, []yaml.MapSlice{
+		{
+			{Key: "source_labels", Value: []string{"__tmp_prometheus_ingress_address"}},
+			{Key: "target_label", Value: "__param_target"},
+		},
+		// 临时维度不上报
+		{
+			{Key: "regex", Value: "__tmp_prometheus_ingress_address"},
+			{Key: "action", Value: "labeldrop"},
+		},
+	}...)
+	relabelings = append(relabelings, probeTargetRelabels(m)...)
+	return relabelings
+}
@@ -13,6 +13,8 @@ import (
 	"testing"
 
 	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -82,3 +84,75 @@ func TestPodMonitorRelabel(t *testing.T) {
 	_, err = yamlToRelabels(yamlSlice)
 	assert.NoError(t, err)
 }
+
+func TestProbeStaticRelabel(t *testing.T) {
+	m := &promv1.Probe{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "testnamespace"},
+		Spec: promv1.ProbeSpec{
+			ProberSpec: promv1.ProberSpec{URL: "blackbox-exporter:9115"},
+			Targets: promv1.ProbeTargets{
+				StaticConfig: &promv1.ProbeTargetStaticConfig{
+					Targets: []string{"https://example.com"},
+					RelabelConfigs: []*promv1.RelabelConfig{
+						{TargetLabel: "env", Replacement: "prod"},
+					},
+				},
+			},
+		},
+	}
+
+	relabels, err := yamlToRelabels(getProbeStaticRelabels(m))
+	assert.NoError(t, err)
+
+	lbs := relabel.Process(labels.FromStrings("__address__", "https://example.com", "job", "Probe/testnamespace/test/0"), relabels...)
+	assert.Equal(t, labels.FromStrings(
+		"__address__", "blackbox-exporter:9115",
+		"__param_target", "https://example.com",
+		"env", "prod",
+		"instance", "https://example.com",
+		"job", "testnamespace/test",
+		"monitor_type", "Probe",
+	), lbs)
+}
+
+func TestProbeIngressRelabel(t *testing.T) {
+	m := &promv1.Probe{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "testnamespace"},
+		Spec: promv1.ProbeSpec{
+			JobName:    "blackbox",
+			ProberSpec: promv1.ProberSpec{URL: "blackbox-exporter:9115"},
+			Targets: promv1.ProbeTargets{
+				Ingress: &promv1.ProbeTargetIngress{
+					Selector: metav1.LabelSelector{
+						MatchLabels: map[string]string{"app": "web"},
+					},
+				},
+			},
+		},
+	}
+
+	relabels, err := yamlToRelabels(getProbeIngressRelabels(m))
+	assert.NoError(t, err)
+
+	base := []string{
+		"__address__", "example.com",
+		"__meta_kubernetes_ingress_scheme", "https",
+		"__meta_kubernetes_ingress_path", "/healthz",
+		"__meta_kubernetes_ingress_name", "web",
+		"__meta_kubernetes_namespace", "default",
+		"job", "Probe/testnamespace/test/0",
+	}
+
+	lbs := relabel.Process(labels.FromStrings(append(base, "__meta_kubernetes_ingress_label_app", "web")...), relabels...)
+	assert.Equal(t, "https://example.com/healthz", lbs.Get("__param_target"))
+	assert.Equal(t, "https://example.com/healthz", lbs.Get("instance"))
+	assert.Equal(t, "blackbox-exporter:9115", lbs.Get("__address__"))
+	assert.Equal(t, "blackbox", lbs.Get("job"))
+	assert.Equal(t, "web", lbs.Get("ingress"))
+	assert.Equal(t, "default", lbs.Get("namespace"))
+	assert.Empty(t, lbs.Get("__tmp_prometheus_ingress_address"))
+
+	// selector 未命中的 ingress 会被丢弃
+	lbs = relabel.Process(labels.FromStrings(append(base, "__meta_kubernetes_ingress_label_app", "api")...), relabels...)
+	assert.Nil(t, lbs)
+}
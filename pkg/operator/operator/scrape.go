@@ -35,6 +35,7 @@ type scrapeStats struct {
 type scrapeStat struct {
 	MonitorName string `json:"monitor_name"`
 	Namespace   string `json:"namespace"`
+	Kind        string `json:"kind"`
 	Lines       int    `json:"lines"`
 	Errors      int    `json:"errors"`
 }
@@ -45,8 +46,25 @@ type scrapeAnalyze struct {
 	Sample string `json:"sample"`
 }
 
+const (
+	limitReasonTarget = "target_limit"
+	limitReasonSample = "sample_limit"
+	limitReasonLabel  = "label_limit"
+)
+
+// scrapeLimitStat 超出采集限制的监控资源或采集目标
+type scrapeLimitStat struct {
+	MonitorName string `json:"monitor_name"`
+	Namespace   string `json:"namespace"`
+	Kind        string `json:"kind"`
+	Target      string `json:"target,omitempty"`
+	Reason      string `json:"reason"`
+	Value       int    `json:"value"`
+	Limit       uint64 `json:"limit"`
+}
+
 func (s scrapeStat) ID() string {
-	return fmt.Sprintf("%s/%s", s.Namespace, s.MonitorName)
+	return fmt.Sprintf("%s/%s/%s", s.Kind, s.Namespace, s.MonitorName)
 }
 
 func parseMetricName(s string) string {
@@ -204,6 +222,7 @@ func (c *Operator) scrapeAllStats(ctx context.Context, workers int) *scrapeStats
 			statsCh <- &scrapeStat{
 				MonitorName: cfg.Meta.Name,
 				Namespace:   cfg.Meta.Namespace,
+				Kind:        cfg.Meta.Kind,
 				Lines:       lines,
 				Errors:      len(errs),
 			}
@@ -236,3 +255,99 @@ func (c *Operator) scrapeAllStats(ctx context.Context, workers int) *scrapeStats
 
 	return ret
 }
+
+// scrapeLimits 检查超出采集限制的资源
+// target_limit 由 operator 执行 直接读取 discover 状态
+// sample_limit/label_limit 由 bkmonitorbeat 执行 这里通过实际抓取进行检查
+func (c *Operator) scrapeLimits(ctx context.Context, workers int) []scrapeLimitStat {
+	ret := make([]scrapeLimitStat, 0)
+
+	c.discoversMut.Lock()
+	for _, dis := range c.discovers {
+		status := dis.TargetLimitStatus()
+		if !status.Exceeded() {
+			continue
+		}
+		meta := dis.MonitorMeta()
+		ret = append(ret, scrapeLimitStat{
+			MonitorName: meta.Name,
+			Namespace:   meta.Namespace,
+			Kind:        meta.Kind,
+			Reason:      limitReasonTarget,
+			Value:       status.Targets,
+			Limit:       status.Limit,
+		})
+	}
+	c.discoversMut.Unlock()
+
+	statefulset, daemonset := c.collectChildConfigs()
+	childConfigs := make([]*discover.ChildConfig, 0, len(statefulset)+len(daemonset))
+	childConfigs = append(childConfigs, statefulset...)
+	childConfigs = append(childConfigs, daemonset...)
+
+	if workers <= 0 {
+		workers = defaultConcurrency
+	}
+	sem := make(chan struct{}, workers)
+
+	var mut sync.Mutex
+	wg := sync.WaitGroup{}
+	for _, cfg := range childConfigs {
+		client, err := scraper.New(cfg.Data)
+		if err != nil {
+			logger.Warnf("failed to crate scraper http client: %v", err)
+			continue
+		}
+		sampleLimit, labelLimit := client.Limits()
+		if sampleLimit == 0 && labelLimit == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(cfg *discover.ChildConfig, client *scraper.Scraper) {
+			sem <- struct{}{}
+			defer func() {
+				wg.Done()
+				<-sem
+			}()
+
+			samples, maxLabels, errs := client.SeriesStats(ctx)
+			for _, err := range errs {
+				logger.Warnf("failed to scrape target, namespace=%s, monitor=%s, err: %v", cfg.Meta.Namespace, cfg.Meta.Name, err)
+			}
+
+			newStat := func(reason string, value int, limit uint64) scrapeLimitStat {
+				return scrapeLimitStat{
+					MonitorName: cfg.Meta.Name,
+					Namespace:   cfg.Meta.Namespace,
+					Kind:        cfg.Meta.Kind,
+					Target:      cfg.Address + cfg.Path,
+					Reason:      reason,
+					Value:       value,
+					Limit:       limit,
+				}
+			}
+
+			mut.Lock()
+			defer mut.Unlock()
+			if sampleLimit > 0 && uint64(samples) > sampleLimit {
+				ret = append(ret, newStat(limitReasonSample, samples, sampleLimit))
+			}
+			if labelLimit > 0 && uint64(maxLabels) > labelLimit {
+				ret = append(ret, newStat(limitReasonLabel, maxLabels, labelLimit))
+			}
+		}(cfg, client)
+	}
+	wg.Wait()
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Namespace != ret[j].Namespace {
+			return ret[i].Namespace < ret[j].Namespace
+		}
+		if ret[i].MonitorName != ret[j].MonitorName {
+			return ret[i].MonitorName < ret[j].MonitorName
+		}
+		return ret[i].Target < ret[j].Target
+	})
+	return ret
+}
@@ -0,0 +1,425 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+	promhttpsd "github.com/prometheus/prometheus/discovery/http"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/common/feature"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/httpd"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/kubernetesd"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/operator/operator/discover/staticd"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// scrapeConfigGVR ScrapeConfig CRD 资源定义
+// 当前依赖的 prometheus-operator 版本尚未提供 v1alpha1.ScrapeConfig 类型 因此使用 dynamic informer 监听
+var scrapeConfigGVR = schema.GroupVersionResource{
+	Group:    "monitoring.coreos.com",
+	Version:  "v1alpha1",
+	Resource: "scrapeconfigs",
+}
+
+// promScrapeConfig 对应 monitoring.coreos.com/v1alpha1 ScrapeConfig 仅保留 operator 支持的字段
+type promScrapeConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              promScrapeConfigSpec `json:"spec"`
+}
+
+type promScrapeConfigSpec struct {
+	JobName              *string                   `json:"jobName,omitempty"`
+	StaticConfigs        []promStaticConfig        `json:"staticConfigs,omitempty"`
+	HTTPSDConfigs        []promHTTPSDConfig        `json:"httpSDConfigs,omitempty"`
+	KubernetesSDConfigs  []promKubernetesSDConfig  `json:"kubernetesSDConfigs,omitempty"`
+	Relabelings          []*promv1.RelabelConfig   `json:"relabelings,omitempty"`
+	MetricsPath          *string                   `json:"metricsPath,omitempty"`
+	ScrapeInterval       promv1.Duration           `json:"scrapeInterval,omitempty"`
+	ScrapeTimeout        promv1.Duration           `json:"scrapeTimeout,omitempty"`
+	HonorTimestamps      *bool                     `json:"honorTimestamps,omitempty"`
+	Params               map[string][]string       `json:"params,omitempty"`
+	Scheme               *string                   `json:"scheme,omitempty"`
+	ProxyURL             *string                   `json:"proxyUrl,omitempty"`
+	BasicAuth            *promv1.BasicAuth         `json:"basicAuth,omitempty"`
+	Authorization        *promv1.SafeAuthorization `json:"authorization,omitempty"`
+	TLSConfig            *promv1.SafeTLSConfig     `json:"tlsConfig,omitempty"`
+	MetricRelabelConfigs []*promv1.RelabelConfig   `json:"metricRelabelings,omitempty"`
+	SampleLimit          uint64                    `json:"sampleLimit,omitempty"`
+	LabelLimit           uint64                    `json:"labelLimit,omitempty"`
+	TargetLimit          uint64                    `json:"targetLimit,omitempty"`
+}
+
+type promStaticConfig struct {
+	Targets []string          `json:"targets,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+type promHTTPSDConfig struct {
+	URL             string          `json:"url"`
+	RefreshInterval promv1.Duration `json:"refreshInterval,omitempty"`
+}
+
+type promKubernetesSDConfig struct {
+	Role       string                  `json:"role"`
+	Namespaces *promNamespaceDiscovery `json:"namespaces,omitempty"`
+}
+
+type promNamespaceDiscovery struct {
+	IncludeOwnNamespace *bool    `json:"ownNamespace,omitempty"`
+	Names               []string `json:"names,omitempty"`
+}
+
+// sdConfigsCount 每个 sdconfig 均对应一个 discover
+func (sc *promScrapeConfig) sdConfigsCount() int {
+	return len(sc.Spec.StaticConfigs) + len(sc.Spec.HTTPSDConfigs) + len(sc.Spec.KubernetesSDConfigs)
+}
+
+func (sc *promScrapeConfig) jobName() string {
+	if sc.Spec.JobName != nil && *sc.Spec.JobName != "" {
+		return *sc.Spec.JobName
+	}
+	return fmt.Sprintf("%s/%s", sc.Namespace, sc.Name)
+}
+
+func scrapeConfigID(obj *promScrapeConfig) string {
+	return fmt.Sprintf("%s/%s", obj.Namespace, obj.Name)
+}
+
+func toPromScrapeConfig(obj interface{}) (*promScrapeConfig, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, errors.Errorf("expected ScrapeConfig type, got %T", obj)
+	}
+
+	sc := &promScrapeConfig{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, sc); err != nil {
+		return nil, errors.Wrap(err, "convert ScrapeConfig failed")
+	}
+	return sc, nil
+}
+
+// ifDenyScrapeConfigNamespace dynamic informer 不支持 namespace 黑名单 需要自行过滤
+func ifDenyScrapeConfigNamespace(namespace string) bool {
+	for _, ns := range configs.G().DenyTargetNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Operator) handleScrapeConfigAdd(obj interface{}) {
+	scrapeConfig, err := toPromScrapeConfig(obj)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	if ifDenyScrapeConfigNamespace(scrapeConfig.Namespace) {
+		return
+	}
+
+	// 新增的 scrapeconfig 命中黑名单则流程终止
+	if ifRejectScrapeConfig(scrapeConfig) {
+		logger.Infof("add action match blacklist rules, scrapeConfig=%s", scrapeConfigID(scrapeConfig))
+		return
+	}
+
+	discovers := c.createScrapeConfigDiscovers(scrapeConfig)
+	for _, dis := range discovers {
+		if err := c.addOrUpdateDiscover(dis); err != nil {
+			logger.Errorf("add or update scrapeConfig discover %s failed: %s", dis, err)
+		}
+	}
+}
+
+func (c *Operator) handleScrapeConfigUpdate(oldObj interface{}, newObj interface{}) {
+	old, err := toPromScrapeConfig(oldObj)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	cur, err := toPromScrapeConfig(newObj)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	if ifDenyScrapeConfigNamespace(cur.Namespace) {
+		return
+	}
+
+	if old.ResourceVersion == cur.ResourceVersion {
+		logger.Debugf("scrapeConfig '%s' does not change", scrapeConfigID(old))
+		return
+	}
+
+	// 对于更新的 scrapeconfig 如果新的 spec 命中黑名单 则需要将原有的 scrapeconfig 移除
+	if ifRejectScrapeConfig(cur) {
+		logger.Infof("update action match blacklist rules, scrapeConfig=%s", scrapeConfigID(cur))
+		for _, name := range c.getScrapeConfigDiscoversName(cur) {
+			c.deleteDiscoverByName(name)
+		}
+		return
+	}
+
+	for _, name := range c.getScrapeConfigDiscoversName(old) {
+		c.deleteDiscoverByName(name)
+	}
+	for _, dis := range c.createScrapeConfigDiscovers(cur) {
+		if err := c.addOrUpdateDiscover(dis); err != nil {
+			logger.Errorf("add or update scrapeConfig discover %s failed: %s", dis, err)
+		}
+	}
+}
+
+func (c *Operator) handleScrapeConfigDelete(obj interface{}) {
+	scrapeConfig, err := toPromScrapeConfig(obj)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	for _, name := range c.getScrapeConfigDiscoversName(scrapeConfig) {
+		c.deleteDiscoverByName(name)
+	}
+}
+
+func (c *Operator) getScrapeConfigDiscoversName(scrapeConfig *promScrapeConfig) []string {
+	var names []string
+	for index := 0; index < scrapeConfig.sdConfigsCount(); index++ {
+		monitorMeta := define.MonitorMeta{
+			Name:      scrapeConfig.Name,
+			Kind:      monitorKindScrapeConfig,
+			Namespace: scrapeConfig.Namespace,
+			Index:     index,
+		}
+		names = append(names, monitorMeta.ID())
+	}
+	return names
+}
+
+func getScrapeConfigRelabels(scrapeConfig *promScrapeConfig) []yaml.MapSlice {
+	relabelings := initRelabelings()
+	relabelings = append(relabelings, yaml.MapSlice{
+		{Key: "target_label", Value: "job"},
+		{Key: "replacement", Value: scrapeConfig.jobName()},
+	})
+	for _, cfg := range scrapeConfig.Spec.Relabelings {
+		relabelings = append(relabelings, generatePromv1RelabelConfig(cfg))
+	}
+	return relabelings
+}
+
+func (c *Operator) createScrapeConfigDiscovers(scrapeConfig *promScrapeConfig) []discover.Discover {
+	var discovers []discover.Discover
+
+	systemResource := feature.IfSystemResource(scrapeConfig.Annotations)
+	meta := define.MonitorMeta{
+		Name:      scrapeConfig.Name,
+		Kind:      monitorKindScrapeConfig,
+		Namespace: scrapeConfig.Namespace,
+	}
+	dataID, err := c.dw.MatchMetricDataID(meta, systemResource)
+	if err != nil {
+		logger.Errorf("scrapeConfig(%+v) no dataid matched", meta)
+		return discovers
+	}
+	specLabels := dataID.Spec.Labels
+
+	relabels := getScrapeConfigRelabels(scrapeConfig)
+	logger.Debugf("scrapeConfig '%s' get relabels: %v", scrapeConfigID(scrapeConfig), relabels)
+	resultLabels, err := yamlToRelabels(relabels)
+	if err != nil {
+		logger.Errorf("failed to convert relabels, err: %s", err)
+		return discovers
+	}
+
+	metricRelabelings := make([]yaml.MapSlice, 0)
+	for _, cfg := range scrapeConfig.Spec.MetricRelabelConfigs {
+		metricRelabelings = append(metricRelabelings, generatePromv1RelabelConfig(cfg))
+	}
+
+	path := "/metrics"
+	if scrapeConfig.Spec.MetricsPath != nil && *scrapeConfig.Spec.MetricsPath != "" {
+		path = *scrapeConfig.Spec.MetricsPath
+	}
+	scheme := "http"
+	if scrapeConfig.Spec.Scheme != nil && *scrapeConfig.Spec.Scheme != "" {
+		scheme = strings.ToLower(*scrapeConfig.Spec.Scheme)
+	}
+	var proxyURL string
+	if scrapeConfig.Spec.ProxyURL != nil {
+		proxyURL = *scrapeConfig.Spec.ProxyURL
+	}
+
+	// authorization 仅支持 Bearer 类型
+	var bearerTokenSecret *corev1.SecretKeySelector
+	if auth := scrapeConfig.Spec.Authorization; auth != nil && (auth.Type == "" || strings.EqualFold(auth.Type, "Bearer")) {
+		bearerTokenSecret = auth.Credentials
+	}
+	httpClientConfig, err := c.resolveSafeHTTPClientConfig(scrapeConfig.Namespace, scrapeConfig.Spec.BasicAuth, bearerTokenSecret, scrapeConfig.Spec.TLSConfig)
+	if err != nil {
+		logger.Errorf("scrapeConfig '%s' resolve http client config failed: %v", scrapeConfigID(scrapeConfig), err)
+		return discovers
+	}
+
+	newCommonOptions := func(index int) *discover.CommonOptions {
+		monitorMeta := meta
+		monitorMeta.Index = index
+		return &discover.CommonOptions{
+			MonitorMeta:            monitorMeta,
+			RelabelRule:            feature.RelabelRule(scrapeConfig.Annotations),
+			RelabelIndex:           feature.RelabelIndex(scrapeConfig.Annotations),
+			NormalizeMetricName:    feature.IfNormalizeMetricName(scrapeConfig.Annotations),
+			AntiAffinity:           feature.IfAntiAffinity(scrapeConfig.Annotations),
+			MatchSelector:          feature.MonitorMatchSelector(scrapeConfig.Annotations),
+			DropSelector:           feature.MonitorDropSelector(scrapeConfig.Annotations),
+			LabelJoinMatcher:       feature.LabelJoinMatcher(scrapeConfig.Annotations),
+			Name:                   monitorMeta.ID(),
+			DataID:                 dataID,
+			Relabels:               resultLabels,
+			Path:                   path,
+			Scheme:                 scheme,
+			Period:                 string(scrapeConfig.Spec.ScrapeInterval),
+			Timeout:                string(scrapeConfig.Spec.ScrapeTimeout),
+			ProxyURL:               proxyURL,
+			ExtraLabels:            specLabels,
+			DisableCustomTimestamp: !ifHonorTimestamps(scrapeConfig.Spec.HonorTimestamps),
+			System:                 systemResource,
+			UrlValues:              scrapeConfig.Spec.Params,
+			MetricRelabelConfigs:   metricRelabelings,
+			SampleLimit:            scrapeConfig.Spec.SampleLimit,
+			LabelLimit:             scrapeConfig.Spec.LabelLimit,
+			TargetLimit:            scrapeConfig.Spec.TargetLimit,
+		}
+	}
+
+	logger.Infof("found new scrapeConfig '%s'", scrapeConfigID(scrapeConfig))
+	index := 0
+	for _, staticConfig := range scrapeConfig.Spec.StaticConfigs {
+		tg := &targetgroup.Group{
+			Source: fmt.Sprintf("%s/%s/%s", strings.ToLower(monitorKindScrapeConfig), scrapeConfig.Namespace, scrapeConfig.Name),
+			Labels: model.LabelSet{},
+		}
+		for k, v := range staticConfig.Labels {
+			tg.Labels[model.LabelName(k)] = model.LabelValue(v)
+		}
+		for _, target := range staticConfig.Targets {
+			tg.Targets = append(tg.Targets, model.LabelSet{model.AddressLabel: model.LabelValue(target)})
+		}
+
+		dis := staticd.New(c.ctx, c.objectsController.NodeNameExists, &staticd.Options{
+			CommonOptions:    newCommonOptions(index),
+			TargetGroups:     []*targetgroup.Group{tg},
+			HTTPClientConfig: httpClientConfig,
+		})
+		index++
+		logger.Infof("create new scrapeConfig static discover: %s", dis.Name())
+		discovers = append(discovers, dis)
+	}
+
+	for _, httpSDConfig := range scrapeConfig.Spec.HTTPSDConfigs {
+		sdConfig := promhttpsd.DefaultSDConfig
+		sdConfig.URL = httpSDConfig.URL
+		if httpSDConfig.RefreshInterval != "" {
+			d, err := model.ParseDuration(string(httpSDConfig.RefreshInterval))
+			if err != nil {
+				logger.Errorf("scrapeConfig '%s' invalid refreshInterval: %v", scrapeConfigID(scrapeConfig), err)
+			} else {
+				sdConfig.RefreshInterval = d
+			}
+		}
+
+		dis := httpd.New(c.ctx, c.objectsController.NodeNameExists, &httpd.Options{
+			CommonOptions:    newCommonOptions(index),
+			SDConfig:         &sdConfig,
+			HTTPClientConfig: httpClientConfig,
+		})
+		index++
+		logger.Infof("create new scrapeConfig http_sd discover: %s", dis.Name())
+		discovers = append(discovers, dis)
+	}
+
+	for _, k8sSDConfig := range scrapeConfig.Spec.KubernetesSDConfigs {
+		var namespaces []string
+		if ns := k8sSDConfig.Namespaces; ns != nil {
+			namespaces = append(namespaces, ns.Names...)
+			if ns.IncludeOwnNamespace != nil && *ns.IncludeOwnNamespace {
+				namespaces = append(namespaces, scrapeConfig.Namespace)
+			}
+		}
+
+		var tlsConfig *promv1.TLSConfig
+		if scrapeConfig.Spec.TLSConfig != nil {
+			tlsConfig = &promv1.TLSConfig{SafeTLSConfig: *scrapeConfig.Spec.TLSConfig.DeepCopy()}
+		}
+
+		dis := kubernetesd.New(c.ctx, strings.ToLower(k8sSDConfig.Role), c.objectsController.NodeNameExists, &kubernetesd.Options{
+			CommonOptions:     newCommonOptions(index),
+			Client:            c.client,
+			Namespaces:        namespaces,
+			KubeConfig:        configs.G().KubeConfig,
+			BasicAuth:         scrapeConfig.Spec.BasicAuth.DeepCopy(),
+			BearerTokenSecret: bearerTokenSecret.DeepCopy(),
+			TLSConfig:         tlsConfig,
+			UseEndpointSlice:  useEndpointslice,
+		})
+		index++
+		logger.Infof("create new scrapeConfig kubernetes_sd discover: %s", dis.Name())
+		discovers = append(discovers, dis)
+	}
+
+	return discovers
+}
+
+// scrapeConfigCRDExists 判断集群是否安装了 ScrapeConfig CRD 未安装时 informer 无法完成同步
+func (c *Operator) scrapeConfigCRDExists() bool {
+	resources, err := c.client.Discovery().ServerResourcesForGroupVersion(scrapeConfigGVR.GroupVersion().String())
+	if err != nil {
+		logger.Warnf("get %s resources failed: %v", scrapeConfigGVR.GroupVersion(), err)
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == scrapeConfigGVR.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+func ifRejectScrapeConfig(monitor *promScrapeConfig) bool {
+	if monitor == nil {
+		return false
+	}
+	for _, rule := range configs.G().MonitorBlacklistMatchRules {
+		if !rule.Validate() {
+			continue
+		}
+		if strings.ToUpper(rule.Kind) == strings.ToUpper(monitorKindScrapeConfig) && rule.Namespace == monitor.Namespace && rule.Name == monitor.Name {
+			return true
+		}
+	}
+	return false
+}
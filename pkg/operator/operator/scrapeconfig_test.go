@@ -0,0 +1,74 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestToPromScrapeConfig(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "monitoring.coreos.com/v1alpha1",
+		"kind":       "ScrapeConfig",
+		"metadata": map[string]interface{}{
+			"name":      "external",
+			"namespace": "blueking",
+		},
+		"spec": map[string]interface{}{
+			"metricsPath":    "/federate",
+			"scrapeInterval": "30s",
+			"params": map[string]interface{}{
+				"match[]": []interface{}{`{job="node"}`},
+			},
+			"staticConfigs": []interface{}{
+				map[string]interface{}{
+					"targets": []interface{}{"10.0.0.1:9100", "10.0.0.2:9100"},
+					"labels":  map[string]interface{}{"env": "prod"},
+				},
+			},
+			"httpSDConfigs": []interface{}{
+				map[string]interface{}{"url": "http://sd.example.com/targets", "refreshInterval": "1m"},
+			},
+			"kubernetesSDConfigs": []interface{}{
+				map[string]interface{}{"role": "Node"},
+			},
+			"relabelings": []interface{}{
+				map[string]interface{}{"targetLabel": "cluster", "replacement": "c1"},
+			},
+		},
+	}}
+
+	sc, err := toPromScrapeConfig(obj)
+	assert.NoError(t, err)
+	assert.Equal(t, "blueking/external", scrapeConfigID(sc))
+	assert.Equal(t, "blueking/external", sc.jobName())
+	assert.Equal(t, "/federate", *sc.Spec.MetricsPath)
+	assert.Equal(t, []string{`{job="node"}`}, sc.Spec.Params["match[]"])
+	assert.Equal(t, []string{"10.0.0.1:9100", "10.0.0.2:9100"}, sc.Spec.StaticConfigs[0].Targets)
+	assert.Equal(t, "http://sd.example.com/targets", sc.Spec.HTTPSDConfigs[0].URL)
+	assert.Equal(t, "Node", sc.Spec.KubernetesSDConfigs[0].Role)
+
+	c := &Operator{}
+	assert.Equal(t, []string{
+		"ScrapeConfig/blueking/external/0",
+		"ScrapeConfig/blueking/external/1",
+		"ScrapeConfig/blueking/external/2",
+	}, c.getScrapeConfigDiscoversName(sc))
+
+	relabels, err := yamlToRelabels(getScrapeConfigRelabels(sc))
+	assert.NoError(t, err)
+	assert.Len(t, relabels, 3)
+
+	_, err = toPromScrapeConfig(struct{}{})
+	assert.Error(t, err)
+}
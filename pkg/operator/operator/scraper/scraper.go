@@ -28,6 +28,8 @@ import (
 	"github.com/elastic/beats/libbeat/outputs"
 	"github.com/elastic/beats/libbeat/outputs/transport"
 	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/textparse"
 	"gopkg.in/yaml.v2"
 )
 
@@ -48,6 +50,8 @@ type TaskConfig struct {
 	ProxyURL    string            `yaml:"proxy_url"`
 	Headers     map[string]string `yaml:"headers"`
 	Timeout     time.Duration     `yaml:"timeout"`
+	SampleLimit uint64            `yaml:"sample_limit"`
+	LabelLimit  uint64            `yaml:"label_limit"`
 }
 
 type Scraper struct {
@@ -134,6 +138,63 @@ func (c *Scraper) Lines(ctx context.Context) (int, []error) {
 	return total, errs
 }
 
+// Limits 返回采集配置中的样本数及维度数限制
+func (c *Scraper) Limits() (sampleLimit uint64, labelLimit uint64) {
+	return c.config.SampleLimit, c.config.LabelLimit
+}
+
+// SeriesStats 返回采集的样本数以及单样本最大维度数（包含指标名）
+func (c *Scraper) SeriesStats(ctx context.Context) (int, int, []error) {
+	var errs []error
+	var samples, maxLabels int
+	for _, host := range c.config.Hosts {
+		resp, err := c.doRequest(ctx, host)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if resp.StatusCode >= 400 {
+			errs = append(errs, fmt.Errorf("scrape error => status code: %v, response: %v", resp.StatusCode, string(b)))
+			continue
+		}
+
+		parser, err := textparse.New(b, resp.Header.Get("Content-Type"))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for {
+			entry, err := parser.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				errs = append(errs, err)
+				break
+			}
+			if entry != textparse.EntrySeries {
+				continue
+			}
+
+			var lset labels.Labels
+			parser.Metric(&lset)
+			samples++
+			if len(lset) > maxLabels {
+				maxLabels = len(lset)
+			}
+		}
+	}
+
+	return samples, maxLabels, errs
+}
+
 func New(data []byte) (*Scraper, error) {
 	var module ModuleConfig
 
@@ -11,6 +11,7 @@ package operator
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/pprof"
@@ -98,6 +99,14 @@ func (c *Operator) CheckScrapeRoute(w http.ResponseWriter, r *http.Request) {
 	writeResponse(w, c.scrapeAllStats(r.Context(), i))
 }
 
+// CheckScrapeLimitsRoute 查看超出 sampleLimit/labelLimit/targetLimit 限制的采集目标
+func (c *Operator) CheckScrapeLimitsRoute(w http.ResponseWriter, r *http.Request) {
+	worker := r.URL.Query().Get("workers")
+	i, _ := strconv.Atoi(worker)
+
+	writeResponse(w, c.scrapeLimits(r.Context(), i))
+}
+
 // CheckScrapeNamespaceMonitorRoute 根据命名空间查看拉取指标信息
 func (c *Operator) CheckScrapeNamespaceMonitorRoute(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -243,7 +252,7 @@ const (
 	formatMonitorResources = `
 [√] check monitor resources
 - Description: 通过 '%s' 关键字匹配到以下监控资源。
-* 监测到 ServiceMonitor/PodMonitor/Probe 资源以及对应的采集目标，请检查资源数量是否一致
+* 监测到 ServiceMonitor/PodMonitor/Probe/ScrapeConfig 资源以及对应的采集目标，请检查资源数量是否一致
 %s
 * 生成的 bkmonitorbeat 采集配置文件
 %s
@@ -559,6 +568,87 @@ func (c *Operator) RelationMetricsRoute(w http.ResponseWriter, _ *http.Request)
 	w.Write(buf.Bytes())
 }
 
+// TopologyGraphRoute 集群拓扑快照
+func (c *Operator) TopologyGraphRoute(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, c.objectsController.TopologyGraph(r.URL.Query().Get("namespace")))
+}
+
+func parseTopologyVersion(r *http.Request) (uint64, error) {
+	s := r.URL.Query().Get("since")
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// TopologyEventsRoute 集群拓扑变更事件 版本过期时返回 410 需重新拉取快照
+func (c *Operator) TopologyEventsRoute(w http.ResponseWriter, r *http.Request) {
+	since, err := parseTopologyVersion(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf(`{"msg": "%s"}`, err)))
+		return
+	}
+
+	events, err := c.objectsController.TopologyEvents(r.URL.Query().Get("namespace"), since)
+	if err != nil {
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(fmt.Sprintf(`{"msg": "%s"}`, err)))
+		return
+	}
+	writeResponse(w, events)
+}
+
+// TopologyWatchRoute 以 ndjson 格式持续推送集群拓扑变更事件
+func (c *Operator) TopologyWatchRoute(w http.ResponseWriter, r *http.Request) {
+	since, err := parseTopologyVersion(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf(`{"msg": "%s"}`, err)))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("failed to use chunked writer"))
+		return
+	}
+
+	// watch 为长连接 取消 server 的写超时
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warnf("failed to reset write deadline: %v", err)
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	started := false
+	err = c.objectsController.WatchTopology(r.Context(), namespace, since, func(event objectsref.TopoEvent) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Transfer-Encoding", "chunked")
+		}
+		b, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+
+	if errors.Is(err, objectsref.ErrTopoVersionExpired) && !started {
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(fmt.Sprintf(`{"msg": "%s"}`, err)))
+		return
+	}
+	if err != nil {
+		logger.Warnf("topology watch (namespace=%s) stopped: %v", namespace, err)
+	}
+}
+
 func (c *Operator) RuleMetricsRoute(w http.ResponseWriter, _ *http.Request) {
 	if configs.G().EnablePromRule {
 		lines := c.promsliController.RuleMetrics()
@@ -566,6 +656,33 @@ func (c *Operator) RuleMetricsRoute(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+// RuleRecordsRoute PrometheusRule 转换后的预计算规则
+func (c *Operator) RuleRecordsRoute(w http.ResponseWriter, r *http.Request) {
+	if !configs.G().EnablePromRuleTranslate {
+		writeResponse(w, nil)
+		return
+	}
+	writeResponse(w, c.promruleController.RecordRules(r.URL.Query().Get("namespace")))
+}
+
+// RuleStrategiesRoute PrometheusRule 转换后的告警策略
+func (c *Operator) RuleStrategiesRoute(w http.ResponseWriter, r *http.Request) {
+	if !configs.G().EnablePromRuleTranslate {
+		writeResponse(w, nil)
+		return
+	}
+	writeResponse(w, c.promruleController.Strategies(r.URL.Query().Get("namespace")))
+}
+
+// RuleErrorsRoute PrometheusRule 转换失败的规则
+func (c *Operator) RuleErrorsRoute(w http.ResponseWriter, _ *http.Request) {
+	if !configs.G().EnablePromRuleTranslate {
+		writeResponse(w, nil)
+		return
+	}
+	writeResponse(w, c.promruleController.Errors())
+}
+
 func (c *Operator) ConfigsRoute(w http.ResponseWriter, _ *http.Request) {
 	b, _ := yaml.Marshal(configs.G())
 
@@ -596,7 +713,13 @@ func (c *Operator) IndexRoute(w http.ResponseWriter, _ *http.Request) {
 * GET /workload/node/{node}
 * GET /pods?all=true|false
 * GET /relation/metrics
+* GET /topology/graph?namespace=${namespace}
+* GET /topology/events?namespace=${namespace}&since=${version}
+* GET /topology/watch?namespace=${namespace}&since=${version}
 * GET /rule/metrics
+* GET /rule/records?namespace=${namespace}
+* GET /rule/strategies?namespace=${namespace}
+* GET /rule/errors
 * GET /configs
 
 # Check Routes
@@ -604,6 +727,7 @@ func (c *Operator) IndexRoute(w http.ResponseWriter, _ *http.Request) {
 * GET /check?monitor=${monitor}&scrape=true|false&workers=N
 * GET /check/dataid
 * GET /check/scrape?workers=N
+* GET /check/scrape_limits?workers=N
 * GET /check/scrape/{namespace}?workers=N&analyze=true|false&topn=M
 * GET /check/scrape/{namespace}/{monitor}?workers=N&analyze=true|false&topn=M
 * GET /check/namespace
@@ -643,13 +767,20 @@ func (c *Operator) ListenAndServe() error {
 	router.HandleFunc("/pods", c.PodsRoute)
 	router.HandleFunc("/labeljoin", c.LabelJoinRoute)
 	router.HandleFunc("/relation/metrics", c.RelationMetricsRoute)
+	router.HandleFunc("/topology/graph", c.TopologyGraphRoute)
+	router.HandleFunc("/topology/events", c.TopologyEventsRoute)
+	router.HandleFunc("/topology/watch", c.TopologyWatchRoute)
 	router.HandleFunc("/rule/metrics", c.RuleMetricsRoute)
+	router.HandleFunc("/rule/records", c.RuleRecordsRoute)
+	router.HandleFunc("/rule/strategies", c.RuleStrategiesRoute)
+	router.HandleFunc("/rule/errors", c.RuleErrorsRoute)
 	router.HandleFunc("/configs", c.ConfigsRoute)
 
 	// check 路由
 	router.HandleFunc("/check", c.CheckRoute)
 	router.HandleFunc("/check/dataid", c.CheckDataIdRoute)
 	router.HandleFunc("/check/scrape", c.CheckScrapeRoute)
+	router.HandleFunc("/check/scrape_limits", c.CheckScrapeLimitsRoute)
 	router.HandleFunc("/check/scrape/{namespace}", c.CheckScrapeNamespaceMonitorRoute)
 	router.HandleFunc("/check/scrape/{namespace}/{monitor}", c.CheckScrapeNamespaceMonitorRoute)
 	router.HandleFunc("/check/namespace", c.CheckNamespaceRoute)
@@ -208,6 +208,9 @@ func (c *Operator) createServiceMonitorDiscovers(serviceMonitor *promv1.ServiceM
 				System:                 systemResource,
 				UrlValues:              endpoint.Params,
 				MetricRelabelConfigs:   metricRelabelings,
+				SampleLimit:            serviceMonitor.Spec.SampleLimit,
+				LabelLimit:             serviceMonitor.Spec.LabelLimit,
+				TargetLimit:            serviceMonitor.Spec.TargetLimit,
 			},
 			Client:            c.client,
 			Namespaces:        namespaces,
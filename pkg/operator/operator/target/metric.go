@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/elastic/beats/libbeat/common/transport/tlscommon"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"gopkg.in/yaml.v2"
 
@@ -88,6 +89,8 @@ type MetricTarget struct {
 	TaskType               string
 	DisableCustomTimestamp bool
 	LabelJoinMatcher       *feature.LabelJoinMatcherSpec
+	SampleLimit            uint64
+	LabelLimit             uint64
 
 	hash uint64 // 缓存 hash 避免重复计算
 }
@@ -221,6 +224,14 @@ func (t *MetricTarget) YamlBytes() ([]byte, error) {
 	module = append(module, yaml.MapItem{Key: "disable_custom_timestamp", Value: t.DisableCustomTimestamp})
 	module = append(module, yaml.MapItem{Key: "normalize_metric_name", Value: t.NormalizeMetricName})
 
+	// 未配置限制时不追加字段 避免已有采集配置的 hash 发生变化
+	if t.SampleLimit > 0 {
+		module = append(module, yaml.MapItem{Key: "sample_limit", Value: t.SampleLimit})
+	}
+	if t.LabelLimit > 0 {
+		module = append(module, yaml.MapItem{Key: "label_limit", Value: t.LabelLimit})
+	}
+
 	address := t.Address
 	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
 		address = fmt.Sprintf("%s://%s", t.Scheme, address)
@@ -282,6 +293,10 @@ func (t *MetricTarget) YamlBytes() ([]byte, error) {
 		if strings.HasPrefix(label.Name, "__") && strings.HasSuffix(label.Name, "__") {
 			continue
 		}
+		// 请求参数维度已转换为 params
+		if strings.HasPrefix(label.Name, model.ParamLabelPrefix) {
+			continue
+		}
 		// 如果有内置管理维度 则追加 label 并统一加上 bk_ 前缀
 		if IsBuiltinLabels(label.Name) {
 			lbs = append(lbs, yaml.MapItem{
@@ -89,6 +89,19 @@ tasks:
 	assert.Equal(t, expected, string(b))
 }
 
+func TestMetricsTargetWithLimits(t *testing.T) {
+	target := MetricTarget{
+		Address:     "http://localhost:8080",
+		Path:        "/metrics",
+		SampleLimit: 1000,
+		LabelLimit:  30,
+	}
+
+	b, err := target.YamlBytes()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "    sample_limit: 1000\n    label_limit: 30\n")
+}
+
 func TestRemoteRelabelConfig(t *testing.T) {
 	cases := []struct {
 		Name   string
@@ -0,0 +1,190 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// deadLetterMaxLineSize : 单条死信记录的最大长度
+const deadLetterMaxLineSize = 64 * 1024 * 1024
+
+// deadLetterFilter : 死信重放过滤条件
+type deadLetterFilter struct {
+	dataID    int
+	stage     string
+	processor string
+}
+
+func (f *deadLetterFilter) match(letter *define.DeadLetter) bool {
+	if letter.DataID != f.dataID {
+		return false
+	}
+	if f.stage != "" && letter.Stage != f.stage {
+		return false
+	}
+	if f.processor != "" && letter.Processor != f.processor {
+		return false
+	}
+	return true
+}
+
+// readDeadLetters : 按行读取死信记录，无法解析的行跳过
+func readDeadLetters(reader io.Reader, fn func(letter *define.DeadLetter)) (invalid int, err error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), deadLetterMaxLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var letter define.DeadLetter
+		if e := json.Unmarshal(line, &letter); e != nil {
+			invalid++
+			continue
+		}
+		fn(&letter)
+	}
+	return invalid, scanner.Err()
+}
+
+func loadReplayPipelineConfig(raw, file string) *config.PipelineConfig {
+	var data []byte
+	switch {
+	case raw != "":
+		data = []byte(raw)
+	case file != "":
+		content, err := os.ReadFile(file)
+		checkError(err, -1, "read pipeline config %s failed", file)
+		data = content
+	default:
+		exitf(-1, "pipeline config is required, use --raw or --config-file")
+	}
+
+	pipe := config.NewPipelineConfig()
+	checkError(json.Unmarshal(data, pipe), -1, "parse pipeline config failed")
+	checkError(pipe.Clean(), -1, "clean pipeline config failed")
+	if pipe.MQConfig == nil {
+		exitf(-1, "mq config of data id %d is empty", pipe.DataID)
+	}
+	return pipe
+}
+
+// deadLetterCmd represents the dead letter command
+var deadLetterCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "Dead letter queue tools",
+}
+
+// deadLetterReplayCmd represents the dead letter replay command
+var deadLetterReplayCmd = &cobra.Command{
+	Use:     "replay",
+	Short:   "Replay dead letters to the mq of pipeline",
+	Long:    "Read dead letters line by line and write the raw payloads back to the mq of pipeline, so that they are processed again by the fixed pipeline",
+	Example: `./transfer dlq replay -i /data/transfer/dead_letter/1001/dead_letter --config-file 1001.json --stage processor`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		input, err := flags.GetString("input")
+		checkError(err, -1, "get input failed")
+		raw, err := flags.GetString("raw")
+		checkError(err, -1, "get raw failed")
+		configFile, err := flags.GetString("config-file")
+		checkError(err, -1, "get config-file failed")
+		stage, err := flags.GetString("stage")
+		checkError(err, -1, "get stage failed")
+		processor, err := flags.GetString("processor")
+		checkError(err, -1, "get processor failed")
+		dryRun, err := flags.GetBool("dry-run")
+		checkError(err, -1, "get dry-run failed")
+
+		pipe := loadReplayPipelineConfig(raw, configFile)
+		filter := &deadLetterFilter{dataID: pipe.DataID, stage: stage, processor: processor}
+
+		reader := os.Stdin
+		if input != "" {
+			reader, err = os.Open(input)
+			checkError(err, -1, "open %s failed", input)
+			defer func() {
+				utils.CheckError(reader.Close())
+			}()
+		}
+
+		var push func(payload define.Payload)
+		if dryRun {
+			push = func(payload define.Payload) {
+				fmt.Printf("%+v\n", payload)
+			}
+		} else {
+			// 回写到 pipeline 的消费队列，数据将重新经过完整的清洗流程
+			ctx := config.IntoContext(context.Background(), config.Configuration)
+			ctx = config.PipelineConfigIntoContext(ctx, pipe)
+			ctx = config.MQConfigIntoContext(ctx, pipe.MQConfig)
+			ctx = config.ShipperConfigIntoContext(ctx, pipe.MQConfig)
+			if len(pipe.ResultTableList) > 0 {
+				ctx = config.ResultTableConfigIntoContext(ctx, pipe.ResultTableList[0])
+			}
+			backend, err := define.NewBackend(ctx, pipe.MQConfig.ClusterType)
+			checkError(err, -1, "create %s backend failed", pipe.MQConfig.ClusterType)
+
+			killCh := make(chan error)
+			go func() {
+				for err := range killCh {
+					exitf(-1, "replay failed: %v", err)
+				}
+			}()
+			defer func() {
+				checkError(backend.Close(), -1, "close backend failed")
+				close(killCh)
+			}()
+			push = func(payload define.Payload) {
+				backend.Push(payload, killCh)
+			}
+		}
+
+		var replayed, skipped int
+		invalid, err := readDeadLetters(reader, func(letter *define.DeadLetter) {
+			if !filter.match(letter) {
+				skipped++
+				return
+			}
+			payload := define.NewJSONPayloadFrom(letter.Payload, replayed)
+			payload.SetTime(time.Now())
+			push(payload)
+			replayed++
+		})
+		checkError(err, -1, "read dead letters failed")
+
+		fmt.Printf("data id %d replayed: %d, skipped: %d, invalid: %d\n", pipe.DataID, replayed, skipped, invalid)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deadLetterCmd)
+	deadLetterCmd.AddCommand(deadLetterReplayCmd)
+	flags := deadLetterReplayCmd.Flags()
+	flags.StringP("input", "i", "", "dead letter file, read from stdin if empty")
+	flags.String("raw", "", "pipeline config in json")
+	flags.String("config-file", "", "pipeline config file in json")
+	flags.String("stage", define.DeadLetterStageProcessor, "only replay dead letters of the stage, empty for all")
+	flags.String("processor", "", "only replay dead letters of the processor")
+	flags.Bool("dry-run", false, "print payloads instead of writing to mq")
+}
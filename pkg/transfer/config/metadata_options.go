@@ -84,6 +84,8 @@ const (
 	PipelineConfigDropEmptyMetrics = "drop_empty_metrics"
 	// PipelineConfigDisableMetricsReporter 是否关闭 metrics_reporter 特性
 	PipelineConfigDisableMetricsReporter = "disable_metrics_reporter"
+	// PipelineConfigOptDeadLetterConfig 死信队列的存储配置，格式同 shipper(kafka/file)
+	PipelineConfigOptDeadLetterConfig = "dead_letter_config"
 
 	// 日志类
 	// PipelineConfigOptSeparatorNode : "字段提取节点路径"
@@ -98,7 +100,9 @@ const (
 	PipelineConfigOptLogSeparatedFields = "separator_field_list"
 	// PipelineConfigOptLogSeparatorRegexp : 日志正则提取清洗专用，提取字段
 	PipelineConfigOptLogSeparatorRegexp = "separator_regexp"
-	PipelineConfigOptionIsLogData       = "is_log_data"
+	// PipelineConfigOptLogSeparatorGrok : 日志 grok 提取清洗专用，grok 表达式
+	PipelineConfigOptLogSeparatorGrok = "separator_grok"
+	PipelineConfigOptionIsLogData     = "is_log_data"
 	// PipelineConfigOptionRetainExtraJson : JSON清洗时, 未定义字段将会归到ext里
 	PipelineConfigOptionRetainExtraJson = "retain_extra_json"
 	// PipelineConfigOptionRetainContent 数据清洗失败时是否保留原始日志文本
@@ -151,6 +155,8 @@ const (
 	ResultTableOptLogSeparatedFields = "separator_field_list"
 	// ResultTableOptLogSeparatorRegexp : 日志正则提取清洗专用，提取字段
 	ResultTableOptLogSeparatorRegexp = "separator_regexp"
+	// ResultTableOptLogGrokPatterns : 日志 grok 提取清洗专用，自定义模式(name -> pattern)
+	ResultTableOptLogGrokPatterns = "grok_patterns"
 
 	ResultTableOptLogSeparatorConfigs = "separator_configs"
 
@@ -182,6 +188,11 @@ const (
 
 	// ResultTableOptMustIncludeDimensions 指标中必须拥有指定的所有维度 否则将丢弃
 	ResultTableOptMustIncludeDimensions = "must_include_dimensions"
+
+	// ResultTableOptEnableSchemaDrift : 开启结构漂移检测，发现新字段、类型变更及维度膨胀时上报事件(bool)
+	ResultTableOptEnableSchemaDrift = "enable_schema_drift"
+	// ResultTableOptSchemaDriftMaxDimensionValues : 结构漂移检测窗口内单个维度允许的最大取值数(int)
+	ResultTableOptSchemaDriftMaxDimensionValues = "schema_drift_max_dimension_values"
 )
 
 // MetaFieldConfig 专用
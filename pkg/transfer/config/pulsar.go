@@ -0,0 +1,71 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package config
+
+import (
+	"fmt"
+)
+
+const (
+	// PulsarSubscriptionShared : 共享订阅，消息轮询分发给所有消费者
+	PulsarSubscriptionShared = "shared"
+	// PulsarSubscriptionKeyShared : 按 key 共享订阅，相同 key 的消息分发到同一个消费者
+	PulsarSubscriptionKeyShared = "key_shared"
+)
+
+// PulsarMetaClusterInfo :
+type PulsarMetaClusterInfo struct {
+	*SimpleMetaClusterInfo
+}
+
+// GetTopic :
+func (c *PulsarMetaClusterInfo) GetTopic() string {
+	return c.StorageConfigHelper.MustGetString("topic")
+}
+
+// SetTopic :
+func (c *PulsarMetaClusterInfo) SetTopic(val string) {
+	c.StorageConfigHelper.Set("topic", val)
+}
+
+// GetSubscriptionType : 订阅类型，未配置时返回空，由全局配置决定
+func (c *PulsarMetaClusterInfo) GetSubscriptionType() string {
+	val, ok := c.StorageConfigHelper.GetString("subscription_type")
+	if !ok {
+		return ""
+	}
+	return val
+}
+
+// SetSubscriptionType :
+func (c *PulsarMetaClusterInfo) SetSubscriptionType(val string) {
+	c.StorageConfigHelper.Set("subscription_type", val)
+}
+
+// GetURL : 服务地址，schema 默认为 pulsar，启用 tls 时为 pulsar+ssl
+func (c *PulsarMetaClusterInfo) GetURL() string {
+	schema, ok := c.ClusterConfigHelper.GetString("schema")
+	if !ok || schema == "" {
+		schema = "pulsar"
+	}
+	return fmt.Sprintf("%s://%s:%d", schema, c.GetDomain(), c.GetPort())
+}
+
+// GetTarget :
+func (c *PulsarMetaClusterInfo) GetTarget() string {
+	return c.GetTopic()
+}
+
+// AsPulsarCluster :
+func (c *MetaClusterInfo) AsPulsarCluster() *PulsarMetaClusterInfo {
+	return &PulsarMetaClusterInfo{
+		SimpleMetaClusterInfo: NewSimpleMetaClusterInfo(c),
+	}
+}
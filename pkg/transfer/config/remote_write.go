@@ -0,0 +1,84 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"github.com/cstockton/go-conv"
+)
+
+// RemoteWriteDefaultPath : remote write 默认写入路径
+const RemoteWriteDefaultPath = "/api/v1/write"
+
+// RemoteWriteMetaClusterInfo :
+type RemoteWriteMetaClusterInfo struct {
+	*SimpleMetaClusterInfo
+}
+
+// GetPath : 写入路径，未配置时使用 prometheus 标准路径
+func (c *RemoteWriteMetaClusterInfo) GetPath() string {
+	path, ok := c.StorageConfigHelper.GetString("path")
+	if !ok || path == "" {
+		return RemoteWriteDefaultPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// SetPath :
+func (c *RemoteWriteMetaClusterInfo) SetPath(val string) {
+	c.StorageConfig["path"] = val
+}
+
+// GetURL : 完整写入地址
+func (c *RemoteWriteMetaClusterInfo) GetURL() string {
+	return c.GetAddress() + c.GetPath()
+}
+
+// GetExternalLabels : 该数据源写入时需要额外附加的标签
+func (c *RemoteWriteMetaClusterInfo) GetExternalLabels() map[string]string {
+	labels := make(map[string]string)
+	value, ok := c.StorageConfig["external_labels"]
+	if !ok {
+		return labels
+	}
+
+	switch items := value.(type) {
+	case map[string]interface{}:
+		for k, v := range items {
+			labels[k] = conv.String(v)
+		}
+	case map[string]string:
+		for k, v := range items {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// SetExternalLabels :
+func (c *RemoteWriteMetaClusterInfo) SetExternalLabels(val map[string]string) {
+	c.StorageConfig["external_labels"] = val
+}
+
+// GetTarget :
+func (c *RemoteWriteMetaClusterInfo) GetTarget() string {
+	return c.GetURL()
+}
+
+// AsRemoteWriteCluster :
+func (c *MetaClusterInfo) AsRemoteWriteCluster() *RemoteWriteMetaClusterInfo {
+	return &RemoteWriteMetaClusterInfo{
+		SimpleMetaClusterInfo: NewSimpleMetaClusterInfo(c),
+	}
+}
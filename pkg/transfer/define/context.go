@@ -23,6 +23,7 @@ const (
 	ContextETLPluginKey
 	ContextStartCacheKey
 	ContextRuntimeKey
+	ContextPreviewKey
 )
 
 //go:generate stringer -type=ContextKey -trimprefix Context
@@ -0,0 +1,95 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package define
+
+import (
+	"sync"
+	"time"
+)
+
+// 死信产生的阶段
+const (
+	DeadLetterStageFrontend  = "frontend"
+	DeadLetterStageProcessor = "processor"
+	DeadLetterStageBackend   = "backend"
+)
+
+// DeadLetter : 被处理节点拒绝的原始数据及原因
+type DeadLetter struct {
+	DataID    int    `json:"data_id"`
+	Processor string `json:"processor"`
+	Stage     string `json:"stage"`
+	Error     string `json:"error"`
+	Payload   []byte `json:"payload"`
+	Time      int64  `json:"time"`
+}
+
+// DeadLetterSource : 死信来源
+type DeadLetterSource struct {
+	DataID    int
+	Processor string
+	Stage     string
+	// Sink 非空时直接投递到该接收器，不再按 dataid 查找
+	Sink DeadLetterSink
+}
+
+// DeadLetterSink : 死信接收器，Put 不应阻塞调用方
+type DeadLetterSink interface {
+	Put(letter *DeadLetter) bool
+}
+
+var deadLetterSinks sync.Map
+
+// RegisterDeadLetterSink : 注册 dataid 对应的死信接收器
+func RegisterDeadLetterSink(dataID int, sink DeadLetterSink) {
+	deadLetterSinks.Store(dataID, sink)
+}
+
+// UnregisterDeadLetterSink : 仅当当前注册的接收器为 sink 时才注销，避免误删重载后的新接收器
+func UnregisterDeadLetterSink(dataID int, sink DeadLetterSink) {
+	deadLetterSinks.CompareAndDelete(dataID, sink)
+}
+
+// GetDeadLetterSink :
+func GetDeadLetterSink(dataID int) (DeadLetterSink, bool) {
+	value, ok := deadLetterSinks.Load(dataID)
+	if !ok {
+		return nil, false
+	}
+	return value.(DeadLetterSink), true
+}
+
+// SendDeadLetter : 将被拒绝 payload 的前端原始数据投递到 dataid 的死信队列，未配置时直接忽略
+func SendDeadLetter(source *DeadLetterSource, payload Payload, err error) bool {
+	sink := source.Sink
+	if sink == nil {
+		sink, _ = GetDeadLetterSink(source.DataID)
+	}
+	if sink == nil || payload == nil {
+		return false
+	}
+
+	data, e := PayloadOrigin(payload)
+	if e != nil {
+		return false
+	}
+
+	letter := &DeadLetter{
+		DataID:    source.DataID,
+		Processor: source.Processor,
+		Stage:     source.Stage,
+		Payload:   data,
+		Time:      time.Now().Unix(),
+	}
+	if err != nil {
+		letter.Error = err.Error()
+	}
+	return sink.Put(letter)
+}
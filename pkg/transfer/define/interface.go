@@ -68,6 +68,11 @@ type payloadCopier interface {
 	copy() Payload
 }
 
+type payloadOrigin interface {
+	Origin() []byte
+	SetOrigin(data []byte)
+}
+
 // DataProcessor : processor to handle data in pipeline
 type DataProcessor interface {
 	Stringer
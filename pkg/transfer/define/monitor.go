@@ -19,6 +19,21 @@ import (
 
 type ProcessorMonitor struct {
 	*monitor.CounterMixin
+	// 死信来源信息，为空时只计数
+	DeadLetterSource *DeadLetterSource
+}
+
+// GetProcessorMonitor : 嵌入了 ProcessorMonitor 的处理器可以通过该方法取得监控对象
+func (m *ProcessorMonitor) GetProcessorMonitor() *ProcessorMonitor {
+	return m
+}
+
+// Reject : 记录一次失败，并将 payload 投递到死信队列
+func (m *ProcessorMonitor) Reject(payload Payload, err error) {
+	m.CounterFails.Inc()
+	if m.DeadLetterSource != nil {
+		SendDeadLetter(m.DeadLetterSource, payload, err)
+	}
 }
 
 var (
@@ -32,6 +32,8 @@ type BasePayload struct {
 	Data []byte
 	t    time.Time
 	flag PayloadFlag
+	// 派生 payload 对应的前端原始数据
+	origin []byte
 
 	r *ETLRecord
 }
@@ -119,6 +121,19 @@ func (p *BasePayload) GetETLRecord() *ETLRecord {
 	return p.r
 }
 
+// Origin : 前端原始数据，前端 payload 返回自身数据
+func (p *BasePayload) Origin() []byte {
+	if p.origin != nil {
+		return p.origin
+	}
+	return p.Data
+}
+
+// SetOrigin :
+func (p *BasePayload) SetOrigin(data []byte) {
+	p.origin = data
+}
+
 // NewBasePayloadFrom :
 func NewBasePayloadFrom(data []byte, sn int) *BasePayload {
 	return &BasePayload{
@@ -183,6 +198,13 @@ func DerivePayload(payload Payload, v interface{}) (derived Payload, err error)
 		derived.SetFlag(t.Flag())
 	}
 
+	// 派生 payload 保留前端原始数据，用于死信重放
+	if source, ok := payload.(payloadOrigin); ok {
+		if target, ok := derived.(payloadOrigin); ok {
+			target.SetOrigin(source.Origin())
+		}
+	}
+
 	err = derived.From(v)
 	if err != nil {
 		return nil, err
@@ -191,6 +213,17 @@ func DerivePayload(payload Payload, v interface{}) (derived Payload, err error)
 	return derived, nil
 }
 
+// PayloadOrigin : 获取 payload 对应的前端原始数据
+func PayloadOrigin(payload Payload) ([]byte, error) {
+	if p, ok := payload.(payloadOrigin); ok {
+		return p.Origin(), nil
+	}
+
+	var data []byte
+	err := payload.To(&data)
+	return data, err
+}
+
 // NewJSONPayload :
 func NewJSONPayload(sn int) *JSONPayload {
 	return NewJSONPayloadFrom(make([]byte, 0), sn)
@@ -0,0 +1,248 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+package drift
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+)
+
+var timeNow = time.Now
+
+// valueType : 推断观测值的字段类型，无法判断时返回空
+func valueType(value interface{}) define.MetaFieldType {
+	switch value.(type) {
+	case bool:
+		return define.MetaFieldTypeBool
+	case float32, float64:
+		return define.MetaFieldTypeFloat
+	case int, int8, int16, int32, int64:
+		return define.MetaFieldTypeInt
+	case uint, uint8, uint16, uint32, uint64:
+		return define.MetaFieldTypeUint
+	case string, []byte:
+		return define.MetaFieldTypeString
+	case time.Time:
+		return define.MetaFieldTypeTimestamp
+	case map[string]interface{}, []interface{}:
+		return define.MetaFieldTypeObject
+	default:
+		return ""
+	}
+}
+
+func isNumberType(t define.MetaFieldType) bool {
+	switch t {
+	case define.MetaFieldTypeInt, define.MetaFieldTypeUint, define.MetaFieldTypeFloat:
+		return true
+	}
+	return false
+}
+
+// isCompatible : 判断观测值能否按配置类型正常转换
+func isCompatible(expected, actual define.MetaFieldType, value interface{}) bool {
+	if actual == "" || expected == actual {
+		return true
+	}
+
+	switch expected {
+	case define.MetaFieldTypeInt, define.MetaFieldTypeUint, define.MetaFieldTypeFloat:
+		if isNumberType(actual) {
+			return true
+		}
+		if s, ok := value.(string); ok {
+			_, err := strconv.ParseFloat(s, 64)
+			return err == nil
+		}
+		return false
+	case define.MetaFieldTypeBool:
+		if s, ok := value.(string); ok {
+			_, err := strconv.ParseBool(s)
+			return err == nil
+		}
+		return false
+	case define.MetaFieldTypeString:
+		return actual != define.MetaFieldTypeObject
+	case define.MetaFieldTypeTimestamp:
+		return isNumberType(actual) || actual == define.MetaFieldTypeString
+	case define.MetaFieldTypeObject, define.MetaFieldTypeNested:
+		return actual == define.MetaFieldTypeString
+	default:
+		return true
+	}
+}
+
+// Detector : 按结果表检测结构漂移，检测结果在窗口内聚合，由 Flush 取出
+type Detector struct {
+	dataID             int
+	resultTable        string
+	fields             map[string]*config.MetaFieldConfig
+	maxDimensionValues int
+	maxEvents          int
+	reportTTL          time.Duration
+
+	lock            sync.Mutex
+	events          map[string]*Event
+	dimensionValues map[string]map[string]struct{}
+	dropped         int
+	// reported 已发布事件的过期时间，过期前不再重复聚合
+	reported map[string]int64
+}
+
+// Observe : 检测单条记录
+func (d *Detector) Observe(record *define.ETLRecord) {
+	now := timeNow().Unix()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.observe(define.MetaFieldTagDimension, record.Dimensions, now)
+	d.observe(define.MetaFieldTagMetric, record.Metrics, now)
+}
+
+func (d *Detector) observe(tag define.MetaFieldTagType, values map[string]interface{}, now int64) {
+	for name, value := range values {
+		actual := valueType(value)
+		field, ok := d.fields[name]
+		if !ok {
+			d.record(&Event{Kind: EventKindNewField, Tag: tag, Field: name, ActualType: actual, Sample: value}, now)
+		} else if !isCompatible(field.Type, actual, value) {
+			d.record(&Event{Kind: EventKindTypeChanged, Tag: tag, Field: name, ExpectedType: field.Type, ActualType: actual, Sample: value}, now)
+		}
+
+		if tag == define.MetaFieldTagDimension {
+			d.observeDimension(name, value, now)
+		}
+	}
+}
+
+func (d *Detector) observeDimension(name string, value interface{}, now int64) {
+	if d.maxDimensionValues <= 0 {
+		return
+	}
+
+	values, ok := d.dimensionValues[name]
+	if !ok {
+		values = make(map[string]struct{})
+		d.dimensionValues[name] = values
+	}
+	// 已经膨胀的维度不再记录取值，避免内存随取值数增长
+	exploded := len(values) > d.maxDimensionValues
+	if !exploded {
+		values[fmt.Sprintf("%v", value)] = struct{}{}
+		if len(values) <= d.maxDimensionValues {
+			return
+		}
+	}
+
+	d.record(&Event{
+		Kind:           EventKindDimensionExplosion,
+		Tag:            define.MetaFieldTagDimension,
+		Field:          name,
+		Sample:         value,
+		MinCardinality: len(values),
+		Threshold:      d.maxDimensionValues,
+	}, now)
+}
+
+func (d *Detector) record(event *Event, now int64) {
+	key := event.key()
+	if expires, ok := d.reported[key]; ok && expires > now {
+		return
+	}
+
+	current, ok := d.events[key]
+	if !ok {
+		if len(d.events) >= d.maxEvents {
+			d.dropped++
+			return
+		}
+		event.DataID = d.dataID
+		event.ResultTable = d.resultTable
+		event.FirstSeen = now
+		d.events[key] = event
+		current = event
+	}
+	current.Count++
+	current.LastSeen = now
+}
+
+// Flush : 取出当前窗口聚合的事件及被丢弃的事件数，并开启新窗口
+func (d *Detector) Flush() ([]*Event, int) {
+	d.lock.Lock()
+	events, dropped := d.events, d.dropped
+	d.events = make(map[string]*Event)
+	d.dimensionValues = make(map[string]map[string]struct{})
+	d.dropped = 0
+	d.lock.Unlock()
+
+	keys := make([]string, 0, len(events))
+	for key := range events {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make([]*Event, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, events[key])
+	}
+	return results, dropped
+}
+
+// Reported : 标记事件已发布，reportTTL 内相同事件不再重复发布
+func (d *Detector) Reported(events []*Event) {
+	if d.reportTTL <= 0 {
+		return
+	}
+	now := timeNow()
+	expires := now.Add(d.reportTTL).Unix()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for key, value := range d.reported {
+		if value <= now.Unix() {
+			delete(d.reported, key)
+		}
+	}
+	for _, event := range events {
+		d.reported[event.key()] = expires
+	}
+}
+
+// NewDetector : 字段同时按字段名和别名登记，兼容清洗前后两种命名
+func NewDetector(dataID int, rt *config.MetaResultTableConfig, maxDimensionValues, maxEvents int, reportTTL time.Duration) *Detector {
+	fields := make(map[string]*config.MetaFieldConfig)
+	for _, field := range rt.FieldList {
+		if field.FieldName != "" {
+			fields[field.FieldName] = field
+		}
+		if field.AliasName != "" {
+			fields[field.AliasName] = field
+		}
+	}
+
+	return &Detector{
+		dataID:             dataID,
+		resultTable:        rt.ResultTable,
+		fields:             fields,
+		maxDimensionValues: maxDimensionValues,
+		maxEvents:          maxEvents,
+		reportTTL:          reportTTL,
+		events:             make(map[string]*Event),
+		dimensionValues:    make(map[string]map[string]struct{}),
+		reported:           make(map[string]int64),
+	}
+}
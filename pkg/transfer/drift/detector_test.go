@@ -0,0 +1,200 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+package drift_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/drift"
+)
+
+// DetectorSuite :
+type DetectorSuite struct {
+	suite.Suite
+	rt *config.MetaResultTableConfig
+}
+
+// SetupTest :
+func (s *DetectorSuite) SetupTest() {
+	s.rt = &config.MetaResultTableConfig{
+		ResultTable: "2_system.cpu",
+		FieldList: []*config.MetaFieldConfig{
+			{FieldName: "usage", Tag: define.MetaFieldTagMetric, Type: define.MetaFieldTypeFloat},
+			{FieldName: "ip", AliasName: "bk_target_ip", Tag: define.MetaFieldTagDimension, Type: define.MetaFieldTypeString},
+			{FieldName: "enabled", Tag: define.MetaFieldTagDimension, Type: define.MetaFieldTypeBool},
+		},
+	}
+}
+
+func (s *DetectorSuite) eventsByKind(events []*drift.Event) map[string][]*drift.Event {
+	results := make(map[string][]*drift.Event)
+	for _, event := range events {
+		results[event.Kind] = append(results[event.Kind], event)
+	}
+	return results
+}
+
+// TestKnownFields :
+func (s *DetectorSuite) TestKnownFields() {
+	detector := drift.NewDetector(1, s.rt, 10, 10, 0)
+	detector.Observe(&define.ETLRecord{
+		Dimensions: map[string]interface{}{"ip": "127.0.0.1", "enabled": "true"},
+		Metrics:    map[string]interface{}{"usage": 1.0},
+	})
+	detector.Observe(&define.ETLRecord{
+		Dimensions: map[string]interface{}{"bk_target_ip": "127.0.0.1", "enabled": true},
+		Metrics:    map[string]interface{}{"usage": "2.5"},
+	})
+
+	events, dropped := detector.Flush()
+	s.Empty(events)
+	s.Equal(0, dropped)
+}
+
+// TestNewField :
+func (s *DetectorSuite) TestNewField() {
+	detector := drift.NewDetector(1, s.rt, 10, 10, 0)
+	for i := 0; i < 3; i++ {
+		detector.Observe(&define.ETLRecord{
+			Dimensions: map[string]interface{}{"ip": "127.0.0.1", "device": "eth0"},
+			Metrics:    map[string]interface{}{"usage": 1.0, "idle": 2},
+		})
+	}
+
+	events, _ := detector.Flush()
+	s.Len(events, 2)
+	for _, event := range events {
+		s.Equal(drift.EventKindNewField, event.Kind)
+		s.Equal(1, event.DataID)
+		s.Equal("2_system.cpu", event.ResultTable)
+		s.Equal(int64(3), event.Count)
+		s.True(event.FirstSeen <= event.LastSeen)
+		switch event.Field {
+		case "device":
+			s.Equal(define.MetaFieldTagDimension, event.Tag)
+			s.Equal(define.MetaFieldTypeString, event.ActualType)
+			s.Equal("eth0", event.Sample)
+		case "idle":
+			s.Equal(define.MetaFieldTagMetric, event.Tag)
+			s.Equal(define.MetaFieldTypeInt, event.ActualType)
+		default:
+			s.Failf("unexpected field", "%s", event.Field)
+		}
+	}
+
+	// 新窗口重新计数
+	events, _ = detector.Flush()
+	s.Empty(events)
+}
+
+// TestTypeChanged :
+func (s *DetectorSuite) TestTypeChanged() {
+	cases := []struct {
+		dimensions map[string]interface{}
+		metrics    map[string]interface{}
+		field      string
+		actual     define.MetaFieldType
+	}{
+		{nil, map[string]interface{}{"usage": "abc"}, "usage", define.MetaFieldTypeString},
+		{nil, map[string]interface{}{"usage": true}, "usage", define.MetaFieldTypeBool},
+		{map[string]interface{}{"ip": map[string]interface{}{"v4": "127.0.0.1"}}, nil, "ip", define.MetaFieldTypeObject},
+		{map[string]interface{}{"enabled": "yes"}, nil, "enabled", define.MetaFieldTypeString},
+		{map[string]interface{}{"enabled": 1.0}, nil, "enabled", define.MetaFieldTypeFloat},
+	}
+
+	for i, c := range cases {
+		detector := drift.NewDetector(1, s.rt, 0, 10, 0)
+		detector.Observe(&define.ETLRecord{Dimensions: c.dimensions, Metrics: c.metrics})
+		events, _ := detector.Flush()
+		s.Len(events, 1, i)
+		if len(events) == 1 {
+			s.Equal(drift.EventKindTypeChanged, events[0].Kind, i)
+			s.Equal(c.field, events[0].Field, i)
+			s.Equal(c.actual, events[0].ActualType, i)
+		}
+	}
+}
+
+// TestDimensionExplosion :
+func (s *DetectorSuite) TestDimensionExplosion() {
+	detector := drift.NewDetector(1, s.rt, 3, 10, 0)
+	for _, ip := range []string{"a", "b", "c", "a", "d", "e", "f"} {
+		detector.Observe(&define.ETLRecord{Dimensions: map[string]interface{}{"ip": ip}})
+	}
+
+	events, _ := detector.Flush()
+	s.Len(events, 1)
+	event := events[0]
+	s.Equal(drift.EventKindDimensionExplosion, event.Kind)
+	s.Equal("ip", event.Field)
+	s.Equal("d", event.Sample)
+	s.Equal(4, event.MinCardinality)
+	s.Equal(3, event.Threshold)
+	s.Equal(int64(3), event.Count)
+
+	// 窗口重置后重新统计取值
+	for _, ip := range []string{"a", "b", "c"} {
+		detector.Observe(&define.ETLRecord{Dimensions: map[string]interface{}{"ip": ip}})
+	}
+	events, _ = detector.Flush()
+	s.Empty(events)
+}
+
+// TestReported :
+func (s *DetectorSuite) TestReported() {
+	observe := func(detector *drift.Detector) []*drift.Event {
+		detector.Observe(&define.ETLRecord{Metrics: map[string]interface{}{"usage": 1.0, "idle": 2}})
+		events, _ := detector.Flush()
+		return events
+	}
+
+	detector := drift.NewDetector(1, s.rt, 0, 10, time.Hour)
+	events := observe(detector)
+	s.Len(events, 1)
+	// 未标记发布的事件下个窗口继续上报
+	s.Len(observe(detector), 1)
+
+	// 已发布的事件在有效期内不再上报，新事件不受影响
+	detector.Reported(events)
+	s.Empty(observe(detector))
+	detector.Observe(&define.ETLRecord{Metrics: map[string]interface{}{"usage": 1.0, "iowait": 2}})
+	events, _ = detector.Flush()
+	s.Len(events, 1)
+	s.Equal("iowait", events[0].Field)
+
+	// 有效期过后重新上报
+	detector = drift.NewDetector(1, s.rt, 0, 10, time.Nanosecond)
+	detector.Reported(observe(detector))
+	s.Len(observe(detector), 1)
+
+	// 不设置有效期时每个窗口都上报
+	detector = drift.NewDetector(1, s.rt, 0, 10, 0)
+	detector.Reported(observe(detector))
+	s.Len(observe(detector), 1)
+}
+
+// TestMaxEvents :
+func (s *DetectorSuite) TestMaxEvents() {
+	detector := drift.NewDetector(1, s.rt, 0, 2, 0)
+	detector.Observe(&define.ETLRecord{Metrics: map[string]interface{}{"a": 1.0, "b": 1.0, "c": 1.0, "d": 1.0}})
+
+	events, dropped := detector.Flush()
+	s.Len(s.eventsByKind(events)[drift.EventKindNewField], 2)
+	s.Equal(2, dropped)
+}
+
+// TestDetectorSuite :
+func TestDetectorSuite(t *testing.T) {
+	suite.Run(t, new(DetectorSuite))
+}
@@ -0,0 +1,42 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+package drift
+
+import (
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+)
+
+// 结构漂移事件类型
+const (
+	EventKindNewField           = "new_field"
+	EventKindTypeChanged        = "type_changed"
+	EventKindDimensionExplosion = "dimension_explosion"
+)
+
+// Event : 结构漂移事件，同一窗口内相同字段的同类事件会合并为一条
+type Event struct {
+	Kind         string                  `json:"kind"`
+	DataID       int                     `json:"data_id"`
+	ResultTable  string                  `json:"result_table"`
+	Field        string                  `json:"field"`
+	Tag          define.MetaFieldTagType `json:"tag"`
+	ExpectedType define.MetaFieldType    `json:"expected_type,omitempty"`
+	ActualType   define.MetaFieldType    `json:"actual_type,omitempty"`
+	Sample       interface{}             `json:"sample,omitempty"`
+	// MinCardinality 维度膨胀时窗口内取值数的下限，超过阈值后不再继续统计，实际取值数不小于该值
+	MinCardinality int   `json:"min_cardinality,omitempty"`
+	Threshold      int   `json:"threshold,omitempty"`
+	Count          int64 `json:"count"`
+	FirstSeen      int64 `json:"first_seen"`
+	LastSeen       int64 `json:"last_seen"`
+}
+
+func (e *Event) key() string {
+	return e.Kind + "|" + string(e.Tag) + "|" + e.Field + "|" + string(e.ActualType)
+}
@@ -0,0 +1,49 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+package drift
+
+import (
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/eventbus"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+const (
+	ConfSchemaDriftWindow             = "schema_drift.window"
+	ConfSchemaDriftMaxDimensionValues = "schema_drift.max_dimension_values"
+	ConfSchemaDriftMaxEvents          = "schema_drift.max_events"
+	ConfSchemaDriftReportTTL          = "schema_drift.report_ttl"
+	ConfSchemaDriftPublisher          = "schema_drift.publisher"
+	ConfSchemaDriftRedisAddress       = "schema_drift.redis.address"
+	ConfSchemaDriftRedisPassword      = "schema_drift.redis.password"
+	ConfSchemaDriftRedisDatabase      = "schema_drift.redis.database"
+	ConfSchemaDriftRedisKey           = "schema_drift.redis.key"
+	ConfSchemaDriftRedisMaxLength     = "schema_drift.redis.max_length"
+	ConfSchemaDriftHTTPURL            = "schema_drift.http.url"
+	ConfSchemaDriftHTTPTimeout        = "schema_drift.http.timeout"
+)
+
+func initConfiguration(c define.Configuration) {
+	c.SetDefault(ConfSchemaDriftWindow, "60s")
+	c.SetDefault(ConfSchemaDriftMaxDimensionValues, 1000)
+	c.SetDefault(ConfSchemaDriftMaxEvents, 500)   // 单个结果表每个窗口最多聚合的事件数
+	c.SetDefault(ConfSchemaDriftReportTTL, "24h") // 已发布的事件在该时间内不再重复发布
+	c.SetDefault(ConfSchemaDriftPublisher, PublisherRedis)
+	c.SetDefault(ConfSchemaDriftRedisAddress, "") // 为空时复用 redis 类型的 storage
+	c.SetDefault(ConfSchemaDriftRedisPassword, "")
+	c.SetDefault(ConfSchemaDriftRedisDatabase, 0)
+	c.SetDefault(ConfSchemaDriftRedisKey, "bkmonitor:transfer:schema_drift")
+	c.SetDefault(ConfSchemaDriftRedisMaxLength, 10000)
+	c.SetDefault(ConfSchemaDriftHTTPURL, "")
+	c.SetDefault(ConfSchemaDriftHTTPTimeout, "10s")
+}
+
+func init() {
+	utils.CheckError(eventbus.Subscribe(eventbus.EvSysConfigPreParse, initConfiguration))
+}
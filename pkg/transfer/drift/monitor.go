@@ -0,0 +1,46 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+package drift
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+)
+
+var (
+	// MonitorSchemaDriftEvents 结构漂移事件计数器
+	MonitorSchemaDriftEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "schema_drift_events_total",
+		Help:      "Schema drift events count",
+	}, []string{"id", "kind"})
+
+	// MonitorSchemaDriftEventsDropped 超出窗口事件上限被丢弃的事件计数器
+	MonitorSchemaDriftEventsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "schema_drift_events_dropped_total",
+		Help:      "Schema drift events dropped count",
+	}, []string{"id"})
+
+	// MonitorSchemaDriftPublishFailed 结构漂移事件发布失败计数器
+	MonitorSchemaDriftPublishFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "schema_drift_publish_failed_total",
+		Help:      "Schema drift events publish failed count",
+	}, []string{"id"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		MonitorSchemaDriftEvents,
+		MonitorSchemaDriftEventsDropped,
+		MonitorSchemaDriftPublishFailed,
+	)
+}
@@ -0,0 +1,150 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+package drift
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// Processor : 结构漂移检测，数据原样透传，检测结果按窗口发布
+type Processor struct {
+	*define.BaseDataProcessor
+	*define.ProcessorMonitor
+	ctx       context.Context
+	dataID    string
+	detector  *Detector
+	publisher Publisher
+	window    time.Duration
+	once      sync.Once
+}
+
+// Process :
+func (p *Processor) Process(d define.Payload, outputChan chan<- define.Payload, killChan chan<- error) {
+	// 检测结果不影响数据流转
+	defer func() {
+		outputChan <- d
+	}()
+	if p.detector == nil {
+		return
+	}
+	p.once.Do(func() {
+		go p.run()
+	})
+
+	var record define.ETLRecord
+	if err := d.To(&record); err != nil {
+		p.CounterFails.Inc()
+		logging.Warnf("%v payload %v to record failed: %v", p, d, err)
+		return
+	}
+	p.detector.Observe(&record)
+	p.CounterSuccesses.Inc()
+}
+
+func (p *Processor) run() {
+	ticker := time.NewTicker(p.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			p.flush()
+			return
+		case <-ticker.C:
+			p.flush()
+		}
+	}
+}
+
+func (p *Processor) flush() {
+	events, dropped := p.detector.Flush()
+	if dropped > 0 {
+		logging.Warnf("%v dropped %d schema drift events exceeding window limit", p, dropped)
+		MonitorSchemaDriftEventsDropped.WithLabelValues(p.dataID).Add(float64(dropped))
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	if err := p.publisher.Publish(events); err != nil {
+		logging.Errorf("%v publish %d schema drift events failed: %v", p, len(events), err)
+		MonitorSchemaDriftPublishFailed.WithLabelValues(p.dataID).Inc()
+		return
+	}
+	p.detector.Reported(events)
+	for _, event := range events {
+		MonitorSchemaDriftEvents.WithLabelValues(p.dataID, event.Kind).Inc()
+	}
+	logging.Infof("%v published %d schema drift events", p, len(events))
+}
+
+// NewProcessor :
+func NewProcessor(ctx context.Context, name string) (*Processor, error) {
+	conf := config.FromContext(ctx)
+	pipe := config.PipelineConfigFromContext(ctx)
+	rt := config.ResultTableConfigFromContext(ctx)
+
+	processor := &Processor{
+		BaseDataProcessor: define.NewBaseDataProcessor(name),
+		ProcessorMonitor:  pipeline.NewDataProcessorMonitor(name, pipe),
+		ctx:               ctx,
+		dataID:            strconv.Itoa(pipe.DataID),
+	}
+
+	// 发布器不可用时只透传数据，不影响流水线启动
+	publisher, err := NewPublisher(ctx, conf.GetString(ConfSchemaDriftPublisher))
+	if err != nil {
+		logging.Warnf("%v create schema drift publisher failed, detection disabled: %v", processor, err)
+		return processor, nil
+	}
+
+	// 结果表可单独配置维度取值上限
+	maxDimensionValues := conf.GetInt(ConfSchemaDriftMaxDimensionValues)
+	if value, ok := utils.NewMapHelper(rt.Option).GetInt(config.ResultTableOptSchemaDriftMaxDimensionValues); ok {
+		maxDimensionValues = value
+	}
+
+	window := conf.GetDuration(ConfSchemaDriftWindow)
+	if window <= 0 {
+		return nil, errors.Wrapf(define.ErrOperationForbidden, "%s should be positive", ConfSchemaDriftWindow)
+	}
+
+	processor.detector = NewDetector(pipe.DataID, rt, maxDimensionValues, conf.GetInt(ConfSchemaDriftMaxEvents), conf.GetDuration(ConfSchemaDriftReportTTL))
+	processor.publisher = publisher
+	processor.window = window
+	return processor, nil
+}
+
+func init() {
+	define.RegisterDataProcessor("schema_drift", func(ctx context.Context, name string) (define.DataProcessor, error) {
+		pipe := config.PipelineConfigFromContext(ctx)
+		if pipe == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is empty")
+		}
+		rt := config.ResultTableConfigFromContext(ctx)
+		if rt == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "result table is empty")
+		}
+		if config.FromContext(ctx) == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "config is empty")
+		}
+		return NewProcessor(ctx, pipe.FormatName(rt.FormatName(name)))
+	})
+}
@@ -0,0 +1,247 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+package drift_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/drift"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
+	. "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/testsuite"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// standInPublisher : 记录发布事件的发布器替身
+type standInPublisher struct {
+	published chan []*drift.Event
+}
+
+func (p *standInPublisher) Publish(events []*drift.Event) error {
+	p.published <- events
+	return nil
+}
+
+// standInRedisList : 记录写入内容的 redis list 替身
+type standInRedisList struct {
+	define.Store
+	key    string
+	values []string
+	maxLen int64
+}
+
+func (l *standInRedisList) RPushBatch(key string, values []string, maxLen int64) error {
+	l.key, l.values, l.maxLen = key, values, maxLen
+	return nil
+}
+
+var testPublisher = &standInPublisher{published: make(chan []*drift.Event, 10)}
+
+func init() {
+	drift.RegisterPublisher("test", func(ctx context.Context) (drift.Publisher, error) {
+		return testPublisher, nil
+	})
+}
+
+// ProcessorSuite :
+type ProcessorSuite struct {
+	ConfigSuite
+}
+
+// SetupTest :
+func (s *ProcessorSuite) SetupTest() {
+	s.PipelineConfig = nil
+	s.ResultTableConfig = nil
+	s.ConfigSuite.SetupTest()
+
+	s.ResultTableConfig.FieldList = []*config.MetaFieldConfig{
+		{FieldName: "usage", Tag: define.MetaFieldTagMetric, Type: define.MetaFieldTypeFloat},
+		{FieldName: "ip", Tag: define.MetaFieldTagDimension, Type: define.MetaFieldTypeString},
+	}
+	s.Config.Set(drift.ConfSchemaDriftWindow, time.Hour)
+	s.Config.Set(drift.ConfSchemaDriftMaxDimensionValues, 1000)
+	s.Config.Set(drift.ConfSchemaDriftMaxEvents, 100)
+	s.Config.Set(drift.ConfSchemaDriftReportTTL, time.Hour)
+	s.Config.Set(drift.ConfSchemaDriftPublisher, "test")
+	s.Config.Set(drift.ConfSchemaDriftRedisAddress, "")
+	s.Config.Set(drift.ConfSchemaDriftRedisKey, "schema_drift")
+	s.Config.Set(drift.ConfSchemaDriftRedisMaxLength, 100)
+	s.Config.Set(drift.ConfSchemaDriftHTTPTimeout, time.Second)
+}
+
+// TestProcess :
+func (s *ProcessorSuite) TestProcess() {
+	s.ResultTableConfig.Option = map[string]interface{}{
+		config.ResultTableOptSchemaDriftMaxDimensionValues: 1,
+	}
+	processor, err := define.NewDataProcessor(s.CTX, "schema_drift")
+	s.NoError(err)
+
+	outputCh := make(chan define.Payload, 10)
+	killCh := make(chan error, 10)
+	for _, data := range []string{
+		`{"time":1,"dimensions":{"ip":"127.0.0.1"},"metrics":{"usage":1.0}}`,
+		`{"time":2,"dimensions":{"ip":"127.0.0.2","device":"eth0"},"metrics":{"usage":"abc"}}`,
+		`not a record`,
+	} {
+		payload := define.NewJSONPayloadFrom([]byte(data), 0)
+		processor.Process(payload, outputCh, killCh)
+		// 无论检测结果如何，数据原样透传
+		s.Equal(payload, <-outputCh)
+	}
+	s.Empty(killCh)
+
+	// 流水线退出时发布剩余事件
+	s.Cancel()
+	var events []*drift.Event
+	select {
+	case events = <-testPublisher.published:
+	case <-time.After(time.Second):
+		s.FailNow("events not published")
+	}
+
+	kinds := make(map[string]string)
+	for _, event := range events {
+		kinds[event.Field] = event.Kind
+	}
+	s.Equal(map[string]string{
+		"device": drift.EventKindNewField,
+		"usage":  drift.EventKindTypeChanged,
+		"ip":     drift.EventKindDimensionExplosion,
+	}, kinds)
+}
+
+// TestPipelineProcessors : 仅在结果表开启时加入流水线，且位于维度注入之前
+func (s *ProcessorSuite) TestPipelineProcessors() {
+	ts, err := pipeline.NewTSConfigBuilder(s.CTX, "")
+	s.NoError(err)
+	flat, err := pipeline.NewFlatBatchConfigBuilder(s.CTX, "")
+	s.NoError(err)
+
+	pipe := &config.PipelineConfig{Option: map[string]interface{}{}}
+	for _, enabled := range []bool{true, false} {
+		rt := &config.MetaResultTableConfig{
+			ResultTable: "test",
+			SchemaType:  config.ResultTableSchemaTypeFree,
+			Option: map[string]interface{}{
+				config.ResultTableOptEnableSchemaDrift: enabled,
+			},
+		}
+
+		processors := ts.GetStandardProcessors("", pipe, rt)
+		s.Equal(enabled, utils.IsStringInSlice("schema_drift", processors), "ts:%v", processors)
+		if enabled {
+			processors = ts.GetStandardPrepareProcessors(pipe, rt)
+			s.Equal("schema_drift", processors[0], "ts:%v", processors)
+		}
+
+		processors = flat.GetStandardProcessors(pipe, rt)
+		s.Equal(enabled, utils.IsStringInSlice("schema_drift", processors), "flat_batch:%v", processors)
+	}
+}
+
+// TestPublisherUnavailable :
+func (s *ProcessorSuite) TestPublisherUnavailable() {
+	for _, publisher := range []string{"unknown", drift.PublisherRedis} {
+		s.Config.Set(drift.ConfSchemaDriftPublisher, publisher)
+		processor, err := define.NewDataProcessor(s.CTX, "schema_drift")
+		s.NoError(err, publisher)
+
+		// 发布器不可用时只透传数据
+		outputCh := make(chan define.Payload, 1)
+		killCh := make(chan error, 1)
+		payload := define.NewJSONPayloadFrom([]byte(`{"time":1,"metrics":{"unknown":1.0}}`), 0)
+		processor.Process(payload, outputCh, killCh)
+		s.Equal(payload, <-outputCh, publisher)
+		s.Empty(killCh, publisher)
+	}
+}
+
+// TestRedisPublisher :
+func (s *ProcessorSuite) TestRedisPublisher() {
+	_, err := drift.NewPublisher(s.CTX, drift.PublisherRedis)
+	s.Error(err)
+
+	store := &standInRedisList{}
+	publisher, err := drift.NewPublisher(define.StoreIntoContext(s.CTX, store), drift.PublisherRedis)
+	s.NoError(err)
+	s.NoError(publisher.Publish([]*drift.Event{{Kind: drift.EventKindNewField, Field: "a"}}))
+	s.Equal("schema_drift", store.key)
+	s.Equal(int64(100), store.maxLen)
+	s.Len(store.values, 1)
+
+	event := new(drift.Event)
+	s.NoError(json.Unmarshal([]byte(store.values[0]), event))
+	s.Equal("a", event.Field)
+}
+
+// TestRedisAddress :
+func (s *ProcessorSuite) TestRedisAddress() {
+	server, err := miniredis.Run()
+	s.NoError(err)
+	defer server.Close()
+
+	s.Config.Set(drift.ConfSchemaDriftRedisAddress, server.Addr())
+	s.Config.Set(drift.ConfSchemaDriftRedisMaxLength, 2)
+	publisher, err := drift.NewPublisher(s.CTX, drift.PublisherRedis)
+	s.NoError(err)
+
+	for _, field := range []string{"a", "b", "c"} {
+		s.NoError(publisher.Publish([]*drift.Event{{Kind: drift.EventKindNewField, Field: field}}))
+	}
+	values, err := server.List("schema_drift")
+	s.NoError(err)
+	s.Len(values, 2)
+
+	event := new(drift.Event)
+	s.NoError(json.Unmarshal([]byte(values[1]), event))
+	s.Equal("c", event.Field)
+}
+
+// TestHTTPPublisher :
+func (s *ProcessorSuite) TestHTTPPublisher() {
+	_, err := drift.NewPublisher(s.CTX, drift.PublisherHTTP)
+	s.Error(err)
+
+	status := http.StatusOK
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ = io.ReadAll(request.Body)
+		writer.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	s.Config.Set(drift.ConfSchemaDriftHTTPURL, server.URL)
+	publisher, err := drift.NewPublisher(s.CTX, drift.PublisherHTTP)
+	s.NoError(err)
+
+	s.NoError(publisher.Publish([]*drift.Event{{Kind: drift.EventKindNewField, Field: "a"}}))
+	var events []*drift.Event
+	s.NoError(json.Unmarshal(body, &events))
+	s.Len(events, 1)
+	s.Equal("a", events[0].Field)
+
+	status = http.StatusInternalServerError
+	s.Error(publisher.Publish(events))
+}
+
+// TestProcessorSuite :
+func TestProcessorSuite(t *testing.T) {
+	suite.Run(t, new(ProcessorSuite))
+}
@@ -0,0 +1,200 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+package drift
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+)
+
+// 内置的事件发布器
+const (
+	PublisherRedis = "redis"
+	PublisherHTTP  = "http"
+)
+
+// Publisher : 结构漂移事件发布器
+type Publisher interface {
+	Publish(events []*Event) error
+}
+
+// PublisherCreator :
+type PublisherCreator func(ctx context.Context) (Publisher, error)
+
+var publisherCreators = make(map[string]PublisherCreator)
+
+// RegisterPublisher :
+func RegisterPublisher(name string, creator PublisherCreator) {
+	publisherCreators[name] = creator
+}
+
+// NewPublisher :
+func NewPublisher(ctx context.Context, name string) (Publisher, error) {
+	creator, ok := publisherCreators[name]
+	if !ok {
+		return nil, errors.Wrapf(define.ErrItemNotFound, "schema drift publisher %s", name)
+	}
+	return creator(ctx)
+}
+
+// RedisList : 支持批量追加 list 的存储
+type RedisList interface {
+	RPushBatch(key string, values []string, maxLen int64) error
+}
+
+// RedisPublisher : 以 json 格式追加到 redis list 中，由 metadata 侧消费
+type RedisPublisher struct {
+	store  RedisList
+	key    string
+	maxLen int64
+}
+
+// Publish :
+func (p *RedisPublisher) Publish(events []*Event) error {
+	values := make([]string, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		values = append(values, string(data))
+	}
+	return p.store.RPushBatch(p.key, values, p.maxLen)
+}
+
+// redisClientList : 使用独立 redis 连接写入 list，不依赖 storage 的类型
+type redisClientList struct {
+	client *redis.Client
+}
+
+// RPushBatch :
+func (l *redisClientList) RPushBatch(key string, values []string, maxLen int64) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		members = append(members, value)
+	}
+
+	ctx := context.Background()
+	pipe := l.client.TxPipeline()
+	pipe.RPush(ctx, key, members...)
+	if maxLen > 0 {
+		pipe.LTrim(ctx, key, -maxLen, -1)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+var (
+	redisListsLock sync.Mutex
+	redisLists     = make(map[string]*redisClientList)
+)
+
+// sharedRedisList : 相同连接配置的发布器共用一个客户端
+func sharedRedisList(options *redis.Options) *redisClientList {
+	key := fmt.Sprintf("%s/%d", options.Addr, options.DB)
+
+	redisListsLock.Lock()
+	defer redisListsLock.Unlock()
+	list, ok := redisLists[key]
+	if !ok {
+		list = &redisClientList{client: redis.NewClient(options)}
+		redisLists[key] = list
+	}
+	return list
+}
+
+// NewRedisPublisher : 配置了独立地址时使用独立连接，否则复用 redis 类型的 storage
+func NewRedisPublisher(ctx context.Context) (*RedisPublisher, error) {
+	conf := config.FromContext(ctx)
+
+	var store RedisList
+	if address := conf.GetString(ConfSchemaDriftRedisAddress); address != "" {
+		store = sharedRedisList(&redis.Options{
+			Addr:     address,
+			Password: conf.GetString(ConfSchemaDriftRedisPassword),
+			DB:       conf.GetInt(ConfSchemaDriftRedisDatabase),
+		})
+	} else {
+		list, ok := define.StoreFromContext(ctx).(RedisList)
+		if !ok {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "store should be redis when %s is empty", ConfSchemaDriftRedisAddress)
+		}
+		store = list
+	}
+
+	return &RedisPublisher{
+		store:  store,
+		key:    conf.GetString(ConfSchemaDriftRedisKey),
+		maxLen: conf.GetInt64(ConfSchemaDriftRedisMaxLength),
+	}, nil
+}
+
+// HTTPPublisher : 以 json 数组的形式回调指定地址
+type HTTPPublisher struct {
+	client *http.Client
+	url    string
+}
+
+// Publish :
+func (p *HTTPPublisher) Publish(events []*Event) error {
+	data, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	response, err := p.client.Post(p.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("schema drift callback %s response status %d", p.url, response.StatusCode)
+	}
+	return nil
+}
+
+// NewHTTPPublisher :
+func NewHTTPPublisher(ctx context.Context) (*HTTPPublisher, error) {
+	conf := config.FromContext(ctx)
+	url := conf.GetString(ConfSchemaDriftHTTPURL)
+	if url == "" {
+		return nil, errors.Wrapf(define.ErrOperationForbidden, "%s is empty", ConfSchemaDriftHTTPURL)
+	}
+
+	return &HTTPPublisher{
+		client: &http.Client{Timeout: conf.GetDuration(ConfSchemaDriftHTTPTimeout)},
+		url:    url,
+	}, nil
+}
+
+func init() {
+	RegisterPublisher(PublisherRedis, func(ctx context.Context) (Publisher, error) {
+		return NewRedisPublisher(ctx)
+	})
+	RegisterPublisher(PublisherHTTP, func(ctx context.Context) (Publisher, error) {
+		return NewHTTPPublisher(ctx)
+	})
+}
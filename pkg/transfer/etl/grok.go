@@ -0,0 +1,279 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package etl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cstockton/go-conv"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+)
+
+// GrokDefaultPatterns : grok 标准模式库，基于 logstash 标准库改写为 RE2 兼容语法
+var GrokDefaultPatterns = map[string]string{
+	"USERNAME":       `[a-zA-Z0-9._-]+`,
+	"USER":           `%{USERNAME}`,
+	"EMAILLOCALPART": `[a-zA-Z][a-zA-Z0-9_.+-=:]+`,
+	"EMAILADDRESS":   `%{EMAILLOCALPART}@%{HOSTNAME}`,
+	"INT":            `[+-]?[0-9]+`,
+	"BASE10NUM":      `[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+)`,
+	"NUMBER":         `%{BASE10NUM}`,
+	"BASE16NUM":      `[+-]?(?:0x)?[0-9A-Fa-f]+`,
+	"POSINT":         `\b[1-9][0-9]*\b`,
+	"NONNEGINT":      `\b[0-9]+\b`,
+	"WORD":           `\b\w+\b`,
+	"NOTSPACE":       `\S+`,
+	"SPACE":          `\s*`,
+	"DATA":           `.*?`,
+	"GREEDYDATA":     `.*`,
+	"QUOTEDSTRING":   `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"QS":             `%{QUOTEDSTRING}`,
+	"UUID":           `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"MAC":            `(?:[A-Fa-f0-9]{2}[:-]){5}[A-Fa-f0-9]{2}|(?:[A-Fa-f0-9]{4}\.){2}[A-Fa-f0-9]{4}`,
+
+	"IPV4":         `(?:(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])`,
+	"IPV6":         `(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}|(?:[0-9A-Fa-f]{1,4}:){1,7}:|(?:[0-9A-Fa-f]{1,4}:){0,6}(?::[0-9A-Fa-f]{1,4}){1,6}|::`,
+	"IP":           `%{IPV6}|%{IPV4}`,
+	"HOSTNAME":     `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":     `%{IP}|%{HOSTNAME}`,
+	"HOSTPORT":     `%{IPORHOST}:%{POSINT}`,
+	"PATH":         `%{UNIXPATH}|%{WINPATH}`,
+	"UNIXPATH":     `(?:/[\w_%!$@:.,+~-]*)+`,
+	"WINPATH":      `(?:[A-Za-z]+:|\\)(?:\\[^\\?*]*)+`,
+	"URIPROTO":     `[A-Za-z][A-Za-z0-9+.-]+`,
+	"URIHOST":      `%{IPORHOST}(?::%{POSINT})?`,
+	"URIPATH":      `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":     `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM": `%{URIPATH}(?:%{URIPARAM})?`,
+	"URI":          `%{URIPROTO}://(?:%{USER}(?::[^@]*)?@)?(?:%{URIHOST})?(?:%{URIPATHPARAM})?`,
+
+	"MONTH":             `\b(?:[Jj]an(?:uary|uar)?|[Ff]eb(?:ruary|ruar)?|[Mm](?:a|ä)?r(?:ch|z)?|[Aa]pr(?:il)?|[Mm]a(?:y|i)?|[Jj]un(?:e|i)?|[Jj]ul(?:y|i)?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo](?:c|k)?t(?:ober)?|[Nn]ov(?:ember)?|[Dd]e(?:c|z)(?:ember)?)\b`,
+	"MONTHNUM":          `0?[1-9]|1[0-2]`,
+	"MONTHNUM2":         `0[1-9]|1[0-2]`,
+	"MONTHDAY":          `(?:0[1-9])|(?:[12][0-9])|(?:3[01])|[1-9]`,
+	"DAY":               `(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)`,
+	"YEAR":              `(?:\d\d){1,2}`,
+	"HOUR":              `2[0123]|[01]?[0-9]`,
+	"MINUTE":            `[0-5][0-9]`,
+	"SECOND":            `(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"DATE_US":           `%{MONTHNUM}[/-]%{MONTHDAY}[/-]%{YEAR}`,
+	"DATE_EU":           `%{MONTHDAY}[./-]%{MONTHNUM}[./-]%{YEAR}`,
+	"ISO8601_TIMEZONE":  `Z|[+-]%{HOUR}(?::?%{MINUTE})`,
+	"ISO8601_SECOND":    `%{SECOND}`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"DATE":              `%{DATE_US}|%{DATE_EU}`,
+	"DATESTAMP":         `%{DATE}[- ]%{TIME}`,
+	"TZ":                `[A-Z]{3}`,
+	"DATESTAMP_RFC822":  `%{DAY} %{MONTH} %{MONTHDAY} %{YEAR} %{TIME} %{TZ}`,
+	"DATESTAMP_OTHER":   `%{DAY} %{MONTH} %{MONTHDAY} %{TIME} %{TZ} %{YEAR}`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+
+	"SYSLOGTIMESTAMP": `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"PROG":            `[\x21-\x5a\x5c\x5e-\x7e]+`,
+	"SYSLOGPROG":      `%{PROG:program}(?:\[%{POSINT:pid}\])?`,
+	"SYSLOGHOST":      `%{IPORHOST}`,
+	"SYSLOGFACILITY":  `<%{NONNEGINT:facility}.%{NONNEGINT:priority}>`,
+	"SYSLOGBASE":      `%{SYSLOGTIMESTAMP:timestamp} (?:%{SYSLOGFACILITY} )?%{SYSLOGHOST:logsource} %{SYSLOGPROG}:`,
+
+	"LOGLEVEL":          `[Aa]lert|ALERT|[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo|INFO|[Ww]arn?(?:ing)?|WARN?(?:ING)?|[Ee]rr?(?:or)?|ERR?(?:OR)?|[Cc]rit?(?:ical)?|CRIT?(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|EMERG(?:ENCY)?|[Ee]merg(?:ency)?`,
+	"HTTPDUSER":         `%{EMAILADDRESS}|%{USER}`,
+	"COMMONAPACHELOG":   `%{IPORHOST:clientip} %{HTTPDUSER:ident} %{HTTPDUSER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response} (?:%{NUMBER:bytes}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+	"NGINXACCESS":       `%{IPORHOST:remote_addr} - %{HTTPDUSER:remote_user} \[%{HTTPDATE:time_local}\] "%{WORD:method} %{NOTSPACE:request} HTTP/%{NUMBER:httpversion}" %{INT:status} %{INT:body_bytes_sent} %{QS:http_referer} %{QS:http_user_agent}`,
+}
+
+var grokReferenceRegexp = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::(int|float|string))?\}`)
+
+// grokField : grok 提取字段定义
+type grokField struct {
+	name     string
+	typeName string
+}
+
+// Grok : 编译后的 grok 表达式
+type Grok struct {
+	regex  *regexp.Regexp
+	groups []*grokField
+	fields []string
+}
+
+// Fields : 提取的字段名列表
+func (g *Grok) Fields() []string {
+	return g.fields
+}
+
+// Parse : 按 grok 表达式解析文本，ok 表示是否匹配
+func (g *Grok) Parse(value string) (results map[string]interface{}, ok bool) {
+	results = make(map[string]interface{}, len(g.fields))
+	for _, name := range g.fields {
+		results[name] = nil
+	}
+
+	indexes := g.regex.FindStringSubmatchIndex(value)
+	if indexes == nil {
+		return results, false
+	}
+
+	for i, field := range g.groups {
+		if field == nil {
+			continue
+		}
+		start, end := indexes[2*i], indexes[2*i+1]
+		if start < 0 {
+			// 可选分组未匹配
+			continue
+		}
+		// 同名字段取第一个非空的值
+		if current, exists := results[field.name]; exists && current != nil && current != "" {
+			continue
+		}
+		results[field.name] = field.convert(value[start:end])
+	}
+
+	return results, true
+}
+
+// convert : 按声明类型转换，转换失败时保留原始字符串
+func (f *grokField) convert(value string) interface{} {
+	switch f.typeName {
+	case "int":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			return v
+		}
+	case "float":
+		v, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			return v
+		}
+	}
+	return value
+}
+
+// grokCompiler : grok 表达式展开器
+type grokCompiler struct {
+	patterns map[string]string
+	fields   map[string]*grokField
+	index    int
+}
+
+// expand : 递归展开模式引用，stack 用于检测循环引用
+func (c *grokCompiler) expand(pattern string, stack []string) (string, error) {
+	var expandErr error
+	result := grokReferenceRegexp.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if expandErr != nil {
+			return ""
+		}
+		parts := grokReferenceRegexp.FindStringSubmatch(ref)
+		name, fieldName, typeName := parts[1], parts[2], parts[3]
+
+		for _, n := range stack {
+			if n == name {
+				expandErr = errors.Wrapf(define.ErrValue, "grok pattern %s is recursive: %s", name, strings.Join(append(stack, name), " -> "))
+				return ""
+			}
+		}
+
+		sub, ok := c.patterns[name]
+		if !ok {
+			expandErr = errors.Wrapf(define.ErrItemNotFound, "grok pattern %s not found", name)
+			return ""
+		}
+
+		expanded, err := c.expand(sub, append(stack, name))
+		if err != nil {
+			expandErr = err
+			return ""
+		}
+
+		if fieldName == "" {
+			return fmt.Sprintf("(?:%s)", expanded)
+		}
+
+		group := fmt.Sprintf("_grok%d", c.index)
+		c.index++
+		c.fields[group] = &grokField{name: fieldName, typeName: typeName}
+		return fmt.Sprintf("(?P<%s>%s)", group, expanded)
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return result, nil
+}
+
+// CompileGrok : 编译 grok 表达式，custom 中的同名模式会覆盖标准库
+func CompileGrok(pattern string, custom map[string]string) (*Grok, error) {
+	patterns := make(map[string]string, len(GrokDefaultPatterns)+len(custom))
+	for name, value := range GrokDefaultPatterns {
+		patterns[name] = value
+	}
+	for name, value := range custom {
+		patterns[name] = value
+	}
+
+	compiler := &grokCompiler{
+		patterns: patterns,
+		fields:   make(map[string]*grokField),
+	}
+	expanded, err := compiler.expand(pattern, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	regex, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, errors.Wrapf(err, "compile grok pattern %s failed", pattern)
+	}
+
+	names := regex.SubexpNames()
+	grok := &Grok{
+		regex:  regex,
+		groups: make([]*grokField, len(names)),
+	}
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		field, ok := compiler.fields[name]
+		if !ok {
+			// 用户直接写的命名分组
+			field = &grokField{name: name}
+		}
+		grok.groups[i] = field
+		if !seen[field.name] {
+			seen[field.name] = true
+			grok.fields = append(grok.fields, field.name)
+		}
+	}
+
+	return grok, nil
+}
+
+// TransformMapByGrok : 按 grok 表达式提取字段，输出格式与 TransformMapByRegexp 一致
+func TransformMapByGrok(grok *Grok) TransformFn {
+	return func(from interface{}) (to interface{}, err error) {
+		value, err := conv.DefaultConv.String(from)
+		if err != nil {
+			return nil, err
+		}
+
+		results, ok := grok.Parse(value)
+		results[config.LogCleanFailedFlag] = !ok
+		return results, nil
+	}
+}
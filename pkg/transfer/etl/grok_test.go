@@ -0,0 +1,197 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package etl_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/etl"
+)
+
+// GrokSuite :
+type GrokSuite struct {
+	suite.Suite
+}
+
+// TestCompile :
+func (s *GrokSuite) TestCompile() {
+	grok, err := etl.CompileGrok(`%{IP:client} %{WORD:method} %{URIPATHPARAM:request} %{NUMBER:bytes:int} %{NUMBER:duration:float}`, nil)
+	s.NoError(err)
+	s.Equal([]string{"client", "method", "request", "bytes", "duration"}, grok.Fields())
+
+	results, ok := grok.Parse("55.3.244.1 GET /index.html?a=1 15824 0.043")
+	s.True(ok)
+	s.Equal(map[string]interface{}{
+		"client":   "55.3.244.1",
+		"method":   "GET",
+		"request":  "/index.html?a=1",
+		"bytes":    int64(15824),
+		"duration": 0.043,
+	}, results)
+
+	results, ok = grok.Parse("not matched")
+	s.False(ok)
+	s.Equal(map[string]interface{}{
+		"client":   nil,
+		"method":   nil,
+		"request":  nil,
+		"bytes":    nil,
+		"duration": nil,
+	}, results)
+}
+
+// TestCustomPatterns :
+func (s *GrokSuite) TestCustomPatterns() {
+	grok, err := etl.CompileGrok(`%{ORDER:order} (?P<status>\w+) %{WORD:word}`, map[string]string{
+		"ORDER": `ORD-%{INT}`,
+		"WORD":  `[a-z]+`,
+	})
+	s.NoError(err)
+
+	results, ok := grok.Parse("ORD-42 paid done")
+	s.True(ok)
+	s.Equal(map[string]interface{}{
+		"order":  "ORD-42",
+		"status": "paid",
+		"word":   "done",
+	}, results)
+}
+
+// TestCompileError :
+func (s *GrokSuite) TestCompileError() {
+	cases := []struct {
+		pattern string
+		custom  map[string]string
+	}{
+		{`%{NOT_EXISTS:x}`, nil},
+		{`%{A:x}`, map[string]string{"A": `%{B}`, "B": `%{A}`}},
+		{`%{BROKEN:x}`, map[string]string{"BROKEN": `(`}},
+	}
+	for _, c := range cases {
+		_, err := etl.CompileGrok(c.pattern, c.custom)
+		s.Error(err, c.pattern)
+	}
+}
+
+// TestConvertFailed :
+func (s *GrokSuite) TestConvertFailed() {
+	grok, err := etl.CompileGrok(`%{NOTSPACE:value:int}`, nil)
+	s.NoError(err)
+
+	results, ok := grok.Parse("abc")
+	s.True(ok)
+	s.Equal("abc", results["value"])
+}
+
+// TestStandardPatterns :
+func (s *GrokSuite) TestStandardPatterns() {
+	cases := []struct {
+		pattern  string
+		line     string
+		expected map[string]interface{}
+	}{
+		{
+			`%{COMBINEDAPACHELOG}`,
+			`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`,
+			map[string]interface{}{
+				"clientip":    "127.0.0.1",
+				"ident":       "-",
+				"auth":        "frank",
+				"timestamp":   "10/Oct/2000:13:55:36 -0700",
+				"verb":        "GET",
+				"request":     "/apache_pb.gif",
+				"httpversion": "1.0",
+				"rawrequest":  nil,
+				"response":    "200",
+				"bytes":       "2326",
+				"referrer":    `"http://www.example.com/start.html"`,
+				"agent":       `"Mozilla/4.08"`,
+			},
+		},
+		{
+			`%{NGINXACCESS}`,
+			`10.0.0.2 - - [19/Oct/2026:08:01:02 +0800] "POST /api/v1/query HTTP/1.1" 502 157 "-" "curl/7.64.1"`,
+			map[string]interface{}{
+				"remote_addr":     "10.0.0.2",
+				"remote_user":     "-",
+				"time_local":      "19/Oct/2026:08:01:02 +0800",
+				"method":          "POST",
+				"request":         "/api/v1/query",
+				"httpversion":     "1.1",
+				"status":          "502",
+				"body_bytes_sent": "157",
+				"http_referer":    `"-"`,
+				"http_user_agent": `"curl/7.64.1"`,
+			},
+		},
+		{
+			`%{SYSLOGBASE} %{GREEDYDATA:message}`,
+			`Oct 19 08:01:02 host-1 sshd[1234]: Accepted publickey for root`,
+			map[string]interface{}{
+				"timestamp": "Oct 19 08:01:02",
+				"facility":  nil,
+				"priority":  nil,
+				"logsource": "host-1",
+				"program":   "sshd",
+				"pid":       "1234",
+				"message":   "Accepted publickey for root",
+			},
+		},
+		{
+			`%{TIMESTAMP_ISO8601:time} %{LOGLEVEL:level} %{GREEDYDATA:message}`,
+			`2026-10-19T08:01:02+08:00 ERROR connection refused`,
+			map[string]interface{}{
+				"time":    "2026-10-19T08:01:02+08:00",
+				"level":   "ERROR",
+				"message": "connection refused",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		grok, err := etl.CompileGrok(c.pattern, nil)
+		s.NoError(err, c.pattern)
+
+		results, ok := grok.Parse(c.line)
+		s.True(ok, c.pattern)
+		s.Equal(c.expected, results, c.pattern)
+	}
+}
+
+// TestTransformMapByGrok :
+func (s *GrokSuite) TestTransformMapByGrok() {
+	grok, err := etl.CompileGrok(`%{WORD:key}:\s+%{INT:value:int}`, nil)
+	s.NoError(err)
+	fn := etl.TransformMapByGrok(grok)
+
+	value, err := fn("option: 1")
+	s.NoError(err)
+	s.Equal(map[string]interface{}{
+		"key":                     "option",
+		"value":                   int64(1),
+		config.LogCleanFailedFlag: false,
+	}, value)
+
+	value, err = fn("option")
+	s.NoError(err)
+	s.Equal(map[string]interface{}{
+		"key":                     nil,
+		"value":                   nil,
+		config.LogCleanFailedFlag: true,
+	}, value)
+}
+
+// TestGrokSuite :
+func TestGrokSuite(t *testing.T) {
+	suite.Run(t, new(GrokSuite))
+}
@@ -10,21 +10,28 @@
 package processor_test
 
 import (
+	"context"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/filesystem"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/filesystem/processor"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
 	. "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/testsuite"
 )
 
@@ -111,6 +118,191 @@ func (s *BackendSuite) TestUsage() {
 	ctrl.Finish()
 }
 
+// TestDeadLetter : 死信通过 file 后端落盘
+func (s *BackendSuite) TestDeadLetter() {
+	pipe := config.PipelineConfigFromContext(s.CTX)
+	pipe.DataID = 1001
+	pipe.Option = map[string]interface{}{
+		config.PipelineConfigOptDeadLetterConfig: map[string]interface{}{
+			"cluster_type": "file",
+		},
+	}
+
+	node, err := pipeline.NewDeadLetterNodeFromContext(s.CTX)
+	s.NoError(err)
+	s.NotNil(node)
+
+	killCh := make(chan error)
+	node.Start(killCh)
+
+	source := &define.DeadLetterSource{DataID: pipe.DataID, Processor: "json_log", Stage: define.DeadLetterStageProcessor}
+	s.True(define.SendDeadLetter(source, define.NewJSONPayloadFrom([]byte(`{"log": not json}`), 0), errors.New("invalid character")))
+	// 其它 dataid 未配置死信队列
+	s.False(define.SendDeadLetter(&define.DeadLetterSource{DataID: 1002}, define.NewJSONPayloadFrom([]byte(`{}`), 0), nil))
+
+	s.NoError(node.Stop())
+	s.NoError(node.Wait())
+	s.False(define.SendDeadLetter(source, define.NewJSONPayloadFrom([]byte(`{}`), 0), nil))
+
+	file, err := filesystem.FS.OpenFile(filepath.Join("1001", pipeline.DeadLetterResultTable), os.O_RDONLY, 0)
+	s.NoError(err)
+	data, err := io.ReadAll(file)
+	s.NoError(err)
+
+	var letter define.DeadLetter
+	s.NoError(json.Unmarshal(data, &letter))
+	s.Equal(1001, letter.DataID)
+	s.Equal("json_log", letter.Processor)
+	s.Equal(define.DeadLetterStageProcessor, letter.Stage)
+	s.Equal("invalid character", letter.Error)
+	s.Equal(`{"log": not json}`, string(letter.Payload))
+}
+
+// failedBulkHandler : 写入总是失败的 bulk handler
+type failedBulkHandler struct {
+	pipeline.BaseBulkHandler
+}
+
+func (h *failedBulkHandler) Handle(ctx context.Context, payload define.Payload, killChan chan<- error) (interface{}, time.Time, bool) {
+	return payload, time.Now(), true
+}
+
+func (h *failedBulkHandler) Flush(ctx context.Context, results []interface{}) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func (h *failedBulkHandler) Close() error {
+	return nil
+}
+
+// TestBackendDeadLetter : 后端写入失败时投递前端原始数据，同一前端数据只投递一次
+func (s *BackendSuite) TestBackendDeadLetter() {
+	pipe := config.PipelineConfigFromContext(s.CTX)
+	pipe.DataID = 1001
+	pipe.Option = map[string]interface{}{
+		config.PipelineConfigOptDeadLetterConfig: map[string]interface{}{
+			"cluster_type": "file",
+		},
+	}
+
+	node, err := pipeline.NewDeadLetterNodeFromContext(s.CTX)
+	s.NoError(err)
+	killCh := make(chan error)
+	node.Start(killCh)
+
+	backend := pipeline.NewBulkBackendAdapter(s.CTX, "failed", &failedBulkHandler{}, 10, time.Millisecond, 1)
+	origin := define.NewJSONPayloadFrom([]byte(`{"data":[{"value":1},{"value":2}]}`), 0)
+	for i := 1; i <= 2; i++ {
+		derived, err := define.DerivePayload(origin, map[string]interface{}{"value": i})
+		s.NoError(err)
+		backend.Push(derived, killCh)
+	}
+	// 等待定时 flush 失败后投递死信
+	time.Sleep(100 * time.Millisecond)
+	s.NoError(backend.Close())
+	s.NoError(node.Stop())
+	s.NoError(node.Wait())
+
+	file, err := filesystem.FS.OpenFile(filepath.Join("1001", pipeline.DeadLetterResultTable), os.O_RDONLY, 0)
+	s.NoError(err)
+	data, err := io.ReadAll(file)
+	s.NoError(err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	s.Len(lines, 1)
+	var letter define.DeadLetter
+	s.NoError(json.Unmarshal([]byte(lines[0]), &letter))
+	s.Equal(define.DeadLetterStageBackend, letter.Stage)
+	s.Equal("write failed", letter.Error)
+	s.Equal(`{"data":[{"value":1},{"value":2}]}`, string(letter.Payload))
+}
+
+// partialBulkHandler : 包含 bad 的数据写入失败，其余写入成功
+type partialBulkHandler struct {
+	pipeline.BaseBulkHandler
+	flushes int32
+}
+
+func (h *partialBulkHandler) Handle(ctx context.Context, payload define.Payload, killChan chan<- error) (interface{}, time.Time, bool) {
+	return payload, time.Now(), true
+}
+
+func (h *partialBulkHandler) Flush(ctx context.Context, results []interface{}) (int, error) {
+	atomic.AddInt32(&h.flushes, 1)
+	var failed []int
+	for i, result := range results {
+		var data map[string]interface{}
+		if err := result.(define.Payload).To(&data); err == nil && data["value"] == "bad" {
+			failed = append(failed, i)
+		}
+	}
+	if len(failed) == 0 {
+		return len(results), nil
+	}
+	return len(results) - len(failed), pipeline.NewBulkPartialError(errors.New("partial write failed"), failed)
+}
+
+func (h *partialBulkHandler) Close() error {
+	return nil
+}
+
+// TestBackendPartialDeadLetter : 部分写入失败时不整体重试，只投递失败的数据
+func (s *BackendSuite) TestBackendPartialDeadLetter() {
+	pipe := config.PipelineConfigFromContext(s.CTX)
+	pipe.DataID = 1002
+	pipe.Option = map[string]interface{}{
+		config.PipelineConfigOptDeadLetterConfig: map[string]interface{}{
+			"cluster_type": "file",
+		},
+	}
+
+	node, err := pipeline.NewDeadLetterNodeFromContext(s.CTX)
+	s.NoError(err)
+	killCh := make(chan error)
+	node.Start(killCh)
+
+	handler := &partialBulkHandler{}
+	backend := pipeline.NewBulkBackendAdapter(s.CTX, "partial", handler, 10, time.Millisecond, 1)
+	for _, data := range []string{`{"value":"good"}`, `{"value":"bad"}`, `{"value":"good"}`} {
+		backend.Push(define.NewJSONPayloadFrom([]byte(data), 0), killCh)
+	}
+	time.Sleep(100 * time.Millisecond)
+	s.NoError(backend.Close())
+	s.NoError(node.Stop())
+	s.NoError(node.Wait())
+	s.Equal(int32(1), atomic.LoadInt32(&handler.flushes))
+
+	file, err := filesystem.FS.OpenFile(filepath.Join("1002", pipeline.DeadLetterResultTable), os.O_RDONLY, 0)
+	s.NoError(err)
+	data, err := io.ReadAll(file)
+	s.NoError(err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	s.Len(lines, 1)
+	var letter define.DeadLetter
+	s.NoError(json.Unmarshal([]byte(lines[0]), &letter))
+	s.Equal(define.DeadLetterStageBackend, letter.Stage)
+	s.Equal("partial write failed", letter.Error)
+	s.Equal(`{"value":"bad"}`, string(letter.Payload))
+}
+
+// TestDeadLetterClusterType : 只允许 kafka 和 file 作为死信后端
+func (s *BackendSuite) TestDeadLetterClusterType() {
+	pipe := config.PipelineConfigFromContext(s.CTX)
+	pipe.Option = map[string]interface{}{
+		config.PipelineConfigOptDeadLetterConfig: map[string]interface{}{
+			"cluster_type": "elasticsearch",
+		},
+	}
+	_, err := pipeline.DeadLetterClusterFromPipeline(pipe)
+	s.Error(err)
+
+	pipe.Option = map[string]interface{}{}
+	cluster, err := pipeline.DeadLetterClusterFromPipeline(pipe)
+	s.NoError(err)
+	s.Nil(cluster)
+}
+
 // TestBackendSuite :
 func TestBackendSuite(t *testing.T) {
 	suite.Run(t, new(BackendSuite))
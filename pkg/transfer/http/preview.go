@@ -0,0 +1,147 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/storage"
+)
+
+// PreviewRequest : 流水线预览请求
+type PreviewRequest struct {
+	// PipelineConfig 与 consul 中 dataid 的配置格式一致
+	PipelineConfig *config.PipelineConfig `json:"pipeline_config"`
+	// ResultTableConfig 非空时只预览该结果表
+	ResultTableConfig *config.MetaResultTableConfig `json:"result_table_config"`
+	// Data 样例数据，字符串按原文处理，其他类型按 json 编码
+	Data []interface{} `json:"data"`
+	// CMDBCache 用于替代真实缓存的数据，key 与缓存中的 key 一致
+	CMDBCache map[string]interface{} `json:"cmdb_cache"`
+}
+
+// PreviewResponse :
+type PreviewResponse struct {
+	Result  bool                    `json:"result"`
+	Data    *pipeline.PreviewResult `json:"data,omitempty"`
+	Message string                  `json:"message,omitempty"`
+}
+
+func previewBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// newPreviewStore : 使用内存缓存替代真实缓存，避免预览时读写线上数据
+func newPreviewStore(items map[string]interface{}) (define.Store, error) {
+	store := storage.NewMapStore()
+	for key, value := range items {
+		data, err := previewBytes(value)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "encode cache %s failed", key)
+		}
+		if err = store.Set(key, data, define.StoreNoExpires); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// preview :
+func preview(request *http.Request, req *PreviewRequest) (*pipeline.PreviewResult, error) {
+	pipe := req.PipelineConfig
+	if pipe == nil || pipe.ETLConfig == "" {
+		return nil, errors.Wrapf(define.ErrValue, "pipeline_config.etl_config is empty")
+	}
+	if req.ResultTableConfig != nil {
+		pipe.ResultTableList = []*config.MetaResultTableConfig{req.ResultTableConfig}
+	}
+	if len(req.Data) == 0 {
+		return nil, errors.Wrapf(define.ErrValue, "data is empty")
+	}
+	if err := pipe.Clean(); err != nil {
+		return nil, errors.WithMessage(err, "clean pipeline config failed")
+	}
+
+	samples := make([][]byte, 0, len(req.Data))
+	for index, value := range req.Data {
+		data, err := previewBytes(value)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "encode data %d failed", index)
+		}
+		samples = append(samples, data)
+	}
+
+	store, err := newPreviewStore(req.CMDBCache)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := config.IntoContext(request.Context(), config.Configuration)
+	ctx = define.StoreIntoContext(ctx, store)
+	return pipeline.Preview(ctx, pipe, samples)
+}
+
+// PreviewView : 使用真实处理器试运行样例数据，返回各处理阶段的输出，不会写入任何后端
+func PreviewView(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(request.Body)
+	defer func() {
+		_ = request.Body.Close()
+	}()
+	if err != nil {
+		logging.Errorf("read body error: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rsp := new(PreviewResponse)
+	req := &PreviewRequest{
+		PipelineConfig: config.NewPipelineConfig(),
+	}
+	if err = json.Unmarshal(body, req); err != nil {
+		rsp.Message = fmt.Sprintf("unmarshal body error: %v", err)
+		WriteJSONResponse(http.StatusBadRequest, writer, rsp)
+		return
+	}
+
+	result, err := preview(request, req)
+	if err != nil {
+		logging.Warnf("preview pipeline failed: %v", err)
+		rsp.Message = err.Error()
+		WriteJSONResponse(http.StatusBadRequest, writer, rsp)
+		return
+	}
+
+	rsp.Result = true
+	rsp.Data = result
+	WriteJSONResponse(http.StatusOK, writer, rsp)
+}
+
+func init() {
+	http.HandleFunc("/preview", PreviewView)
+}
@@ -0,0 +1,152 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	transferhttp "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/http"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
+	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/testsuite"
+)
+
+const previewPipelineConfig = `{"result_table_list":[{"schema_type":"free","result_table":"2_log.durant_log1000008","shipper_list":[{"cluster_type":"elasticsearch","cluster_config":{"domain_name":"127.0.0.1","port":9200},"storage_config":{},"auth_info":{}}],"field_list":[{"default_value":null,"alias_name":"log","tag":"metric","type":"string","is_config_by_user":true,"field_name":"log"},{"default_value":"","field_name":"","tag":"","type":"timestamp","is_config_by_user":true,"alias_name":"time","option":{"es_format":"epoch_millis","es_type":"date"}},{"default_value":null,"field_name":"key","tag":"metric","type":"string","is_config_by_user":true}]}],"mq_config":{"cluster_type":"kafka","cluster_config":{"domain_name":"127.0.0.1","port":9092},"storage_config":{"topic":"test","partition":1},"auth_info":{}},"data_id":1200147,"etl_config":"bk_log_json","option":{"group_info_alias":"_private_","encoding":"UTF-8"}}`
+
+// PreviewSuite :
+type PreviewSuite struct {
+	suite.Suite
+}
+
+func (s *PreviewSuite) request(body string) (int, *transferhttp.PreviewResponse) {
+	request := httptest.NewRequest(http.MethodPost, "/preview", bytes.NewBufferString(body))
+	recorder := httptest.NewRecorder()
+	transferhttp.PreviewView(recorder, request)
+
+	rsp := new(transferhttp.PreviewResponse)
+	s.NoError(json.Unmarshal(recorder.Body.Bytes(), rsp))
+	return recorder.Code, rsp
+}
+
+// TestPreview :
+func (s *PreviewSuite) TestPreview() {
+	code, rsp := s.request(`{"pipeline_config":` + previewPipelineConfig + `,"data":[
+		"{\"_path_\":\"/tmp/1.log\",\"_private_\":[{\"bk_app_code\":\"bk_log_search\"}],\"_server_\":\"127.0.0.1\",\"_time_\":\"2019-10-08 17:41:49\",\"_utctime_\":\"2019-10-08 09:41:49\",\"_value_\":[\"{\\\"key\\\":\\\"a\\\"}\"]}",
+		"{\"_value_\": ["
+	]}`)
+	s.Equal(http.StatusOK, code)
+	s.True(rsp.Result, rsp.Message)
+	s.Equal(1200147, rsp.Data.DataID)
+	s.Len(rsp.Data.ResultTables, 1)
+
+	table := rsp.Data.ResultTables[0]
+	s.Equal("2_log.durant_log1000008", table.ResultTable)
+
+	names := make([]string, 0, len(table.Stages))
+	for _, stage := range table.Stages {
+		names = append(names, stage.Name)
+	}
+	s.Equal([]string{"encoding", "json_log", "log_format"}, names)
+
+	parser := table.Stages[1]
+	s.Equal(2, parser.Input)
+	s.Len(parser.Output, 1)
+	s.Len(parser.Errors, 1)
+	s.Equal(`{"_value_": [`, parser.Errors[0].Payload)
+
+	format := table.Stages[2]
+	s.Equal(1, format.Input)
+	s.Len(format.Output, 1)
+	s.Empty(format.Errors)
+	record := format.Output[0].(map[string]interface{})
+	metrics := record["metrics"].(map[string]interface{})
+	s.Equal("a", metrics["key"])
+	s.Equal("{\"key\":\"a\"}", metrics["log"])
+}
+
+const previewTimeSeriesPipelineConfig = `{"etl_config":"bk_standard","result_table_list":[{"schema_type":"free","result_table":"2_script.cpu","shipper_list":[{"cluster_type":"influxdb","cluster_config":{"domain_name":"127.0.0.1","port":10201},"storage_config":{"real_table_name":"cpu","database":"2_script"},"auth_info":{}}],"field_list":[{"tag":"dimension","type":"int","is_config_by_user":true,"field_name":"bk_biz_id"},{"tag":"dimension","type":"int","is_config_by_user":true,"field_name":"bk_cloud_id"},{"tag":"dimension","type":"string","is_config_by_user":true,"field_name":"ip"},{"tag":"dimension","type":"string","is_config_by_user":true,"field_name":"bk_cmdb_level"},{"tag":"metric","type":"float","is_config_by_user":true,"field_name":"cpu"},{"tag":"","type":"timestamp","is_config_by_user":true,"field_name":"time"}]}],"option":{"use_source_time":false,"dead_letter_config":{"cluster_type":"file"}},"mq_config":{"cluster_type":"kafka","cluster_config":{"domain_name":"127.0.0.1","port":9092},"storage_config":{"topic":"test","partition":1},"auth_info":{}},"data_id":1200007}`
+
+// TestPreviewTimeSeries : 时序流水线注入的维度来自请求中的 cmdb_cache，且不会创建任何后端和死信节点
+func (s *PreviewSuite) TestPreviewTimeSeries() {
+	newFrontend, newBackend := define.NewFrontend, define.NewBackend
+	defer func() {
+		define.NewFrontend, define.NewBackend = newFrontend, newBackend
+	}()
+	var created []string
+	define.NewFrontend = func(ctx context.Context, name string) (define.Frontend, error) {
+		created = append(created, "frontend:"+name)
+		return newFrontend(ctx, name)
+	}
+	define.NewBackend = func(ctx context.Context, name string) (define.Backend, error) {
+		created = append(created, "backend:"+name)
+		return newBackend(ctx, name)
+	}
+
+	code, rsp := s.request(`{"pipeline_config":` + previewTimeSeriesPipelineConfig + `,
+		"data":["{\"cloudid\":0,\"ip\":\"127.0.0.1\",\"dimensions\":{},\"metrics\":{\"cpu\":11},\"time\":1564477933}"],
+		"cmdb_cache":{"model-host-0-127.0.0.1":{"ip":"127.0.0.1","bk_cloud_id":0,"Topo":[{"bk_biz_id":"2","bk_set_id":"3","bk_module_id":"4"}],"BizID":[2]}}
+	}`)
+	s.Equal(http.StatusOK, code)
+	s.True(rsp.Result, rsp.Message)
+	s.Empty(created)
+	_, ok := define.GetDeadLetterSink(1200007)
+	s.False(ok)
+	s.Len(rsp.Data.ResultTables, 1)
+
+	table := rsp.Data.ResultTables[0]
+	names := make([]string, 0, len(table.Stages))
+	for _, stage := range table.Stages {
+		names = append(names, stage.Name)
+	}
+	s.Equal([]string{"standard", "cmdb_injector", "group_injector", "time_injector", "ts_format"}, names)
+
+	format := table.Stages[len(table.Stages)-1]
+	s.Empty(format.Errors)
+	s.Len(format.Output, 1)
+	record := format.Output[0].(map[string]interface{})
+	dimensions := record["dimensions"].(map[string]interface{})
+	s.Equal(2.0, dimensions["bk_biz_id"])
+	s.Equal(`[{"bk_biz_id":"2","bk_module_id":"4","bk_set_id":"3"}]`, dimensions["bk_cmdb_level"])
+	// time_injector 使用接收时间替换数据时间
+	s.NotEqual(1564477933.0, record["time"])
+}
+
+// TestSkipReporter :
+func (s *PreviewSuite) TestSkipReporter() {
+	s.Contains(pipeline.PreviewSkippedProcessors, "sampling_reporter")
+	s.Contains(pipeline.PreviewSkippedProcessors, "metrics_reporter")
+}
+
+// TestBadRequest :
+func (s *PreviewSuite) TestBadRequest() {
+	cases := []string{
+		`{"data":["{}"]}`,
+		`{"pipeline_config":` + previewPipelineConfig + `}`,
+		`{"pipeline_config":{"etl_config":"not_exists","result_table_list":[{"result_table":"a.b"}]},"data":["{}"]}`,
+		`not json`,
+	}
+	for _, c := range cases {
+		_, rsp := s.request(c)
+		s.False(rsp.Result, c)
+		s.NotEmpty(rsp.Message, c)
+	}
+}
+
+// TestPreviewSuite :
+func TestPreviewSuite(t *testing.T) {
+	suite.Run(t, new(PreviewSuite))
+}
@@ -183,7 +183,12 @@ func (b *Backend) Push(d define.Payload, killChan chan<- error) {
 				case err := <-b.producer.Errors():
 					if err != nil {
 						logging.Errorf("%v write kafka failed, err: %v", b, err)
-						b.CounterFails.Inc()
+						// 写入失败的消息通过 Metadata 找回原始 payload 投递到死信队列
+						var payload define.Payload
+						if err.Msg != nil {
+							payload, _ = err.Msg.Metadata.(define.Payload)
+						}
+						b.Reject(payload, err.Err)
 					}
 				case <-b.ctx.Done():
 					return
@@ -272,7 +277,7 @@ func (b *Backend) SendMsg(payload define.Payload) {
 	err = payload.To(&message)
 	if err != nil {
 		logging.Warnf("%v load %#v error %v", b, payload, err)
-		b.CounterFails.Inc()
+		b.Reject(payload, err)
 		return
 	}
 
@@ -281,6 +286,7 @@ func (b *Backend) SendMsg(payload define.Payload) {
 		Key:       sarama.StringEncoder(b.Key),
 		Value:     sarama.ByteEncoder(message),
 		Partition: b.Partition,
+		Metadata:  payload,
 	})
 	if !ok {
 		b.CounterFails.Inc()
@@ -158,6 +158,59 @@ func (s *BackendSuit) TestPushErrFormat() {
 	}, cases)
 }
 
+// deadLetterSink :
+type deadLetterSink chan *define.DeadLetter
+
+// Put :
+func (s deadLetterSink) Put(letter *define.DeadLetter) bool {
+	s <- letter
+	return true
+}
+
+// TestPushProducerError : 异步写入失败的数据投递到死信队列
+func (s *BackendSuit) TestPushProducerError() {
+	mockCtrl := gomock.NewController(s.T())
+	s.CTX = config.IntoContext(s.CTX, config.Configuration)
+	kafka.NewKafkaProducerConfig = func(conf define.Configuration) (*sarama.Config, error) {
+		c := sarama.NewConfig()
+		return c, c.Validate()
+	}
+	producer := NewMockProducer(mockCtrl)
+	input := make(chan *sarama.ProducerMessage)
+	errs := make(chan *sarama.ProducerError)
+	// 模拟 broker 拒绝全部消息
+	kafka.NewProducer = func(cluster []string, conf *sarama.Config) (kafka.Producer, error) {
+		go func() {
+			for msg := range input {
+				errs <- &sarama.ProducerError{Msg: msg, Err: sarama.ErrMessageSizeTooLarge}
+			}
+		}()
+		return producer, nil
+	}
+
+	producer.EXPECT().Input().Return(input).AnyTimes()
+	producer.EXPECT().Errors().Return(errs).AnyTimes()
+	producer.EXPECT().Close().Return(nil).AnyTimes()
+
+	var err error
+	s.backend, err = kafka.NewKafkaBackend(s.CTX, "test")
+	s.NoError(err)
+	sink := make(deadLetterSink, 1)
+	s.backend.DeadLetterSource.Sink = sink
+
+	data := `{"time":1558494970,"dimensions":{"tag":"1"},"metrics":{"field":1}}`
+	s.backend.Push(define.NewJSONPayloadFrom([]byte(data), 1), s.KillCh)
+
+	letter := <-sink
+	s.Equal(define.DeadLetterStageBackend, letter.Stage)
+	s.Equal("kafka", letter.Processor)
+	s.Equal(sarama.ErrMessageSizeTooLarge.Error(), letter.Error)
+	s.JSONEq(data, string(letter.Payload))
+
+	s.NoError(s.backend.Close())
+	close(input)
+}
+
 // TestBackend :
 func TestBackend(t *testing.T) {
 	suite.Run(t, new(BackendSuit))
@@ -77,6 +77,11 @@ func NewKafkaBackendProcessorMonitor(pipe *config.PipelineConfig) *define.Proces
 				"topic": topic,
 			}),
 		),
+		DeadLetterSource: &define.DeadLetterSource{
+			DataID:    pipe.DataID,
+			Processor: "kafka",
+			Stage:     define.DeadLetterStageBackend,
+		},
 	}
 }
 
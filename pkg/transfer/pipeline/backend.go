@@ -16,6 +16,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
@@ -94,6 +95,22 @@ type BulkHandler interface {
 	Close() error
 }
 
+// BulkPartialError : 批量写入部分失败，Failed 为失败结果在批次中的下标，成功的部分不再重试
+type BulkPartialError struct {
+	error
+	Failed []int
+}
+
+// Unwrap :
+func (e *BulkPartialError) Unwrap() error {
+	return e.error
+}
+
+// NewBulkPartialError :
+func NewBulkPartialError(err error, failed []int) *BulkPartialError {
+	return &BulkPartialError{error: err, Failed: failed}
+}
+
 // BulkManager
 type BulkManager interface {
 	define.Stringer
@@ -147,15 +164,23 @@ type BulkBackendAdapter struct {
 	bufferUsageObserver prometheus.Observer
 	flushTimeObserver   *monitor.TimeObserver
 	pool                sync.Pool
+	payloadPool         sync.Pool
 	bufferSize          int
 	flushInterval       time.Duration
 	flushRetries        int
 	pushOnce            sync.Once
-	resultChan          chan interface{}
+	resultChan          chan bulkResult
 	buffer              []interface{}
+	payloads            []define.Payload
 	pushSem             utils.Semaphore
 }
 
+// bulkResult : 待写入的结果及其来源 payload，写入失败时用于投递死信
+type bulkResult struct {
+	result  interface{}
+	payload define.Payload
+}
+
 func getBufferSizeAndFlushInterval(ctx context.Context, name string) (int, time.Duration) {
 	bufferSize := BulkDefaultBufferSize
 	flushInterval := BulkDefaultFlushInterval
@@ -220,9 +245,11 @@ func NewBulkBackendAdapter(ctx context.Context, name string, handler BulkHandler
 		bufferSize:            bufferSize,
 		flushInterval:         flushInterval,
 		flushRetries:          flushRetries,
-		resultChan:            make(chan interface{}, define.CoreNum()),
+		resultChan:            make(chan bulkResult, define.CoreNum()),
 		pool:                  sync.Pool{New: func() interface{} { return make([]interface{}, 0, bufferSize) }},
+		payloadPool:           sync.Pool{New: func() interface{} { return make([]define.Payload, 0, bufferSize) }},
 		buffer:                make([]interface{}, 0, bufferSize),
+		payloads:              make([]define.Payload, 0, bufferSize),
 		pushSem: utils.NewChainingSemaphore(
 			BulkGlobalPushSemaphore, utils.NewWeightedSemaphore(concurrency),
 		),
@@ -239,22 +266,34 @@ func (b *BulkBackendAdapter) isFull() bool {
 	return len(b.buffer) == cap(b.buffer)
 }
 
-func (b *BulkBackendAdapter) add(result interface{}) {
-	b.buffer = append(b.buffer, result)
+func (b *BulkBackendAdapter) add(result bulkResult) {
+	b.buffer = append(b.buffer, result.result)
+	b.payloads = append(b.payloads, result.payload)
 	if b.isFull() {
 		b.flush()
 	}
 }
 
-func (b *BulkBackendAdapter) flushWithRetries(buffer []interface{}) int {
+func (b *BulkBackendAdapter) flushWithRetries(buffer []interface{}) (int, error) {
+	var (
+		n   int
+		err error
+	)
 	ctx := b.context
 	flushRetries := b.flushRetries
 	interval := b.flushInterval / time.Duration(flushRetries)
 	for i := 0; i <= flushRetries; i++ {
-		n, err := b.handler.Flush(ctx, buffer)
+		n, err = b.handler.Flush(ctx, buffer)
 		if err == nil {
 			logging.Debugf("backend %v flushed %d results", b, n)
-			return n
+			return n, nil
+		}
+
+		// 部分写入成功时整体重试会导致重复写入，失败的部分由调用方处理
+		var partial *BulkPartialError
+		if errors.As(err, &partial) {
+			logging.Errorf("backend %v flush %d of %d results error %v", b, len(partial.Failed), len(buffer), err)
+			return n, err
 		}
 
 		if i < flushRetries {
@@ -269,7 +308,30 @@ func (b *BulkBackendAdapter) flushWithRetries(buffer []interface{}) int {
 		}
 	}
 
-	return 0
+	return 0, err
+}
+
+// sendDeadLetters : 写入失败的数据投递到死信队列，同一前端数据派生的多条结果只投递一次
+func (b *BulkBackendAdapter) sendDeadLetters(payloads []define.Payload, err error) {
+	if b.DeadLetterSource == nil {
+		return
+	}
+
+	sent := make(map[string]struct{})
+	for _, payload := range payloads {
+		if payload == nil {
+			continue
+		}
+		origin, e := define.PayloadOrigin(payload)
+		if e != nil {
+			continue
+		}
+		if _, ok := sent[string(origin)]; ok {
+			continue
+		}
+		sent[string(origin)] = struct{}{}
+		define.SendDeadLetter(b.DeadLetterSource, payload, err)
+	}
 }
 
 func (b *BulkBackendAdapter) flush() {
@@ -279,6 +341,8 @@ func (b *BulkBackendAdapter) flush() {
 
 	buffer := b.buffer
 	b.buffer = b.pool.Get().([]interface{})
+	payloads := b.payloads
+	b.payloads = b.payloadPool.Get().([]define.Payload)
 
 	err := b.concurrency.Acquire(b.context, 1)
 	if err != nil {
@@ -287,10 +351,14 @@ func (b *BulkBackendAdapter) flush() {
 	}
 
 	b.waitGroup.Add(1)
-	go func(buffer []interface{}) {
+	go func(buffer []interface{}, payloads []define.Payload) {
 		size := float64(len(buffer))
 		defer func() {
+			for i := range payloads {
+				payloads[i] = nil
+			}
 			b.pool.Put(buffer[:0])
+			b.payloadPool.Put(payloads[:0])
 			b.waitGroup.Done()
 			b.concurrency.Release(1)
 		}()
@@ -298,7 +366,7 @@ func (b *BulkBackendAdapter) flush() {
 			logging.Errorf("backend %v flush %.0f results panic %+v", b, size, e)
 		})
 		observerRecord := b.flushTimeObserver.Start()
-		n := b.flushWithRetries(buffer)
+		n, err := b.flushWithRetries(buffer)
 		observerRecord.Finish()
 		flushed := float64(n)
 		if flushed > size {
@@ -306,8 +374,21 @@ func (b *BulkBackendAdapter) flush() {
 		}
 		b.CounterSuccesses.Add(flushed)
 		b.CounterFails.Add(size - flushed)
+		// 部分写入成功时只投递失败的记录，无法区分失败记录的部分写入不投递，避免重放时重复写入
+		var partial *BulkPartialError
+		if errors.As(err, &partial) {
+			failed := make([]define.Payload, 0, len(partial.Failed))
+			for _, index := range partial.Failed {
+				if index >= 0 && index < len(payloads) {
+					failed = append(failed, payloads[index])
+				}
+			}
+			b.sendDeadLetters(failed, err)
+		} else if n == 0 && err != nil {
+			b.sendDeadLetters(payloads, err)
+		}
 		b.bufferUsageObserver.Observe(size / float64(b.bufferSize))
-	}(buffer)
+	}(buffer, payloads)
 }
 
 func (b *BulkBackendAdapter) cleanUp() {
@@ -393,7 +474,7 @@ func (b *BulkBackendAdapter) Push(d define.Payload, killChan chan<- error) {
 		defer b.pushWaitGroup.Done()
 		result, at, ok := b.handler.Handle(b.context, d, killChan)
 		if !ok {
-			b.CounterFails.Inc()
+			b.Reject(d, errors.Wrapf(define.ErrValue, "backend %v handle payload failed", b))
 			return
 		}
 
@@ -402,7 +483,7 @@ func (b *BulkBackendAdapter) Push(d define.Payload, killChan chan<- error) {
 		b.ObserveProcessElapsed(time.Since(t).Seconds())
 
 		select {
-		case b.resultChan <- result:
+		case b.resultChan <- bulkResult{result: result, payload: d}:
 			logging.Debugf("backend %v pushed payload %v to buffer", b, d)
 		case <-b.pushContext.Done():
 			return
@@ -31,6 +31,9 @@ func (b *FlatBatchConfigBuilder) GetStandardProcessors(pipe *config.PipelineConf
 
 	processors = append(processors, "flat_batch_handler")
 	if rt != nil && rt.ResultTable != "" {
+		if utils.NewMapHelper(rt.Option).GetOrDefault(config.ResultTableOptEnableSchemaDrift, false) == true {
+			processors = append(processors, "schema_drift")
+		}
 		processors = append(processors, "ts_format")
 	}
 
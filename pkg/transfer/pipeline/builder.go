@@ -371,6 +371,19 @@ func (b *Builder) Finish() (*Pipeline, error) {
 		return nil, err
 	}
 
+	// 预览模式不会启动流水线，无需死信节点
+	if PreviewRecorderFromContext(b.ctx) != nil {
+		return NewPipeline(b.ctx, b.name, nodes), nil
+	}
+
+	// 死信节点不参与数据流转，放在最后启动和停止；创建失败不影响流水线本身
+	deadLetter, err := NewDeadLetterNodeFromContext(b.ctx)
+	if err != nil {
+		logging.Errorf("pipeline %s create dead letter node failed: %v", b.name, err)
+	} else if deadLetter != nil {
+		nodes = append(nodes, deadLetter)
+	}
+
 	return NewPipeline(b.ctx, b.name, nodes), nil
 }
 
@@ -430,9 +443,17 @@ func (b *ConfigBuilder) FrontendProcessor(ctx context.Context) (Node, error) {
 		b.FrontendClusterConfigInitFn(mqConf)
 	}
 
-	frontend, err := define.NewFrontend(ctx, mqConf.ClusterType)
-	if err != nil {
-		return nil, errors.Wrapf(err, "create frontend by type %v", mqConf.ClusterType)
+	var (
+		frontend define.Frontend
+		err      error
+	)
+	if PreviewRecorderFromContext(ctx) != nil {
+		frontend = &previewFrontend{BaseFrontend: define.NewBaseFrontend("preview")}
+	} else {
+		frontend, err = define.NewFrontend(ctx, mqConf.ClusterType)
+		if err != nil {
+			return nil, errors.Wrapf(err, "create frontend by type %v", mqConf.ClusterType)
+		}
 	}
 	ctx, cancel := context.WithCancel(ctx)
 	frontendProcessor := NewFrontendNode(ctx, cancel, frontend, b.frontendWaitDelay)
@@ -462,6 +483,11 @@ func (b *ConfigBuilder) SetupFrontend() *ConfigBuilder {
 
 // GetBackendByContext : 按照context中的结果表shipper配置，得到该结果表的写入后端processor
 func (b *ConfigBuilder) GetBackendByContext(ctx context.Context) (Node, error) {
+	// 预览模式不创建真实的写入后端
+	if PreviewRecorderFromContext(ctx) != nil {
+		return NewGluttonousNode(ctx), nil
+	}
+
 	rt := config.ResultTableConfigFromContext(ctx)
 	processors := make([]Node, 0, len(rt.ShipperList))
 	for _, s := range rt.ShipperList {
@@ -492,9 +518,22 @@ func (b *ConfigBuilder) GetBackendByContext(ctx context.Context) (Node, error) {
 
 // DataProcessor :
 func (b *ConfigBuilder) DataProcessor(ctx context.Context, name string) (Node, error) {
-	processor, err := define.NewDataProcessor(ctx, name)
-	if err != nil {
-		return nil, err
+	var (
+		processor define.DataProcessor
+		err       error
+	)
+	recorder := PreviewRecorderFromContext(ctx)
+	if recorder != nil && recorder.isSkipped(name) {
+		// 预览时跳过会对外上报的处理器
+		processor = NewGluttonous()
+	} else {
+		processor, err = define.NewDataProcessor(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if recorder != nil {
+		recorder.record(ctx, name, processor)
 	}
 	ctx, cancel := context.WithCancel(ctx)
 	return NewProcessNode(ctx, cancel, processor), nil
@@ -0,0 +1,198 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// DeadLetterDefaultBufferSize : 死信缓冲区大小，写满后直接丢弃
+var DeadLetterDefaultBufferSize = 1000
+
+// DeadLetterResultTable : 死信写入后端时使用的结果表名
+const DeadLetterResultTable = "dead_letter"
+
+// 死信队列仅支持不会再产生死信的后端，避免循环投递
+var deadLetterClusterTypes = []string{"kafka", "file"}
+
+// DeadLetterClusterFromPipeline : 从 pipeline option 中解析死信队列存储配置，未配置时返回 nil
+func DeadLetterClusterFromPipeline(pipe *config.PipelineConfig) (*config.MetaClusterInfo, error) {
+	options := utils.NewMapHelper(pipe.Option)
+	value, ok := options.Get(config.PipelineConfigOptDeadLetterConfig)
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	cluster := config.NewMetaClusterInfo()
+	err := mapstructure.Decode(value, cluster)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "parse %s", config.PipelineConfigOptDeadLetterConfig)
+	}
+	utils.CheckError(cluster.Clean())
+
+	if !utils.IsStringInSlice(cluster.ClusterType, deadLetterClusterTypes) {
+		return nil, errors.Wrapf(define.ErrOperationForbidden, "dead letter cluster type %s not supported", cluster.ClusterType)
+	}
+	return cluster, nil
+}
+
+// DeadLetterNode : 死信节点，接收本 dataid 下被拒绝的数据并写入配置的后端
+type DeadLetterNode struct {
+	*BaseNode
+	dataID  int
+	backend define.Backend
+	lock    sync.RWMutex
+	closed  bool
+	ch      chan define.Payload
+	sn      int64
+}
+
+// String :
+func (n *DeadLetterNode) String() string {
+	return fmt.Sprintf("dead_letter:%v", n.backend)
+}
+
+// Put : 非阻塞写入，队列已满或节点已停止时丢弃
+func (n *DeadLetterNode) Put(letter *define.DeadLetter) bool {
+	dataID := strconv.Itoa(n.dataID)
+
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	if n.closed {
+		MonitorDeadLetterDropped.WithLabelValues(dataID).Inc()
+		return false
+	}
+
+	payload := define.NewJSONPayload(int(atomic.AddInt64(&n.sn, 1)))
+	if err := payload.From(letter); err != nil {
+		logging.Warnf("%v dump dead letter %+v error %v", n, letter, err)
+		MonitorDeadLetterDropped.WithLabelValues(dataID).Inc()
+		return false
+	}
+
+	select {
+	case n.ch <- payload:
+		MonitorDeadLetterHandled.WithLabelValues(dataID, letter.Stage).Inc()
+		return true
+	default:
+		MonitorDeadLetterDropped.WithLabelValues(dataID).Inc()
+		return false
+	}
+}
+
+// Start :
+func (n *DeadLetterNode) Start(killChan chan<- error) {
+	n.BaseNode.Start(killChan)
+	define.RegisterDeadLetterSink(n.dataID, n)
+
+	// 死信后端的异常不应影响主流水线，单独消费其错误直到后端关闭
+	errCh := make(chan error)
+	done := make(chan struct{})
+	n.waitGroup.Add(1)
+	go func() {
+		defer n.waitGroup.Done()
+		for {
+			select {
+			case err := <-errCh:
+				logging.Errorf("%v backend error %v", n, err)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	n.waitGroup.Add(1)
+	go func() {
+		defer n.waitGroup.Done()
+		defer close(done)
+		defer utils.RecoverError(func(e error) {
+			logging.Errorf("%v push panic %+v", n, e)
+		})
+		for payload := range n.ch {
+			n.backend.Push(payload, errCh)
+		}
+		err := n.backend.Close()
+		if err != nil {
+			logging.Warnf("%v close backend error %v", n, err)
+		}
+	}()
+}
+
+// Stop :
+func (n *DeadLetterNode) Stop() error {
+	define.UnregisterDeadLetterSink(n.dataID, n)
+
+	n.lock.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.ch)
+	}
+	n.lock.Unlock()
+
+	return n.BaseNode.Stop()
+}
+
+// NewDeadLetterNode :
+func NewDeadLetterNode(ctx context.Context, cluster *config.MetaClusterInfo) (*DeadLetterNode, error) {
+	pipe := config.PipelineConfigFromContext(ctx)
+	if pipe == nil {
+		return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is empty")
+	}
+
+	ctx = config.ShipperConfigIntoContext(ctx, cluster)
+	ctx = config.ResultTableConfigIntoContext(ctx, &config.MetaResultTableConfig{
+		ResultTable: DeadLetterResultTable,
+	})
+	backend, err := define.NewBackend(ctx, cluster.ClusterType)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "create dead letter backend %s", cluster.ClusterType)
+	}
+	// 死信后端自身写入失败时只计数，避免失败数据再次投递回死信队列形成循环
+	if m, ok := backend.(interface {
+		GetProcessorMonitor() *define.ProcessorMonitor
+	}); ok && m.GetProcessorMonitor() != nil {
+		m.GetProcessorMonitor().DeadLetterSource = nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	node := &DeadLetterNode{
+		dataID:  pipe.DataID,
+		backend: backend,
+		ch:      make(chan define.Payload, DeadLetterDefaultBufferSize),
+	}
+	node.BaseNode = NewBaseNode(ctx, cancel, node.String())
+	return node, nil
+}
+
+// NewDeadLetterNodeFromContext : 按 pipeline 配置创建死信节点，未开启时返回 nil
+func NewDeadLetterNodeFromContext(ctx context.Context) (*DeadLetterNode, error) {
+	pipe := config.PipelineConfigFromContext(ctx)
+	if pipe == nil {
+		return nil, nil
+	}
+
+	cluster, err := DeadLetterClusterFromPipeline(pipe)
+	if err != nil || cluster == nil {
+		return nil, err
+	}
+	return NewDeadLetterNode(ctx, cluster)
+}
@@ -27,6 +27,8 @@ const (
 	ConfKeyPayloadFlushConcurrency    = "pipeline.backend.concurrency"
 	ConfKeyPayloadFlushMaxConcurrency = "pipeline.backend.max_concurrency"
 
+	ConfKeyDeadLetterBufferSize = "pipeline.dead_letter.buffer_size"
+
 	ConfKeyPipeLineDefaultNums = "pipeline.processor.default_nums"
 	ConfKeyPipeLineNums        = "pipeline.processor.nums"
 )
@@ -63,6 +65,7 @@ func init() {
 		conf.SetDefault(ConfKeyPayloadFlushReties, BulkDefaultFlushRetries)
 		conf.SetDefault(ConfKeyPayloadFlushConcurrency, BulkDefaultConcurrency)
 		conf.SetDefault(ConfKeyPayloadFlushMaxConcurrency, BulkDefaultMaxConcurrency)
+		conf.SetDefault(ConfKeyDeadLetterBufferSize, DeadLetterDefaultBufferSize)
 
 		conf.SetDefault(ConfKeyPipeLineDefaultNums, 1)
 	}))
@@ -74,6 +77,7 @@ func init() {
 		BulkDefaultFlushRetries = conf.GetInt(ConfKeyPayloadFlushReties)
 		BulkDefaultConcurrency = conf.GetInt64(ConfKeyPayloadFlushConcurrency)
 		BulkDefaultMaxConcurrency = conf.GetInt64(ConfKeyPayloadFlushMaxConcurrency)
+		DeadLetterDefaultBufferSize = conf.GetInt(ConfKeyDeadLetterBufferSize)
 
 		BulkGlobalConcurrencySemaphore = utils.NewWeightedSemaphore(BulkDefaultMaxConcurrency)
 
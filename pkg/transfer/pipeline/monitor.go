@@ -43,10 +43,31 @@ var (
 		Help:      "Pipeline process elapsed seconds",
 		Buckets:   monitor.DefBuckets,
 	}, []string{"id", "cluster"})
+
+	// MonitorDeadLetterHandled 写入死信队列的记录数
+	MonitorDeadLetterHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "pipeline_dead_letter_handled_total",
+		Help:      "Count of records written to dead letter queue",
+	}, []string{"id", "stage"})
+
+	// MonitorDeadLetterDropped 死信队列已满或已关闭时丢弃的记录数
+	MonitorDeadLetterDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "pipeline_dead_letter_dropped_total",
+		Help:      "Count of records dropped by dead letter queue",
+	}, []string{"id"})
 )
 
 func NewFrontendProcessorMonitor(pipe *config.PipelineConfig) *define.ProcessorMonitor {
 	dataID := strconv.Itoa(pipe.DataID)
+	source := &define.DeadLetterSource{
+		DataID: pipe.DataID,
+		Stage:  define.DeadLetterStageFrontend,
+	}
+	if pipe.MQConfig != nil {
+		source.Processor = pipe.MQConfig.ClusterType
+	}
 	return &define.ProcessorMonitor{
 		CounterMixin: monitor.NewCounterMixin(
 			define.MonitorFrontendHandled.With(prometheus.Labels{
@@ -60,6 +81,7 @@ func NewFrontendProcessorMonitor(pipe *config.PipelineConfig) *define.ProcessorM
 				"cluster":  define.ConfClusterID,
 			}),
 		),
+		DeadLetterSource: source,
 	}
 }
 
@@ -76,6 +98,11 @@ func NewDataProcessorMonitor(name string, pipe *config.PipelineConfig) *define.P
 				"pipeline": name,
 			}),
 		),
+		DeadLetterSource: &define.DeadLetterSource{
+			DataID:    pipe.DataID,
+			Processor: name,
+			Stage:     define.DeadLetterStageProcessor,
+		},
 	}
 }
 
@@ -111,6 +138,8 @@ func NewBackendProcessorMonitor(pipe *config.PipelineConfig, shipper *config.Met
 		labels["target"] = "kafka"
 	} else if shipper.ClusterType == "redis" {
 		labels["target"] = "redis"
+	} else if shipper.ClusterType == "remote_write" {
+		labels["target"] = "remote_write"
 	} else {
 		labels["target"] = "influxdb"
 	}
@@ -120,6 +149,11 @@ func NewBackendProcessorMonitor(pipe *config.PipelineConfig, shipper *config.Met
 			define.MonitorBackendHandled.With(labels),
 			define.MonitorBackendDropped.With(labels),
 		),
+		DeadLetterSource: &define.DeadLetterSource{
+			DataID:    pipe.DataID,
+			Processor: labels["target"],
+			Stage:     define.DeadLetterStageBackend,
+		},
 	}
 }
 
@@ -128,5 +162,7 @@ func init() {
 		MonitorBulkBackendBufferUsage,
 		MonitorBulkBackendSendDuration,
 		MonitorProcessElapsedDuration,
+		MonitorDeadLetterHandled,
+		MonitorDeadLetterDropped,
 	)
 }
@@ -0,0 +1,274 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/cstockton/go-conv"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// PreviewSkippedProcessors : 预览时不执行的处理器，这些处理器会向外部存储上报数据
+var PreviewSkippedProcessors = []string{"sampling_reporter", "metrics_reporter", "schema_drift"}
+
+// PreviewError : 预览时处理器产生的错误
+type PreviewError struct {
+	Error   string      `json:"error"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+// PreviewStage : 单个处理器的预览结果
+type PreviewStage struct {
+	Name    string          `json:"name"`
+	Skipped bool            `json:"skipped"`
+	Input   int             `json:"input"`
+	Output  []interface{}   `json:"output"`
+	Errors  []*PreviewError `json:"errors"`
+
+	lock      sync.Mutex
+	processor define.DataProcessor
+}
+
+// Put : 作为死信接收器收集处理器拒绝的数据
+func (s *PreviewStage) Put(letter *define.DeadLetter) bool {
+	s.addError(letter.Error, letter.Payload)
+	return true
+}
+
+func (s *PreviewStage) addError(message string, payload []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item := &PreviewError{Error: message}
+	if payload != nil {
+		item.Payload = previewValue(payload)
+	}
+	s.Errors = append(s.Errors, item)
+}
+
+// run : 同步执行处理器，返回全部输出
+func (s *PreviewStage) run(inputs []define.Payload) (outputs []define.Payload) {
+	s.Input = len(inputs)
+	s.Output = make([]interface{}, 0)
+	s.Errors = make([]*PreviewError, 0)
+	if s.Skipped {
+		return inputs
+	}
+
+	outputCh := make(chan define.Payload)
+	killCh := make(chan error)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for payload := range outputCh {
+			var data []byte
+			if err := payload.To(&data); err != nil {
+				s.addError(err.Error(), nil)
+				continue
+			}
+			outputs = append(outputs, payload)
+			s.lock.Lock()
+			s.Output = append(s.Output, previewValue(data))
+			s.lock.Unlock()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for err := range killCh {
+			if err != nil {
+				s.addError(err.Error(), nil)
+			}
+		}
+	}()
+
+	func() {
+		defer utils.RecoverError(func(err error) {
+			logging.Warnf("preview processor %s panic: %v", s.Name, err)
+			s.addError(fmt.Sprintf("panic: %v", err), nil)
+		})
+		for _, payload := range inputs {
+			s.processor.Process(payload, outputCh, killCh)
+		}
+		s.processor.Finish(outputCh, killCh)
+	}()
+
+	close(outputCh)
+	close(killCh)
+	wg.Wait()
+	return outputs
+}
+
+// PreviewResultTable : 单个结果表处理链的预览结果
+type PreviewResultTable struct {
+	ResultTable string          `json:"result_table"`
+	Stages      []*PreviewStage `json:"stages"`
+}
+
+// PreviewResult : 预览结果
+type PreviewResult struct {
+	DataID       int                   `json:"data_id"`
+	ETLConfig    string                `json:"etl_config"`
+	ResultTables []*PreviewResultTable `json:"result_tables"`
+}
+
+// PreviewRecorder : 预览模式下记录流水线构造出的处理器
+// 此时流水线不会启动，也不会创建真实的前端、后端以及死信节点
+type PreviewRecorder struct {
+	lock   sync.Mutex
+	tables []*PreviewResultTable
+}
+
+// NewPreviewRecorder :
+func NewPreviewRecorder() *PreviewRecorder {
+	return &PreviewRecorder{}
+}
+
+// PreviewRecorderIntoContext :
+func PreviewRecorderIntoContext(ctx context.Context, recorder *PreviewRecorder) context.Context {
+	return context.WithValue(ctx, define.ContextPreviewKey, recorder)
+}
+
+// PreviewRecorderFromContext :
+func PreviewRecorderFromContext(ctx context.Context) *PreviewRecorder {
+	recorder, _ := ctx.Value(define.ContextPreviewKey).(*PreviewRecorder)
+	return recorder
+}
+
+// isSkipped :
+func (r *PreviewRecorder) isSkipped(name string) bool {
+	for _, n := range PreviewSkippedProcessors {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// record : 按结果表记录处理器，并发分支只记录第一条
+func (r *PreviewRecorder) record(ctx context.Context, name string, processor define.DataProcessor) {
+	runtimeConfig := config.RuntimeConfigFromContext(ctx)
+	if runtimeConfig == nil || runtimeConfig.PipelineCount != 0 {
+		return
+	}
+
+	table := ""
+	if rt := config.ResultTableConfigFromContext(ctx); rt != nil {
+		table = rt.ResultTable
+	}
+
+	stage := &PreviewStage{
+		Name:      name,
+		Skipped:   r.isSkipped(name),
+		processor: processor,
+	}
+	// 处理器拒绝的数据直接投递到当前阶段，不进入真实的死信队列
+	if m, ok := processor.(interface {
+		GetProcessorMonitor() *define.ProcessorMonitor
+	}); ok && m.GetProcessorMonitor() != nil {
+		monitor := m.GetProcessorMonitor()
+		source := &define.DeadLetterSource{Stage: define.DeadLetterStageProcessor, Processor: name}
+		if monitor.DeadLetterSource != nil {
+			*source = *monitor.DeadLetterSource
+		}
+		source.Sink = stage
+		monitor.DeadLetterSource = source
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, t := range r.tables {
+		if t.ResultTable == table {
+			t.Stages = append(t.Stages, stage)
+			return
+		}
+	}
+	r.tables = append(r.tables, &PreviewResultTable{
+		ResultTable: table,
+		Stages:      []*PreviewStage{stage},
+	})
+}
+
+// previewFrontend : 预览模式下的前端，不拉取任何数据
+type previewFrontend struct {
+	*define.BaseFrontend
+}
+
+// Pull :
+func (f *previewFrontend) Pull(outputChan chan<- define.Payload, killChan chan<- error) {}
+
+// Flow :
+func (f *previewFrontend) Flow() int { return 0 }
+
+// Commit :
+func (f *previewFrontend) Commit() error { return nil }
+
+// Reset :
+func (f *previewFrontend) Reset() error { return nil }
+
+// Close :
+func (f *previewFrontend) Close() error { return nil }
+
+// previewValue : 输出优先按 json 展示
+func previewValue(data []byte) interface{} {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err == nil {
+		return value
+	}
+	return conv.String(data)
+}
+
+// Preview : 使用真实的处理器试运行样例数据，返回每个处理阶段的输出和错误，不会写入任何后端
+func Preview(ctx context.Context, pipe *config.PipelineConfig, samples [][]byte) (*PreviewResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	recorder := NewPreviewRecorder()
+	ctx = PreviewRecorderIntoContext(ctx, recorder)
+	ctx = config.PipelineConfigIntoContext(ctx, pipe)
+	ctx = config.MQConfigIntoContext(ctx, pipe.MQConfig)
+
+	_, err := define.NewPipeline(ctx, pipe.ETLConfig)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "create pipeline %s failed", pipe.ETLConfig)
+	}
+	if len(recorder.tables) == 0 {
+		return nil, errors.Wrapf(define.ErrOperationForbidden, "no processor found for pipeline %s", pipe.ETLConfig)
+	}
+
+	for _, table := range recorder.tables {
+		inputs := make([]define.Payload, 0, len(samples))
+		for index, sample := range samples {
+			payload := define.NewDefaultPayload()
+			if err := payload.From(sample); err != nil {
+				return nil, errors.WithMessagef(err, "load sample %d failed", index)
+			}
+			inputs = append(inputs, payload)
+		}
+
+		for _, stage := range table.Stages {
+			inputs = stage.run(inputs)
+		}
+	}
+
+	return &PreviewResult{
+		DataID:       pipe.DataID,
+		ETLConfig:    pipe.ETLConfig,
+		ResultTables: recorder.tables,
+	}, nil
+}
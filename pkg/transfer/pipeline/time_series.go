@@ -31,6 +31,10 @@ func (b *TSConfigBuilder) GetStandardPrepareProcessors(pipe *config.PipelineConf
 	processors := make([]string, 0)
 	option := utils.NewMapHelper(pipe.Option)
 	rtOption := utils.NewMapHelper(rt.Option)
+	// 结构漂移检测需要在注入维度前进行，避免把注入的维度当作新字段
+	if rtOption.GetOrDefault(config.ResultTableOptEnableSchemaDrift, false) == true {
+		processors = append(processors, "schema_drift")
+	}
 	// 加入cmdb_level 节点 且 未配置拆分结构
 	if rtOption.GetOrDefault(config.PipelineConfigOptEnableDimensionCmdbLevel, true) == true && len(rtOption.GetOrDefault(config.ResultTableListConfigOptMetricSplitLevel, []interface{}{}).([]interface{})) == 0 {
 		processors = append(processors, "cmdb_injector")
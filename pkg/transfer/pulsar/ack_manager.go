@@ -0,0 +1,106 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pulsar
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pulsarcli "github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+type AckFn func(id pulsarcli.MessageID)
+
+// DelayAckManager : 延迟确认管理器，与 kafka.DelayOffsetManager 保持一致
+// 每个周期只确认上一个周期内标记的消息，确保确认时数据已经流经流水线
+type DelayAckManager struct {
+	locker   utils.Semaphore
+	wg       sync.WaitGroup
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	once     sync.Once
+	callback AckFn
+	topic    string
+	locked   []pulsarcli.MessageID
+	waiting  []pulsarcli.MessageID
+}
+
+func (m *DelayAckManager) Mark(id pulsarcli.MessageID) {
+	m.once.Do(func() {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			logging.Infof("topic %s ack manager will check every %v", m.topic, m.interval)
+			ticker := time.NewTicker(m.interval)
+		loop:
+			for {
+				select {
+				case <-m.ctx.Done():
+					break loop
+
+				case now := <-ticker.C:
+					// 避免 ticker 与 ctx.Done 同时就绪时 ticker 抢到执行权限
+					select {
+					case <-m.ctx.Done():
+						break loop
+					default:
+					}
+					logging.Debugf("ready to ack %d messages on topic %s at %v", len(m.locked), m.topic, now)
+
+					// 确认往前退一个周期 即当次只会确认上一个周期内标记的消息
+					for _, id := range m.locked {
+						m.callback(id)
+					}
+
+					if err := m.locker.Acquire(m.ctx, 1); err != nil {
+						logging.Infof("topic %s acquire lock failed %v, break loop", m.topic, err)
+						break loop
+					}
+
+					m.locked = m.waiting
+					m.waiting = nil
+
+					m.locker.Release(1)
+				}
+			}
+
+			ticker.Stop()
+			logging.Infof("topic %s ack manager finished", m.topic)
+		}()
+	})
+
+	if m.locker.Acquire(m.ctx, 1) == nil {
+		defer m.locker.Release(1)
+		m.waiting = append(m.waiting, id)
+	}
+}
+
+// Close : 未确认的消息会在重新订阅后由服务端再次投递
+func (m *DelayAckManager) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+func NewDelayAckManager(ctx context.Context, callback AckFn, topic string, interval time.Duration) *DelayAckManager {
+	ctx, cancel := context.WithCancel(ctx)
+	return &DelayAckManager{
+		locker:   utils.NewWeightedSemaphore(1),
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		callback: callback,
+		topic:    topic,
+	}
+}
@@ -0,0 +1,305 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pulsar
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pulsarcli "github.com/apache/pulsar-client-go/pulsar"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+const (
+	sslInsecureSkipVerify = "ssl_insecure_skip_verify"
+)
+
+// Consumer : 前端依赖的 pulsar 消费者方法
+type Consumer interface {
+	Receive(ctx context.Context) (pulsarcli.Message, error)
+	AckID(id pulsarcli.MessageID) error
+	Close()
+}
+
+// clientConsumer : 关闭消费者时一并关闭客户端
+type clientConsumer struct {
+	pulsarcli.Consumer
+	client pulsarcli.Client
+}
+
+// AckID : 当前客户端版本的 ack 为异步发送，不返回错误
+func (c *clientConsumer) AckID(id pulsarcli.MessageID) error {
+	c.Consumer.AckID(id)
+	return nil
+}
+
+// Close :
+func (c *clientConsumer) Close() {
+	c.Consumer.Close()
+	c.client.Close()
+}
+
+var (
+	NewPulsarConsumer = func(clientOpts pulsarcli.ClientOptions, consumerOpts pulsarcli.ConsumerOptions) (Consumer, error) {
+		client, err := pulsarcli.NewClient(clientOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		consumer, err := client.Subscribe(consumerOpts)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+
+		return &clientConsumer{Consumer: consumer, client: client}, nil
+	}
+
+	NewPulsarOptions = func(ctx context.Context, conf define.Configuration) (pulsarcli.ClientOptions, pulsarcli.ConsumerOptions, error) {
+		mqConfig := config.MQConfigFromContext(ctx)
+		pulsarConfig := mqConfig.AsPulsarCluster()
+
+		clientOpts := pulsarcli.ClientOptions{
+			URL:               pulsarConfig.GetURL(),
+			ConnectionTimeout: conf.GetDuration(ConfPulsarConnectionTimeout),
+			OperationTimeout:  conf.GetDuration(ConfPulsarOperationTimeout),
+			Logger:            newPulsarLogger(nil),
+		}
+		insecure, _ := utils.NewMapHelper(mqConfig.ClusterConfig).GetBool(sslInsecureSkipVerify)
+		clientOpts.TLSAllowInsecureConnection = insecure
+
+		// pulsar 使用 token 认证，密码即为 token
+		token, _ := utils.NewMapHelper(mqConfig.AuthInfo).GetString("password")
+		if token != "" {
+			clientOpts.Authentication = pulsarcli.NewAuthenticationToken(token)
+		}
+
+		topic := pulsarConfig.GetTopic()
+		consumerOpts := pulsarcli.ConsumerOptions{
+			Topic:             topic,
+			SubscriptionName:  fmt.Sprintf("%s%s", conf.GetString(ConfPulsarSubscriptionPrefix), topic),
+			Name:              define.ProcessID,
+			ReceiverQueueSize: conf.GetInt(ConfPulsarReceiverQueueSize),
+		}
+
+		subscriptionType := pulsarConfig.GetSubscriptionType()
+		if subscriptionType == "" {
+			subscriptionType = conf.GetString(ConfPulsarSubscriptionType)
+		}
+		switch subscriptionType {
+		case config.PulsarSubscriptionShared:
+			consumerOpts.Type = pulsarcli.Shared
+		case config.PulsarSubscriptionKeyShared:
+			consumerOpts.Type = pulsarcli.KeyShared
+		default:
+			return clientOpts, consumerOpts, errors.Wrapf(define.ErrValue, "unsupported subscription type %s", subscriptionType)
+		}
+
+		switch position := conf.GetString(ConfPulsarInitialPosition); position {
+		case "latest":
+			consumerOpts.SubscriptionInitialPosition = pulsarcli.SubscriptionPositionLatest
+		case "earliest":
+			consumerOpts.SubscriptionInitialPosition = pulsarcli.SubscriptionPositionEarliest
+		default:
+			return clientOpts, consumerOpts, errors.Wrapf(define.ErrValue, "unsupported initial position %s", position)
+		}
+
+		return clientOpts, consumerOpts, nil
+	}
+)
+
+// Frontend :
+type Frontend struct {
+	*define.BaseFrontend
+	*define.ProcessorMonitor
+	wg          sync.WaitGroup
+	ctx         context.Context
+	cancelFunc  context.CancelFunc
+	consumer    Consumer
+	fr          *define.FlowRecorder
+	fl          *define.FlowLimiter
+	topic       string
+	ackInterval time.Duration
+	killOnce    uint32 // 确保 kill 信号只会被发送一次
+}
+
+// NewFrontend :
+func NewFrontend(rootCtx context.Context, name string) define.Frontend {
+	ctx, cancelFunc := context.WithCancel(rootCtx)
+	conf := config.FromContext(ctx)
+
+	rate := config.MQConfigFromContext(ctx).ConsumeRate
+	if rate <= 0 {
+		rate = define.DataIdFlowBytes()
+	}
+	return &Frontend{
+		BaseFrontend:     define.NewBaseFrontend(name),
+		ProcessorMonitor: pipeline.NewFrontendProcessorMonitor(config.PipelineConfigFromContext(ctx)),
+		ctx:              ctx,
+		cancelFunc:       cancelFunc,
+		ackInterval:      conf.GetDuration(ConfPulsarAckInterval),
+		fr:               define.NewFlowRecorder(conf.GetDuration(ConfPulsarFlowInterval)),
+		fl:               define.NewFlowLimiter(name, rate),
+	}
+}
+
+func (f *Frontend) Flow() int {
+	return f.fr.Get()
+}
+
+func (f *Frontend) init() error {
+	conf := config.FromContext(f.ctx)
+	pipeConfig := config.PipelineConfigFromContext(f.ctx)
+	pulsarConfig := config.MQConfigFromContext(f.ctx).AsPulsarCluster()
+
+	// 由于 dataid 归属的 transfer 集群会发生切换
+	// 所以使用时间作为其 values 值，这样查询的时候可以使用 max 语法查询出来
+	MonitorFrontendPulsar.WithLabelValues(
+		strconv.Itoa(pipeConfig.DataID), define.ConfClusterID, pulsarConfig.GetDomain(), pulsarConfig.GetTopic(),
+	).Set(float64(time.Now().UnixMilli()))
+
+	clientOpts, consumerOpts, err := NewPulsarOptions(f.ctx, conf)
+	if err != nil {
+		logging.Errorf("frontend %v make config error %v", f, err)
+		return err
+	}
+
+	f.topic = consumerOpts.Topic
+	logging.Infof("consuming pulsar %s topic %s for subscription %s", clientOpts.URL, consumerOpts.Topic, consumerOpts.SubscriptionName)
+	f.consumer, err = NewPulsarConsumer(clientOpts, consumerOpts)
+	if err != nil {
+		logging.Errorf("consume pulsar topic %s failed: %v", consumerOpts.Topic, err)
+		return err
+	}
+
+	return nil
+}
+
+func (f *Frontend) kill(killChan chan<- error, err error) {
+	if !atomic.CompareAndSwapUint32(&f.killOnce, 0, 1) {
+		return
+	}
+	select {
+	case killChan <- err:
+	case <-f.ctx.Done():
+	}
+}
+
+// Pull : pull data
+func (f *Frontend) Pull(outputChan chan<- define.Payload, killChan chan<- error) {
+	f.wg.Add(1)
+	defer f.wg.Done()
+	defer utils.RecoverError(func(err error) {
+		logging.Errorf("frontend %v panic by error: %v", f, err)
+	})
+
+	if config.MQConfigFromContext(f.ctx) == nil {
+		killChan <- errors.Wrapf(define.ErrOperationForbidden, "get frontend %v config failed", f)
+		return
+	}
+
+	err := f.init()
+	if err != nil {
+		logging.Errorf("frontend %v kill by error %v", f, err)
+		killChan <- err
+		return
+	}
+
+	ackedCounter := MonitorFrontendAcked.With(prometheus.Labels{"topic": f.topic})
+	ackFailedCounter := MonitorFrontendAckFailed.With(prometheus.Labels{"topic": f.topic})
+	ackManager := NewDelayAckManager(f.ctx, func(id pulsarcli.MessageID) {
+		if err := f.consumer.AckID(id); err != nil {
+			logging.Warnf("frontend %v ack message %v failed: %v", f, id, err)
+			ackFailedCounter.Inc()
+			return
+		}
+		ackedCounter.Inc()
+	}, f.topic, f.ackInterval)
+	defer ackManager.Close()
+
+	logging.Infof("pulsar frontend %v consuming topic %s", f, f.topic)
+loop:
+	for {
+		msg, err := f.consumer.Receive(f.ctx)
+		if err != nil {
+			select {
+			case <-f.ctx.Done():
+				break loop
+			default:
+			}
+			logging.Errorf("pulsar frontend %v consuming topic %s error %v", f, f.topic, err)
+			f.kill(killChan, err)
+			return
+		}
+
+		value := msg.Payload()
+		logging.Debugf("%v topic:%q message id:%v message length:%v", f, msg.Topic(), msg.ID(), len(value))
+
+		msgLen := len(value)
+		define.LimitRate(msgLen) // 全局流控（确保进程整体不会失控）
+		f.fl.Consume(msgLen)     // dataid 流控（确保 dataid 不会失控）
+		f.fr.Add(msgLen)         // dataid 流量记录
+
+		payload := f.PayloadCreator()
+		err = payload.From(value)
+		if err != nil {
+			// 无法解析的消息重复投递也没有意义，先投递到死信队列再确认
+			f.Reject(define.NewJSONPayloadFrom(value, 0), err)
+			logging.Errorf("decode message from %s failed: %v", msg.Topic(), value)
+			ackManager.Mark(msg.ID())
+			continue
+		}
+		logging.Debugf("%v pulled a message %v from %s", f, payload, msg.Key())
+		f.CounterSuccesses.Inc()
+
+		// sent 不成功就 hold 在这里 等到发送成功或者收到 Done 信号
+		select {
+		case <-f.ctx.Done():
+			break loop
+		case outputChan <- payload:
+		}
+		ackManager.Mark(msg.ID())
+	}
+	logging.Infof("pulsar frontend %v consuming topic %s finished", f, f.topic)
+}
+
+// Close : close frontend
+func (f *Frontend) Close() error {
+	f.fr.Stop()
+	f.cancelFunc()
+	f.wg.Wait()
+
+	// 可能还没初始化就 Close 判断 consumer 是否为 nil
+	if f.consumer != nil {
+		f.consumer.Close()
+	}
+	return nil
+}
+
+func init() {
+	define.RegisterFrontend("pulsar", func(ctx context.Context, name string) (define.Frontend, error) {
+		pipeConfig := config.PipelineConfigFromContext(ctx)
+		if pipeConfig == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is nil")
+		}
+		return NewFrontend(ctx, pipeConfig.FormatName(name)), nil
+	})
+}
@@ -0,0 +1,281 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pulsar_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	pulsarcli "github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pulsar"
+	. "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/testsuite"
+)
+
+// standInMessageID : 进程内消息 id 替身
+type standInMessageID struct {
+	ledgerID int64
+	entryID  int64
+}
+
+func newStandInMessageID(ledgerID, entryID int64) pulsarcli.MessageID {
+	return &standInMessageID{ledgerID: ledgerID, entryID: entryID}
+}
+
+func (id *standInMessageID) Serialize() []byte { return nil }
+
+func (id *standInMessageID) LedgerID() int64 { return id.ledgerID }
+
+func (id *standInMessageID) EntryID() int64 { return id.entryID }
+
+func (id *standInMessageID) BatchIdx() int32 { return 0 }
+
+func (id *standInMessageID) PartitionIdx() int32 { return 0 }
+
+// undecodablePayload : 无法从消息解析的 payload
+type undecodablePayload struct {
+	*define.JSONPayload
+}
+
+func (p *undecodablePayload) From(v interface{}) error {
+	return errors.New("undecodable")
+}
+
+// standInDeadLetterSink : 进程内死信接收器替身
+type standInDeadLetterSink struct {
+	lock    sync.Mutex
+	letters []*define.DeadLetter
+}
+
+func (s *standInDeadLetterSink) Put(letter *define.DeadLetter) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.letters = append(s.letters, letter)
+	return true
+}
+
+// standInMessage : 进程内消息替身
+type standInMessage struct {
+	pulsarcli.Message
+	id      pulsarcli.MessageID
+	payload []byte
+}
+
+func (m *standInMessage) ID() pulsarcli.MessageID { return m.id }
+
+func (m *standInMessage) Payload() []byte { return m.payload }
+
+func (m *standInMessage) Topic() string { return "test" }
+
+func (m *standInMessage) Key() string { return "" }
+
+// standInConsumer : 进程内消费者替身，消息通道关闭后 Receive 返回 err
+type standInConsumer struct {
+	messages chan pulsarcli.Message
+	err      error
+	lock     sync.Mutex
+	acked    []pulsarcli.MessageID
+	closed   bool
+}
+
+func (c *standInConsumer) Receive(ctx context.Context) (pulsarcli.Message, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg, ok := <-c.messages:
+		if !ok {
+			return nil, c.err
+		}
+		return msg, nil
+	}
+}
+
+func (c *standInConsumer) AckID(id pulsarcli.MessageID) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.acked = append(c.acked, id)
+	return nil
+}
+
+func (c *standInConsumer) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+}
+
+func (c *standInConsumer) ackedIDs() []pulsarcli.MessageID {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]pulsarcli.MessageID(nil), c.acked...)
+}
+
+// FrontendSuite :
+type FrontendSuite struct {
+	ConfigSuite
+	newPulsarConsumer func(pulsarcli.ClientOptions, pulsarcli.ConsumerOptions) (pulsar.Consumer, error)
+	consumer          *standInConsumer
+	consumerOpts      pulsarcli.ConsumerOptions
+}
+
+// SetupTest :
+func (s *FrontendSuite) SetupTest() {
+	s.PipelineConfig = nil
+	s.ResultTableConfig = nil
+	s.ConfigSuite.SetupTest()
+
+	s.PipelineConfig.MQConfig.ClusterType = "pulsar"
+	pulsarConfig := s.PipelineConfig.MQConfig.AsPulsarCluster()
+	pulsarConfig.SetTopic("test")
+	pulsarConfig.SetDomain("localhost")
+	pulsarConfig.SetPort(6650)
+	s.Config.Set(pulsar.ConfPulsarAckInterval, 10*time.Millisecond)
+	s.Config.Set(pulsar.ConfPulsarFlowInterval, time.Minute)
+	s.Config.Set(pulsar.ConfPulsarSubscriptionPrefix, "bkmonitor_transfer_")
+	s.Config.Set(pulsar.ConfPulsarSubscriptionType, config.PulsarSubscriptionShared)
+	s.Config.Set(pulsar.ConfPulsarInitialPosition, "latest")
+
+	s.consumer = &standInConsumer{messages: make(chan pulsarcli.Message)}
+	s.newPulsarConsumer = pulsar.NewPulsarConsumer
+	pulsar.NewPulsarConsumer = func(_ pulsarcli.ClientOptions, opts pulsarcli.ConsumerOptions) (pulsar.Consumer, error) {
+		s.consumerOpts = opts
+		return s.consumer, nil
+	}
+}
+
+// TearDownTest :
+func (s *FrontendSuite) TearDownTest() {
+	s.ConfigSuite.TearDownTest()
+	pulsar.NewPulsarConsumer = s.newPulsarConsumer
+}
+
+// TestPull :
+func (s *FrontendSuite) TestPull() {
+	f, err := define.NewFrontend(s.CTX, "pulsar")
+	s.NoError(err)
+
+	outCh := make(chan define.Payload)
+	killCh := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		f.Pull(outCh, killCh)
+		close(done)
+	}()
+
+	ids := []pulsarcli.MessageID{
+		newStandInMessageID(1, 1),
+		newStandInMessageID(1, 2),
+		newStandInMessageID(1, 3),
+	}
+	go func() {
+		for _, id := range ids {
+			s.consumer.messages <- &standInMessage{id: id, payload: []byte(`{"value":1}`)}
+		}
+	}()
+
+	for range ids {
+		payload := <-outCh
+		value := make(map[string]interface{})
+		s.NoError(payload.To(&value))
+		s.Equal(1.0, value["value"])
+	}
+
+	// 确认延后一个周期
+	s.Eventually(func() bool {
+		return len(s.consumer.ackedIDs()) == len(ids)
+	}, time.Second, 10*time.Millisecond)
+	s.Equal(ids, s.consumer.ackedIDs())
+
+	s.Equal(pulsarcli.Shared, s.consumerOpts.Type)
+	s.Equal("bkmonitor_transfer_test", s.consumerOpts.SubscriptionName)
+
+	s.NoError(f.Close())
+	<-done
+	s.True(s.consumer.closed)
+	s.Len(killCh, 0)
+}
+
+// TestDecodeFailed : 无法解析的消息投递到死信队列后再确认
+func (s *FrontendSuite) TestDecodeFailed() {
+	sink := &standInDeadLetterSink{}
+	define.RegisterDeadLetterSink(s.PipelineConfig.DataID, sink)
+	defer define.UnregisterDeadLetterSink(s.PipelineConfig.DataID, sink)
+
+	f := pulsar.NewFrontend(s.CTX, "test").(*pulsar.Frontend)
+	f.PayloadCreator = func() define.Payload {
+		return &undecodablePayload{JSONPayload: define.NewJSONPayload(0)}
+	}
+
+	killCh := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		f.Pull(make(chan define.Payload), killCh)
+		close(done)
+	}()
+
+	id := newStandInMessageID(1, 1)
+	s.consumer.messages <- &standInMessage{id: id, payload: []byte(`not json`)}
+
+	s.Eventually(func() bool {
+		return len(s.consumer.ackedIDs()) == 1
+	}, time.Second, 10*time.Millisecond)
+	s.Equal([]pulsarcli.MessageID{id}, s.consumer.ackedIDs())
+
+	sink.lock.Lock()
+	s.Len(sink.letters, 1)
+	s.Equal(define.DeadLetterStageFrontend, sink.letters[0].Stage)
+	s.Equal("pulsar", sink.letters[0].Processor)
+	s.Equal("undecodable", sink.letters[0].Error)
+	s.Equal(`not json`, string(sink.letters[0].Payload))
+	sink.lock.Unlock()
+
+	s.NoError(f.Close())
+	<-done
+}
+
+// TestReceiveError :
+func (s *FrontendSuite) TestReceiveError() {
+	f := pulsar.NewFrontend(s.CTX, "test")
+
+	s.consumer.err = errors.New("connection closed")
+	close(s.consumer.messages)
+
+	killCh := make(chan error, 1)
+	f.Pull(make(chan define.Payload), killCh)
+	s.Equal(s.consumer.err, <-killCh)
+	s.NoError(f.Close())
+}
+
+// TestOptions :
+func (s *FrontendSuite) TestOptions() {
+	s.PipelineConfig.MQConfig.AsPulsarCluster().SetSubscriptionType(config.PulsarSubscriptionKeyShared)
+	s.PipelineConfig.MQConfig.AuthInfo = map[string]interface{}{"password": "token"}
+	clientOpts, consumerOpts, err := pulsar.NewPulsarOptions(s.CTX, s.Config)
+	s.NoError(err)
+	s.Equal("pulsar://localhost:6650", clientOpts.URL)
+	s.NotNil(clientOpts.Authentication)
+	s.Equal(pulsarcli.KeyShared, consumerOpts.Type)
+	s.Equal("test", consumerOpts.Topic)
+	s.Equal(pulsarcli.SubscriptionPositionLatest, consumerOpts.SubscriptionInitialPosition)
+
+	s.PipelineConfig.MQConfig.AsPulsarCluster().SetSubscriptionType("exclusive")
+	_, _, err = pulsar.NewPulsarOptions(s.CTX, s.Config)
+	s.Error(err)
+}
+
+// TestFrontendSuite :
+func TestFrontendSuite(t *testing.T) {
+	suite.Run(t, new(FrontendSuite))
+}
@@ -0,0 +1,43 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pulsar
+
+import (
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/eventbus"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+const (
+	ConfPulsarSubscriptionPrefix = "pulsar.consumer.subscription_prefix"
+	ConfPulsarSubscriptionType   = "pulsar.consumer.subscription_type"
+	ConfPulsarInitialPosition    = "pulsar.consumer.initial_position"
+	ConfPulsarReceiverQueueSize  = "pulsar.consumer.receiver_queue_size"
+	ConfPulsarAckInterval        = "pulsar.consumer.ack_interval"
+	ConfPulsarConnectionTimeout  = "pulsar.connection_timeout"
+	ConfPulsarOperationTimeout   = "pulsar.operation_timeout"
+	ConfPulsarFlowInterval       = "pulsar.flow_interval"
+)
+
+func initConfiguration(c define.Configuration) {
+	c.SetDefault(ConfPulsarSubscriptionPrefix, "bkmonitor_transfer_")
+	c.SetDefault(ConfPulsarSubscriptionType, config.PulsarSubscriptionShared)
+	c.SetDefault(ConfPulsarInitialPosition, "latest")
+	c.SetDefault(ConfPulsarReceiverQueueSize, 1000)
+	c.SetDefault(ConfPulsarAckInterval, "3s") // 与 kafka offset 提交周期保持一致
+	c.SetDefault(ConfPulsarConnectionTimeout, "10s")
+	c.SetDefault(ConfPulsarOperationTimeout, "30s")
+	c.SetDefault(ConfPulsarFlowInterval, "60s")
+}
+
+func init() {
+	utils.CheckError(eventbus.Subscribe(eventbus.EvSysConfigPreParse, initConfiguration))
+}
@@ -0,0 +1,85 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pulsar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/pulsar-client-go/pulsar/log"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+)
+
+// pulsarLogger : 将 pulsar 客户端日志转发到 transfer 日志
+type pulsarLogger struct {
+	prefix string
+}
+
+func newPulsarLogger(fields log.Fields) *pulsarLogger {
+	if len(fields) == 0 {
+		return &pulsarLogger{}
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	items := make([]string, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+	return &pulsarLogger{prefix: "[" + strings.Join(items, " ") + "] "}
+}
+
+func (l *pulsarLogger) with(fields log.Fields) *pulsarLogger {
+	sub := newPulsarLogger(fields)
+	sub.prefix = l.prefix + sub.prefix
+	return sub
+}
+
+func (l *pulsarLogger) SubLogger(fields log.Fields) log.Logger { return l.with(fields) }
+
+func (l *pulsarLogger) WithFields(fields log.Fields) log.Entry { return l.with(fields) }
+
+func (l *pulsarLogger) WithField(name string, value interface{}) log.Entry {
+	return l.with(log.Fields{name: value})
+}
+
+func (l *pulsarLogger) WithError(err error) log.Entry {
+	return l.with(log.Fields{"error": err})
+}
+
+func (l *pulsarLogger) Debug(args ...interface{}) { logging.Debug(l.prefix + fmt.Sprint(args...)) }
+
+func (l *pulsarLogger) Info(args ...interface{}) { logging.Info(l.prefix + fmt.Sprint(args...)) }
+
+func (l *pulsarLogger) Warn(args ...interface{}) { logging.Warn(l.prefix + fmt.Sprint(args...)) }
+
+func (l *pulsarLogger) Error(args ...interface{}) { logging.Error(l.prefix + fmt.Sprint(args...)) }
+
+func (l *pulsarLogger) Debugf(format string, args ...interface{}) {
+	logging.Debugf(l.prefix+format, args...)
+}
+
+func (l *pulsarLogger) Infof(format string, args ...interface{}) {
+	logging.Infof(l.prefix+format, args...)
+}
+
+func (l *pulsarLogger) Warnf(format string, args ...interface{}) {
+	logging.Warnf(l.prefix+format, args...)
+}
+
+func (l *pulsarLogger) Errorf(format string, args ...interface{}) {
+	logging.Errorf(l.prefix+format, args...)
+}
@@ -0,0 +1,47 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pulsar
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+)
+
+var (
+	// MonitorFrontendAcked pulsar 前端确认计数器
+	MonitorFrontendAcked = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "pulsar_frontend_ack_total",
+		Help:      "Pulsar frontend acknowledgements count",
+	}, []string{"topic"})
+
+	// MonitorFrontendAckFailed pulsar 前端确认失败计数器
+	MonitorFrontendAckFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "pulsar_frontend_ack_failed_total",
+		Help:      "Pulsar frontend failed acknowledgements count",
+	}, []string{"topic"})
+
+	// MonitorFrontendPulsar 前端 pulsar 来源
+	MonitorFrontendPulsar = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: define.AppName,
+		Name:      "pipeline_frontend_pulsar",
+		Help:      "Frontend pulsar cluster",
+	}, []string{"id", "cluster", "pulsar", "topic"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		MonitorFrontendAcked,
+		MonitorFrontendAckFailed,
+		MonitorFrontendPulsar,
+	)
+}
@@ -0,0 +1,405 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/cstockton/go-conv"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// BackendName :
+var BackendName = "remote_write"
+
+const (
+	labelMetricName     = "__name__"
+	remoteWriteVersion  = "0.1.0"
+	maxErrorMessageSize = 512
+)
+
+// recoverableError : 可以通过重试恢复的错误，如 5xx、429 以及网络异常
+type recoverableError struct {
+	error
+}
+
+// seriesBatch : 单条记录转换后的时序数据，hash 用于分片
+type seriesBatch struct {
+	hash   uint64
+	series []TimeSeries
+}
+
+// sanitizeName : 将非法字符替换为下划线，保证指标名和标签名符合 prometheus 规范
+func sanitizeName(name string, allowColon bool) string {
+	var builder strings.Builder
+	for i, r := range name {
+		valid := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(i > 0 && r >= '0' && r <= '9') || (allowColon && r == ':')
+		if valid {
+			builder.WriteRune(r)
+		} else if i == 0 && r >= '0' && r <= '9' {
+			builder.WriteRune('_')
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune('_')
+		}
+	}
+	return builder.String()
+}
+
+// BulkHandler :
+type BulkHandler struct {
+	pipeline.BaseBulkHandler
+	dataID         string
+	url            string
+	username       string
+	password       string
+	externalLabels map[string]string
+	shards         int
+	maxRetries     int
+	minBackoff     time.Duration
+	maxBackoff     time.Duration
+	cli            *http.Client
+}
+
+// Handle : 将记录转换为时序数据，每个指标一条序列
+func (b *BulkHandler) Handle(ctx context.Context, payload define.Payload, killChan chan<- error) (result interface{}, at time.Time, ok bool) {
+	var record define.ETLRecord
+	err := payload.To(&record)
+	if err != nil {
+		logging.Warnf("%v error %v dropped payload %+v", b, err, payload)
+		return nil, time.Time{}, false
+	}
+
+	ts := time.Now()
+	if record.Time != nil {
+		ts = utils.ParseTimeStamp(*record.Time)
+	}
+
+	// 按维度名排序处理，替换非法字符后重名时结果保持稳定
+	keys := make([]string, 0, len(record.Dimensions))
+	for key := range record.Dimensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	labels := make(map[string]string, len(record.Dimensions)+len(b.externalLabels))
+	for _, key := range keys {
+		value := record.Dimensions[key]
+		if key == define.RecordCMDBLevelFieldName || value == nil {
+			continue
+		}
+		v := conv.String(value)
+		// 空值标签在 prometheus 中等价于不存在
+		if v == "" {
+			continue
+		}
+
+		name := sanitizeName(key, false)
+		// __name__ 保留给指标名，重复的标签名会导致整个请求被拒绝
+		if name == labelMetricName {
+			logging.Debugf("%v skip dimension %s with reserved label name", b, key)
+			continue
+		}
+		// 替换非法字符后重名时优先保留本身合法的维度，否则保留排序靠前的维度
+		if _, exists := labels[name]; exists && key != name {
+			logging.Debugf("%v skip dimension %s duplicated with label %s", b, key, name)
+			continue
+		}
+		labels[name] = v
+	}
+	// 与 prometheus 的 external_labels 语义一致，不覆盖数据中已有的标签
+	for key, value := range b.externalLabels {
+		if _, exists := labels[key]; !exists {
+			labels[key] = value
+		}
+	}
+
+	base := make([]Label, 0, len(labels)+1)
+	for key, value := range labels {
+		base = append(base, Label{Name: key, Value: value})
+	}
+	series := TimeSeries{Labels: base}
+	series.SortLabels()
+
+	hash := xxhash.New()
+	for _, label := range series.Labels {
+		_, _ = hash.WriteString(label.Name)
+		_, _ = hash.WriteString("=")
+		_, _ = hash.WriteString(label.Value)
+		_, _ = hash.WriteString(",")
+	}
+
+	batch := &seriesBatch{hash: hash.Sum64()}
+	for name, value := range record.Metrics {
+		if value == nil {
+			continue
+		}
+		f, err := conv.DefaultConv.Float64(value)
+		if err != nil {
+			logging.Debugf("%v skip metric %s with invalid value %v", b, name, value)
+			continue
+		}
+
+		metricLabels := make([]Label, 0, len(base)+1)
+		metricLabels = append(metricLabels, Label{Name: labelMetricName, Value: sanitizeName(name, true)})
+		metricLabels = append(metricLabels, series.Labels...)
+		item := TimeSeries{
+			Labels:  metricLabels,
+			Samples: []Sample{{Value: f, Timestamp: ts.UnixMilli()}},
+		}
+		item.SortLabels()
+		batch.series = append(batch.series, item)
+	}
+
+	if len(batch.series) == 0 {
+		logging.Warnf("%v dropped payload %+v for metric is empty", b, payload)
+		return nil, time.Time{}, false
+	}
+
+	return batch, ts, true
+}
+
+// Flush : 按标签哈希分片后并发发送，同一序列总是落在同一分片以保证写入顺序
+func (b *BulkHandler) Flush(ctx context.Context, results []interface{}) (int, error) {
+	// 记录每个分片对应的结果下标，分片失败时用于定位失败的记录
+	shards := make([][]int, b.shards)
+	for i, value := range results {
+		batch := value.(*seriesBatch)
+		index := batch.hash % uint64(b.shards)
+		shards[index] = append(shards[index], i)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		count   int
+		failed  []int
+		lastErr error
+	)
+	for index, indexes := range shards {
+		if len(indexes) == 0 {
+			continue
+		}
+
+		var series []TimeSeries
+		for _, i := range indexes {
+			series = append(series, results[i].(*seriesBatch).series...)
+		}
+
+		wg.Add(1)
+		go func(index int, indexes []int, series []TimeSeries) {
+			defer wg.Done()
+			err := b.send(ctx, series)
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				logging.Errorf("%v shard %d send %d series failed: %v", b, index, len(series), err)
+				failed = append(failed, indexes...)
+				lastErr = err
+				return
+			}
+			count += len(indexes)
+		}(index, indexes, series)
+	}
+	wg.Wait()
+
+	logging.Debugf("%v pushed %d of %d records", b, count, len(results))
+
+	if lastErr == nil {
+		return count, nil
+	}
+	err := errors.WithMessagef(lastErr, "%v write series of %d records", b, len(failed))
+	// 部分分片成功时不再整体重试，避免重复写入，失败的记录交由死信处理
+	if count > 0 {
+		sort.Ints(failed)
+		return count, pipeline.NewBulkPartialError(err, failed)
+	}
+	return 0, err
+}
+
+// send : 发送单个分片，可恢复错误按指数退避重试
+func (b *BulkHandler) send(ctx context.Context, series []TimeSeries) error {
+	body := EncodeWriteRequest(series)
+	backoff := b.minBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := b.write(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		var recoverable *recoverableError
+		if !errors.As(err, &recoverable) || attempt >= b.maxRetries {
+			return err
+		}
+
+		MonitorRemoteWriteRetries.WithLabelValues(b.dataID).Inc()
+		logging.Debugf("%v retry after %v for %v", b, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > b.maxBackoff {
+			backoff = b.maxBackoff
+		}
+	}
+}
+
+func (b *BulkHandler) write(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", define.AppName)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	if b.username != "" || b.password != "" {
+		req.SetBasicAuth(b.username, b.password)
+	}
+
+	resp, err := b.cli.Do(req)
+	if err != nil {
+		MonitorRemoteWriteRequests.WithLabelValues(b.dataID, "error").Inc()
+		return &recoverableError{err}
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	MonitorRemoteWriteRequests.WithLabelValues(b.dataID, strconv.Itoa(resp.StatusCode)).Inc()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorMessageSize))
+	err = fmt.Errorf("server returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(message))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return &recoverableError{err}
+	}
+	return err
+}
+
+// Close :
+func (b *BulkHandler) Close() error {
+	b.cli.CloseIdleConnections()
+	return nil
+}
+
+// NewBulkHandler :
+func NewBulkHandler(conf define.Configuration, pipe *config.PipelineConfig, shipper *config.MetaClusterInfo) *BulkHandler {
+	cluster := shipper.AsRemoteWriteCluster()
+	url := cluster.GetURL()
+
+	auth := config.NewAuthInfo(shipper)
+	userName, err := auth.GetUserName()
+	if err != nil {
+		logging.Debugf("%v may not establish connection %v: username", url, define.ErrGetAuth)
+	}
+	passWord, err := auth.GetPassword()
+	if err != nil {
+		logging.Debugf("%v may not establish connection %v: password", url, define.ErrGetAuth)
+	}
+
+	externalLabels := make(map[string]string)
+	for key, value := range cluster.GetExternalLabels() {
+		name := sanitizeName(key, false)
+		if name == labelMetricName {
+			continue
+		}
+		externalLabels[name] = value
+	}
+
+	shards := conf.GetInt(ConfRemoteWriteShards)
+	if shards <= 0 {
+		shards = 1
+	}
+
+	logging.Infof("remote write %d connect to %s", pipe.DataID, url)
+
+	return &BulkHandler{
+		dataID:         strconv.Itoa(pipe.DataID),
+		url:            url,
+		username:       userName,
+		password:       passWord,
+		externalLabels: externalLabels,
+		shards:         shards,
+		maxRetries:     conf.GetInt(ConfRemoteWriteMaxRetries),
+		minBackoff:     conf.GetDuration(ConfRemoteWriteMinBackoff),
+		maxBackoff:     conf.GetDuration(ConfRemoteWriteMaxBackoff),
+		cli:            &http.Client{Timeout: conf.GetDuration(ConfRemoteWriteTimeout)},
+	}
+}
+
+// Backend :
+type Backend struct {
+	*pipeline.BulkBackendAdapter
+}
+
+// NewBackend :
+func NewBackend(ctx context.Context, name string, maxQps int) *Backend {
+	bulk := NewBulkHandler(
+		config.FromContext(ctx),
+		config.PipelineConfigFromContext(ctx),
+		config.ShipperConfigFromContext(ctx),
+	)
+	return &Backend{
+		BulkBackendAdapter: pipeline.NewBulkBackendDefaultAdapter(ctx, name, bulk, maxQps),
+	}
+}
+
+func init() {
+	define.RegisterBackend(BackendName, func(ctx context.Context, name string) (define.Backend, error) {
+		if config.FromContext(ctx) == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "config is empty")
+		}
+		if config.ShipperConfigFromContext(ctx) == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "shipper config is empty")
+		}
+		pipeConfig := config.PipelineConfigFromContext(ctx)
+		if pipeConfig == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is empty")
+		}
+		rt := config.ResultTableConfigFromContext(ctx)
+		if rt == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "resultTable config is empty")
+		}
+
+		options := utils.NewMapHelper(pipeConfig.Option)
+		maxQps, _ := options.GetInt(config.PipelineConfigOptMaxQps)
+		backend := NewBackend(ctx, pipeConfig.FormatName(name), maxQps)
+		if rt.SchemaType == config.ResultTableSchemaTypeFree && options.GetOrDefault(config.PipelineConfigOptDisableMetricCutter, false) == false {
+			return pipeline.NewBackendWithCutterAdapter(ctx, backend), nil
+		}
+		return backend, nil
+	})
+}
@@ -0,0 +1,354 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remotewrite_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/remotewrite"
+)
+
+// consumeMessage : 依次解析 protobuf 消息中的字段
+func consumeMessage(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		m := protowire.ConsumeFieldValue(num, typ, b)
+		if m < 0 {
+			return protowire.ParseError(m)
+		}
+		fn(num, typ, b[:m])
+		b = b[m:]
+	}
+	return nil
+}
+
+// decodeWriteRequest : 测试用的 WriteRequest 解码
+func decodeWriteRequest(data []byte) ([]remotewrite.TimeSeries, error) {
+	var series []remotewrite.TimeSeries
+	err := consumeMessage(data, func(_ protowire.Number, _ protowire.Type, value []byte) {
+		var ts remotewrite.TimeSeries
+		body, _ := protowire.ConsumeBytes(value)
+		_ = consumeMessage(body, func(num protowire.Number, _ protowire.Type, value []byte) {
+			item, _ := protowire.ConsumeBytes(value)
+			switch num {
+			case 1:
+				var label remotewrite.Label
+				_ = consumeMessage(item, func(num protowire.Number, _ protowire.Type, value []byte) {
+					v, _ := protowire.ConsumeString(value)
+					if num == 1 {
+						label.Name = v
+					} else {
+						label.Value = v
+					}
+				})
+				ts.Labels = append(ts.Labels, label)
+			case 2:
+				var sample remotewrite.Sample
+				_ = consumeMessage(item, func(num protowire.Number, _ protowire.Type, value []byte) {
+					if num == 1 {
+						v, _ := protowire.ConsumeFixed64(value)
+						sample.Value = math.Float64frombits(v)
+					} else {
+						v, _ := protowire.ConsumeVarint(value)
+						sample.Timestamp = int64(v)
+					}
+				})
+				ts.Samples = append(ts.Samples, sample)
+			}
+		})
+		series = append(series, ts)
+	})
+	return series, err
+}
+
+// BackendSuite :
+type BackendSuite struct {
+	suite.Suite
+	server  *httptest.Server
+	handler http.HandlerFunc
+	conf    define.Configuration
+	shipper *config.MetaClusterInfo
+}
+
+// SetupTest :
+func (s *BackendSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handler(w, r)
+	}))
+
+	host, port, err := net.SplitHostPort(s.server.Listener.Addr().String())
+	s.NoError(err)
+	portNum, err := strconv.Atoi(port)
+	s.NoError(err)
+
+	s.conf = config.NewConfiguration()
+	remotewrite.InitConfiguration(s.conf)
+	s.conf.Set(remotewrite.ConfRemoteWriteMinBackoff, time.Millisecond)
+	s.conf.Set(remotewrite.ConfRemoteWriteMaxBackoff, 5*time.Millisecond)
+
+	s.shipper = config.NewMetaClusterInfo()
+	s.shipper.ClusterType = remotewrite.BackendName
+	cluster := s.shipper.AsRemoteWriteCluster()
+	cluster.SetSchema("http")
+	cluster.SetDomain(host)
+	cluster.SetPort(portNum)
+	cluster.SetExternalLabels(map[string]string{"cluster": "c1", "ip": "0.0.0.0"})
+	config.NewAuthInfo(s.shipper).SetUserName("admin")
+	config.NewAuthInfo(s.shipper).SetPassword("secret")
+}
+
+// TearDownTest :
+func (s *BackendSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *BackendSuite) newHandler() *remotewrite.BulkHandler {
+	pipe := config.NewPipelineConfig()
+	pipe.DataID = 1001
+	return remotewrite.NewBulkHandler(s.conf, pipe, s.shipper)
+}
+
+func (s *BackendSuite) handle(bulk *remotewrite.BulkHandler, data string) interface{} {
+	result, _, ok := bulk.Handle(context.Background(), define.NewJSONPayloadFrom([]byte(data), 0), nil)
+	s.True(ok)
+	return result
+}
+
+// TestWrite :
+func (s *BackendSuite) TestWrite() {
+	var (
+		lock   sync.Mutex
+		series []remotewrite.TimeSeries
+	)
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("/api/v1/write", r.URL.Path)
+		s.Equal("snappy", r.Header.Get("Content-Encoding"))
+		s.Equal("application/x-protobuf", r.Header.Get("Content-Type"))
+		s.Equal("0.1.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+		username, password, ok := r.BasicAuth()
+		s.True(ok)
+		s.Equal("admin", username)
+		s.Equal("secret", password)
+
+		body, err := io.ReadAll(r.Body)
+		s.NoError(err)
+		data, err := snappy.Decode(nil, body)
+		s.NoError(err)
+		items, err := decodeWriteRequest(data)
+		s.NoError(err)
+
+		lock.Lock()
+		series = append(series, items...)
+		lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	bulk := s.newHandler()
+	results := []interface{}{
+		s.handle(bulk, `{"time":1700000000,"dimensions":{"ip":"127.0.0.1","bk.biz_id":2,"empty":""},"metrics":{"usage":1.5,"load":"2"}}`),
+		s.handle(bulk, `{"time":1700000060,"dimensions":{"ip":"127.0.0.2"},"metrics":{"usage":3}}`),
+	}
+
+	n, err := bulk.Flush(context.Background(), results)
+	s.NoError(err)
+	s.Equal(2, n)
+	s.Len(series, 3)
+
+	expected := map[string]remotewrite.TimeSeries{
+		"usage127.0.0.1": {
+			Labels: []remotewrite.Label{
+				{Name: "__name__", Value: "usage"},
+				{Name: "bk_biz_id", Value: "2"},
+				{Name: "cluster", Value: "c1"},
+				{Name: "ip", Value: "127.0.0.1"},
+			},
+			Samples: []remotewrite.Sample{{Value: 1.5, Timestamp: 1700000000000}},
+		},
+		"load127.0.0.1": {
+			Labels: []remotewrite.Label{
+				{Name: "__name__", Value: "load"},
+				{Name: "bk_biz_id", Value: "2"},
+				{Name: "cluster", Value: "c1"},
+				{Name: "ip", Value: "127.0.0.1"},
+			},
+			Samples: []remotewrite.Sample{{Value: 2, Timestamp: 1700000000000}},
+		},
+		"usage127.0.0.2": {
+			Labels: []remotewrite.Label{
+				{Name: "__name__", Value: "usage"},
+				{Name: "cluster", Value: "c1"},
+				{Name: "ip", Value: "127.0.0.2"},
+			},
+			Samples: []remotewrite.Sample{{Value: 3, Timestamp: 1700000060000}},
+		},
+	}
+	for _, ts := range series {
+		var name, ip string
+		for _, label := range ts.Labels {
+			switch label.Name {
+			case "__name__":
+				name = label.Value
+			case "ip":
+				ip = label.Value
+			}
+		}
+		s.Equal(expected[name+ip], ts)
+	}
+}
+
+// TestRetry :
+func (s *BackendSuite) TestRetry() {
+	var requests int
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	bulk := s.newHandler()
+	n, err := bulk.Flush(context.Background(), []interface{}{
+		s.handle(bulk, `{"time":1700000000,"dimensions":{"ip":"127.0.0.1"},"metrics":{"usage":1}}`),
+	})
+	s.NoError(err)
+	s.Equal(1, n)
+	s.Equal(3, requests)
+}
+
+// TestRetryExhausted :
+func (s *BackendSuite) TestRetryExhausted() {
+	var requests int
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+
+	bulk := s.newHandler()
+	n, err := bulk.Flush(context.Background(), []interface{}{
+		s.handle(bulk, `{"time":1700000000,"dimensions":{"ip":"127.0.0.1"},"metrics":{"usage":1}}`),
+	})
+	s.Error(err)
+	s.Equal(0, n)
+	s.Equal(1+s.conf.GetInt(remotewrite.ConfRemoteWriteMaxRetries), requests)
+}
+
+// TestNoRetryOnClientError :
+func (s *BackendSuite) TestNoRetryOnClientError() {
+	var requests int
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}
+
+	bulk := s.newHandler()
+	n, err := bulk.Flush(context.Background(), []interface{}{
+		s.handle(bulk, `{"time":1700000000,"dimensions":{"ip":"127.0.0.1"},"metrics":{"usage":1}}`),
+	})
+	s.ErrorContains(err, "out of order sample")
+	s.Equal(0, n)
+	s.Equal(1, requests)
+}
+
+// TestPartialFailure :
+func (s *BackendSuite) TestPartialFailure() {
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		s.NoError(err)
+		data, err := snappy.Decode(nil, body)
+		s.NoError(err)
+		items, err := decodeWriteRequest(data)
+		s.NoError(err)
+		for _, item := range items {
+			for _, label := range item.Labels {
+				if label.Name == "ip" && label.Value == "bad" {
+					http.Error(w, "invalid sample", http.StatusBadRequest)
+					return
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	s.conf.Set(remotewrite.ConfRemoteWriteShards, 16)
+	bulk := s.newHandler()
+	var results []interface{}
+	for _, ip := range []string{"127.0.0.1", "bad", "127.0.0.2", "127.0.0.3"} {
+		results = append(results, s.handle(bulk, `{"time":1700000000,"dimensions":{"ip":"`+ip+`"},"metrics":{"usage":1}}`))
+	}
+
+	// 部分分片失败时返回失败记录的下标，成功的记录不计入失败
+	n, err := bulk.Flush(context.Background(), results)
+	s.ErrorContains(err, "invalid sample")
+	var partial *pipeline.BulkPartialError
+	s.True(errors.As(err, &partial))
+	s.Equal([]int{1}, partial.Failed)
+	s.Equal(3, n)
+}
+
+// TestHandleDuplicatedLabels :
+func (s *BackendSuite) TestHandleDuplicatedLabels() {
+	bulk := s.newHandler()
+	result := s.handle(bulk, `{"time":1700000000,"dimensions":{"a.b":"1","a_b":"2","a-b":"3","__name__":"x","ip":"127.0.0.1"},"metrics":{"usage":1}}`)
+
+	var series []remotewrite.TimeSeries
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		data, err := snappy.Decode(nil, body)
+		s.NoError(err)
+		series, err = decodeWriteRequest(data)
+		s.NoError(err)
+		w.WriteHeader(http.StatusNoContent)
+	}
+	n, err := bulk.Flush(context.Background(), []interface{}{result})
+	s.NoError(err)
+	s.Equal(1, n)
+	s.Len(series, 1)
+	s.Equal([]remotewrite.Label{
+		{Name: "__name__", Value: "usage"},
+		{Name: "a_b", Value: "2"},
+		{Name: "cluster", Value: "c1"},
+		{Name: "ip", Value: "127.0.0.1"},
+	}, series[0].Labels)
+}
+
+// TestHandleEmptyMetrics :
+func (s *BackendSuite) TestHandleEmptyMetrics() {
+	bulk := s.newHandler()
+	_, _, ok := bulk.Handle(context.Background(), define.NewJSONPayloadFrom([]byte(`{"time":1700000000,"dimensions":{"ip":"127.0.0.1"},"metrics":{"usage":"abc"}}`), 0), nil)
+	s.False(ok)
+}
+
+// TestBackendSuite :
+func TestBackendSuite(t *testing.T) {
+	suite.Run(t, new(BackendSuite))
+}
@@ -0,0 +1,44 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remotewrite
+
+import (
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/eventbus"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+const (
+	// ConfRemoteWriteShards : 并发发送的分片数
+	ConfRemoteWriteShards = "remote_write.backend.shards"
+	// ConfRemoteWriteMaxRetries : 单个分片的最大重试次数
+	ConfRemoteWriteMaxRetries = "remote_write.backend.max_retries"
+	// ConfRemoteWriteMinBackoff : 首次重试的等待时间
+	ConfRemoteWriteMinBackoff = "remote_write.backend.min_backoff"
+	// ConfRemoteWriteMaxBackoff : 重试等待时间上限
+	ConfRemoteWriteMaxBackoff = "remote_write.backend.max_backoff"
+	// ConfRemoteWriteTimeout : 单次请求超时
+	ConfRemoteWriteTimeout = "remote_write.backend.timeout"
+)
+
+// InitConfiguration :
+func InitConfiguration(c define.Configuration) {
+	c.SetDefault(ConfRemoteWriteShards, 4)
+	c.SetDefault(ConfRemoteWriteMaxRetries, 3)
+	c.SetDefault(ConfRemoteWriteMinBackoff, 100*time.Millisecond)
+	c.SetDefault(ConfRemoteWriteMaxBackoff, 5*time.Second)
+	c.SetDefault(ConfRemoteWriteTimeout, 10*time.Second)
+}
+
+func init() {
+	utils.CheckError(eventbus.Subscribe(eventbus.EvSysConfigPreParse, InitConfiguration))
+}
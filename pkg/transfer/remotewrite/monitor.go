@@ -0,0 +1,39 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remotewrite
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+)
+
+var (
+	// MonitorRemoteWriteRequests remote write 请求次数，按返回码区分
+	MonitorRemoteWriteRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "remote_write_backend_requests_total",
+		Help:      "Count of remote write requests",
+	}, []string{"id", "code"})
+
+	// MonitorRemoteWriteRetries remote write 重试次数
+	MonitorRemoteWriteRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "remote_write_backend_retries_total",
+		Help:      "Count of remote write retries",
+	}, []string{"id"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		MonitorRemoteWriteRequests,
+		MonitorRemoteWriteRetries,
+	)
+}
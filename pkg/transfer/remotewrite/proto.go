@@ -0,0 +1,103 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remotewrite
+
+import (
+	"math"
+	"sort"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// prometheus remote write 协议(prompb.WriteRequest)字段编号
+const (
+	fieldWriteRequestTimeSeries protowire.Number = 1
+	fieldTimeSeriesLabels       protowire.Number = 1
+	fieldTimeSeriesSamples      protowire.Number = 2
+	fieldLabelName              protowire.Number = 1
+	fieldLabelValue             protowire.Number = 2
+	fieldSampleValue            protowire.Number = 1
+	fieldSampleTimestamp        protowire.Number = 2
+)
+
+// Label :
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample : 时间戳单位为毫秒
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// TimeSeries :
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// SortLabels : 协议要求标签按名称有序
+func (ts *TimeSeries) SortLabels() {
+	sort.Slice(ts.Labels, func(i, j int) bool {
+		return ts.Labels[i].Name < ts.Labels[j].Name
+	})
+}
+
+func appendLabel(b []byte, label Label) []byte {
+	var buf []byte
+	buf = protowire.AppendTag(buf, fieldLabelName, protowire.BytesType)
+	buf = protowire.AppendString(buf, label.Name)
+	buf = protowire.AppendTag(buf, fieldLabelValue, protowire.BytesType)
+	buf = protowire.AppendString(buf, label.Value)
+
+	b = protowire.AppendTag(b, fieldTimeSeriesLabels, protowire.BytesType)
+	return protowire.AppendBytes(b, buf)
+}
+
+func appendSample(b []byte, sample Sample) []byte {
+	var buf []byte
+	buf = protowire.AppendTag(buf, fieldSampleValue, protowire.Fixed64Type)
+	buf = protowire.AppendFixed64(buf, math.Float64bits(sample.Value))
+	buf = protowire.AppendTag(buf, fieldSampleTimestamp, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(sample.Timestamp))
+
+	b = protowire.AppendTag(b, fieldTimeSeriesSamples, protowire.BytesType)
+	return protowire.AppendBytes(b, buf)
+}
+
+func appendTimeSeries(b []byte, ts TimeSeries) []byte {
+	var buf []byte
+	for _, label := range ts.Labels {
+		buf = appendLabel(buf, label)
+	}
+	for _, sample := range ts.Samples {
+		buf = appendSample(buf, sample)
+	}
+
+	b = protowire.AppendTag(b, fieldWriteRequestTimeSeries, protowire.BytesType)
+	return protowire.AppendBytes(b, buf)
+}
+
+// MarshalWriteRequest : 将时序数据编码为 WriteRequest 的 protobuf 格式
+func MarshalWriteRequest(series []TimeSeries) []byte {
+	var b []byte
+	for _, ts := range series {
+		b = appendTimeSeries(b, ts)
+	}
+	return b
+}
+
+// EncodeWriteRequest : 编码并使用 snappy block 格式压缩
+func EncodeWriteRequest(series []TimeSeries) []byte {
+	return snappy.Encode(nil, MarshalWriteRequest(series))
+}
@@ -128,6 +128,7 @@ type monitorRedis struct {
 	monitorHDel                  *monitor.CounterMixin // HDel 命令执行的成功/失败次数
 	monitorZAdd                  *monitor.CounterMixin // ZAdd 命令执行的成功/失败次数
 	monitorZRangeByScore         *monitor.CounterMixin // ZRangeByScore 命令执行的成功/失败次数
+	monitorRPush                 *monitor.CounterMixin // RPush 命令执行的成功/失败次数
 	monitorHScanDuration         *monitor.TimeObserver // HScan 命令执行成功耗时
 	monitorHGetDuration          *monitor.TimeObserver // HGet 命令执行成功耗时
 	monitorHMGetDuration         *monitor.TimeObserver // HMGet 命令执行成功耗时
@@ -135,6 +136,7 @@ type monitorRedis struct {
 	monitorHDelDuration          *monitor.TimeObserver // HDel 命令执行成功耗时
 	monitorZAddDuration          *monitor.TimeObserver // ZAdd 命令执行成功耗时
 	monitorZRangeByScoreDuration *monitor.TimeObserver // ZRangeByScore 命令执行成功耗时
+	monitorRPushDuration         *monitor.TimeObserver // RPush 命令执行成功耗时
 }
 
 // RedisStore :
@@ -378,6 +380,42 @@ func (s *RedisStore) ZRangeByScore(key string, min, max float64) ([]string, erro
 	return members, nil
 }
 
+// RPushBatch : 将多个value追加到redis的list尾部，maxLen大于0时只保留最新的maxLen个元素
+// RPush命令使用文档：https://redis.io/commands/rpush/
+func (s *RedisStore) RPushBatch(key string, values []string, maxLen int64) error {
+	valuesLen := len(values)
+	for start := 0; start < valuesLen; start = start + s.writeSize {
+		end := start + s.writeSize
+		if end > valuesLen {
+			end = valuesLen
+		}
+
+		members := make([]interface{}, 0, end-start)
+		for _, value := range values[start:end] {
+			members = append(members, value)
+		}
+
+		observer := s.redis.Slave.monitorRPushDuration.Start()
+		_, err := s.redis.RPush(s.redis.Ctx(), key, members...).Result()
+		observer.Finish()
+		if err != nil {
+			logging.Errorf("RPush error, key: %s, err: %s", key, err)
+			s.redis.monitorRPush.CounterFails.Inc()
+			return err
+		}
+		s.redis.monitorRPush.CounterSuccesses.Inc()
+	}
+
+	if maxLen > 0 && valuesLen > 0 {
+		if err := s.redis.LTrim(s.redis.Ctx(), key, -maxLen, -1).Err(); err != nil {
+			logging.Errorf("LTrim error, key: %s, err: %s", key, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
 // HSetBatch : 将多个field-value paris写入到redis的hash中
 // HSet命令使用文档：https://redis.io/commands/hset/
 func (s *RedisStore) HSetBatch(key string, fieldValuePairs map[string]string) error {
@@ -1234,6 +1272,10 @@ func generateMonitorRedis(client *redis.Client, redisAddr string) *monitorRedis
 			MonitorRedisCommandSuccess.With(prometheus.Labels{"command": "ZRangeByScore"}),
 			MonitorRedisCommandFail.With(prometheus.Labels{"command": "ZRangeByScore"}),
 		),
+		monitorRPush: monitor.NewCounterMixin(
+			MonitorRedisCommandSuccess.With(prometheus.Labels{"command": "RPush"}),
+			MonitorRedisCommandFail.With(prometheus.Labels{"command": "RPush"}),
+		),
 		monitorHScanDuration: monitor.NewTimeObserver(
 			MonitorRedisExecuteDuration.With(prometheus.Labels{"command": "HScan"}),
 		),
@@ -1255,6 +1297,9 @@ func generateMonitorRedis(client *redis.Client, redisAddr string) *monitorRedis
 		monitorZRangeByScoreDuration: monitor.NewTimeObserver(
 			MonitorRedisExecuteDuration.With(prometheus.Labels{"command": "ZRangeByScore"}),
 		),
+		monitorRPushDuration: monitor.NewTimeObserver(
+			MonitorRedisExecuteDuration.With(prometheus.Labels{"command": "RPush"}),
+		),
 	}
 }
 
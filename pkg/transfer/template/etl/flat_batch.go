@@ -85,7 +85,7 @@ func (p *FlatBatchHandler) Process(d define.Payload, outputChan chan<- define.Pa
 	originMap := etl.NewMapContainer()
 	err := d.To(&originMap)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.MinuteErrorfSampling(p.String(), "%v convert payload %#v error %v", p, d, err)
 		return
 	}
@@ -93,10 +93,11 @@ func (p *FlatBatchHandler) Process(d define.Payload, outputChan chan<- define.Pa
 	containers := p.extractContainers(originMap)
 	if len(containers) == 0 {
 		logging.Debugf("%v loaded an empty payload %v", p, d)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrValue, "empty payload"))
 		return
 	}
 
+	var lastErr error
 	handled := 0
 	for _, from := range containers {
 		if bizID, err := from.Get(define.RecordBizID); err == nil {
@@ -109,12 +110,14 @@ func (p *FlatBatchHandler) Process(d define.Payload, outputChan chan<- define.Pa
 		err = p.schema.Transform(from, to)
 		if err != nil {
 			logging.MinuteErrorfSampling(p.String(), "%v transform %v error %v", p, d, err)
+			lastErr = err
 			continue
 		}
 
 		output, err := define.DerivePayload(d, &to)
 		if err != nil {
 			logging.Errorf("%v create payload from %v error: %+v", p, d, err)
+			lastErr = err
 			continue
 		}
 
@@ -124,7 +127,7 @@ func (p *FlatBatchHandler) Process(d define.Payload, outputChan chan<- define.Pa
 
 	if handled == 0 {
 		logging.Warnf("%v handle %#v failed", p, d)
-		p.CounterFails.Inc()
+		p.Reject(d, lastErr)
 	} else {
 		logging.Debugf("%v push %d items from %v", p, handled, d)
 		p.CounterSuccesses.Inc()
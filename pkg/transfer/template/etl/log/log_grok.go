@@ -0,0 +1,103 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package log
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/etl"
+	template "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/template/etl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// grokPatternsFromOption : 从结果表配置中读取自定义 grok 模式
+func grokPatternsFromOption(option map[string]interface{}) (map[string]string, error) {
+	value, ok := utils.NewMapHelper(option).Get(config.ResultTableOptLogGrokPatterns)
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	switch patterns := value.(type) {
+	case map[string]string:
+		return patterns, nil
+	case map[string]interface{}:
+		results := make(map[string]string, len(patterns))
+		for name, pattern := range patterns {
+			str, ok := pattern.(string)
+			if !ok {
+				return nil, errors.Wrapf(define.ErrType, "grok pattern %s is not a string", name)
+			}
+			results[name] = str
+		}
+		return results, nil
+	default:
+		return nil, errors.Wrapf(define.ErrType, "%s should be a map", config.ResultTableOptLogGrokPatterns)
+	}
+}
+
+// compileGrokFromContext : 按流水线配置编译 grok 表达式
+func compileGrokFromContext(ctx context.Context) (*etl.Grok, error) {
+	pipe := config.PipelineConfigFromContext(ctx)
+	if pipe == nil {
+		return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is empty")
+	}
+
+	pattern, ok := utils.NewMapHelper(pipe.Option).GetString(config.PipelineConfigOptLogSeparatorGrok)
+	if !ok {
+		return nil, errors.Wrapf(define.ErrOperationForbidden, "grok not set")
+	}
+
+	var custom map[string]string
+	rt := config.ResultTableConfigFromContext(ctx)
+	if rt != nil {
+		var err error
+		custom, err = grokPatternsFromOption(rt.Option)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return etl.CompileGrok(pattern, custom)
+}
+
+// NewGrokLogProcessor
+func NewGrokLogProcessor(ctx context.Context, name string) (*template.RecordProcessor, error) {
+	grok, err := compileGrokFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLogProcessor(ctx, name, func(record *etl.TSSchemaRecord, decoder *etl.PayloadDecoder) {
+		record.AddMetrics(
+			// 提取
+			etl.NewPrepareField(
+				FieldSeparatorValues, etl.ExtractByPath(FieldName),
+				etl.TransformMapByGrok(grok),
+			),
+			// 合并
+			etl.NewMergeField(FieldSeparatorValues),
+		)
+	})
+}
+
+func init() {
+	define.RegisterDataProcessor("grok_log", func(ctx context.Context, name string) (define.DataProcessor, error) {
+		pipeConfig := config.PipelineConfigFromContext(ctx)
+		if pipeConfig == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is empty")
+		}
+
+		return NewGrokLogProcessor(ctx, pipeConfig.FormatName(name))
+	})
+}
@@ -81,6 +81,44 @@ func (s *JSONLogTest) TestUsage() {
 	)
 }
 
+// deadLetterCollector :
+type deadLetterCollector struct {
+	letters []*define.DeadLetter
+}
+
+// Put :
+func (c *deadLetterCollector) Put(letter *define.DeadLetter) bool {
+	c.letters = append(c.letters, letter)
+	return true
+}
+
+// TestDeadLetter : 无法解析的数据投递到死信队列
+func (s *JSONLogTest) TestDeadLetter() {
+	s.CTX = testsuite.PipelineConfigStringInfoContext(
+		s.CTX, s.PipelineConfig,
+		`{"result_table_list":[{"schema_type":"free","result_table":"2_log.durant_log1000008","field_list":[{"default_value":null,"alias_name":"log","tag":"metric","type":"string","is_config_by_user":true,"field_name":"log"}]}],"data_id":1200146,"etl_config":"bk_log_json","option":{"group_info_alias":"_private_","encoding":"UTF-8"}}`,
+	)
+	processor, err := log.NewJSONLogProcessor(s.CTX, "test")
+	s.NoError(err)
+
+	collector := new(deadLetterCollector)
+	define.RegisterDeadLetterSink(1200146, collector)
+	defer define.UnregisterDeadLetterSink(1200146, collector)
+
+	outputCh := make(chan define.Payload, 1)
+	killCh := make(chan error, 1)
+	processor.Process(define.NewJSONPayloadFrom([]byte(`{"_value_": [`), 0), outputCh, killCh)
+
+	s.Len(outputCh, 0)
+	s.Len(collector.letters, 1)
+	letter := collector.letters[0]
+	s.Equal(1200146, letter.DataID)
+	s.Equal("test", letter.Processor)
+	s.Equal(define.DeadLetterStageProcessor, letter.Stage)
+	s.NotEmpty(letter.Error)
+	s.Equal(`{"_value_": [`, string(letter.Payload))
+}
+
 // TestServletTest :
 func TestJsonLogTest(t *testing.T) {
 	suite.Run(t, new(JSONLogTest))
@@ -13,6 +13,7 @@ import (
 	"context"
 
 	"github.com/cstockton/go-conv"
+	"github.com/pkg/errors"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
@@ -37,15 +38,16 @@ func (p *RecordProcessor) Process(d define.Payload, outputChan chan<- define.Pay
 	containers, err := p.Decode(d)
 	if err != nil {
 		logging.MinuteErrorfSampling(p.String(), "%v load %#v error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 	if len(containers) == 0 {
 		logging.Debugf("%v loaded an empty payload %v", p, d)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrValue, "empty payload"))
 		return
 	}
 
+	var lastErr error
 	handled := 0
 	for _, from := range containers {
 		if bizID, err := from.Get(define.RecordBizID); err == nil {
@@ -58,12 +60,14 @@ func (p *RecordProcessor) Process(d define.Payload, outputChan chan<- define.Pay
 		err = p.schema.Transform(from, to)
 		if err != nil {
 			logging.MinuteErrorfSampling(p.String(), "%v transform %v error %v", p, d, err)
+			lastErr = err
 			continue
 		}
 
 		output, err := define.DerivePayload(d, &to)
 		if err != nil {
 			logging.Errorf("%v create payload from %v error: %+v", p, d, err)
+			lastErr = err
 			continue
 		}
 
@@ -73,7 +77,7 @@ func (p *RecordProcessor) Process(d define.Payload, outputChan chan<- define.Pay
 
 	if handled == 0 {
 		logging.Warnf("%v handle %#v failed", p, d)
-		p.CounterFails.Inc()
+		p.Reject(d, lastErr)
 	} else {
 		logging.Debugf("%v push %d items from %v", p, handled, d)
 		p.CounterSuccesses.Inc()
@@ -44,14 +44,14 @@ func (p *EventProcessor) Process(d define.Payload, outputChan chan<- define.Payl
 	record := new(EventRecord)
 	err := d.To(record)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Warnf("convert event record failed, processor: %v, record: %+v, err: %+v", p, err, d)
 		return
 	}
 
 	// 时间是否不存在
 	if record.Timestamp == nil || *record.Timestamp == 0.0 {
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrValue, "event record time is empty"))
 		logging.Warnf("%v event record time is empty: %v", p, d)
 		return
 	}
@@ -59,7 +59,7 @@ func (p *EventProcessor) Process(d define.Payload, outputChan chan<- define.Payl
 	for _, checkElement := range []interface{}{record.Target, record.EventName} {
 		// 如果这个不是空接口，那么需要判断string是否为空
 		if checkElement == nil || checkElement.(string) == "" {
-			p.CounterFails.Inc()
+			p.Reject(d, errors.Wrapf(define.ErrValue, "event record target/eventName is empty"))
 			logging.Warnf("%v event record target/eventName is empty: %v", p, d)
 			return
 		}
@@ -68,7 +68,7 @@ func (p *EventProcessor) Process(d define.Payload, outputChan chan<- define.Payl
 	// 事件内容必须是非空
 	for _, checkElement := range []map[string]interface{}{record.Event} {
 		if checkElement == nil || len(checkElement) == 0 {
-			p.CounterFails.Inc()
+			p.Reject(d, errors.Wrapf(define.ErrValue, "event record event is empty"))
 			logging.Warnf("%v event record event is empty: %v", p, d)
 			return
 		}
@@ -98,7 +98,7 @@ func (p *EventProcessor) Process(d define.Payload, outputChan chan<- define.Payl
 
 	output, err := define.DerivePayload(d, record)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Warnf("%v create payload error %v: %v", p, err, d)
 		return
 	}
@@ -47,7 +47,7 @@ func (p *TimeseriesV2Handler) Process(d define.Payload, outputChan chan<- define
 	records := &CustomTimeseries{}
 	err := d.To(records)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Warnf("%v convert payload %#v error %v", p, d, err)
 		return
 	}
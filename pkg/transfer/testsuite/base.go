@@ -13,6 +13,7 @@ import (
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/consul"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/conv"
+	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/drift"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/elasticsearch"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/esb"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/filesystem/processor"
@@ -20,7 +21,9 @@ import (
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/kafka"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
+	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pulsar"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/redis"
+	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/remotewrite"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/scheduler"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/storage"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/template/etl"
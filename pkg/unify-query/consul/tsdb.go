@@ -17,9 +17,10 @@ const (
 	OfflineDataArchive         = "offline_data_archive"
 	RedisStorageType           = "redis"
 	ElasticsearchStorageType   = "elasticsearch"
+	ClickHouseStorageType      = "clickhouse"
 )
 
-var typeList = []string{InfluxDBStorageType, ElasticsearchStorageType, BkSqlStorageType, VictoriaMetricsStorageType}
+var typeList = []string{InfluxDBStorageType, ElasticsearchStorageType, BkSqlStorageType, VictoriaMetricsStorageType, ClickHouseStorageType}
 
 // GetTsDBStorageInfo 获取 tsDB 存储实例
 func GetTsDBStorageInfo() (map[string]*Storage, error) {
@@ -0,0 +1,156 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package downsample
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/prometheus/prometheus/promql"
+)
+
+const (
+	// MethodLTTB 按比例保留视觉上最重要的点，默认算法
+	MethodLTTB = "lttb"
+	// MethodM4 每个时间桶保留首、尾、最小、最大 4 个点，折线渲染与原始数据像素级一致
+	MethodM4 = "m4"
+	// MethodMinMax 每个时间桶保留最小、最大 2 个点
+	MethodMinMax = "minmax"
+	// MethodAvg 每个时间桶保留 1 个平均值点，时间桶为左开右闭区间，时间戳为桶的结束时间，与下推到存储的 avg_over_time 一致
+	MethodAvg = "avg"
+)
+
+// CheckMethod 校验降采样算法，为空时使用 lttb
+func CheckMethod(method string) error {
+	switch method {
+	case "", MethodLTTB, MethodM4, MethodMinMax, MethodAvg:
+		return nil
+	default:
+		return fmt.Errorf("down sample method %s is not support", method)
+	}
+}
+
+// DownsampleByMethod 按照算法降采样，lttb 使用 factor 计算保留的点数，其余算法按 interval（毫秒）对齐分桶
+func DownsampleByMethod(method string, points []promql.Point, factor float64, interval int64) []promql.Point {
+	switch method {
+	case MethodM4:
+		return bucketDownsample(points, interval, bucketStart, m4Bucket)
+	case MethodMinMax:
+		return bucketDownsample(points, interval, bucketStart, minMaxBucket)
+	case MethodAvg:
+		return bucketDownsample(points, interval, bucketEnd, avgBucket)
+	default:
+		return Downsample(points, factor)
+	}
+}
+
+// bucketStart 左闭右开分桶，返回桶的起始时间
+func bucketStart(t, interval int64) int64 {
+	return t - t%interval
+}
+
+// bucketEnd 左开右闭分桶，返回桶的结束时间，与 xx_over_time 在 t 时刻计算 (t-interval, t] 区间的口径一致
+func bucketEnd(t, interval int64) int64 {
+	if r := t % interval; r != 0 {
+		return t - r + interval
+	}
+	return t
+}
+
+// bucketDownsample 按时间对齐分桶，points 需要按时间升序，bucketTime 计算数据点所在桶的时间戳
+func bucketDownsample(
+	points []promql.Point, interval int64,
+	bucketTime func(t, interval int64) int64,
+	pick func(ts int64, bucket []promql.Point) []promql.Point,
+) []promql.Point {
+	if interval <= 0 || len(points) < 2 {
+		return points
+	}
+
+	out := make([]promql.Point, 0)
+	begin := 0
+	ts := bucketTime(points[0].T, interval)
+	for i := 1; i <= len(points); i++ {
+		if i < len(points) && bucketTime(points[i].T, interval) == ts {
+			continue
+		}
+
+		out = append(out, pick(ts, points[begin:i])...)
+		if i < len(points) {
+			begin = i
+			ts = bucketTime(points[i].T, interval)
+		}
+	}
+	return out
+}
+
+// extremes 获取桶内最小值和最大值的下标，忽略 NaN
+func extremes(bucket []promql.Point) (minIdx, maxIdx int) {
+	minIdx, maxIdx = -1, -1
+	for i, p := range bucket {
+		if math.IsNaN(p.V) {
+			continue
+		}
+		if minIdx < 0 || p.V < bucket[minIdx].V {
+			minIdx = i
+		}
+		if maxIdx < 0 || p.V > bucket[maxIdx].V {
+			maxIdx = i
+		}
+	}
+	return
+}
+
+// pickIndexes 按下标顺序输出，重复的下标只输出一次
+func pickIndexes(bucket []promql.Point, indexes ...int) []promql.Point {
+	out := make([]promql.Point, 0, len(indexes))
+	last := -1
+	sort.Ints(indexes)
+	for _, idx := range indexes {
+		if idx < 0 || idx == last {
+			continue
+		}
+		out = append(out, bucket[idx])
+		last = idx
+	}
+	return out
+}
+
+func m4Bucket(_ int64, bucket []promql.Point) []promql.Point {
+	minIdx, maxIdx := extremes(bucket)
+	return pickIndexes(bucket, 0, minIdx, maxIdx, len(bucket)-1)
+}
+
+func minMaxBucket(_ int64, bucket []promql.Point) []promql.Point {
+	minIdx, maxIdx := extremes(bucket)
+	if minIdx < 0 {
+		return pickIndexes(bucket, 0)
+	}
+	return pickIndexes(bucket, minIdx, maxIdx)
+}
+
+func avgBucket(ts int64, bucket []promql.Point) []promql.Point {
+	var (
+		sum   float64
+		count int
+	)
+	for _, p := range bucket {
+		if math.IsNaN(p.V) {
+			continue
+		}
+		sum += p.V
+		count++
+	}
+	if count == 0 {
+		return []promql.Point{{T: ts, V: math.NaN()}}
+	}
+	return []promql.Point{{T: ts, V: sum / float64(count)}}
+}
@@ -0,0 +1,100 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package downsample
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownsampleByMethod(t *testing.T) {
+	data := []promql.Point{
+		{T: 0, V: 3},
+		{T: 10, V: 1},
+		{T: 20, V: 5},
+		{T: 30, V: 2},
+		{T: 40, V: 4},
+		{T: 50, V: math.NaN()},
+		{T: 60, V: 6},
+		{T: 70, V: 7},
+	}
+
+	testCases := map[string]struct {
+		method   string
+		interval int64
+		expected []promql.Point
+	}{
+		"m4": {
+			method:   MethodM4,
+			interval: 40,
+			expected: []promql.Point{
+				{T: 0, V: 3}, {T: 10, V: 1}, {T: 20, V: 5}, {T: 30, V: 2},
+				{T: 40, V: 4}, {T: 70, V: 7},
+			},
+		},
+		"minmax": {
+			method:   MethodMinMax,
+			interval: 40,
+			expected: []promql.Point{
+				{T: 10, V: 1}, {T: 20, V: 5},
+				{T: 40, V: 4}, {T: 70, V: 7},
+			},
+		},
+		"avg": {
+			method:   MethodAvg,
+			interval: 40,
+			expected: []promql.Point{
+				{T: 0, V: 3},
+				{T: 40, V: 3},
+				{T: 80, V: 6.5},
+			},
+		},
+		"avg with unaligned interval": {
+			method:   MethodAvg,
+			interval: 25,
+			expected: []promql.Point{
+				{T: 0, V: 3},
+				{T: 25, V: 3},
+				{T: 50, V: 3},
+				{T: 75, V: 6.5},
+			},
+		},
+		"zero interval": {
+			method:   MethodM4,
+			interval: 0,
+			expected: data,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual := DownsampleByMethod(c.method, data, 0, c.interval)
+			assert.Equal(t, len(c.expected), len(actual))
+			for i := range c.expected {
+				assert.Equal(t, c.expected[i].T, actual[i].T)
+				if math.IsNaN(c.expected[i].V) {
+					assert.True(t, math.IsNaN(actual[i].V))
+				} else {
+					assert.InDelta(t, c.expected[i].V, actual[i].V, 1e-9)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckMethod(t *testing.T) {
+	for _, m := range []string{"", MethodLTTB, MethodM4, MethodMinMax, MethodAvg} {
+		assert.Nil(t, CheckMethod(m))
+	}
+	assert.NotNil(t, CheckMethod("max"))
+}
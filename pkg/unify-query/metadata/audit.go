@@ -0,0 +1,133 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"sync"
+)
+
+// AuditBackend 查询命中的存储
+type AuditBackend struct {
+	StorageType string `json:"storage_type"`
+	StorageID   string `json:"storage_id,omitempty"`
+	TableID     string `json:"table_id,omitempty"`
+}
+
+// Audit 单次请求的审计信息，在查询过程中逐步补充，所有方法支持 nil 调用
+type Audit struct {
+	lock sync.Mutex
+
+	backends []AuditBackend
+	series   int64
+	rows     int64
+	err      string
+}
+
+// InitAudit 初始化请求的审计信息，未初始化的请求不记录
+func InitAudit(ctx context.Context) *Audit {
+	a := &Audit{}
+	if md != nil {
+		md.set(ctx, AuditKey, a)
+	}
+	return a
+}
+
+// GetAudit
+func GetAudit(ctx context.Context) *Audit {
+	if md != nil {
+		r, ok := md.get(ctx, AuditKey)
+		if ok {
+			if v, ok := r.(*Audit); ok {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// AddBackend 记录查询命中的存储，相同的存储只记录一次
+func (a *Audit) AddBackend(storageType, storageID, tableID string) {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	backend := AuditBackend{
+		StorageType: storageType,
+		StorageID:   storageID,
+		TableID:     tableID,
+	}
+	for _, b := range a.backends {
+		if b == backend {
+			return
+		}
+	}
+	a.backends = append(a.backends, backend)
+}
+
+// AddResult 累加返回的 series 数量以及数据行数
+func (a *Audit) AddResult(series, rows int64) {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.series += series
+	a.rows += rows
+}
+
+// SetError 记录请求异常
+func (a *Audit) SetError(err error) {
+	if a == nil || err == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.err = err.Error()
+}
+
+// Backends
+func (a *Audit) Backends() []AuditBackend {
+	if a == nil {
+		return nil
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	backends := make([]AuditBackend, len(a.backends))
+	copy(backends, a.backends)
+	return backends
+}
+
+// Result 返回 series 数量以及数据行数
+func (a *Audit) Result() (series, rows int64) {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.series, a.rows
+}
+
+// Error
+func (a *Audit) Error() string {
+	if a == nil {
+		return ""
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.err
+}
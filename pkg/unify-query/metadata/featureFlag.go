@@ -11,6 +11,7 @@ package metadata
 
 import (
 	"context"
+	"time"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/featureFlag"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
@@ -90,3 +91,31 @@ func GetIsK8sFeatureFlag(ctx context.Context) bool {
 	status := featureFlag.BoolVariation(ctx, ffUser, "is-k8s", false)
 	return status
 }
+
+// QueryCostLimit 查询开销限制，值为 0 时不做限制
+type QueryCostLimit struct {
+	MaxSeries int           `json:"max_series"`
+	MaxPoints int           `json:"max_points"`
+	MaxRange  time.Duration `json:"max_range"`
+}
+
+// GetQueryCostLimitFeatureFlag 按照空间以及用户读取查询开销限制，未命中特性开关时使用默认配置
+func GetQueryCostLimitFeatureFlag(ctx context.Context, defaultLimit QueryCostLimit) QueryCostLimit {
+	var (
+		user = GetUser(ctx)
+	)
+
+	ffUser := featureFlag.FFUser(user.HashID, map[string]interface{}{
+		"name":     user.Name,
+		"source":   user.Source,
+		"spaceUid": user.SpaceUid,
+	})
+
+	return QueryCostLimit{
+		MaxSeries: featureFlag.IntVariation(ctx, ffUser, "query-max-series", defaultLimit.MaxSeries),
+		MaxPoints: featureFlag.IntVariation(ctx, ffUser, "query-max-points", defaultLimit.MaxPoints),
+		MaxRange: time.Duration(featureFlag.IntVariation(
+			ctx, ffUser, "query-max-range", int(defaultLimit.MaxRange.Seconds()),
+		)) * time.Second,
+	}
+}
@@ -118,6 +118,12 @@ type Query struct {
 	Size          int
 	Orders        Orders
 	NeedAddTime   bool
+	// Collapse es 按字段折叠，每个字段值只返回排序最靠前的一条
+	Collapse string
+
+	// Cursor 开启游标翻页，es 使用 search_after 翻页并在原始数据中返回排序值
+	Cursor      bool
+	SearchAfter []any
 }
 
 type Orders map[string]bool
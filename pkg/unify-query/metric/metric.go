@@ -94,6 +94,24 @@ var (
 		[]string{"space_uid", "source_type", "tsdb_type", "url"},
 	)
 
+	queryCacheRequestTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "unify_query",
+			Name:      "query_cache_request_total",
+			Help:      "query frontend results cache request",
+		},
+		[]string{"space_uid", "result"},
+	)
+
+	queryCostRejectedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "unify_query",
+			Name:      "query_cost_rejected_total",
+			Help:      "query rejected by cost limit",
+		},
+		[]string{"space_uid", "source", "reason"},
+	)
+
 	vmQuerySpaceUidInfo = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "unify_query",
@@ -124,6 +142,19 @@ func TsDBRequestBytes(ctx context.Context, bytes int, params ...string) {
 	observe(ctx, metric, float64(bytes))
 }
 
+func QueryCacheRequestAdd(ctx context.Context, value int, params ...string) {
+	if value <= 0 {
+		return
+	}
+	metric, _ := queryCacheRequestTotal.GetMetricWithLabelValues(params...)
+	counterAdd(ctx, metric, float64(value))
+}
+
+func QueryCostRejectedInc(ctx context.Context, params ...string) {
+	metric, _ := queryCostRejectedTotal.GetMetricWithLabelValues(params...)
+	counterInc(ctx, metric)
+}
+
 func ResultTableInfoSet(ctx context.Context, value float64, params ...string) {
 	metric, _ := resultTableInfo.GetMetricWithLabelValues(params...)
 	gaugeSet(ctx, metric, value)
@@ -202,5 +233,6 @@ func init() {
 	prometheus.MustRegister(
 		apiRequestTotal, apiRequestSecondHistogram, resultTableInfo,
 		tsDBRequestSecondHistogram, vmQuerySpaceUidInfo, tsDBRequestBytesHistogram,
+		queryCacheRequestTotal, queryCostRejectedTotal,
 	)
 }
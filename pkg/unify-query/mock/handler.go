@@ -85,10 +85,11 @@ type VmResponse struct {
 }
 
 var (
-	Vm       = &vmResultData{}
-	BkSQL    = &bkSQLResultData{}
-	InfluxDB = &influxdbResultData{}
-	Es       = &elasticSearchResultData{}
+	Vm         = &vmResultData{}
+	BkSQL      = &bkSQLResultData{}
+	InfluxDB   = &influxdbResultData{}
+	Es         = &elasticSearchResultData{}
+	ClickHouse = &clickHouseResultData{}
 )
 
 type resultData struct {
@@ -153,6 +154,10 @@ type elasticSearchResultData struct {
 	resultData
 }
 
+type clickHouseResultData struct {
+	resultData
+}
+
 func mockHandler(ctx context.Context) {
 	httpmock.Activate()
 
@@ -160,12 +165,15 @@ func mockHandler(ctx context.Context) {
 	mockInfluxDBHandler(ctx)
 	mockBkSQLHandler(ctx)
 	mockElasticSearchHandler(ctx)
+	mockClickHouseHandler(ctx)
 }
 
 const (
 	EsUrl    = "http://127.0.0.1:93002"
 	BkSQLUrl = "http://127.0.0.1:92001"
 	VmUrl    = "http://127.0.0.1:12001/bk_data/query_sync"
+
+	ClickHouseUrl = "http://127.0.0.1:98123"
 )
 
 type BkSQLRequest struct {
@@ -225,6 +233,29 @@ func mockBkSQLHandler(ctx context.Context) {
 	})
 }
 
+func mockClickHouseHandler(ctx context.Context) {
+	httpmock.RegisterResponder(http.MethodPost, ClickHouseUrl+"/", func(r *http.Request) (w *http.Response, err error) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return
+		}
+
+		d, ok := ClickHouse.Get(string(body))
+		if !ok {
+			w = httpmock.NewStringResponse(http.StatusBadRequest, fmt.Sprintf(`clickhouse mock data is empty in "%s"`, body))
+			return
+		}
+
+		switch t := d.(type) {
+		case string:
+			w = httpmock.NewStringResponse(http.StatusOK, t)
+		default:
+			w, err = httpmock.NewJsonResponse(http.StatusOK, d)
+		}
+		return
+	})
+}
+
 func mockInfluxDBHandler(ctx context.Context) {
 	host1 := "http://127.0.0.1:6371"
 	host2 := "http://127.0.0.2:6371"
@@ -0,0 +1,229 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+)
+
+// QueryRangeFunc 实际执行区间查询的函数
+type QueryRangeFunc func(ctx context.Context, start, end time.Time) (promql.Matrix, error)
+
+// Options 结果缓存配置
+type Options struct {
+	// SplitInterval 分块的时间长度，会向上对齐到 step 的整数倍
+	SplitInterval time.Duration
+	// MaxFreshness 距离当前时间小于该值的数据可能还在写入，不进行缓存
+	MaxFreshness time.Duration
+	// TTL 分块的过期时间
+	TTL time.Duration
+}
+
+// Result 缓存命中情况
+type Result struct {
+	Hit  int
+	Miss int
+}
+
+// ResultsCache 按照 step 对齐的时间块切分区间查询，只缓存已经完整的时间块，未命中的部分合并后再回源查询
+type ResultsCache struct {
+	store Store
+	opt   Options
+	now   func() time.Time
+}
+
+func NewResultsCache(store Store, opt Options) *ResultsCache {
+	return &ResultsCache{
+		store: store,
+		opt:   opt,
+		now:   time.Now,
+	}
+}
+
+// block 一个时间块，start 和 end 都是 step 对齐的毫秒时间戳，两端都包含
+type block struct {
+	start     int64
+	end       int64
+	cacheable bool
+}
+
+// QueryRange 查询 [start, end] 内按照 step 对齐的数据，key 需要唯一标识查询语句，不能包含起止时间
+func (c *ResultsCache) QueryRange(
+	ctx context.Context, key string, start, end time.Time, step time.Duration, fn QueryRangeFunc,
+) (promql.Matrix, Result, error) {
+	var res Result
+
+	s, e := start.UnixMilli(), end.UnixMilli()
+	stepMs := step.Milliseconds()
+	if stepMs <= 0 || e < s || c.opt.SplitInterval <= 0 {
+		res.Miss++
+		matrix, err := fn(ctx, start, end)
+		return matrix, res, err
+	}
+
+	interval, blocks := c.blocks(s, e, stepMs)
+	blockKey := func(b block) string {
+		return fmt.Sprintf("%s:%d:%d:%d", key, stepMs, interval, b.start)
+	}
+
+	var (
+		parts   []promql.Matrix
+		pending []block
+	)
+
+	// flush 将连续未命中的时间块合并为一次查询，并将完整的时间块写入缓存
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		first, last := pending[0], pending[len(pending)-1]
+
+		qs, qe := first.start, last.end
+		if !first.cacheable && qs < s {
+			qs = s
+		}
+		if !last.cacheable && qe > e {
+			qe = e
+		}
+
+		matrix, err := fn(ctx, time.UnixMilli(qs), time.UnixMilli(qe))
+		if err != nil {
+			return err
+		}
+		for _, b := range pending {
+			if b.cacheable {
+				c.store.Set(ctx, blockKey(b), trimMatrix(matrix, b.start, b.end), c.opt.TTL)
+			}
+		}
+		parts = append(parts, matrix)
+		pending = pending[:0]
+		return nil
+	}
+
+	for _, b := range blocks {
+		if b.cacheable {
+			if matrix, ok := c.store.Get(ctx, blockKey(b)); ok {
+				res.Hit++
+				if err := flush(); err != nil {
+					return nil, res, err
+				}
+				parts = append(parts, matrix)
+				continue
+			}
+		}
+
+		res.Miss++
+		pending = append(pending, b)
+	}
+	if err := flush(); err != nil {
+		return nil, res, err
+	}
+
+	return mergeMatrix(parts, s, e), res, nil
+}
+
+// blocks 按照 step 对齐切分时间块，返回对齐后的分块长度
+func (c *ResultsCache) blocks(s, e, stepMs int64) (int64, []block) {
+	// 分块长度向上对齐到 step 的整数倍，并以 start 相对 step 的偏移作为分块的相位，保证每个块内的计算时间点与原查询一致
+	interval := (c.opt.SplitInterval.Milliseconds() + stepMs - 1) / stepMs * stepMs
+	phase := s % stepMs
+	freshLimit := c.now().Add(-c.opt.MaxFreshness).UnixMilli()
+
+	blocks := make([]block, 0, (e-s)/interval+2)
+	for i := (s - phase) / interval; i <= (e-phase)/interval; i++ {
+		b := block{
+			start: phase + i*interval,
+			end:   phase + (i+1)*interval - stepMs,
+		}
+		b.cacheable = b.end <= freshLimit
+		blocks = append(blocks, b)
+	}
+	return interval, blocks
+}
+
+// Split 区间查询切分后的时间块，毫秒时间戳，两端都包含
+type Split struct {
+	Start     int64 `json:"start"`
+	End       int64 `json:"end"`
+	Cacheable bool  `json:"cacheable"`
+}
+
+// Splits 返回区间查询按照缓存分块切分后的时间块，只做计算不读取缓存，首尾的时间块会截取到查询范围内
+func (c *ResultsCache) Splits(start, end time.Time, step time.Duration) []Split {
+	s, e := start.UnixMilli(), end.UnixMilli()
+	stepMs := step.Milliseconds()
+	if stepMs <= 0 || e < s || c.opt.SplitInterval <= 0 {
+		return []Split{{Start: s, End: e}}
+	}
+
+	_, blocks := c.blocks(s, e, stepMs)
+	splits := make([]Split, 0, len(blocks))
+	for _, b := range blocks {
+		split := Split{Start: b.start, End: b.end, Cacheable: b.cacheable}
+		if split.Start < s {
+			split.Start = s
+		}
+		if split.End > e {
+			split.End = e
+		}
+		splits = append(splits, split)
+	}
+	return splits
+}
+
+// trimMatrix 截取 [start, end] 内的数据点
+func trimMatrix(matrix promql.Matrix, start, end int64) promql.Matrix {
+	result := make(promql.Matrix, 0, len(matrix))
+	for _, series := range matrix {
+		points := make([]promql.Point, 0, len(series.Points))
+		for _, p := range series.Points {
+			if p.T >= start && p.T <= end {
+				points = append(points, p)
+			}
+		}
+		if len(points) > 0 {
+			result = append(result, promql.Series{Metric: series.Metric, Points: points})
+		}
+	}
+	return result
+}
+
+// mergeMatrix 合并按照时间顺序排列的分块结果，截取 [start, end] 内的数据点，并按照维度排序
+func mergeMatrix(parts []promql.Matrix, start, end int64) promql.Matrix {
+	var (
+		index  = make(map[uint64]int)
+		result = make(promql.Matrix, 0)
+	)
+	for _, part := range parts {
+		for _, series := range part {
+			hash := series.Metric.Hash()
+			idx, ok := index[hash]
+			for _, p := range series.Points {
+				if p.T < start || p.T > end {
+					continue
+				}
+				if !ok {
+					idx = len(result)
+					index[hash] = idx
+					result = append(result, promql.Series{Metric: series.Metric})
+					ok = true
+				}
+				result[idx].Points = append(result[idx].Points, p)
+			}
+		}
+	}
+	sort.Sort(result)
+	return result
+}
@@ -0,0 +1,220 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+)
+
+type mapStore map[string]promql.Matrix
+
+func (s mapStore) Get(_ context.Context, key string) (promql.Matrix, bool) {
+	m, ok := s[key]
+	return m, ok
+}
+
+func (s mapStore) Set(_ context.Context, key string, matrix promql.Matrix, _ time.Duration) {
+	s[key] = matrix
+}
+
+type rangeCall struct {
+	start time.Time
+	end   time.Time
+}
+
+// mockQueryRange 生成 a、b 两条序列，a 的值为时间戳，b 只在偶数分钟有值
+func mockQueryRange(step time.Duration, calls *[]rangeCall) QueryRangeFunc {
+	return func(_ context.Context, start, end time.Time) (promql.Matrix, error) {
+		*calls = append(*calls, rangeCall{start: start.UTC(), end: end.UTC()})
+		a := promql.Series{Metric: labels.FromStrings("name", "a")}
+		b := promql.Series{Metric: labels.FromStrings("name", "b")}
+		for t := start; !t.After(end); t = t.Add(step) {
+			a.Points = append(a.Points, promql.Point{T: t.UnixMilli(), V: float64(t.Unix())})
+			if t.Minute()%2 == 0 {
+				b.Points = append(b.Points, promql.Point{T: t.UnixMilli(), V: 1})
+			}
+		}
+		matrix := promql.Matrix{}
+		for _, s := range []promql.Series{a, b} {
+			if len(s.Points) > 0 {
+				matrix = append(matrix, s)
+			}
+		}
+		return matrix, nil
+	}
+}
+
+func TestResultsCache_QueryRange(t *testing.T) {
+	ctx := context.Background()
+	step := time.Minute
+	now := time.Date(2023, 5, 4, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		start     time.Time
+		end       time.Time
+		hit       int
+		miss      int
+		fetches   []rangeCall
+		newBlocks int
+	}{
+		{
+			// 首次查询：两个完整的天块合并为一次查询，当天的块未完整只查询到 end
+			name:  "first query",
+			start: time.Date(2023, 5, 2, 6, 0, 0, 0, time.UTC),
+			end:   time.Date(2023, 5, 4, 11, 0, 0, 0, time.UTC),
+			hit:   0,
+			miss:  3,
+			fetches: []rangeCall{
+				{start: time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC), end: time.Date(2023, 5, 4, 11, 0, 0, 0, time.UTC)},
+			},
+			newBlocks: 2,
+		},
+		{
+			// 再次查询：命中两个天块，只查询未完整的尾部
+			name:  "repeat query",
+			start: time.Date(2023, 5, 2, 0, 30, 0, 0, time.UTC),
+			end:   time.Date(2023, 5, 4, 11, 30, 0, 0, time.UTC),
+			hit:   2,
+			miss:  1,
+			fetches: []rangeCall{
+				{start: time.Date(2023, 5, 4, 0, 0, 0, 0, time.UTC), end: time.Date(2023, 5, 4, 11, 30, 0, 0, time.UTC)},
+			},
+		},
+		{
+			// 向前扩展：只查询缺失的天块
+			name:  "extend query",
+			start: time.Date(2023, 5, 1, 23, 0, 0, 0, time.UTC),
+			end:   time.Date(2023, 5, 3, 1, 0, 0, 0, time.UTC),
+			hit:   2,
+			miss:  1,
+			fetches: []rangeCall{
+				{start: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), end: time.Date(2023, 5, 1, 23, 59, 0, 0, time.UTC)},
+			},
+			newBlocks: 1,
+		},
+	}
+
+	store := mapStore{}
+	cache := NewResultsCache(store, Options{
+		SplitInterval: 24 * time.Hour,
+		MaxFreshness:  10 * time.Minute,
+		TTL:           time.Hour,
+	})
+	cache.now = func() time.Time { return now }
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				calls  []rangeCall
+				direct []rangeCall
+			)
+			blocks := len(store)
+
+			matrix, res, err := cache.QueryRange(ctx, "key", c.start, c.end, step, mockQueryRange(step, &calls))
+			assert.Nil(t, err)
+			assert.Equal(t, c.hit, res.Hit)
+			assert.Equal(t, c.miss, res.Miss)
+			assert.Equal(t, c.fetches, calls)
+			assert.Equal(t, c.newBlocks, len(store)-blocks)
+
+			// 切分缓存后的结果需要与直接查询一致
+			expected, _ := mockQueryRange(step, &direct)(ctx, c.start, c.end)
+			assert.Equal(t, expected, matrix)
+		})
+	}
+}
+
+func TestResultsCache_QueryRangeAlign(t *testing.T) {
+	ctx := context.Background()
+	// step 无法整除分块长度，分块长度向上对齐为 step 的整数倍，并且保持 start 的相位
+	step := 7 * time.Minute
+	start := time.Date(2023, 5, 1, 3, 5, 0, 0, time.UTC)
+	end := start.Add(100 * step)
+
+	store := mapStore{}
+	cache := NewResultsCache(store, Options{
+		SplitInterval: time.Hour,
+		MaxFreshness:  time.Minute,
+		TTL:           time.Hour,
+	})
+	cache.now = func() time.Time { return end.Add(time.Hour) }
+
+	for i := 0; i < 2; i++ {
+		var calls, direct []rangeCall
+		matrix, res, err := cache.QueryRange(ctx, "key", start, end, step, mockQueryRange(step, &calls))
+		assert.Nil(t, err)
+
+		expected, _ := mockQueryRange(step, &direct)(ctx, start, end)
+		assert.Equal(t, expected, matrix)
+
+		for _, call := range calls {
+			assert.Equal(t, int64(0), (call.start.UnixMilli()-start.UnixMilli())%step.Milliseconds())
+		}
+		for key := range store {
+			assert.Contains(t, key, fmt.Sprintf(":%d:%d:", step.Milliseconds(), (9*step).Milliseconds()))
+		}
+
+		if i == 0 {
+			assert.Equal(t, 0, res.Hit)
+			assert.Len(t, calls, 1)
+		} else {
+			assert.Equal(t, 0, res.Miss)
+			assert.Len(t, calls, 0)
+		}
+	}
+}
+
+func TestResultsCache_QueryRangeError(t *testing.T) {
+	ctx := context.Background()
+	store := mapStore{}
+	cache := NewResultsCache(store, Options{
+		SplitInterval: time.Hour,
+		TTL:           time.Hour,
+	})
+
+	end := time.Now().Add(-2 * time.Hour)
+	_, _, err := cache.QueryRange(ctx, "key", end.Add(-3*time.Hour), end, time.Minute,
+		func(_ context.Context, _, _ time.Time) (promql.Matrix, error) {
+			return nil, fmt.Errorf("storage error")
+		},
+	)
+	assert.EqualError(t, err, "storage error")
+	assert.Len(t, store, 0)
+}
+
+func TestResultsCache_Splits(t *testing.T) {
+	start := time.Unix(3600*3+600, 0)
+	end := time.Unix(3600*6+1200, 0)
+	now := time.Unix(3600*5+1800, 0)
+
+	cache := NewResultsCache(mapStore{}, Options{
+		SplitInterval: time.Hour,
+		MaxFreshness:  10 * time.Minute,
+	})
+	cache.now = func() time.Time { return now }
+
+	assert.Equal(t, []Split{
+		{Start: start.UnixMilli(), End: time.Unix(3600*4-60, 0).UnixMilli(), Cacheable: true},
+		{Start: time.Unix(3600*4, 0).UnixMilli(), End: time.Unix(3600*5-60, 0).UnixMilli(), Cacheable: true},
+		{Start: time.Unix(3600*5, 0).UnixMilli(), End: time.Unix(3600*6-60, 0).UnixMilli(), Cacheable: false},
+		{Start: time.Unix(3600*6, 0).UnixMilli(), End: end.UnixMilli(), Cacheable: false},
+	}, cache.Splits(start, end, time.Minute))
+
+	// 没有 step 时不进行切分
+	assert.Equal(t, []Split{{Start: start.UnixMilli(), End: end.UnixMilli()}}, cache.Splits(start, end, 0))
+}
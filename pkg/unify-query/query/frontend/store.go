@@ -0,0 +1,108 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"time"
+
+	goRedis "github.com/go-redis/redis/v8"
+	"github.com/prometheus/prometheus/promql"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/memcache"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/redis"
+)
+
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+// Store 分块结果的存储
+type Store interface {
+	Get(ctx context.Context, key string) (promql.Matrix, bool)
+	Set(ctx context.Context, key string, matrix promql.Matrix, ttl time.Duration)
+}
+
+// MemoryStore 基于进程内缓存的存储，直接保存结果对象
+type MemoryStore struct {
+	cache memcache.Cache
+}
+
+func NewMemoryStore(cache memcache.Cache) *MemoryStore {
+	return &MemoryStore{cache: cache}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (promql.Matrix, bool) {
+	val, ok := s.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	matrix, ok := val.(promql.Matrix)
+	return matrix, ok
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, matrix promql.Matrix, ttl time.Duration) {
+	s.cache.SetWithTTL(key, matrix, matrixCost(matrix), ttl)
+}
+
+// RedisStore 基于 redis 的存储，多实例之间共享分块结果
+type RedisStore struct {
+	prefix string
+}
+
+func NewRedisStore(prefix string) *RedisStore {
+	return &RedisStore{prefix: prefix}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (promql.Matrix, bool) {
+	res, err := redis.Get(ctx, s.prefix+key)
+	if err != nil {
+		if !errors.Is(err, goRedis.Nil) {
+			log.Warnf(ctx, "[query frontend] redis get %s error: %s", key, err)
+		}
+		return nil, false
+	}
+
+	var matrix promql.Matrix
+	if err = gob.NewDecoder(bytes.NewBufferString(res)).Decode(&matrix); err != nil {
+		log.Warnf(ctx, "[query frontend] decode %s error: %s", key, err)
+		return nil, false
+	}
+	return matrix, true
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, matrix promql.Matrix, ttl time.Duration) {
+	// gob 可以保留 NaN 以及 Inf 等数值，json 无法序列化
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(matrix); err != nil {
+		log.Warnf(ctx, "[query frontend] encode %s error: %s", key, err)
+		return
+	}
+	if _, err := redis.Set(ctx, s.prefix+key, buf.String(), ttl); err != nil {
+		log.Warnf(ctx, "[query frontend] redis set %s error: %s", key, err)
+	}
+}
+
+// matrixCost 估算结果占用的内存大小
+func matrixCost(matrix promql.Matrix) int64 {
+	var cost int64
+	for _, series := range matrix {
+		for _, lb := range series.Metric {
+			cost += int64(len(lb.Name) + len(lb.Value))
+		}
+		cost += int64(len(series.Points)) * 16
+	}
+	return cost
+}
@@ -0,0 +1,392 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	prom "github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+const (
+	HoltWintersSeasonal = "holt_winters_seasonal"
+	HoltWintersForecast = "holt_winters_forecast"
+	PredictLinearUpper  = "predict_linear_upper"
+	PredictLinearLower  = "predict_linear_lower"
+	ZScoreOverTime      = "zscore_over_time"
+	MadOverTime         = "mad_over_time"
+	ZScoreOutlier       = "zscore_outlier"
+	MadOutlier          = "mad_outlier"
+	MovingPercentile    = "moving_percentile"
+)
+
+// engineFunction 扩展到 promql 引擎中的函数，只能由 unify-query 内置引擎计算
+type engineFunction struct {
+	argTypes []parser.ValueType
+	call     prom.FunctionCall
+	// atModifierUnsafe 结果依赖计算时间，与 predict_linear 一样不能作为 step 不变的表达式处理
+	atModifierUnsafe bool
+}
+
+var engineFunctions = map[string]engineFunction{
+	// holt_winters_seasonal(v range-vector, sf, tf, gf scalar, season scalar) 带周期的三次指数平滑，season 单位为秒
+	HoltWintersSeasonal: {
+		argTypes: []parser.ValueType{parser.ValueTypeMatrix, parser.ValueTypeScalar, parser.ValueTypeScalar, parser.ValueTypeScalar, parser.ValueTypeScalar},
+		call:     funcHoltWintersSeasonal,
+	},
+	// holt_winters_forecast(v range-vector, sf, tf, gf scalar, season scalar, t scalar) 带周期的三次指数平滑预测 t 秒后的值
+	HoltWintersForecast: {
+		argTypes: []parser.ValueType{parser.ValueTypeMatrix, parser.ValueTypeScalar, parser.ValueTypeScalar, parser.ValueTypeScalar, parser.ValueTypeScalar, parser.ValueTypeScalar},
+		call:     funcHoltWintersForecast,
+	},
+	// predict_linear_upper(v range-vector, t scalar, z scalar) predict_linear 置信区间上界
+	PredictLinearUpper: {
+		argTypes:         []parser.ValueType{parser.ValueTypeMatrix, parser.ValueTypeScalar, parser.ValueTypeScalar},
+		call:             funcPredictLinearUpper,
+		atModifierUnsafe: true,
+	},
+	// predict_linear_lower(v range-vector, t scalar, z scalar) predict_linear 置信区间下界
+	PredictLinearLower: {
+		argTypes:         []parser.ValueType{parser.ValueTypeMatrix, parser.ValueTypeScalar, parser.ValueTypeScalar},
+		call:             funcPredictLinearLower,
+		atModifierUnsafe: true,
+	},
+	// zscore_over_time(v range-vector) 窗口内最后一个点的 z-score
+	ZScoreOverTime: {
+		argTypes: []parser.ValueType{parser.ValueTypeMatrix},
+		call:     funcZScoreOverTime,
+	},
+	// mad_over_time(v range-vector) 窗口内的绝对中位差
+	MadOverTime: {
+		argTypes: []parser.ValueType{parser.ValueTypeMatrix},
+		call:     funcMadOverTime,
+	},
+	// zscore_outlier(v range-vector, threshold scalar) 最后一个点的 z-score 绝对值超过阈值时为 1，否则为 0
+	ZScoreOutlier: {
+		argTypes: []parser.ValueType{parser.ValueTypeMatrix, parser.ValueTypeScalar},
+		call:     funcZScoreOutlier,
+	},
+	// mad_outlier(v range-vector, threshold scalar) 最后一个点基于 mad 的修正 z-score 绝对值超过阈值时为 1，否则为 0
+	MadOutlier: {
+		argTypes: []parser.ValueType{parser.ValueTypeMatrix, parser.ValueTypeScalar},
+		call:     funcMadOutlier,
+	},
+	// moving_percentile(v range-vector, p scalar) 窗口内的百分位数，p 取值范围 [0, 100]
+	MovingPercentile: {
+		argTypes: []parser.ValueType{parser.ValueTypeMatrix, parser.ValueTypeScalar},
+		call:     funcMovingPercentile,
+	},
+}
+
+// IsEngineFunction 判断是否为扩展函数，扩展函数无法下推到 vm 等存储计算
+func IsEngineFunction(name string) bool {
+	_, ok := engineFunctions[name]
+	return ok
+}
+
+// init 注册扩展函数，使原生 promql 和结构化查询都可以直接使用
+func init() {
+	for name, f := range engineFunctions {
+		parser.Functions[name] = &parser.Function{
+			Name:       name,
+			ArgTypes:   f.argTypes,
+			ReturnType: parser.ValueTypeVector,
+		}
+		prom.FunctionCalls[name] = f.call
+		if f.atModifierUnsafe {
+			prom.AtModifierUnsafeFunctions[name] = struct{}{}
+		}
+	}
+}
+
+func scalarArg(vals []parser.Value, index int) float64 {
+	return vals[index].(prom.Vector)[0].V
+}
+
+func checkSmoothingFactor(name string, v float64) {
+	if v <= 0 || v >= 1 {
+		panic(fmt.Errorf("invalid %s factor. Expected: 0 < %s < 1, got: %f", name, name, v))
+	}
+}
+
+// holtWinters 加法模型的三次指数平滑，返回最后一个点的水平、趋势以及周期分量
+func holtWinters(vals []parser.Value) (level, trend float64, seasonal []float64, period int, ok bool) {
+	points := vals[0].(prom.Matrix)[0].Points
+	sf := scalarArg(vals, 1)
+	tf := scalarArg(vals, 2)
+	gf := scalarArg(vals, 3)
+	season := scalarArg(vals, 4)
+
+	checkSmoothingFactor("smoothing", sf)
+	checkSmoothingFactor("trend", tf)
+	checkSmoothingFactor("seasonal", gf)
+
+	n := len(points)
+	if n < 4 || season <= 0 {
+		return
+	}
+
+	// 根据平均采样间隔把周期换算为点数
+	interval := float64(points[n-1].T-points[0].T) / float64(n-1) / 1e3
+	if interval <= 0 {
+		return
+	}
+	period = int(math.Round(season / interval))
+	// 至少需要两个完整周期用于初始化
+	if period < 2 || n < period*2 {
+		return
+	}
+
+	var first, second float64
+	for i := 0; i < period; i++ {
+		first += points[i].V
+		second += points[i+period].V
+	}
+	first /= float64(period)
+	second /= float64(period)
+
+	level = first
+	trend = (second - first) / float64(period)
+	seasonal = make([]float64, n)
+	for i := 0; i < period; i++ {
+		seasonal[i] = points[i].V - level
+	}
+
+	for i := period; i < n; i++ {
+		prevLevel := level
+		level = sf*(points[i].V-seasonal[i-period]) + (1-sf)*(level+trend)
+		trend = tf*(level-prevLevel) + (1-tf)*trend
+		seasonal[i] = gf*(points[i].V-level) + (1-gf)*seasonal[i-period]
+	}
+
+	ok = true
+	return
+}
+
+// === holt_winters_seasonal(Matrix parser.ValueTypeMatrix, sf, tf, gf, season parser.ValueTypeScalar) Vector ===
+func funcHoltWintersSeasonal(vals []parser.Value, args parser.Expressions, enh *prom.EvalNodeHelper) prom.Vector {
+	level, _, seasonal, _, ok := holtWinters(vals)
+	if !ok {
+		return enh.Out
+	}
+
+	return append(enh.Out, prom.Sample{
+		Point: prom.Point{V: level + seasonal[len(seasonal)-1]},
+	})
+}
+
+// === holt_winters_forecast(Matrix parser.ValueTypeMatrix, sf, tf, gf, season, t parser.ValueTypeScalar) Vector ===
+func funcHoltWintersForecast(vals []parser.Value, args parser.Expressions, enh *prom.EvalNodeHelper) prom.Vector {
+	level, trend, seasonal, period, ok := holtWinters(vals)
+	if !ok {
+		return enh.Out
+	}
+
+	points := vals[0].(prom.Matrix)[0].Points
+	n := len(points)
+	interval := float64(points[n-1].T-points[0].T) / float64(n-1) / 1e3
+	h := int(math.Round(scalarArg(vals, 5) / interval))
+	if h < 1 {
+		return append(enh.Out, prom.Sample{
+			Point: prom.Point{V: level + seasonal[n-1]},
+		})
+	}
+
+	return append(enh.Out, prom.Sample{
+		Point: prom.Point{V: level + float64(h)*trend + seasonal[n-period+(h-1)%period]},
+	})
+}
+
+// predictLinearBand 以 enh.Ts 为截距时间做线性回归，返回 t 秒后的预测值以及预测标准误差
+func predictLinearBand(vals []parser.Value, enh *prom.EvalNodeHelper) (float64, float64, bool) {
+	points := vals[0].(prom.Matrix)[0].Points
+	duration := scalarArg(vals, 1)
+
+	n := float64(len(points))
+	// 计算残差方差至少需要 3 个点
+	if n < 3 {
+		return 0, 0, false
+	}
+
+	var sumX, sumY float64
+	for _, p := range points {
+		sumX += float64(p.T-enh.Ts) / 1e3
+		sumY += p.V
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy float64
+	for _, p := range points {
+		dx := float64(p.T-enh.Ts)/1e3 - meanX
+		sxx += dx * dx
+		sxy += dx * (p.V - meanY)
+	}
+	if sxx == 0 {
+		return 0, 0, false
+	}
+
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var sse float64
+	for _, p := range points {
+		residual := p.V - (intercept + slope*float64(p.T-enh.Ts)/1e3)
+		sse += residual * residual
+	}
+
+	se := math.Sqrt(sse/(n-2)) * math.Sqrt(1+1/n+(duration-meanX)*(duration-meanX)/sxx)
+	return intercept + slope*duration, se, true
+}
+
+// === predict_linear_upper(Matrix parser.ValueTypeMatrix, t, z parser.ValueTypeScalar) Vector ===
+func funcPredictLinearUpper(vals []parser.Value, args parser.Expressions, enh *prom.EvalNodeHelper) prom.Vector {
+	predict, se, ok := predictLinearBand(vals, enh)
+	if !ok {
+		return enh.Out
+	}
+
+	return append(enh.Out, prom.Sample{
+		Point: prom.Point{V: predict + scalarArg(vals, 2)*se},
+	})
+}
+
+// === predict_linear_lower(Matrix parser.ValueTypeMatrix, t, z parser.ValueTypeScalar) Vector ===
+func funcPredictLinearLower(vals []parser.Value, args parser.Expressions, enh *prom.EvalNodeHelper) prom.Vector {
+	predict, se, ok := predictLinearBand(vals, enh)
+	if !ok {
+		return enh.Out
+	}
+
+	return append(enh.Out, prom.Sample{
+		Point: prom.Point{V: predict - scalarArg(vals, 2)*se},
+	})
+}
+
+// zScore 最后一个点相对于窗口的 z-score，标准差为 0 时返回 0
+func zScore(points []prom.Point) float64 {
+	var sum float64
+	for _, p := range points {
+		sum += p.V
+	}
+	mean := sum / float64(len(points))
+
+	var variance float64
+	for _, p := range points {
+		variance += (p.V - mean) * (p.V - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(points)))
+	if stddev == 0 {
+		return 0
+	}
+
+	return (points[len(points)-1].V - mean) / stddev
+}
+
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// mad 返回窗口的中位数以及绝对中位差
+func mad(points []prom.Point) (float64, float64) {
+	values := make([]float64, 0, len(points))
+	for _, p := range points {
+		values = append(values, p.V)
+	}
+	m := median(values)
+
+	for i, p := range points {
+		values[i] = math.Abs(p.V - m)
+	}
+	return m, median(values)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// === zscore_over_time(Matrix parser.ValueTypeMatrix) Vector ===
+func funcZScoreOverTime(vals []parser.Value, args parser.Expressions, enh *prom.EvalNodeHelper) prom.Vector {
+	points := vals[0].(prom.Matrix)[0].Points
+	return append(enh.Out, prom.Sample{
+		Point: prom.Point{V: zScore(points)},
+	})
+}
+
+// === mad_over_time(Matrix parser.ValueTypeMatrix) Vector ===
+func funcMadOverTime(vals []parser.Value, args parser.Expressions, enh *prom.EvalNodeHelper) prom.Vector {
+	_, v := mad(vals[0].(prom.Matrix)[0].Points)
+	return append(enh.Out, prom.Sample{
+		Point: prom.Point{V: v},
+	})
+}
+
+// === zscore_outlier(Matrix parser.ValueTypeMatrix, threshold parser.ValueTypeScalar) Vector ===
+func funcZScoreOutlier(vals []parser.Value, args parser.Expressions, enh *prom.EvalNodeHelper) prom.Vector {
+	points := vals[0].(prom.Matrix)[0].Points
+	return append(enh.Out, prom.Sample{
+		Point: prom.Point{V: boolValue(math.Abs(zScore(points)) > scalarArg(vals, 1))},
+	})
+}
+
+// === mad_outlier(Matrix parser.ValueTypeMatrix, threshold parser.ValueTypeScalar) Vector ===
+func funcMadOutlier(vals []parser.Value, args parser.Expressions, enh *prom.EvalNodeHelper) prom.Vector {
+	points := vals[0].(prom.Matrix)[0].Points
+	m, d := mad(points)
+	last := points[len(points)-1].V
+
+	var outlier bool
+	if d == 0 {
+		// 超过一半的点相同时 mad 为 0，此时偏离中位数即视为异常
+		outlier = last != m
+	} else {
+		// 修正 z-score，0.6745 为正态分布下 mad 与标准差的换算系数
+		outlier = math.Abs(0.6745*(last-m)/d) > scalarArg(vals, 1)
+	}
+
+	return append(enh.Out, prom.Sample{
+		Point: prom.Point{V: boolValue(outlier)},
+	})
+}
+
+// === moving_percentile(Matrix parser.ValueTypeMatrix, p parser.ValueTypeScalar) Vector ===
+func funcMovingPercentile(vals []parser.Value, args parser.Expressions, enh *prom.EvalNodeHelper) prom.Vector {
+	points := vals[0].(prom.Matrix)[0].Points
+	p := scalarArg(vals, 1)
+	if math.IsNaN(p) || p < 0 || p > 100 {
+		panic(fmt.Errorf("invalid percentile. Expected: 0 <= p <= 100, got: %f", p))
+	}
+
+	values := make([]float64, 0, len(points))
+	for _, point := range points {
+		values = append(values, point.V)
+	}
+	sort.Float64s(values)
+
+	// 与 quantile_over_time 一致，落在两个点之间时按权重插值
+	rank := p / 100 * float64(len(values)-1)
+	lower := math.Floor(rank)
+	upper := math.Min(float64(len(values)-1), lower+1)
+	weight := rank - lower
+
+	return append(enh.Out, prom.Sample{
+		Point: prom.Point{V: values[int(lower)]*(1-weight) + values[int(upper)]*weight},
+	})
+}
@@ -0,0 +1,84 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package promql_test
+
+import (
+	"testing"
+
+	prom "github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/promql"
+)
+
+func TestEngineFunctions(t *testing.T) {
+	test, err := prom.NewTest(t, `
+load 1m
+	metric{job="a"} 1 2 3 4 5 6 7 8 9 10
+	noisy{job="a"} 10 10 10 10 10 10 10 10 10 50
+	line{job="a"} 1 3 2 4 3 5
+	seasonal{job="a"} 1 5 1 5 1 5 1 5
+
+eval instant at 9m moving_percentile(metric[10m], 90)
+	{job="a"} 9.1
+
+eval instant at 9m zscore_over_time(noisy[10m])
+	{job="a"} 3
+
+eval instant at 9m zscore_outlier(noisy[10m], 2.5)
+	{job="a"} 1
+
+eval instant at 9m zscore_outlier(noisy[10m], 3.5)
+	{job="a"} 0
+
+eval instant at 9m mad_over_time(metric[10m])
+	{job="a"} 2.5
+
+eval instant at 9m mad_outlier(metric[10m], 3.5)
+	{job="a"} 0
+
+eval instant at 9m mad_outlier(noisy[10m], 3.5)
+	{job="a"} 1
+
+eval instant at 5m predict_linear_upper(line[6m], 60, 2)
+	{job="a"} 7.6
+
+eval instant at 5m predict_linear_lower(line[6m], 60, 2)
+	{job="a"} 2.8
+
+eval instant at 7m holt_winters_seasonal(seasonal[8m], 0.5, 0.5, 0.5, 120)
+	{job="a"} 5
+
+eval instant at 7m holt_winters_forecast(seasonal[8m], 0.5, 0.5, 0.5, 120, 60)
+	{job="a"} 1
+
+eval instant at 7m holt_winters_forecast(seasonal[8m], 0.5, 0.5, 0.5, 120, 120)
+	{job="a"} 5
+
+eval_fail instant at 9m moving_percentile(metric[10m], 120)
+
+eval_fail instant at 7m holt_winters_seasonal(seasonal[8m], 1, 0.5, 0.5, 120)
+`)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer test.Close()
+
+	assert.Nil(t, test.Run())
+}
+
+func TestIsEngineFunction(t *testing.T) {
+	assert.True(t, promql.IsEngineFunction(promql.HoltWintersSeasonal))
+	assert.False(t, promql.IsEngineFunction("holt_winters"))
+
+	_, err := parser.ParseExpr(`moving_percentile(metric[10m])`)
+	assert.NotNil(t, err)
+}
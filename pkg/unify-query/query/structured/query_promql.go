@@ -33,6 +33,8 @@ type QueryPromQL struct {
 	Match               string   `json:"match,omitempty"`
 	// DownSampleRange 降采样：大于Step才能生效，可以为空
 	DownSampleRange string `json:"down_sample_range,omitempty" example:"5m"`
+	// DownSampleMethod 降采样算法：lttb（默认）、m4、minmax、avg
+	DownSampleMethod string `json:"down_sample_method,omitempty" example:"lttb"`
 	// Timezone 时区
 	Timezone string `json:"timezone,omitempty" example:"Asia/Shanghai"`
 	// LookBackDelta 偏移量
@@ -54,12 +54,16 @@ type QueryTs struct {
 	Step string `json:"step,omitempty" example:"1m"`
 	// DownSampleRange 降采样：大于Step才能生效，可以为空
 	DownSampleRange string `json:"down_sample_range,omitempty" example:"5m"`
+	// DownSampleMethod 降采样算法：lttb（默认）、m4、minmax、avg
+	DownSampleMethod string `json:"down_sample_method,omitempty" example:"lttb"`
 	// Timezone 时区
 	Timezone string `json:"timezone,omitempty" example:"Asia/Shanghai"`
 	// LookBackDelta 偏移量
 	LookBackDelta string `json:"look_back_delta,omitempty"`
 	// Instant 瞬时数据
 	Instant bool `json:"instant"`
+	// ScrollID 流式导出的游标，从上一次中断的位置继续导出
+	ScrollID string `json:"scroll_id,omitempty"`
 }
 
 // 根据 timezone 偏移对齐
@@ -349,6 +353,8 @@ type Query struct {
 	Limit int `json:"limit,omitempty" example:"0"`
 	// From 翻页开启数字
 	From int `json:"from,omitempty" example:"0"`
+	// Collapse 按字段折叠原始数据，每个字段值只返回排序最靠前的一条
+	Collapse string `json:"-" swaggerignore:"true"`
 	// Timestamp @-modifier 标记
 	Timestamp *int64 `json:"timestamp,omitempty"`
 	// StartOrEnd @-modifier 标记，start or end
@@ -444,7 +450,11 @@ func (q *Query) Aggregates() (aggs metadata.Aggregates, err error) {
 		return
 	}
 
-	if name, ok := domSampledFunc[am.Method+q.TimeAggregation.Function]; ok {
+	name, ok := domSampledFunc[am.Method+q.TimeAggregation.Function]
+	if !ok {
+		name, ok = logDomSampledFunc[q.DataSource][am.Method+q.TimeAggregation.Function]
+	}
+	if ok {
 		agg := metadata.Aggregate{
 			Name:       name,
 			Dimensions: am.Dimensions,
@@ -453,6 +463,19 @@ func (q *Query) Aggregates() (aggs metadata.Aggregates, err error) {
 			TimeZone:   q.Timezone,
 			Args:       am.VArgsList,
 		}
+
+		// 百分位使用 quantile_over_time 的分位数，转换为 0 ~ 100 的百分位
+		if name == PERCENTILES {
+			if len(q.TimeAggregation.VargsList) == 0 {
+				return
+			}
+			phi, isFloat := q.TimeAggregation.VargsList[0].(float64)
+			if !isFloat || phi < 0 || phi > 1 {
+				return
+			}
+			// 按 1e-6 精度取整，避免浮点误差，例如 0.29 * 100 = 28.999999999999996
+			agg.Args = []any{math.Round(phi*100*1e6) / 1e6}
+		}
 		aggs = append(aggs, agg)
 
 		// 是否命中降采样计算
@@ -570,6 +593,7 @@ func (q *Query) ToQueryMetric(ctx context.Context, spaceUid string) (*metadata.Q
 
 		query.Size = q.Limit
 		query.From = q.From
+		query.Collapse = q.Collapse
 		query.Aggregates = aggregates
 
 		// 针对 vmRt 不为空的情况，进行 vm 判定
@@ -942,6 +966,9 @@ func (q *Query) ToPromExpr(ctx context.Context, promExprOpt *PromExprOption) (pa
 					//    2. 因为多指标共用一个最大的计算周期，会增加较小计算周期的数据量，例如：sum(count_over_time(metric[1d]))  + sum(count_over_time(metric[1m]))，都会使用 1d 来计算；
 					// 这里选用方案一，使用 last_over_time 来扩展计算周期，如果因为增加 last_over_time 函数可能会引起的未知问题，需要考虑方案二；
 					q.TimeAggregation.Function = LastOT
+					// 例如 quantile_over_time 的参数已经在存储引擎中计算，last_over_time 不需要参数
+					q.TimeAggregation.VargsList = nil
+					q.TimeAggregation.Position = 0
 				}
 			}
 		}
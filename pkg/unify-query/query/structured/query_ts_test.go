@@ -1153,6 +1153,138 @@ func TestAggregations(t *testing.T) {
 				},
 			},
 		},
+		"test log query with sum rate": {
+			query: &Query{
+				DataSource: BkLog,
+				AggregateMethodList: AggregateMethodList{
+					{
+						Method:     "sum",
+						Dimensions: []string{"service"},
+					},
+				},
+				TimeAggregation: TimeAggregation{
+					Function: "rate",
+					Window:   "5m",
+				},
+				Step: "5m",
+			},
+			aggs: md.Aggregates{
+				{
+					Name:       "rate",
+					Dimensions: []string{"service"},
+					Window:     time.Minute * 5,
+				},
+			},
+		},
+		"test log query with sum increase": {
+			query: &Query{
+				DataSource: BkData,
+				AggregateMethodList: AggregateMethodList{
+					{
+						Method:     "sum",
+						Dimensions: []string{"service"},
+					},
+				},
+				TimeAggregation: TimeAggregation{
+					Function: "increase",
+					Window:   "1m",
+				},
+				Step: "1m",
+			},
+			aggs: md.Aggregates{
+				{
+					Name:       "count",
+					Dimensions: []string{"service"},
+					Window:     time.Minute,
+				},
+			},
+		},
+		"test log query with quantile quantile_over_time": {
+			query: &Query{
+				DataSource: BkLog,
+				AggregateMethodList: AggregateMethodList{
+					{
+						Method:     "quantile",
+						Dimensions: []string{"service"},
+						VArgsList:  []interface{}{0.9},
+					},
+				},
+				TimeAggregation: TimeAggregation{
+					Function:  "quantile_over_time",
+					Window:    "1m",
+					VargsList: []interface{}{0.95},
+				},
+				Step: "1m",
+			},
+			aggs: md.Aggregates{
+				{
+					Name:       "percentiles",
+					Dimensions: []string{"service"},
+					Window:     time.Minute,
+					Args:       []interface{}{95.0},
+				},
+			},
+		},
+		"test log query with fractional quantile quantile_over_time": {
+			query: &Query{
+				DataSource: BkLog,
+				AggregateMethodList: AggregateMethodList{
+					{
+						Method:     "quantile",
+						Dimensions: []string{"service"},
+						VArgsList:  []interface{}{0.9},
+					},
+				},
+				TimeAggregation: TimeAggregation{
+					Function:  "quantile_over_time",
+					Window:    "1m",
+					VargsList: []interface{}{0.29},
+				},
+				Step: "1m",
+			},
+			aggs: md.Aggregates{
+				{
+					Name:       "percentiles",
+					Dimensions: []string{"service"},
+					Window:     time.Minute,
+					Args:       []interface{}{29.0},
+				},
+			},
+		},
+		"test bksql query with quantile quantile_over_time": {
+			query: &Query{
+				DataSource: BkData,
+				AggregateMethodList: AggregateMethodList{
+					{
+						Method:     "quantile",
+						Dimensions: []string{"service"},
+						VArgsList:  []interface{}{0.9},
+					},
+				},
+				TimeAggregation: TimeAggregation{
+					Function:  "quantile_over_time",
+					Window:    "1m",
+					VargsList: []interface{}{0.9},
+				},
+				Step: "1m",
+			},
+		},
+		"test metric query with sum rate": {
+			query: &Query{
+				DataSource: BkMonitor,
+				AggregateMethodList: AggregateMethodList{
+					{
+						Method:     "sum",
+						Dimensions: []string{"service"},
+					},
+				},
+				TimeAggregation: TimeAggregation{
+					Function: "rate",
+					Window:   "1m",
+				},
+				Step: "1m",
+			},
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			aggs, err := c.query.Aggregates()
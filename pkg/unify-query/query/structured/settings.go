@@ -24,6 +24,15 @@ const (
 	CountOT = "count_over_time"
 	LastOT  = "last_over_time"
 	AvgOT   = "avg_over_time"
+
+	RateOT     = "rate"
+	IncreaseOT = "increase"
+	QuantileOT = "quantile_over_time"
+
+	// RATE 日志类存储按照聚合周期计算文档数量后除以周期秒数
+	RATE = "rate"
+	// PERCENTILES 日志类存储的百分位聚合，参数为 0 ~ 100 的百分位
+	PERCENTILES = "percentiles"
 )
 
 var domSampledFunc = map[string]string{
@@ -34,3 +43,23 @@ var domSampledFunc = map[string]string{
 	MEAN + AvgOT:  AVG,
 	SUM + CountOT: COUNT,
 }
+
+// logDomSampledFunc 日志类数据源额外支持的降采样聚合，每一条日志作为一个点，例如：
+// sum by (service) (rate(bklog:table:level[5m])) 计算每个服务每秒的日志条数
+// quantile by (service) (0.9, quantile_over_time(0.9, bklog:table:cost[5m])) 计算每个服务 cost 字段的 P90
+var logDomSampledFunc = map[string]map[string]string{
+	BkLog: {
+		SUM + RateOT:                 RATE,
+		SUM + IncreaseOT:             COUNT,
+		QuantileAggName + QuantileOT: PERCENTILES,
+	},
+	BkApm: {
+		SUM + RateOT:                 RATE,
+		SUM + IncreaseOT:             COUNT,
+		QuantileAggName + QuantileOT: PERCENTILES,
+	},
+	BkData: {
+		SUM + RateOT:     RATE,
+		SUM + IncreaseOT: COUNT,
+	},
+}
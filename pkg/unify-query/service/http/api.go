@@ -73,38 +73,7 @@ func HandlerFieldKeys(c *gin.Context) {
 		return
 	}
 
-	p, _ := ants.NewPool(QueryMaxRouting)
-	defer p.Release()
-
-	var (
-		wg  sync.WaitGroup
-		lbl = set.New[string]()
-	)
-
-	for _, queryMetric := range queryRef {
-		for _, qry := range queryMetric.QueryList {
-			wg.Add(1)
-			qry := qry
-			_ = p.Submit(func() {
-				defer wg.Done()
-				instance := prometheus.GetTsDbInstance(ctx, qry)
-				if instance == nil {
-					return
-				}
-
-				res, err := instance.QueryLabelValues(ctx, qry, labels.MetricName, start, end)
-				if err != nil {
-					return
-				}
-				lbl.Add(res...)
-			})
-		}
-	}
-	wg.Wait()
-
-	data := lbl.ToArray()
-	sort.Strings(data)
-
+	data := queryReferenceLabelValues(ctx, queryRef, labels.MetricName, start, end)
 	resp.success(ctx, data)
 }
 
@@ -149,38 +118,7 @@ func HandlerTagKeys(c *gin.Context) {
 		return
 	}
 
-	p, _ := ants.NewPool(QueryMaxRouting)
-	defer p.Release()
-
-	var (
-		wg  sync.WaitGroup
-		lbl = set.New[string]()
-	)
-
-	for _, queryMetric := range queryRef {
-		for _, qry := range queryMetric.QueryList {
-			wg.Add(1)
-			qry := qry
-			_ = p.Submit(func() {
-				defer wg.Done()
-				instance := prometheus.GetTsDbInstance(ctx, qry)
-				if instance == nil {
-					return
-				}
-
-				res, err := instance.QueryLabelNames(ctx, qry, start, end)
-				if err != nil {
-					return
-				}
-				lbl.Add(res...)
-			})
-		}
-	}
-	wg.Wait()
-
-	data := lbl.ToArray()
-	sort.Strings(data)
-
+	data := queryReferenceLabelNames(ctx, queryRef, start, end)
 	resp.success(ctx, data)
 }
 
@@ -535,3 +473,128 @@ func infoParamsToQueryRefAndTime(ctx context.Context, params *infos.Params) (que
 	queryRef, err = queryTs.ToQueryReference(ctx)
 	return
 }
+
+// queryReferenceLabelNames 并发查询 queryRef 中所有存储的维度名，结果去重并排序
+func queryReferenceLabelNames(ctx context.Context, queryRef metadata.QueryReference, start, end time.Time) []string {
+	p, _ := ants.NewPool(QueryMaxRouting)
+	defer p.Release()
+
+	var (
+		wg  sync.WaitGroup
+		lbl = set.New[string]()
+	)
+
+	for _, queryMetric := range queryRef {
+		for _, qry := range queryMetric.QueryList {
+			wg.Add(1)
+			qry := qry
+			_ = p.Submit(func() {
+				defer wg.Done()
+				instance := prometheus.GetTsDbInstance(ctx, qry)
+				if instance == nil {
+					return
+				}
+
+				res, err := instance.QueryLabelNames(ctx, qry, start, end)
+				if err != nil {
+					return
+				}
+				lbl.Add(res...)
+			})
+		}
+	}
+	wg.Wait()
+
+	data := lbl.ToArray()
+	sort.Strings(data)
+	return data
+}
+
+// queryReferenceLabelValues 并发查询 queryRef 中所有存储的维度值，结果去重并排序
+func queryReferenceLabelValues(ctx context.Context, queryRef metadata.QueryReference, name string, start, end time.Time) []string {
+	p, _ := ants.NewPool(QueryMaxRouting)
+	defer p.Release()
+
+	var (
+		wg  sync.WaitGroup
+		lbl = set.New[string]()
+	)
+
+	for _, queryMetric := range queryRef {
+		for _, qry := range queryMetric.QueryList {
+			wg.Add(1)
+			qry := qry
+			_ = p.Submit(func() {
+				defer wg.Done()
+				instance := prometheus.GetTsDbInstance(ctx, qry)
+				if instance == nil {
+					return
+				}
+
+				res, err := instance.QueryLabelValues(ctx, qry, name, start, end)
+				if err != nil {
+					return
+				}
+				lbl.Add(res...)
+			})
+		}
+	}
+	wg.Wait()
+
+	data := lbl.ToArray()
+	sort.Strings(data)
+	return data
+}
+
+// queryReferenceSeries 并发查询 queryRef 中所有存储的 series，limit 大于 0 时各存储及合并结果最多返回 limit 条
+func queryReferenceSeries(ctx context.Context, queryRef metadata.QueryReference, start, end time.Time, limit int) ([]map[string]string, error) {
+	p, _ := ants.NewPool(QueryMaxRouting)
+	defer p.Release()
+
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		data     = make([]map[string]string, 0)
+		firstErr error
+	)
+
+	for _, queryMetric := range queryRef {
+		for _, qry := range queryMetric.QueryList {
+			if limit > 0 {
+				qry.Size = limit
+				qry.OffsetInfo.SLimit = limit
+			}
+
+			wg.Add(1)
+			qry := qry
+			_ = p.Submit(func() {
+				defer wg.Done()
+				instance := prometheus.GetTsDbInstance(ctx, qry)
+				if instance == nil {
+					return
+				}
+
+				res, err := instance.QuerySeries(ctx, qry, start, end)
+
+				lock.Lock()
+				defer lock.Unlock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					return
+				}
+				data = append(data, res...)
+			})
+		}
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if limit > 0 && len(data) > limit {
+		data = data[:limit]
+	}
+	return data, nil
+}
@@ -0,0 +1,81 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/http/middleware"
+)
+
+var (
+	// auditParams 审计中间件配置，重载配置时整体替换
+	auditParams = &middleware.AuditParams{}
+	// auditFileSink 重载配置时需要关闭旧的文件
+	auditFileSink *middleware.AuditFileSink
+)
+
+// setAudit 根据配置初始化审计记录输出以及慢查询列表
+func setAudit() {
+	ctx := context.TODO()
+	params := &middleware.AuditParams{
+		MaxQueryLength: viper.GetInt(AuditMaxQueryLengthConfigPath),
+	}
+
+	if auditFileSink != nil {
+		if err := auditFileSink.Close(); err != nil {
+			log.Warnf(ctx, "close audit file error: %s", err)
+		}
+		auditFileSink = nil
+	}
+
+	switch viper.GetString(AuditSinkConfigPath) {
+	case middleware.AuditSinkFile:
+		sink, err := middleware.NewAuditFileSink(
+			viper.GetString(AuditPathConfigPath),
+			viper.GetInt64(AuditMaxSizeConfigPath)*1024*1024,
+			viper.GetInt(AuditMaxBackupsConfigPath),
+		)
+		if err != nil {
+			log.Errorf(ctx, "new audit file sink error: %s", err)
+		} else {
+			auditFileSink = sink
+			params.Sink = sink
+		}
+	case middleware.AuditSinkLog:
+		params.Sink = &middleware.AuditLogSink{}
+	}
+
+	// 慢查询列表保留在内存中，配置不变时沿用之前的记录
+	size := viper.GetInt(SlowQueriesSizeConfigPath)
+	window := viper.GetDuration(SlowQueriesWindowConfigPath)
+	if size > 0 {
+		old := auditParams.SlowQueries
+		if old != nil && old.Size() == size && old.Window() == window {
+			params.SlowQueries = old
+		} else {
+			params.SlowQueries = middleware.NewSlowQueries(size, window)
+		}
+	}
+
+	auditParams = params
+}
+
+// HandlerSlowQueries 返回最近一段时间内耗时最长的请求
+func HandlerSlowQueries(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"slow_queries": auditParams.SlowQueries.List(),
+	})
+}
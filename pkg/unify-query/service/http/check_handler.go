@@ -18,10 +18,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 
-	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
-	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/prometheus"
 )
 
 type CheckItem struct {
@@ -140,64 +138,30 @@ func HandlerCheckQueryPromQL(c *gin.Context) {
 	c.String(http.StatusOK, checkResponse.String())
 }
 
-// checkQueryTs 根据传入的查询进行校验判断
+// checkQueryTs 根据传入的查询进行校验判断，路由及存储实例的查询计划与 explain 接口共用
 func checkQueryTs(ctx context.Context, q *structured.QueryTs, r *CheckResponse) {
-	var err error
-
 	r.Step("query ts", q)
 
 	user := metadata.GetUser(ctx)
 	r.Step("metadata user", user)
 
-	// 查询转换信息
-	qr, err := q.ToQueryReference(ctx)
+	plan, err := explainQueryTs(ctx, q)
 	if err != nil {
-		r.Error("q.ToQueryReference", err)
+		r.Error("explainQueryTs", err)
 		return
 	}
-	r.Step("query-reference", qr)
-
-	start, end, _, _, err := structured.ToTime(q.Start, q.End, q.Step, q.Timezone)
-	if err != nil {
-		r.Error("structured.ToTime", err)
-		return
+	r.Step("query promQL", plan.PromQL)
+	r.Step("query instance", plan.Engine)
+	if plan.DirectQuery {
+		r.Step("query vmExpand", plan.VmExpand)
 	}
+	r.Step("query time splits", plan.TimeSplits)
 
-	// 写入查询缓存
-	metadata.GetQueryParams(ctx).SetTime(start.Unix(), end.Unix())
-
-	promQL, err := q.ToPromQL(ctx)
-	if err != nil {
-		r.Error("q.ToPromQL", err)
-		return
-	}
-	r.Step("query promQL", promQL)
-
-	// vm query
-	if metadata.GetQueryParams(ctx).IsDirectQuery() {
-		// 判断是否查询 vm
-		vmExpand := qr.ToVmExpand(ctx)
-
-		r.Step("query instance", consul.VictoriaMetricsStorageType)
-		r.Step("query vmExpand", vmExpand)
-	} else {
-		for _, qm := range qr {
-			for _, qry := range qm.QueryList {
-				instance := prometheus.GetTsDbInstance(ctx, qry)
-				if instance == nil {
-					r.Error("prometheus.GetInstance", fmt.Errorf("instance is null, with storageID %s", qry.StorageID))
-					continue
-				}
-
-				r.Step("instance id", qry.StorageID)
-				r.Step("instance type", instance.InstanceType())
-				r.Step("query struct", qry)
-			}
-		}
+	for _, route := range plan.Routes {
+		r.Step("query route", route)
 	}
 
-	status := metadata.GetStatus(ctx)
-	if status != nil {
-		r.Step("metadata status", status)
+	if plan.Status != nil {
+		r.Step("metadata status", plan.Status)
 	}
 }
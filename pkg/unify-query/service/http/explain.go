@@ -0,0 +1,271 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/frontend"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/prometheus"
+)
+
+// ExplainRoute 单个结果表的路由信息以及存储实例的查询计划
+type ExplainRoute struct {
+	ReferenceName string   `json:"reference_name"`
+	MetricName    string   `json:"metric_name"`
+	TableID       string   `json:"table_id"`
+	DataSource    string   `json:"data_source,omitempty"`
+	StorageType   string   `json:"storage_type"`
+	StorageID     string   `json:"storage_id,omitempty"`
+	ClusterName   string   `json:"cluster_name,omitempty"`
+	DB            string   `json:"db,omitempty"`
+	Measurements  []string `json:"measurements,omitempty"`
+	Fields        []string `json:"fields,omitempty"`
+	VmRt          string   `json:"vm_rt,omitempty"`
+
+	InstanceType string          `json:"instance_type,omitempty"`
+	Plan         *tsdb.QueryPlan `json:"plan,omitempty"`
+
+	// EstimatedSeries 通过存储实例的 series 接口预估的 series 数量，-1 表示无法预估
+	EstimatedSeries int `json:"estimated_series"`
+	// SeriesLimited 预估达到查询上限，实际的 series 数量不小于预估值
+	SeriesLimited bool `json:"series_limited,omitempty"`
+	// EstimatedShards 查询命中的索引或者表的数量
+	EstimatedShards int `json:"estimated_shards"`
+
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ExplainResponse 查询计划，只做查询转换以及 series、索引信息的预估，不执行查询
+type ExplainResponse struct {
+	SpaceUid string `json:"space_uid"`
+	Start    int64  `json:"start"`
+	End      int64  `json:"end"`
+	Step     string `json:"step"`
+	Instant  bool   `json:"instant"`
+
+	// Engine 执行 PromQL 的实例类型，直查时为 vm，否则为 prometheus 引擎
+	Engine      string             `json:"engine"`
+	DirectQuery bool               `json:"direct_query"`
+	PromQL      string             `json:"promql"`
+	VmExpand    *metadata.VmExpand `json:"vm_expand,omitempty"`
+
+	// TimeSplits 区间查询按照结果缓存切分的时间块，毫秒时间戳
+	TimeSplits      []frontend.Split `json:"time_splits"`
+	EstimatedSeries int              `json:"estimated_series"`
+	EstimatedShards int              `json:"estimated_shards"`
+
+	Routes []*ExplainRoute  `json:"routes"`
+	Status *metadata.Status `json:"status,omitempty"`
+}
+
+// HandlerQueryTsExplain
+// @Summary  explain query ts without executing
+// @ID       query_ts_explain
+// @Produce  json
+// @Param    traceparent            header    string                        false  "TraceID" default(00-3967ac0f1648bf0216b27631730d7eb9-8e3c31d5109e78dd-01)
+// @Param    Bk-Query-Source   		header    string                        false  "来源" default(username:goodman)
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param	 X-Bk-Scope-Skip-Space  header	  string						false  "是否跳过空间验证" default()
+// @Param    data                  	body      structured.QueryTs  			true   "json data"
+// @Success  200                   	{object}  ExplainResponse
+// @Failure  400                   	{object}  ErrResponse
+// @Router   /query/ts/explain [post]
+func HandlerQueryTsExplain(c *gin.Context) {
+	var (
+		ctx = c.Request.Context()
+
+		resp = &response{
+			c: c,
+		}
+
+		err error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-query-ts-explain")
+	defer span.End(&err)
+
+	// 解析请求 body
+	query := &structured.QueryTs{}
+	err = json.NewDecoder(c.Request.Body).Decode(query)
+	if err != nil {
+		log.Errorf(ctx, err.Error())
+		resp.failed(ctx, err)
+		return
+	}
+
+	res, err := explainQueryTs(ctx, query)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	resp.success(ctx, res)
+}
+
+// explainQueryTs 解析查询的空间路由、各存储实例的原生查询语句、预估的 series 以及索引数量、时间切分，check 接口共用该逻辑
+func explainQueryTs(ctx context.Context, query *structured.QueryTs) (res *ExplainResponse, err error) {
+	ctx, span := trace.NewSpan(ctx, "explain-query-ts")
+	defer span.End(&err)
+
+	start, end, step, timezone, err := structured.ToTime(query.Start, query.End, query.Step, query.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	query.Timezone = timezone
+	metadata.GetQueryParams(ctx).SetTime(start.Unix(), end.Unix())
+
+	instance, stmt, err := queryTsToInstanceAndStmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	queryRef, err := query.ToQueryReference(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res = &ExplainResponse{
+		SpaceUid:    metadata.GetUser(ctx).SpaceUid,
+		Start:       start.Unix(),
+		End:         end.Unix(),
+		Step:        step.String(),
+		Instant:     query.Instant,
+		Engine:      instance.InstanceType(),
+		DirectQuery: isDirectQuery(ctx, query),
+		PromQL:      stmt,
+		Routes:      make([]*ExplainRoute, 0),
+	}
+	if res.DirectQuery {
+		res.VmExpand = metadata.GetExpand(ctx)
+	}
+
+	// 区间查询开启结果缓存时，会按照时间块切分回源查询
+	if !query.Instant && queryCache != nil && queryCacheable(query, stmt) {
+		res.TimeSplits = queryCache.Splits(start, end, step)
+	} else {
+		res.TimeSplits = []frontend.Split{{Start: start.UnixMilli(), End: end.UnixMilli()}}
+	}
+
+	// 瞬时查询的存储查询范围为回溯窗口
+	qStart := start
+	if query.Instant {
+		qStart = end.Add(-queryCostInstantLookBack(query))
+	}
+
+	names := make([]string, 0, len(queryRef))
+	for name := range queryRef {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seriesLimit := viper.GetInt(QueryExplainSeriesLimitConfigPath)
+	for _, name := range names {
+		qm := queryRef[name]
+		routes := make([]*ExplainRoute, 0, len(qm.QueryList))
+		for _, qry := range qm.QueryList {
+			route := explainRoute(ctx, qm, qry, qStart, end, seriesLimit)
+			if route.EstimatedSeries > 0 {
+				res.EstimatedSeries += route.EstimatedSeries
+			}
+			res.EstimatedShards += route.EstimatedShards
+			routes = append(routes, route)
+		}
+		// 同一个 reference 下的结果表顺序不固定，按照结果表排序保证输出稳定
+		sort.SliceStable(routes, func(i, j int) bool {
+			return routes[i].TableID < routes[j].TableID
+		})
+		res.Routes = append(res.Routes, routes...)
+	}
+
+	res.Status = metadata.GetStatus(ctx)
+	return res, nil
+}
+
+// explainRoute 生成单个结果表的查询计划，存储实例只用于语句转换以及 series、索引信息的预估，不执行查询
+func explainRoute(ctx context.Context, qm *metadata.QueryMetric, qry *metadata.Query, start, end time.Time, seriesLimit int) *ExplainRoute {
+	route := &ExplainRoute{
+		ReferenceName:   qm.ReferenceName,
+		MetricName:      qry.MetricName,
+		TableID:         qry.TableID,
+		DataSource:      qry.DataSource,
+		StorageType:     qry.StorageType,
+		StorageID:       qry.StorageID,
+		ClusterName:     qry.ClusterName,
+		DB:              qry.DB,
+		Measurements:    qry.Measurements,
+		Fields:          qry.Fields,
+		VmRt:            qry.VmRt,
+		EstimatedSeries: -1,
+	}
+
+	instance := prometheus.GetTsDbInstance(ctx, qry)
+	if instance == nil {
+		route.Errors = append(route.Errors, fmt.Sprintf("instance is null, with storageID %s", qry.StorageID))
+		return route
+	}
+	route.InstanceType = instance.InstanceType()
+
+	if explainer, ok := instance.(tsdb.Explainer); ok {
+		// 查询结构体会被存储实例修改，这里使用副本避免影响查询计划
+		q := *qry
+		plan, err := explainer.Explain(ctx, &q, start, end)
+		if err != nil {
+			route.Errors = append(route.Errors, fmt.Sprintf("explain: %s", err))
+		} else {
+			route.Plan = plan
+			route.EstimatedShards = len(plan.Indexes)
+		}
+	} else {
+		route.Errors = append(route.Errors, fmt.Sprintf("%s not support explain", instance.InstanceType()))
+	}
+
+	series, err := explainSeries(ctx, instance, qry, start, end, seriesLimit)
+	if err != nil {
+		route.Errors = append(route.Errors, fmt.Sprintf("estimate series: %s", err))
+	} else {
+		route.EstimatedSeries = len(series)
+		route.SeriesLimited = seriesLimit > 0 && len(series) >= seriesLimit
+	}
+	return route
+}
+
+// explainSeries 通过存储实例的 series 接口预估 series 数量，部分存储实例未实现该接口会 panic
+func explainSeries(ctx context.Context, instance tsdb.Instance, qry *metadata.Query, start, end time.Time, limit int) (series []map[string]string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s not support: %v", instance.InstanceType(), r)
+		}
+	}()
+
+	// 与查询开销预估一致，通过 size 限制 series 查询的数量
+	q := *qry
+	if limit > 0 {
+		q.Size = limit
+		q.OffsetInfo.SLimit = limit
+	}
+	series, err = instance.QuerySeries(ctx, &q, start, end)
+	if limit > 0 && len(series) > limit {
+		series = series[:limit]
+	}
+	return series, err
+}
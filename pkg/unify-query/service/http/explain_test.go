@@ -0,0 +1,158 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/featureFlag"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+func TestExplainQueryTs(t *testing.T) {
+	mock.Init()
+	ctx := metadata.InitHashID(context.Background())
+	influxdb.MockSpaceRouter(ctx)
+
+	// 特性开关是全局 mock，测试结束后恢复为 vm 直查，避免影响其他用例
+	defer featureFlag.MockFeatureFlag(ctx, `{
+		"must-vm-query": {
+			"variations": {"true": true, "false": false},
+			"defaultRule": {"variation": "true"}
+		}
+	}`)
+
+	mock.Vm.Set(map[string]any{
+		`series:17296020001729609200{result_table_id="2_bcs_prom_computation_result_table", __name__="container_cpu_usage_seconds_total_value"}`: []map[string]string{
+			{"__name__": "container_cpu_usage_seconds_total_value", "namespace": "a"},
+			{"__name__": "container_cpu_usage_seconds_total_value", "namespace": "b"},
+		},
+	})
+
+	testCases := map[string]struct {
+		mustVmQuery string
+		query       *structured.QueryTs
+
+		engine      string
+		directQuery bool
+		promql      string
+		tableID     string
+		language    string
+		statement   string
+		series      int
+	}{
+		"vm direct query": {
+			mustVmQuery: "true",
+			query: &structured.QueryTs{
+				QueryList: []*structured.Query{
+					{
+						FieldName:     "container_cpu_usage_seconds_total",
+						ReferenceName: "a",
+						TimeAggregation: structured.TimeAggregation{
+							Function: "rate",
+							Window:   "5m",
+						},
+						AggregateMethodList: structured.AggregateMethodList{
+							{Method: "sum", Dimensions: []string{"namespace"}},
+						},
+					},
+				},
+				MetricMerge: "a",
+				Start:       "1729602000",
+				End:         "1729609200",
+				Step:        "1m",
+			},
+			engine:      "victoria_metrics",
+			directQuery: true,
+			promql:      "sum by (namespace) (rate(a[5m] offset -59s999ms))",
+			tableID:     influxdb.ResultTableVM,
+			language:    "promql",
+			statement:   `{result_table_id="2_bcs_prom_computation_result_table", __name__="container_cpu_usage_seconds_total_value"}`,
+			series:      2,
+		},
+		"influxdb query": {
+			mustVmQuery: "false",
+			query: &structured.QueryTs{
+				QueryList: []*structured.Query{
+					{
+						FieldName:     "kube_pod_info",
+						ReferenceName: "a",
+					},
+				},
+				MetricMerge: "a",
+				Start:       "1729602000",
+				End:         "1729609200",
+				Step:        "1m",
+				Instant:     true,
+			},
+			engine:    "prometheus",
+			promql:    "a",
+			tableID:   influxdb.ResultTableInfluxDB,
+			language:  "influxql",
+			statement: `SELECT "value" AS _value, *::tag, "time" AS _time FROM kube_pod_info WHERE time > 1729608900000000000 and time < 1729609200000000000 LIMIT 100000005 SLIMIT 100005 TZ('UTC')`,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := metadata.InitHashID(ctx)
+			metadata.SetUser(ctx, "username:test", influxdb.SpaceUid, "")
+			assert.NoError(t, featureFlag.MockFeatureFlag(ctx, `{
+				"must-vm-query": {
+					"variations": {"true": true, "false": false},
+					"defaultRule": {"variation": "`+c.mustVmQuery+`"}
+				}
+			}`))
+
+			res, err := explainQueryTs(ctx, c.query)
+			assert.NoError(t, err)
+			if res == nil {
+				return
+			}
+
+			assert.Equal(t, c.engine, res.Engine)
+			assert.Equal(t, c.directQuery, res.DirectQuery)
+			assert.Equal(t, c.promql, res.PromQL)
+			assert.Len(t, res.TimeSplits, 1)
+
+			// 指标可能路由到多个结果表，按照结果表查找对应的查询计划
+			var route *ExplainRoute
+			for _, r := range res.Routes {
+				if r.TableID == c.tableID {
+					route = r
+				}
+			}
+			if !assert.NotNil(t, route, fmt.Sprintf("%+v", res.Routes)) {
+				return
+			}
+			assert.Empty(t, route.Errors)
+			assert.Equal(t, c.series, route.EstimatedSeries)
+			assert.NotNil(t, route.Plan)
+			if route.Plan != nil {
+				assert.Equal(t, c.language, route.Plan.Language)
+				assert.Equal(t, []string{c.statement}, route.Plan.Statements)
+				assert.Equal(t, len(route.Plan.Indexes), route.EstimatedShards)
+			}
+
+			// check 接口与 explain 共用路由以及查询计划
+			check := &CheckResponse{}
+			checkQueryTs(ctx, c.query, check)
+			assert.Contains(t, check.String(), "step-name: query route")
+			assert.Contains(t, check.String(), c.tableID)
+		})
+	}
+}
@@ -46,12 +46,20 @@ func setDefaultConfig() {
 	viper.SetDefault(TSQueryReferenceQueryHandlePathConfigPath, "/query/ts/reference")
 	viper.SetDefault(TSQueryRawQueryHandlePathConfigPath, "/query/ts/raw")
 	viper.SetDefault(TSQueryRawMAXLimitConfigPath, 1e2)
+	viper.SetDefault(TSQueryRawStreamHandlePathConfigPath, "/query/ts/raw/stream")
+	viper.SetDefault(TSQueryRawStreamPageSizeConfigPath, 1e3)
 	viper.SetDefault(TSQueryInfoHandlePathConfigPath, "/query/ts/info")
 	viper.SetDefault(TSQueryStructToPromQLHandlePathConfigPath, "/query/ts/struct_to_promql")
 	viper.SetDefault(TSQueryPromQLToStructHandlePathConfigPath, "/query/ts/promql_to_struct")
 
 	viper.SetDefault(TSQueryLabelValuesPathConfigPath, "/query/ts/label/:label_name/values")
 	viper.SetDefault(TSQueryClusterMetricsPathConfigPath, "/query/ts/cluster_metrics")
+	viper.SetDefault(TSQueryExplainPathConfigPath, "/query/ts/explain")
+	viper.SetDefault(SlowQueriesPathConfigPath, "/debug/slow_queries")
+
+	viper.SetDefault(PromAPIPathConfigPath, "/api/v1")
+	viper.SetDefault(PromAPISpacePathConfigPath, "/space/:space_uid/api/v1")
+	viper.SetDefault(TraceJaegerPathConfigPath, "/query/trace/jaeger/:table_id")
 
 	viper.SetDefault(PrintHandlePathConfigPath, "/print")
 	viper.SetDefault(FeatureFlagHandlePathConfigPath, "/ff")
@@ -73,9 +81,48 @@ func setDefaultConfig() {
 
 	viper.SetDefault(QueryMaxRoutingConfigPath, 2)
 
+	// 区间查询结果缓存配置
+	viper.SetDefault(QueryCacheEnableConfigPath, false)
+	viper.SetDefault(QueryCacheStoreConfigPath, "memory")
+	viper.SetDefault(QueryCacheSplitIntervalConfigPath, "24h")
+	viper.SetDefault(QueryCacheMaxFreshnessConfigPath, "10m")
+	viper.SetDefault(QueryCacheTTLConfigPath, "24h")
+	viper.SetDefault(QueryCacheRedisPrefixConfigPath, "bkmonitorv3:unify-query:query_cache:")
+
+	// 查询开销限制配置，为 0 时不限制
+	viper.SetDefault(QueryCostEnableConfigPath, false)
+	viper.SetDefault(QueryCostMaxSeriesConfigPath, 0)
+	viper.SetDefault(QueryCostMaxPointsConfigPath, 0)
+	viper.SetDefault(QueryCostMaxRangeConfigPath, "0s")
+
+	// 查询计划预估 series 的上限，达到上限时预估值为下限
+	viper.SetDefault(QueryExplainSeriesLimitConfigPath, 10000)
+
+	// 审计记录配置，sink 为空时不记录，可选 file、log，max_size 单位为 MB
+	viper.SetDefault(AuditSinkConfigPath, "")
+	viper.SetDefault(AuditPathConfigPath, "unify-query-audit.log")
+	viper.SetDefault(AuditMaxSizeConfigPath, 100)
+	viper.SetDefault(AuditMaxBackupsConfigPath, 5)
+	viper.SetDefault(AuditMaxQueryLengthConfigPath, 4096)
+
+	// 慢查询列表配置，保留时间窗口内耗时最长的 N 个请求
+	viper.SetDefault(SlowQueriesSizeConfigPath, 100)
+	viper.SetDefault(SlowQueriesWindowConfigPath, "1h")
+
 	viper.SetDefault(ClusterMetricQueryPrefixConfigPath, "bkmonitor")
 	viper.SetDefault(ClusterMetricQueryTimeoutConfigPath, "30s")
 
+	// trace 查询配置，未指定时间范围时查询最近 lookback 的数据，max_spans 为单次查询的最大 span 数量
+	viper.SetDefault(TraceLookbackConfigPath, "24h")
+	viper.SetDefault(TraceMaxSpansConfigPath, 1e4)
+
+	// prometheus 兼容接口配置，未指定 match[] 时维度值查询需要扫描整个空间，限制最大返回数量
+	viper.SetDefault(PromAPILabelValuesMaxLimitConfigPath, 1e3)
+
+	// prometheus remote read 配置
+	viper.SetDefault(PromAPIRemoteReadSampleLimitConfigPath, 5e7)
+	viper.SetDefault(PromAPIRemoteReadMaxBytesInFrameConfigPath, 1048576)
+
 }
 
 // LoadConfig
@@ -105,6 +152,9 @@ func LoadConfig() {
 		MinInterval: viper.GetString(SegmentedMinInterval),
 	})
 
+	setQueryCache()
+	setAudit()
+
 	log.Debugf(context.TODO(), "reload success new config address->[%s] port->[%d] username->[%s] password->[%s]"+
 		"going to reload the service.",
 		IPAddress, Port, Username, Password)
@@ -0,0 +1,346 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+)
+
+const (
+	AuditSinkFile = "file"
+	AuditSinkLog  = "log"
+)
+
+// AuditRecord 单次请求的审计记录
+type AuditRecord struct {
+	Time       time.Time               `json:"time"`
+	TraceID    string                  `json:"trace_id,omitempty"`
+	SpaceUid   string                  `json:"space_uid"`
+	User       string                  `json:"user"`
+	Source     string                  `json:"source"`
+	Method     string                  `json:"method"`
+	Path       string                  `json:"path"`
+	Query      string                  `json:"query"`
+	Backends   []metadata.AuditBackend `json:"backends"`
+	Series     int64                   `json:"series"`
+	Rows       int64                   `json:"rows"`
+	Status     int                     `json:"status"`
+	DurationMs int64                   `json:"duration_ms"`
+	Error      string                  `json:"error,omitempty"`
+
+	duration time.Duration
+}
+
+// AuditSink 审计记录输出
+type AuditSink interface {
+	Write(record *AuditRecord) error
+}
+
+// AuditParams
+type AuditParams struct {
+	Sink        AuditSink
+	SlowQueries *SlowQueries
+	// MaxQueryLength 记录的查询语句最大长度，超过则截断
+	MaxQueryLength int
+}
+
+// Audit 记录每个请求的审计信息，写入到 sink 以及慢查询列表
+func Audit(p *AuditParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p == nil || (p.Sink == nil && p.SlowQueries == nil) {
+			c.Next()
+			return
+		}
+
+		var (
+			start = time.Now()
+			ctx   = c.Request.Context()
+			audit = metadata.InitAudit(ctx)
+			query = auditQuery(c, p.MaxQueryLength)
+		)
+
+		c.Next()
+
+		var (
+			user         = metadata.GetUser(ctx)
+			series, rows = audit.Result()
+			duration     = time.Since(start)
+		)
+		record := &AuditRecord{
+			Time:       start,
+			SpaceUid:   user.SpaceUid,
+			User:       user.Name,
+			Source:     user.Source,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Query:      query,
+			Backends:   audit.Backends(),
+			Series:     series,
+			Rows:       rows,
+			Status:     c.Writer.Status(),
+			DurationMs: duration.Milliseconds(),
+			Error:      audit.Error(),
+
+			duration: duration,
+		}
+		if traceID := oteltrace.SpanContextFromContext(ctx).TraceID(); traceID.IsValid() {
+			record.TraceID = traceID.String()
+		}
+
+		if p.Sink != nil {
+			if err := p.Sink.Write(record); err != nil {
+				log.Warnf(ctx, "write audit record error: %s", err)
+			}
+		}
+		if p.SlowQueries != nil {
+			p.SlowQueries.Add(record)
+		}
+	}
+}
+
+// auditQuery 获取请求的查询语句，包含 url 参数以及 body，body 读取后需要重新写回
+func auditQuery(c *gin.Context, maxLength int) string {
+	query := c.Request.URL.RawQuery
+	if c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		_ = c.Request.Body.Close()
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// 二进制 body，例如 remote read 的 protobuf 不记录
+		if err == nil && len(body) > 0 && utf8.Valid(body) {
+			if query != "" {
+				query += " "
+			}
+			query += string(body)
+		}
+	}
+
+	if maxLength > 0 && len(query) > maxLength {
+		query = query[:maxLength] + "..."
+	}
+	return query
+}
+
+// AuditLogSink 审计记录写入到服务日志
+type AuditLogSink struct{}
+
+func (s *AuditLogSink) Write(record *AuditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	log.Infof(context.Background(), "audit: %s", body)
+	return nil
+}
+
+// AuditFileSink 审计记录按行写入本地文件，文件超过大小后滚动
+type AuditFileSink struct {
+	lock sync.Mutex
+
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+// NewAuditFileSink maxSize 为单个文件的最大字节数，maxBackups 为保留的历史文件数
+func NewAuditFileSink(path string, maxSize int64, maxBackups int) (*AuditFileSink, error) {
+	s := &AuditFileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *AuditFileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// rotate 历史文件依次后移，path.1 为最近一次滚动的文件
+func (s *AuditFileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	if s.maxBackups > 0 {
+		for i := s.maxBackups - 1; i > 0; i-- {
+			_ = os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+		}
+		if err := os.Rename(s.path, s.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(s.path); err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+func (s *AuditFileSink) Write(record *AuditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	body = append(body, '\n')
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("audit file %s is closed", s.path)
+	}
+
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(body)) > s.maxSize {
+		if err = s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(body)
+	s.size += int64(n)
+	return err
+}
+
+// Close
+func (s *AuditFileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// SlowQueries 保存最近一段时间内耗时最长的 N 个请求
+type SlowQueries struct {
+	lock sync.Mutex
+
+	size   int
+	window time.Duration
+	// records 按耗时倒序
+	records []*AuditRecord
+}
+
+// NewSlowQueries window 为 0 时不过期
+func NewSlowQueries(size int, window time.Duration) *SlowQueries {
+	return &SlowQueries{
+		size:    size,
+		window:  window,
+		records: make([]*AuditRecord, 0, size),
+	}
+}
+
+// expire 清理超出时间窗口的记录
+func (s *SlowQueries) expire(now time.Time) {
+	if s.window <= 0 {
+		return
+	}
+
+	records := s.records[:0]
+	for _, r := range s.records {
+		if now.Sub(r.Time) <= s.window {
+			records = append(records, r)
+		}
+	}
+	for i := len(records); i < len(s.records); i++ {
+		s.records[i] = nil
+	}
+	s.records = records
+}
+
+// Size
+func (s *SlowQueries) Size() int {
+	return s.size
+}
+
+// Window
+func (s *SlowQueries) Window() time.Duration {
+	return s.window
+}
+
+// Add
+func (s *SlowQueries) Add(record *AuditRecord) {
+	if s == nil || s.size <= 0 || record == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	if s.window > 0 && now.Sub(record.Time) > s.window {
+		return
+	}
+	s.expire(now)
+
+	idx := sort.Search(len(s.records), func(i int) bool {
+		return s.records[i].duration < record.duration
+	})
+	if idx >= s.size {
+		return
+	}
+
+	if len(s.records) < s.size {
+		s.records = append(s.records, nil)
+	}
+	copy(s.records[idx+1:], s.records[idx:])
+	s.records[idx] = record
+}
+
+// List 按耗时倒序返回慢查询
+func (s *SlowQueries) List() []*AuditRecord {
+	if s == nil {
+		return make([]*AuditRecord, 0)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expire(time.Now())
+	records := make([]*AuditRecord, len(s.records))
+	copy(records, s.records)
+	return records
+}
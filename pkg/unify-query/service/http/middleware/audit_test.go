@@ -0,0 +1,141 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package middleware
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+)
+
+type memorySink struct {
+	records []*AuditRecord
+}
+
+func (s *memorySink) Write(record *AuditRecord) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	metadata.InitMetadata()
+	gin.SetMode(gin.TestMode)
+
+	sink := &memorySink{}
+	slowQueries := NewSlowQueries(10, time.Hour)
+
+	g := gin.New()
+	g.Use(
+		Timer(&Params{}),
+		Audit(&AuditParams{Sink: sink, SlowQueries: slowQueries, MaxQueryLength: 20}),
+	)
+	g.POST("/query/ts", func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		// body 被审计中间件读取后仍然可以被处理函数读取
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(c.Request.Body).Decode(&body))
+
+		audit := metadata.GetAudit(ctx)
+		audit.AddBackend("influxdb", "1", "system.cpu_summary")
+		audit.AddBackend("influxdb", "1", "system.cpu_summary")
+		audit.AddBackend("victoria_metrics", "2", "")
+		audit.AddResult(2, 10)
+		c.JSON(http.StatusOK, body)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/query/ts?debug=true", strings.NewReader(`{"metric_merge":"a","query_list":[]}`))
+	req.Header.Set(metadata.BkQuerySourceHeader, "username:admin")
+	req.Header.Set(metadata.SpaceUIDHeader, "bkcc__2")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"metric_merge":"a","query_list":[]}`, w.Body.String())
+
+	assert.Len(t, sink.records, 1)
+	if len(sink.records) == 1 {
+		r := sink.records[0]
+		assert.Equal(t, "bkcc__2", r.SpaceUid)
+		assert.Equal(t, "admin", r.User)
+		assert.Equal(t, "username", r.Source)
+		assert.Equal(t, "/query/ts", r.Path)
+		assert.Equal(t, `debug=true {"metric_...`, r.Query)
+		assert.Equal(t, []metadata.AuditBackend{
+			{StorageType: "influxdb", StorageID: "1", TableID: "system.cpu_summary"},
+			{StorageType: "victoria_metrics", StorageID: "2"},
+		}, r.Backends)
+		assert.Equal(t, int64(2), r.Series)
+		assert.Equal(t, int64(10), r.Rows)
+		assert.Equal(t, http.StatusOK, r.Status)
+	}
+	assert.Len(t, slowQueries.List(), 1)
+}
+
+func TestSlowQueries(t *testing.T) {
+	now := time.Now()
+	s := NewSlowQueries(3, time.Hour)
+
+	for i, d := range []time.Duration{
+		time.Second, 5 * time.Second, 2 * time.Second, 4 * time.Second, 3 * time.Second,
+	} {
+		s.Add(&AuditRecord{Time: now, Path: string(rune('a' + i)), duration: d})
+	}
+	// 超出时间窗口的记录不保留
+	s.Add(&AuditRecord{Time: now.Add(-2 * time.Hour), Path: "expired", duration: time.Minute})
+
+	paths := make([]string, 0)
+	for _, r := range s.List() {
+		paths = append(paths, r.Path)
+	}
+	assert.Equal(t, []string{"b", "d", "e"}, paths)
+}
+
+func TestAuditFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewAuditFileSink(path, 200, 2)
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, sink.Write(&AuditRecord{Path: "/query/ts", Query: strings.Repeat("a", 100)}))
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		f, err := os.Open(name)
+		assert.NoError(t, err)
+		if err != nil {
+			continue
+		}
+
+		lines := 0
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			r := &AuditRecord{}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), r))
+			lines++
+		}
+		_ = f.Close()
+		assert.Equal(t, 1, lines, name)
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
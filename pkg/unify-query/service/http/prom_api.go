@@ -0,0 +1,1026 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	promPromql "github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/set"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/infos"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+)
+
+// prometheus http api 返回状态及错误类型，与 prometheus web/api/v1 保持一致
+const (
+	promStatusSuccess = "success"
+	promStatusError   = "error"
+
+	promErrorTimeout  = "timeout"
+	promErrorCanceled = "canceled"
+	promErrorExec     = "execution"
+	promErrorBadData  = "bad_data"
+	promErrorInternal = "internal"
+
+	promMetricTypeCounter   = "counter"
+	promMetricTypeGauge     = "gauge"
+	promMetricTypeHistogram = "histogram"
+	promMetricTypeSummary   = "summary"
+
+	// promMaxPoints 与 prometheus 一致，单条 series 最多返回 11000 个点
+	promMaxPoints = 11000
+)
+
+var (
+	promMinTime = time.Unix(math.MinInt64/1000+62135596801, 0).UTC()
+	promMaxTime = time.Unix(math.MaxInt64/1000-62135596801, 999999999).UTC()
+)
+
+// promAPIResponse prometheus http api 返回结构
+type promAPIResponse struct {
+	Status    string   `json:"status"`
+	Data      any      `json:"data,omitempty"`
+	ErrorType string   `json:"errorType,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// promQueryData query / query_range 返回的 data 结构
+type promQueryData struct {
+	ResultType parser.ValueType `json:"resultType"`
+	Result     parser.Value     `json:"result"`
+}
+
+// promMetadata metadata 接口返回的指标元信息
+type promMetadata struct {
+	Type string `json:"type"`
+	Help string `json:"help"`
+	Unit string `json:"unit"`
+}
+
+// promExemplar query_exemplars 接口返回的单个 exemplar
+type promExemplar struct {
+	Labels    map[string]string `json:"labels"`
+	Value     string            `json:"value"`
+	Timestamp float64           `json:"timestamp"`
+}
+
+// promExemplarData query_exemplars 接口返回的 series 及其 exemplars
+type promExemplarData struct {
+	SeriesLabels map[string]string `json:"seriesLabels"`
+	Exemplars    []promExemplar    `json:"exemplars"`
+}
+
+type promAPIError struct {
+	typ string
+	err error
+}
+
+func (e *promAPIError) Error() string {
+	return e.err.Error()
+}
+
+func promBadData(err error) *promAPIError {
+	return &promAPIError{typ: promErrorBadData, err: err}
+}
+
+func promExecError(ctx context.Context, err error) *promAPIError {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &promAPIError{typ: promErrorTimeout, err: err}
+	case errors.Is(err, context.Canceled), errors.Is(ctx.Err(), context.Canceled):
+		return &promAPIError{typ: promErrorCanceled, err: err}
+	}
+	return &promAPIError{typ: promErrorExec, err: err}
+}
+
+type promResponse struct {
+	c *gin.Context
+}
+
+func (r *promResponse) failed(ctx context.Context, apiErr *promAPIError) {
+	var code int
+	switch apiErr.typ {
+	case promErrorBadData:
+		code = http.StatusBadRequest
+	case promErrorExec:
+		code = http.StatusUnprocessableEntity
+	case promErrorCanceled, promErrorTimeout:
+		code = http.StatusServiceUnavailable
+	default:
+		code = http.StatusInternalServerError
+	}
+
+	rsp := promAPIResponse{
+		Status:    promStatusError,
+		ErrorType: apiErr.typ,
+		Error:     apiErr.Error(),
+	}
+	// 查询开销超限时与 QueryTs 一致在 data 中返回具体原因
+	var costErr *QueryCostError
+	if errors.As(apiErr.err, &costErr) {
+		rsp.Data = costErr
+	}
+
+	user := metadata.GetUser(ctx)
+	metric.APIRequestInc(ctx, r.c.Request.URL.Path, metric.StatusFailed, user.SpaceUid, user.Source)
+	r.c.JSON(code, rsp)
+}
+
+func (r *promResponse) success(ctx context.Context, data any) {
+	user := metadata.GetUser(ctx)
+	metric.APIRequestInc(ctx, r.c.Request.URL.Path, metric.StatusSuccess, user.SpaceUid, user.Source)
+	r.c.JSON(http.StatusOK, promAPIResponse{
+		Status: promStatusSuccess,
+		Data:   data,
+	})
+}
+
+// promAPISpace 从路径前缀中获取空间 UID，优先级高于 header 中的空间 UID
+func promAPISpace(c *gin.Context) {
+	spaceUid := c.Param("space_uid")
+	if spaceUid == "" {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user := metadata.GetUser(ctx)
+	metadata.SetUser(ctx, user.Key, spaceUid, user.SkipSpace)
+}
+
+// parsePromTime 解析 prometheus 时间参数，支持 unix 时间戳（可带小数）以及 RFC3339 格式
+func parsePromTime(s string, defaultTime time.Time) (time.Time, error) {
+	if s == "" {
+		return defaultTime, nil
+	}
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, ns := math.Modf(t)
+		ns = math.Round(ns*1000) / 1000
+		return time.Unix(int64(sec), int64(ns*float64(time.Second))).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+
+	// 与 prometheus 一致，兼容客户端传入的 Go 最小最大时间
+	switch s {
+	case promMinTime.Format(time.RFC3339Nano):
+		return promMinTime, nil
+	case promMaxTime.Format(time.RFC3339Nano):
+		return promMaxTime, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
+
+// parsePromDuration 解析 prometheus 时长参数，支持秒数（可带小数）以及 5m 等格式
+func parsePromDuration(s string) (time.Duration, error) {
+	if d, err := strconv.ParseFloat(s, 64); err == nil {
+		ts := d * float64(time.Second)
+		if ts > float64(math.MaxInt64) || ts < float64(math.MinInt64) {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration. It overflows int64", s)
+		}
+		return time.Duration(ts), nil
+	}
+	if d, err := model.ParseDuration(s); err == nil {
+		return time.Duration(d), nil
+	}
+	return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+}
+
+// promTimeRange 解析 start 以及 end 参数，缺省时使用最近 1h
+func promTimeRange(c *gin.Context) (start, end time.Time, err error) {
+	end, err = parsePromTime(c.Request.FormValue("end"), time.Now())
+	if err != nil {
+		return
+	}
+	start, err = parsePromTime(c.Request.FormValue("start"), end.Add(-time.Hour))
+	if err != nil {
+		return
+	}
+	if end.Before(start) {
+		err = errors.New("end timestamp must not be before start time")
+	}
+	return
+}
+
+// promQueryContext 根据 timeout 参数设置查询超时
+func promQueryContext(c *gin.Context, ctx context.Context) (context.Context, context.CancelFunc, error) {
+	to := c.Request.FormValue("timeout")
+	if to == "" {
+		return ctx, func() {}, nil
+	}
+	timeout, err := parsePromDuration(to)
+	if err != nil {
+		return ctx, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+// promMatchersToQueryRef 将 match[] 中的指标选择器转换为 queryRef
+func promMatchersToQueryRef(ctx context.Context, match string, start, end time.Time) (metadata.QueryReference, error) {
+	if _, err := parser.ParseMetricSelector(match); err != nil {
+		return nil, err
+	}
+
+	query, err := promQLToStruct(ctx, &structured.QueryPromQL{
+		PromQL: match,
+		Start:  strconv.FormatInt(start.Unix(), 10),
+		End:    strconv.FormatInt(end.Unix(), 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return query.ToQueryReference(ctx)
+}
+
+// promSpaceQueryRef 未指定 match[] 时，查询空间下的全部数据
+func promSpaceQueryRef(ctx context.Context, start, end time.Time) (metadata.QueryReference, error) {
+	queryRef, _, _, err := infoParamsToQueryRefAndTime(ctx, &infos.Params{
+		Start: strconv.FormatInt(start.Unix(), 10),
+		End:   strconv.FormatInt(end.Unix(), 10),
+	})
+	return queryRef, err
+}
+
+// queryPromValue 执行 promql 查询并返回 prometheus 原生结构
+// evalTime 为瞬时查询的计算时间，保留秒以下精度，路由及缓存仍按秒级 start、end 处理
+func queryPromValue(ctx context.Context, queryPromQL *structured.QueryPromQL, evalTime time.Time) (parser.Value, *promAPIError) {
+	var (
+		err error
+		res parser.Value
+	)
+
+	ctx, span := trace.NewSpan(ctx, "query-prom-value")
+	defer span.End(&err)
+
+	if queryPromQL.PromQL == "" {
+		err = errors.New("promql is empty")
+		return nil, promBadData(err)
+	}
+	if _, err = parser.ParseExpr(queryPromQL.PromQL); err != nil {
+		return nil, promBadData(err)
+	}
+
+	query, err := promQLToStruct(ctx, queryPromQL)
+	if err != nil {
+		return nil, promBadData(err)
+	}
+
+	start, end, step, timezone, err := structured.ToTime(query.Start, query.End, query.Step, query.Timezone)
+	if err != nil {
+		return nil, promBadData(err)
+	}
+	query.Timezone = timezone
+
+	// 写入查询时间到全局缓存
+	metadata.GetQueryParams(ctx).SetTime(start.Unix(), end.Unix())
+	if err = checkQueryCost(ctx, query, start, end, step); err != nil {
+		var costErr *QueryCostError
+		if errors.As(err, &costErr) {
+			return nil, promExecError(ctx, err)
+		}
+		return nil, promBadData(err)
+	}
+	instance, stmt, err := queryTsToInstanceAndStmt(ctx, query)
+	if err != nil {
+		return nil, promBadData(err)
+	}
+
+	span.Set("stmt", stmt)
+	span.Set("storage-type", instance.InstanceType())
+
+	if query.Instant {
+		if evalTime.IsZero() {
+			evalTime = end
+		}
+		res, err = instance.DirectQuery(ctx, stmt, evalTime)
+	} else {
+		res, err = queryRangeWithCache(ctx, query, instance, stmt, start, end, step)
+	}
+	if err != nil {
+		return nil, promExecError(ctx, err)
+	}
+
+	return decodePromValue(ctx, res), nil
+}
+
+// decodePromValue 还原被转义的维度名并过滤内部维度
+func decodePromValue(ctx context.Context, value parser.Value) parser.Value {
+	decodeFunc := metadata.GetPromDataFormat(ctx).DecodeFunc()
+	decodeLabels := func(lbs labels.Labels) labels.Labels {
+		builder := labels.NewBuilder(nil)
+		for _, lb := range lbs {
+			if lb.Name == influxdb.BKTaskIndex {
+				continue
+			}
+			builder.Set(decodeFunc(lb.Name), lb.Value)
+		}
+		return builder.Labels(nil)
+	}
+
+	switch v := value.(type) {
+	case promPromql.Matrix:
+		for i := range v {
+			v[i].Metric = decodeLabels(v[i].Metric)
+		}
+		sort.Sort(v)
+		return v
+	case promPromql.Vector:
+		for i := range v {
+			v[i].Metric = decodeLabels(v[i].Metric)
+		}
+		return v
+	}
+	return value
+}
+
+// HandlerPromAPIQuery
+// @Summary  prometheus instant query
+// @ID       prom_api_query
+// @Produce  json
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param    query                  query     string                        true   "promql"
+// @Param    time                   query     string                        false  "查询时间"
+// @Success  200                   	{object}  promAPIResponse
+// @Failure  400                   	{object}  promAPIResponse
+// @Router   /api/v1/query [get]
+func HandlerPromAPIQuery(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &promResponse{c: c}
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-prom-api-query")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+
+	ts, err := parsePromTime(c.Request.FormValue("time"), time.Now())
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+
+	ctx, cancel, err := promQueryContext(c, ctx)
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+	defer cancel()
+
+	t := strconv.FormatInt(ts.Unix(), 10)
+	res, apiErr := queryPromValue(ctx, &structured.QueryPromQL{
+		PromQL:        c.Request.FormValue("query"),
+		Start:         t,
+		End:           t,
+		LookBackDelta: c.Request.FormValue("lookback_delta"),
+		Instant:       true,
+	}, ts)
+	if apiErr != nil {
+		err = apiErr
+		resp.failed(ctx, apiErr)
+		return
+	}
+
+	resp.success(ctx, promQueryData{ResultType: res.Type(), Result: res})
+}
+
+// HandlerPromAPIQueryRange
+// @Summary  prometheus range query
+// @ID       prom_api_query_range
+// @Produce  json
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param    query                  query     string                        true   "promql"
+// @Param    start                  query     string                        true   "开始时间"
+// @Param    end                    query     string                        true   "结束时间"
+// @Param    step                   query     string                        true   "步长"
+// @Success  200                   	{object}  promAPIResponse
+// @Failure  400                   	{object}  promAPIResponse
+// @Router   /api/v1/query_range [get]
+func HandlerPromAPIQueryRange(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &promResponse{c: c}
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-prom-api-query-range")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+
+	start, err := parsePromTime(c.Request.FormValue("start"), time.Time{})
+	if err == nil && start.IsZero() {
+		err = errors.New("invalid parameter \"start\": start is required")
+	}
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+	end, err := parsePromTime(c.Request.FormValue("end"), time.Time{})
+	if err == nil && end.IsZero() {
+		err = errors.New("invalid parameter \"end\": end is required")
+	}
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+	if end.Before(start) {
+		err = errors.New("end timestamp must not be before start time")
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+
+	step, err := parsePromDuration(c.Request.FormValue("step"))
+	if err == nil && step <= 0 {
+		err = errors.New("zero or negative query resolution step widths are not accepted. Try a positive integer")
+	}
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+	if end.Sub(start)/step > promMaxPoints {
+		err = errors.New("exceeded maximum resolution of 11,000 points per timeseries. Try decreasing the query resolution (?step=XX)")
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+
+	ctx, cancel, err := promQueryContext(c, ctx)
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+	defer cancel()
+
+	res, apiErr := queryPromValue(ctx, &structured.QueryPromQL{
+		PromQL:        c.Request.FormValue("query"),
+		Start:         strconv.FormatInt(start.Unix(), 10),
+		End:           strconv.FormatInt(end.Unix(), 10),
+		Step:          model.Duration(step).String(),
+		LookBackDelta: c.Request.FormValue("lookback_delta"),
+	}, time.Time{})
+	if apiErr != nil {
+		err = apiErr
+		resp.failed(ctx, apiErr)
+		return
+	}
+
+	resp.success(ctx, promQueryData{ResultType: res.Type(), Result: res})
+}
+
+// HandlerPromAPISeries
+// @Summary  prometheus series
+// @ID       prom_api_series
+// @Produce  json
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param    match[]                query     string                        true   "指标选择器"
+// @Success  200                   	{object}  promAPIResponse
+// @Failure  400                   	{object}  promAPIResponse
+// @Router   /api/v1/series [get]
+func HandlerPromAPISeries(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &promResponse{c: c}
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-prom-api-series")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+
+	if err = c.Request.ParseForm(); err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+	matches := c.Request.Form["match[]"]
+	if len(matches) == 0 {
+		err = errors.New("no match[] parameter provided")
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+
+	start, end, err := promTimeRange(c)
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+	metadata.GetQueryParams(ctx).SetTime(start.Unix(), end.Unix())
+
+	var (
+		exists = make(map[string]struct{})
+		data   = make([]map[string]string, 0)
+	)
+	for _, match := range matches {
+		queryRef, matchErr := promMatchersToQueryRef(ctx, match, start, end)
+		if matchErr != nil {
+			err = matchErr
+			resp.failed(ctx, promBadData(err))
+			return
+		}
+
+		seriesList, seriesErr := queryReferenceSeries(ctx, queryRef, start, end, 0)
+		if seriesErr != nil {
+			err = seriesErr
+			resp.failed(ctx, promExecError(ctx, err))
+			return
+		}
+		for _, series := range seriesList {
+			key := labels.FromMap(series).String()
+			if _, ok := exists[key]; ok {
+				continue
+			}
+			exists[key] = struct{}{}
+			data = append(data, series)
+		}
+	}
+
+	span.Set("result-num", len(data))
+	resp.success(ctx, data)
+}
+
+// HandlerPromAPILabels
+// @Summary  prometheus label names
+// @ID       prom_api_labels
+// @Produce  json
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param    match[]                query     string                        false  "指标选择器"
+// @Success  200                   	{object}  promAPIResponse
+// @Failure  400                   	{object}  promAPIResponse
+// @Router   /api/v1/labels [get]
+func HandlerPromAPILabels(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &promResponse{c: c}
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-prom-api-labels")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+
+	if err = c.Request.ParseForm(); err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+
+	start, end, err := promTimeRange(c)
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+	metadata.GetQueryParams(ctx).SetTime(start.Unix(), end.Unix())
+
+	queryRefs, err := promQueryRefs(ctx, c.Request.Form["match[]"], start, end)
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+
+	var data []string
+	for _, queryRef := range queryRefs {
+		data = append(data, queryReferenceLabelNames(ctx, queryRef, start, end)...)
+	}
+	data = sortedUnique(data)
+
+	span.Set("result-num", len(data))
+	resp.success(ctx, data)
+}
+
+// HandlerPromAPILabelValues
+// @Summary  prometheus label values
+// @ID       prom_api_label_values
+// @Produce  json
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param    match[]                query     string                        false  "指标选择器"
+// @Success  200                   	{object}  promAPIResponse
+// @Failure  400                   	{object}  promAPIResponse
+// @Router   /api/v1/label/{name}/values [get]
+func HandlerPromAPILabelValues(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &promResponse{c: c}
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-prom-api-label-values")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+
+	name := c.Param("name")
+	if !model.LabelNameRE.MatchString(name) {
+		err = fmt.Errorf("invalid label name: %q", name)
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+
+	if err = c.Request.ParseForm(); err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+
+	start, end, err := promTimeRange(c)
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+	metadata.GetQueryParams(ctx).SetTime(start.Unix(), end.Unix())
+
+	limit, _ := strconv.Atoi(c.Request.FormValue("limit"))
+	matches := c.Request.Form["match[]"]
+
+	var data []string
+	if len(matches) == 0 {
+		queryRef, refErr := promSpaceQueryRef(ctx, start, end)
+		if refErr != nil {
+			err = refErr
+			resp.failed(ctx, promBadData(err))
+			return
+		}
+
+		// 未指定 match[] 时需要扫描整个空间，限制各存储的返回数量
+		limit = promLabelValuesLimit(limit)
+		setQueryReferenceSize(queryRef, limit)
+		data = queryReferenceLabelValues(ctx, queryRef, name, start, end)
+	}
+
+	// 与 HandlerLabelValues 一致，通过 DirectLabelValues 查询指标选择器下的维度值
+	for _, match := range matches {
+		var (
+			query    *structured.QueryTs
+			matchers []*labels.Matcher
+			values   []string
+		)
+		query, err = promQLToStruct(ctx, &structured.QueryPromQL{
+			PromQL: match,
+			Start:  strconv.FormatInt(start.Unix(), 10),
+			End:    strconv.FormatInt(end.Unix(), 10),
+		})
+		if err != nil {
+			resp.failed(ctx, promBadData(err))
+			return
+		}
+
+		instance, stmt, stmtErr := queryTsToInstanceAndStmt(ctx, query)
+		if stmtErr != nil {
+			err = stmtErr
+			resp.failed(ctx, promBadData(err))
+			return
+		}
+
+		matchers, err = parser.ParseMetricSelector(stmt)
+		if err != nil {
+			resp.failed(ctx, promBadData(err))
+			return
+		}
+
+		values, err = instance.DirectLabelValues(ctx, name, start, end, limit, matchers...)
+		if err != nil {
+			resp.failed(ctx, promExecError(ctx, err))
+			return
+		}
+		data = append(data, values...)
+	}
+
+	data = sortedUnique(data)
+	if limit > 0 && len(data) > limit {
+		data = data[:limit]
+	}
+
+	span.Set("result-num", len(data))
+	resp.success(ctx, data)
+}
+
+// HandlerPromAPIMetadata
+// @Summary  prometheus metric metadata
+// @ID       prom_api_metadata
+// @Produce  json
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param    metric                 query     string                        false  "指标名"
+// @Success  200                   	{object}  promAPIResponse
+// @Failure  400                   	{object}  promAPIResponse
+// @Router   /api/v1/metadata [get]
+func HandlerPromAPIMetadata(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &promResponse{c: c}
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-prom-api-metadata")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+
+	limit := -1
+	if s := c.Request.FormValue("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil {
+			err = errors.New("limit must be a number")
+			resp.failed(ctx, promBadData(err))
+			return
+		}
+	}
+
+	end := time.Now()
+	start := end.Add(-time.Hour)
+	metadata.GetQueryParams(ctx).SetTime(start.Unix(), end.Unix())
+
+	var names []string
+	if name := c.Request.FormValue("metric"); name != "" {
+		queryRef, refErr := promMatchersToQueryRef(ctx, name, start, end)
+		if refErr != nil {
+			err = refErr
+			resp.failed(ctx, promBadData(err))
+			return
+		}
+		names = queryReferenceLabelValues(ctx, queryRef, labels.MetricName, start, end)
+	} else {
+		queryRef, refErr := promSpaceQueryRef(ctx, start, end)
+		if refErr != nil {
+			err = refErr
+			resp.failed(ctx, promBadData(err))
+			return
+		}
+		names = queryReferenceLabelValues(ctx, queryRef, labels.MetricName, start, end)
+	}
+
+	data := promMetricsMetadata(names, limit)
+	span.Set("result-num", len(data))
+	resp.success(ctx, data)
+}
+
+// promMetricsMetadata 存储中没有记录指标类型，按照 prometheus 指标命名规范推断
+// _bucket 为 histogram，存在 _sum、_count 但没有 _bucket 的为 summary，二者均按指标族名称返回，
+// _total 为 counter，其余为 gauge
+func promMetricsMetadata(names []string, limit int) map[string][]promMetadata {
+	nameSet := set.New[string](names...)
+	families := make(map[string]string)
+	for _, name := range names {
+		switch {
+		case strings.HasSuffix(name, "_bucket"):
+			families[strings.TrimSuffix(name, "_bucket")] = promMetricTypeHistogram
+		case strings.HasSuffix(name, "_total"):
+			families[name] = promMetricTypeCounter
+		}
+	}
+	for _, name := range names {
+		for _, suffix := range []string{"_sum", "_count"} {
+			if !strings.HasSuffix(name, suffix) {
+				continue
+			}
+			family := strings.TrimSuffix(name, suffix)
+			if _, ok := families[family]; ok {
+				continue
+			}
+			// 同时存在 _sum 及 _count 时才认为是 summary，避免误判如 xxx_count 的 gauge
+			if nameSet.Existed(family+"_sum") && nameSet.Existed(family+"_count") {
+				families[family] = promMetricTypeSummary
+			}
+		}
+	}
+
+	covered := func(name string) bool {
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			family := strings.TrimSuffix(name, suffix)
+			if family == name {
+				continue
+			}
+			if typ, ok := families[family]; ok && typ != promMetricTypeCounter {
+				return true
+			}
+		}
+		return false
+	}
+	for _, name := range names {
+		if _, ok := families[name]; ok || covered(name) {
+			continue
+		}
+		families[name] = promMetricTypeGauge
+	}
+
+	familyNames := make([]string, 0, len(families))
+	for family := range families {
+		familyNames = append(familyNames, family)
+	}
+
+	data := make(map[string][]promMetadata)
+	for _, family := range sortedUnique(familyNames) {
+		if limit >= 0 && len(data) >= limit {
+			break
+		}
+		data[family] = []promMetadata{{Type: families[family]}}
+	}
+	return data
+}
+
+// promLabelValuesLimit 未指定或超过配置上限时使用配置上限
+func promLabelValuesLimit(limit int) int {
+	maxLimit := viper.GetInt(PromAPILabelValuesMaxLimitConfigPath)
+	if maxLimit > 0 && (limit <= 0 || limit > maxLimit) {
+		return maxLimit
+	}
+	return limit
+}
+
+// setQueryReferenceSize 限制 queryRef 中各查询的返回数量
+func setQueryReferenceSize(queryRef metadata.QueryReference, size int) {
+	if size <= 0 {
+		return
+	}
+	for _, queryMetric := range queryRef {
+		for _, qry := range queryMetric.QueryList {
+			qry.Size = size
+		}
+	}
+}
+
+// HandlerPromAPIQueryExemplars
+// @Summary  prometheus query exemplars
+// @ID       prom_api_query_exemplars
+// @Produce  json
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param    query                  query     string                        true   "promql"
+// @Success  200                   	{object}  promAPIResponse
+// @Failure  400                   	{object}  promAPIResponse
+// @Router   /api/v1/query_exemplars [get]
+func HandlerPromAPIQueryExemplars(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &promResponse{c: c}
+		user = metadata.GetUser(ctx)
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-prom-api-query-exemplars")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+
+	start, end, err := promTimeRange(c)
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+
+	q := c.Request.FormValue("query")
+	if _, err = parser.ParseExpr(q); err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+
+	query, err := promQLToStruct(ctx, &structured.QueryPromQL{
+		PromQL: q,
+		Start:  strconv.FormatInt(start.Unix(), 10),
+		End:    strconv.FormatInt(end.Unix(), 10),
+	})
+	if err != nil {
+		resp.failed(ctx, promBadData(err))
+		return
+	}
+	query.SpaceUid = user.SpaceUid
+
+	res, err := queryExemplar(ctx, query)
+	if err != nil {
+		resp.failed(ctx, promExecError(ctx, err))
+		return
+	}
+
+	data := make([]promExemplarData, 0)
+	if promData, ok := res.(*PromData); ok {
+		data = promDataToExemplars(promData)
+	}
+	resp.success(ctx, data)
+}
+
+// promDataToExemplars 将 exemplar 查询结果转换为 prometheus 格式
+func promDataToExemplars(promData *PromData) []promExemplarData {
+	data := make([]promExemplarData, 0, len(promData.Tables))
+	for _, table := range promData.Tables {
+		item := promExemplarData{
+			SeriesLabels: make(map[string]string, len(table.GroupKeys)+1),
+			Exemplars:    make([]promExemplar, 0, len(table.Values)),
+		}
+		if table.MetricName != "" {
+			item.SeriesLabels[labels.MetricName] = table.MetricName
+		}
+		for i, k := range table.GroupKeys {
+			if i < len(table.GroupValues) {
+				item.SeriesLabels[k] = table.GroupValues[i]
+			}
+		}
+
+		for _, row := range table.Values {
+			exemplar := promExemplar{Labels: make(map[string]string)}
+			for i, column := range table.Columns {
+				if i >= len(row) {
+					break
+				}
+				switch column {
+				case DefaultTime:
+					if ts, err := strconv.ParseFloat(fmt.Sprint(row[i]), 64); err == nil {
+						exemplar.Timestamp = ts / 1e3
+					}
+				case DefaultValue:
+					exemplar.Value = fmt.Sprint(row[i])
+				default:
+					if row[i] != nil {
+						exemplar.Labels[column] = fmt.Sprint(row[i])
+					}
+				}
+			}
+			item.Exemplars = append(item.Exemplars, exemplar)
+		}
+		data = append(data, item)
+	}
+	return data
+}
+
+// promQueryRefs 将多个 match[] 转换为 queryRef 列表，未指定时查询空间下的全部数据
+func promQueryRefs(ctx context.Context, matches []string, start, end time.Time) ([]metadata.QueryReference, error) {
+	if len(matches) == 0 {
+		queryRef, err := promSpaceQueryRef(ctx, start, end)
+		if err != nil {
+			return nil, err
+		}
+		return []metadata.QueryReference{queryRef}, nil
+	}
+
+	queryRefs := make([]metadata.QueryReference, 0, len(matches))
+	for _, match := range matches {
+		queryRef, err := promMatchersToQueryRef(ctx, match, start, end)
+		if err != nil {
+			return nil, err
+		}
+		queryRefs = append(queryRefs, queryRef)
+	}
+	return queryRefs, nil
+}
+
+func sortedUnique(list []string) []string {
+	sort.Strings(list)
+	ret := make([]string, 0, len(list))
+	for i, s := range list {
+		if i > 0 && s == list[i-1] {
+			continue
+		}
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+// registerPromAPIHandlers 注册 prometheus 兼容的 http api
+func registerPromAPIHandlers(registerHandler *RegisterHandlers, prefix string, handlers ...gin.HandlerFunc) {
+	route := func(method, p string, handler gin.HandlerFunc) {
+		handlerFuncs := make([]gin.HandlerFunc, 0, len(handlers)+1)
+		handlerFuncs = append(handlerFuncs, handlers...)
+		registerHandler.register(method, strings.TrimSuffix(prefix, "/")+p, append(handlerFuncs, handler)...)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		route(method, "/query", HandlerPromAPIQuery)
+		route(method, "/query_range", HandlerPromAPIQueryRange)
+		route(method, "/series", HandlerPromAPISeries)
+		route(method, "/labels", HandlerPromAPILabels)
+		route(method, "/query_exemplars", HandlerPromAPIQueryExemplars)
+	}
+	route(http.MethodGet, "/label/:name/values", HandlerPromAPILabelValues)
+	route(http.MethodGet, "/metadata", HandlerPromAPIMetadata)
+	route(http.MethodPost, "/read", HandlerPromAPIRemoteRead)
+}
@@ -0,0 +1,306 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb/decoder"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/promql"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/victoriaMetrics"
+)
+
+func TestPromAPIHandler(t *testing.T) {
+	mock.Init()
+	ctx := metadata.InitHashID(context.Background())
+	influxdb.MockSpaceRouter(ctx)
+	promql.MockEngine()
+
+	mock.InfluxDB.Set(map[string]any{
+		`SELECT "usage" AS _value, *::tag, "time" AS _time FROM cpu_summary WHERE time > 1729607844123000000 and time < 1729608144123000000 AND (bk_biz_id='2') LIMIT 100000005 SLIMIT 100005 TZ('UTC')`: &decoder.Response{
+			Results: []decoder.Result{
+				{
+					Series: []*decoder.Row{
+						{
+							Columns: []string{
+								influxdb.ResultColumnName,
+								influxdb.TimeColumnName,
+							},
+							Values: [][]any{
+								{5, 1729608100000000000},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	start := time.Unix(1729859485, 0)
+	end := time.Unix(1729863085, 0)
+
+	mock.Vm.Set(map[string]any{
+		`query_range:17296020001729605600600count by (bcs_cluster_id) (a)`: victoriaMetrics.Data{
+			ResultType: victoriaMetrics.MatrixType,
+			Result: []victoriaMetrics.Series{
+				{
+					Metric: map[string]string{
+						"bcs_cluster_id": "BCS-K8S-00000",
+					},
+					Values: []victoriaMetrics.Value{
+						{1729602000, "2042"},
+						{1729602600, "2056"},
+					},
+				},
+			},
+		},
+		`query:1729608144sum by (bcs_cluster_id) (a)`: victoriaMetrics.Data{
+			ResultType: victoriaMetrics.VectorType,
+			Result: []victoriaMetrics.Series{
+				{
+					Metric: map[string]string{
+						"bcs_cluster_id": "BCS-K8S-00000",
+					},
+					Value: victoriaMetrics.Value{
+						1729608144, "1172",
+					},
+				},
+			},
+		},
+		`label_values:17298594851729863085container{bcs_cluster_id="BCS-K8S-00000", namespace="kube-system", result_table_id="2_bcs_prom_computation_result_table", __name__="container_cpu_usage_seconds_total_value"}`: []string{
+			"kube-proxy",
+			"POD",
+		},
+	})
+
+	testCases := map[string]struct {
+		handler func(c *gin.Context)
+		method  string
+		path    string
+		params  gin.Params
+		values  url.Values
+
+		code     int
+		expected string
+	}{
+		"query_range": {
+			handler: HandlerPromAPIQueryRange,
+			method:  http.MethodGet,
+			values: url.Values{
+				"query": []string{`count(container_cpu_usage_seconds_total) by (bcs_cluster_id)`},
+				"start": []string{"1729602000"},
+				"end":   []string{"1729605600"},
+				"step":  []string{"600"},
+			},
+			code:     http.StatusOK,
+			expected: `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"bcs_cluster_id":"BCS-K8S-00000"},"values":[[1729602000,"2042"],[1729602600,"2056"]]}]}}`,
+		},
+		"query_range with rfc3339 and duration step": {
+			handler: HandlerPromAPIQueryRange,
+			method:  http.MethodPost,
+			values: url.Values{
+				"query": []string{`count(container_cpu_usage_seconds_total) by (bcs_cluster_id)`},
+				"start": []string{"2024-10-22T13:00:00Z"},
+				"end":   []string{"2024-10-22T14:00:00Z"},
+				"step":  []string{"10m"},
+			},
+			code:     http.StatusOK,
+			expected: `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"bcs_cluster_id":"BCS-K8S-00000"},"values":[[1729602000,"2042"],[1729602600,"2056"]]}]}}`,
+		},
+		"query": {
+			handler: HandlerPromAPIQuery,
+			method:  http.MethodGet,
+			values: url.Values{
+				"query": []string{`sum(kube_pod_info) by (bcs_cluster_id)`},
+				"time":  []string{"1729608144.123"},
+			},
+			code:     http.StatusOK,
+			expected: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"bcs_cluster_id":"BCS-K8S-00000"},"value":[1729608144,"1172"]}]}}`,
+		},
+		"query with float time on prometheus engine": {
+			handler: HandlerPromAPIQuery,
+			method:  http.MethodGet,
+			values: url.Values{
+				"query": []string{`sum(system:cpu_summary:usage)`},
+				"time":  []string{"1729608144.123"},
+			},
+			code:     http.StatusOK,
+			expected: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1729608144.123,"5"]}]}}`,
+		},
+		"query with bad promql": {
+			handler: HandlerPromAPIQuery,
+			method:  http.MethodGet,
+			values: url.Values{
+				"query": []string{`sum(kube_pod_info`},
+			},
+			code:     http.StatusBadRequest,
+			expected: `{"status":"error","errorType":"bad_data","error":"1:18: parse error: unclosed left parenthesis"}`,
+		},
+		"query_range with negative step": {
+			handler: HandlerPromAPIQueryRange,
+			method:  http.MethodGet,
+			values: url.Values{
+				"query": []string{`kube_pod_info`},
+				"start": []string{"1729602000"},
+				"end":   []string{"1729605600"},
+				"step":  []string{"-1"},
+			},
+			code:     http.StatusBadRequest,
+			expected: `{"status":"error","errorType":"bad_data","error":"zero or negative query resolution step widths are not accepted. Try a positive integer"}`,
+		},
+		"query_range with too many points": {
+			handler: HandlerPromAPIQueryRange,
+			method:  http.MethodGet,
+			values: url.Values{
+				"query": []string{`kube_pod_info`},
+				"start": []string{"0"},
+				"end":   []string{"1729605600"},
+				"step":  []string{"1"},
+			},
+			code:     http.StatusBadRequest,
+			expected: `{"status":"error","errorType":"bad_data","error":"exceeded maximum resolution of 11,000 points per timeseries. Try decreasing the query resolution (?step=XX)"}`,
+		},
+		"series without match": {
+			handler:  HandlerPromAPISeries,
+			method:   http.MethodGet,
+			values:   url.Values{},
+			code:     http.StatusBadRequest,
+			expected: `{"status":"error","errorType":"bad_data","error":"no match[] parameter provided"}`,
+		},
+		"label values": {
+			handler: HandlerPromAPILabelValues,
+			method:  http.MethodGet,
+			params:  gin.Params{{Key: "name", Value: "container"}},
+			values: url.Values{
+				"match[]": []string{`container_cpu_usage_seconds_total{bcs_cluster_id="BCS-K8S-00000", namespace="kube-system"}`},
+				"start":   []string{fmt.Sprintf("%d", start.Unix())},
+				"end":     []string{fmt.Sprintf("%d", end.Unix())},
+			},
+			code:     http.StatusOK,
+			expected: `{"status":"success","data":["POD","kube-proxy"]}`,
+		},
+		"label values with invalid name": {
+			handler:  HandlerPromAPILabelValues,
+			method:   http.MethodGet,
+			params:   gin.Params{{Key: "name", Value: "a-b"}},
+			values:   url.Values{},
+			code:     http.StatusBadRequest,
+			expected: `{"status":"error","errorType":"bad_data","error":"invalid label name: \"a-b\""}`,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx = metadata.InitHashID(ctx)
+			metadata.SetUser(ctx, "", influxdb.SpaceUid, "")
+
+			var (
+				req *http.Request
+				u   = "http://127.0.0.1/api/v1/"
+			)
+			if c.method == http.MethodPost {
+				req, _ = http.NewRequestWithContext(ctx, c.method, u, strings.NewReader(c.values.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req, _ = http.NewRequestWithContext(ctx, c.method, u+"?"+c.values.Encode(), nil)
+			}
+
+			w := &Writer{}
+			ginC := &gin.Context{
+				Request: req,
+				Writer:  w,
+				Params:  c.params,
+			}
+			c.handler(ginC)
+			assert.Equal(t, []string{fmt.Sprintf("%d", c.code)}, w.Header()["code"])
+			assert.Equal(t, c.expected, w.body())
+		})
+	}
+}
+
+func TestParsePromTime(t *testing.T) {
+	for s, expected := range map[string]time.Time{
+		"1729608144":           time.Unix(1729608144, 0),
+		"1729608144.5":         time.Unix(1729608144, 5e8),
+		"2024-10-22T14:42:24Z": time.Unix(1729608144, 0),
+	} {
+		ts, err := parsePromTime(s, time.Time{})
+		assert.NoError(t, err)
+		assert.True(t, expected.Equal(ts), s)
+	}
+
+	_, err := parsePromTime("abc", time.Time{})
+	assert.Error(t, err)
+
+	d, err := parsePromDuration("1.5")
+	assert.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, d)
+
+	d, err = parsePromDuration("5m")
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, d)
+}
+
+func TestPromMetricsMetadata(t *testing.T) {
+	names := []string{
+		"http_requests_total",
+		"http_request_duration_seconds_bucket",
+		"http_request_duration_seconds_count",
+		"http_request_duration_seconds_sum",
+		"rpc_duration_seconds",
+		"rpc_duration_seconds_count",
+		"rpc_duration_seconds_sum",
+		"queue_count",
+		"up",
+	}
+
+	data := promMetricsMetadata(names, -1)
+	assert.Equal(t, map[string][]promMetadata{
+		"http_requests_total":           {{Type: promMetricTypeCounter}},
+		"http_request_duration_seconds": {{Type: promMetricTypeHistogram}},
+		"rpc_duration_seconds":          {{Type: promMetricTypeSummary}},
+		"queue_count":                   {{Type: promMetricTypeGauge}},
+		"up":                            {{Type: promMetricTypeGauge}},
+	}, data)
+
+	data = promMetricsMetadata(names, 2)
+	assert.Len(t, data, 2)
+}
+
+func TestPromLabelValuesLimit(t *testing.T) {
+	maxLimit := viper.GetInt(PromAPILabelValuesMaxLimitConfigPath)
+	defer viper.Set(PromAPILabelValuesMaxLimitConfigPath, maxLimit)
+
+	viper.Set(PromAPILabelValuesMaxLimitConfigPath, 100)
+	assert.Equal(t, 100, promLabelValuesLimit(0))
+	assert.Equal(t, 10, promLabelValuesLimit(10))
+	assert.Equal(t, 100, promLabelValuesLimit(1000))
+
+	queryRef := metadata.QueryReference{
+		"a": {QueryList: metadata.QueryList{{}, {}}},
+	}
+	setQueryReferenceSize(queryRef, promLabelValuesLimit(0))
+	for _, qry := range queryRef["a"].QueryList {
+		assert.Equal(t, 100, qry.Size)
+	}
+}
@@ -84,9 +84,9 @@ func (d *PromData) Fill(tables *promql.Tables) error {
 }
 
 // Downsample 对结果数据进行降采样
-func (d *PromData) Downsample(factor float64) {
+func (d *PromData) Downsample(method string, factor float64, interval int64) {
 	for _, table := range d.Tables {
-		points := downsample.Downsample(table.GetPromPoints(), factor)
+		points := downsample.DownsampleByMethod(method, table.GetPromPoints(), factor, interval)
 		table.SetValuesByPoints(points)
 	}
 }
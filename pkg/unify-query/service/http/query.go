@@ -402,7 +402,7 @@ func queryTsToInstanceAndStmt(ctx context.Context, query *structured.QueryTs) (i
 		return
 	}
 
-	if metadata.GetQueryParams(ctx).IsDirectQuery() {
+	if isDirectQuery(ctx, query) {
 		// 判断是否是直查
 		vmExpand := queryRef.ToVmExpand(ctx)
 		metadata.SetExpand(ctx, vmExpand)
@@ -443,6 +443,35 @@ func queryTsToInstanceAndStmt(ctx context.Context, query *structured.QueryTs) (i
 	return
 }
 
+// isDirectQuery 判断是否使用 vm 直查，扩展函数只能由内置引擎计算，vm 无法识别，
+// 使用扩展函数时改为内置引擎查询，原始数据由各存储实例提供
+func isDirectQuery(ctx context.Context, query *structured.QueryTs) bool {
+	if !metadata.GetQueryParams(ctx).IsDirectQuery() {
+		return false
+	}
+
+	expr, err := query.ToPromExpr(ctx, &structured.PromExprOption{})
+	if err != nil {
+		return true
+	}
+	if name := engineFunctionName(expr); name != "" {
+		log.Infof(ctx, "function %s is not supported by direct query, use prom engine instead", name)
+		return false
+	}
+	return true
+}
+
+// engineFunctionName 获取表达式中使用的第一个扩展函数名
+func engineFunctionName(expr parser.Expr) (name string) {
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if call, ok := node.(*parser.Call); ok && name == "" && promql.IsEngineFunction(call.Func.Name) {
+			name = call.Func.Name
+		}
+		return nil
+	})
+	return
+}
+
 func queryTsWithPromEngine(ctx context.Context, query *structured.QueryTs) (any, error) {
 	var (
 		err error
@@ -460,6 +489,11 @@ func queryTsWithPromEngine(ctx context.Context, query *structured.QueryTs) (any,
 		span.End(&err)
 	}()
 
+	if err = downsample.CheckMethod(query.DownSampleMethod); err != nil {
+		return nil, err
+	}
+	pushDownSample(query)
+
 	start, end, step, timezone, err := structured.ToTime(query.Start, query.End, query.Step, query.Timezone)
 	if err != nil {
 		return nil, err
@@ -468,6 +502,9 @@ func queryTsWithPromEngine(ctx context.Context, query *structured.QueryTs) (any,
 
 	// 写入查询时间到全局缓存
 	metadata.GetQueryParams(ctx).SetTime(start.Unix(), end.Unix())
+	if err = checkQueryCost(ctx, query, start, end, step); err != nil {
+		return nil, err
+	}
 	instance, stmt, err = queryTsToInstanceAndStmt(ctx, query)
 	if err != nil {
 		return nil, err
@@ -478,7 +515,7 @@ func queryTsWithPromEngine(ctx context.Context, query *structured.QueryTs) (any,
 	if query.Instant {
 		res, err = instance.DirectQuery(ctx, stmt, end)
 	} else {
-		res, err = instance.DirectQueryRange(ctx, stmt, start, end, step)
+		res, err = queryRangeWithCache(ctx, query, instance, stmt, start, end, step)
 	}
 	if err != nil {
 		return nil, err
@@ -526,13 +563,42 @@ func queryTsWithPromEngine(ctx context.Context, query *structured.QueryTs) (any,
 
 	if ok, factor, downSampleError := downsample.CheckDownSampleRange(step.String(), query.DownSampleRange); ok {
 		if downSampleError == nil {
-			resp.Downsample(factor)
+			interval, _ := model.ParseDuration(query.DownSampleRange)
+			resp.Downsample(query.DownSampleMethod, factor, time.Duration(interval).Milliseconds())
 		}
 	}
 
 	return resp, err
 }
 
+// pushDownSample avg 降采样下推到存储：没有时间聚合的查询改写为 avg_over_time，窗口和步长都使用降采样周期，
+// 结果的时间戳为窗口的结束时间，与内存 avg 降采样的口径一致；
+// vm 直接按 rollup 计算，influxdb 在外层聚合为 avg/mean 时转为 GROUP BY time 计算；
+// m4、minmax 需要保留极值点的原始时间戳，无法下推，只在返回前进行内存降采样
+func pushDownSample(query *structured.QueryTs) {
+	if query.DownSampleMethod != downsample.MethodAvg || query.Instant {
+		return
+	}
+
+	if ok, _, err := downsample.CheckDownSampleRange(query.Step, query.DownSampleRange); !ok || err != nil {
+		return
+	}
+
+	for _, q := range query.QueryList {
+		if q.TimeAggregation.Function != "" {
+			return
+		}
+	}
+
+	for _, q := range query.QueryList {
+		q.TimeAggregation = structured.TimeAggregation{
+			Function: structured.AvgOT,
+			Window:   structured.Window(query.DownSampleRange),
+		}
+	}
+	query.Step = query.DownSampleRange
+}
+
 func structToPromQL(ctx context.Context, query *structured.QueryTs) (*structured.QueryPromQL, error) {
 	if query == nil {
 		return nil, nil
@@ -570,6 +636,7 @@ func promQLToStruct(ctx context.Context, queryPromQL *structured.QueryPromQL) (*
 	query.LookBackDelta = queryPromQL.LookBackDelta
 	query.Instant = queryPromQL.Instant
 	query.DownSampleRange = queryPromQL.DownSampleRange
+	query.DownSampleMethod = queryPromQL.DownSampleMethod
 
 	// 补充业务ID
 	if len(queryPromQL.BKBizIDs) > 0 {
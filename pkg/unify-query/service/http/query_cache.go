@@ -0,0 +1,143 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	promPromql "github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/memcache"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/frontend"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
+)
+
+// queryCache 区间查询结果缓存，未开启时为 nil
+var queryCache *frontend.ResultsCache
+
+// setQueryCache 根据配置初始化区间查询结果缓存
+func setQueryCache() {
+	if !viper.GetBool(QueryCacheEnableConfigPath) {
+		queryCache = nil
+		return
+	}
+
+	var store frontend.Store
+	switch viper.GetString(QueryCacheStoreConfigPath) {
+	case frontend.StoreRedis:
+		store = frontend.NewRedisStore(viper.GetString(QueryCacheRedisPrefixConfigPath))
+	default:
+		cache, err := memcache.NewRistretto()
+		if err != nil {
+			log.Errorf(context.TODO(), "new query cache error: %s", err)
+			queryCache = nil
+			return
+		}
+		store = frontend.NewMemoryStore(cache)
+	}
+
+	queryCache = frontend.NewResultsCache(store, frontend.Options{
+		SplitInterval: viper.GetDuration(QueryCacheSplitIntervalConfigPath),
+		MaxFreshness:  viper.GetDuration(QueryCacheMaxFreshnessConfigPath),
+		TTL:           viper.GetDuration(QueryCacheTTLConfigPath),
+	})
+}
+
+// queryCacheKey 生成缓存 key，包含空间、存储类型、查询语句以及去掉起止时间后的查询结构体
+func queryCacheKey(ctx context.Context, query *structured.QueryTs, instance tsdb.Instance, stmt string) (string, error) {
+	qry := *query
+	qry.Start = ""
+	qry.End = ""
+
+	body, err := json.Marshal(qry)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum([]byte(fmt.Sprintf(
+		"%s\n%s\n%s\n%s", metadata.GetUser(ctx).SpaceUid, instance.InstanceType(), stmt, body,
+	)))
+	return fmt.Sprintf("%x", sum), nil
+}
+
+// queryCacheable 判断查询是否可以切分缓存，使用了 @ start() / @ end() 的查询结果依赖查询的起止时间，不能切分
+func queryCacheable(query *structured.QueryTs, stmt string) bool {
+	for _, q := range query.QueryList {
+		if q.StartOrEnd != 0 {
+			return false
+		}
+	}
+
+	expr, err := parser.ParseExpr(stmt)
+	if err != nil {
+		return false
+	}
+
+	cacheable := true
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			if n.StartOrEnd != 0 {
+				cacheable = false
+			}
+		case *parser.SubqueryExpr:
+			if n.StartOrEnd != 0 {
+				cacheable = false
+			}
+		}
+		return nil
+	})
+	return cacheable
+}
+
+// queryRangeWithCache 区间查询，开启缓存时按照时间块切分，只回源查询未缓存的部分
+func queryRangeWithCache(
+	ctx context.Context, query *structured.QueryTs, instance tsdb.Instance, stmt string,
+	start, end time.Time, step time.Duration,
+) (matrix promPromql.Matrix, err error) {
+	cache := queryCache
+	if cache == nil || !queryCacheable(query, stmt) {
+		return instance.DirectQueryRange(ctx, stmt, start, end, step)
+	}
+
+	ctx, span := trace.NewSpan(ctx, "query-range-with-cache")
+	defer span.End(&err)
+
+	key, err := queryCacheKey(ctx, query, instance, stmt)
+	if err != nil {
+		return nil, err
+	}
+	span.Set("cache-key", key)
+
+	matrix, res, err := cache.QueryRange(ctx, key, start, end, step,
+		func(ctx context.Context, start, end time.Time) (promPromql.Matrix, error) {
+			return instance.DirectQueryRange(ctx, stmt, start, end, step)
+		},
+	)
+
+	span.Set("cache-hit", res.Hit)
+	span.Set("cache-miss", res.Miss)
+
+	spaceUid := metadata.GetUser(ctx).SpaceUid
+	metric.QueryCacheRequestAdd(ctx, res.Hit, spaceUid, "hit")
+	metric.QueryCacheRequestAdd(ctx, res.Miss, spaceUid, "miss")
+	return matrix, err
+}
@@ -0,0 +1,59 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+func TestQueryCacheable(t *testing.T) {
+	testCases := map[string]struct {
+		query     *structured.QueryTs
+		stmt      string
+		cacheable bool
+	}{
+		"reference": {
+			query:     &structured.QueryTs{QueryList: []*structured.Query{{ReferenceName: "a"}}},
+			stmt:      `sum(rate(a[1m] offset 1d))`,
+			cacheable: true,
+		},
+		"query at start": {
+			query:     &structured.QueryTs{QueryList: []*structured.Query{{ReferenceName: "a", StartOrEnd: parser.START}}},
+			stmt:      `a`,
+			cacheable: false,
+		},
+		"promql at end": {
+			query:     &structured.QueryTs{},
+			stmt:      `sum(rate(metric[1m] @ end()))`,
+			cacheable: false,
+		},
+		"subquery at start": {
+			query:     &structured.QueryTs{},
+			stmt:      `max_over_time(rate(metric[1m])[10m:1m] @ start())`,
+			cacheable: false,
+		},
+		"invalid promql": {
+			query:     &structured.QueryTs{},
+			stmt:      `sum(`,
+			cacheable: false,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.cacheable, queryCacheable(c.query, c.stmt))
+		})
+	}
+}
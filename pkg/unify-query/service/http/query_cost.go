@@ -0,0 +1,159 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+)
+
+const (
+	QueryCostReasonRange  = "range"
+	QueryCostReasonSeries = "series"
+	QueryCostReasonPoints = "points"
+)
+
+// QueryCost 查询开销预估
+type QueryCost struct {
+	Series int           `json:"series"`
+	Points int           `json:"points"`
+	Range  time.Duration `json:"range"`
+	Step   time.Duration `json:"step"`
+}
+
+// QueryCostError 查询开销超过限制
+type QueryCostError struct {
+	Reason string                  `json:"reason"`
+	Cost   QueryCost               `json:"cost"`
+	Limit  metadata.QueryCostLimit `json:"limit"`
+}
+
+func (e *QueryCostError) Error() string {
+	switch e.Reason {
+	case QueryCostReasonRange:
+		return fmt.Sprintf("query cost exceeded: range %s is greater than limit %s", e.Cost.Range, e.Limit.MaxRange)
+	case QueryCostReasonSeries:
+		return fmt.Sprintf("query cost exceeded: series %d is greater than limit %d", e.Cost.Series, e.Limit.MaxSeries)
+	default:
+		return fmt.Sprintf("query cost exceeded: points %d is greater than limit %d", e.Cost.Points, e.Limit.MaxPoints)
+	}
+}
+
+// QueryCostErrResponse 查询开销超过限制时的返回
+type QueryCostErrResponse struct {
+	Err string `json:"error"`
+	*QueryCostError
+}
+
+// queryCostLimit 读取当前空间以及用户的查询开销限制
+func queryCostLimit(ctx context.Context) metadata.QueryCostLimit {
+	return metadata.GetQueryCostLimitFeatureFlag(ctx, metadata.QueryCostLimit{
+		MaxSeries: viper.GetInt(QueryCostMaxSeriesConfigPath),
+		MaxPoints: viper.GetInt(QueryCostMaxPointsConfigPath),
+		MaxRange:  viper.GetDuration(QueryCostMaxRangeConfigPath),
+	})
+}
+
+// checkQueryCost 根据时间范围、步长以及 series 数量预估查询开销，超过空间或用户的限制时拒绝查询
+func checkQueryCost(ctx context.Context, query *structured.QueryTs, start, end time.Time, step time.Duration) (err error) {
+	if !viper.GetBool(QueryCostEnableConfigPath) {
+		return nil
+	}
+
+	ctx, span := trace.NewSpan(ctx, "check-query-cost")
+	defer span.End(&err)
+
+	var (
+		user  = metadata.GetUser(ctx)
+		limit = queryCostLimit(ctx)
+		cost  = QueryCost{Step: step}
+		steps = 1
+	)
+
+	if query.Instant {
+		// 瞬时查询只需要预估回溯窗口内的 series
+		start = end.Add(-queryCostInstantLookBack(query))
+	} else {
+		cost.Range = end.Sub(start)
+		if step > 0 {
+			steps = int(cost.Range/step) + 1
+		}
+	}
+
+	reject := func(reason string) error {
+		metric.QueryCostRejectedInc(ctx, user.SpaceUid, user.Source, reason)
+		return &QueryCostError{Reason: reason, Cost: cost, Limit: limit}
+	}
+
+	defer func() {
+		span.Set("query-cost", cost)
+		span.Set("query-cost-limit", limit)
+	}()
+
+	if limit.MaxRange > 0 && cost.Range > limit.MaxRange {
+		return reject(QueryCostReasonRange)
+	}
+
+	// series 预估需要查询存储，只有配置了相关限制才进行
+	if limit.MaxSeries <= 0 && limit.MaxPoints <= 0 {
+		return nil
+	}
+
+	queryRef, err := query.ToQueryReference(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 只需要判断是否超过限制，series 最多查询到超限的第一条即可
+	series, err := queryReferenceSeries(ctx, queryRef, start, end, queryCostSeriesLimit(limit, steps))
+	if err != nil {
+		return err
+	}
+	cost.Series = len(series)
+	cost.Points = cost.Series * steps
+
+	if limit.MaxSeries > 0 && cost.Series > limit.MaxSeries {
+		return reject(QueryCostReasonSeries)
+	}
+	if limit.MaxPoints > 0 && cost.Points > limit.MaxPoints {
+		return reject(QueryCostReasonPoints)
+	}
+	return nil
+}
+
+// queryCostSeriesLimit 预估 series 时的查询上限，为 series 及 points 限制换算后较小值加一
+func queryCostSeriesLimit(limit metadata.QueryCostLimit, steps int) int {
+	seriesLimit := limit.MaxSeries
+	if limit.MaxPoints > 0 && steps > 0 {
+		pointsLimit := limit.MaxPoints / steps
+		if seriesLimit <= 0 || pointsLimit < seriesLimit {
+			seriesLimit = pointsLimit
+		}
+	}
+	return seriesLimit + 1
+}
+
+// queryCostInstantLookBack 瞬时查询的回溯窗口，未指定时与 prometheus 默认值一致
+func queryCostInstantLookBack(query *structured.QueryTs) time.Duration {
+	if query.LookBackDelta != "" {
+		if d, err := time.ParseDuration(query.LookBackDelta); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 5 * time.Minute
+}
@@ -0,0 +1,208 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/featureFlag"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+func TestCheckQueryCost(t *testing.T) {
+	mock.Init()
+	ctx := metadata.InitHashID(context.Background())
+	influxdb.MockSpaceRouter(ctx)
+
+	viper.Set(QueryCostEnableConfigPath, true)
+	viper.Set(QueryCostMaxRangeConfigPath, "24h")
+	viper.Set(QueryCostMaxSeriesConfigPath, 0)
+	viper.Set(QueryCostMaxPointsConfigPath, 0)
+	defer viper.Set(QueryCostEnableConfigPath, false)
+
+	// 指定空间放宽时间范围限制并且限制 series 数量
+	err := featureFlag.MockFeatureFlag(ctx, fmt.Sprintf(`{
+		"must-vm-query": {
+			"variations": {"true": true, "false": false},
+			"defaultRule": {"variation": "true"}
+		},
+		"query-max-range": {
+			"variations": {"default": 86400, "large": 604800},
+			"targeting": [{
+				"query": "spaceUid eq \"%s\"",
+				"percentage": {"large": 100, "default": 0}
+			}],
+			"defaultRule": {"variation": "default"}
+		},
+		"query-max-series": {
+			"variations": {"default": 0, "small": 1},
+			"targeting": [{
+				"query": "spaceUid eq \"%s\"",
+				"percentage": {"small": 100, "default": 0}
+			}],
+			"defaultRule": {"variation": "default"}
+		}
+	}`, influxdb.SpaceUid, influxdb.SpaceUid))
+	assert.NoError(t, err)
+
+	end := time.Unix(1729863085, 0)
+	mock.Vm.Set(map[string]any{
+		fmt.Sprintf(`series:%d%d{result_table_id="2_bcs_prom_computation_result_table", __name__="container_cpu_usage_seconds_total_value"}`, end.Add(-time.Hour).Unix(), end.Unix()): []map[string]string{
+			{"__name__": "container_cpu_usage_seconds_total_value", "pod": "a"},
+			{"__name__": "container_cpu_usage_seconds_total_value", "pod": "b"},
+		},
+	})
+
+	testCases := map[string]struct {
+		spaceUid string
+		start    time.Time
+		reason   string
+	}{
+		"default range limit": {
+			spaceUid: "other_space",
+			start:    end.Add(-48 * time.Hour),
+			reason:   QueryCostReasonRange,
+		},
+		"space range limit": {
+			spaceUid: influxdb.SpaceUid,
+			start:    end.Add(-8 * 24 * time.Hour),
+			reason:   QueryCostReasonRange,
+		},
+		"space series limit": {
+			spaceUid: influxdb.SpaceUid,
+			start:    end.Add(-time.Hour),
+			reason:   QueryCostReasonSeries,
+		},
+		"series query failed": {
+			spaceUid: influxdb.SpaceUid,
+			start:    end.Add(-2 * time.Hour),
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := metadata.InitHashID(ctx)
+			metadata.SetUser(ctx, "username:test", c.spaceUid, "")
+
+			query, err := promQLToStruct(ctx, &structured.QueryPromQL{
+				PromQL: `container_cpu_usage_seconds_total`,
+				Start:  fmt.Sprintf("%d", c.start.Unix()),
+				End:    fmt.Sprintf("%d", end.Unix()),
+				Step:   "1m",
+			})
+			assert.NoError(t, err)
+
+			err = checkQueryCost(ctx, query, c.start, end, time.Minute)
+			var costErr *QueryCostError
+			if c.reason == "" {
+				// 预估 series 失败时不能放行查询
+				assert.Error(t, err)
+				assert.False(t, errors.As(err, &costErr))
+				return
+			}
+
+			assert.True(t, errors.As(err, &costErr), fmt.Sprintf("%v", err))
+			if costErr != nil {
+				assert.Equal(t, c.reason, costErr.Reason)
+			}
+		})
+	}
+}
+
+func TestQueryCostSeriesLimit(t *testing.T) {
+	for name, c := range map[string]struct {
+		limit    metadata.QueryCostLimit
+		steps    int
+		expected int
+	}{
+		"series only": {
+			limit:    metadata.QueryCostLimit{MaxSeries: 100},
+			steps:    61,
+			expected: 101,
+		},
+		"points only": {
+			limit:    metadata.QueryCostLimit{MaxPoints: 6100},
+			steps:    61,
+			expected: 101,
+		},
+		"points is smaller": {
+			limit:    metadata.QueryCostLimit{MaxSeries: 1000, MaxPoints: 610},
+			steps:    61,
+			expected: 11,
+		},
+		"series is smaller": {
+			limit:    metadata.QueryCostLimit{MaxSeries: 5, MaxPoints: 6100},
+			steps:    61,
+			expected: 6,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.expected, queryCostSeriesLimit(c.limit, c.steps))
+		})
+	}
+}
+
+func TestHandlerQueryTsCostLimit(t *testing.T) {
+	mock.Init()
+	ctx := metadata.InitHashID(context.Background())
+	influxdb.MockSpaceRouter(ctx)
+
+	viper.Set(QueryCostEnableConfigPath, true)
+	viper.Set(QueryCostMaxRangeConfigPath, "1h")
+	defer func() {
+		viper.Set(QueryCostEnableConfigPath, false)
+		viper.Set(QueryCostMaxRangeConfigPath, "0s")
+	}()
+	assert.NoError(t, featureFlag.MockFeatureFlag(ctx, `{
+		"must-vm-query": {
+			"variations": {"true": true, "false": false},
+			"defaultRule": {"variation": "true"}
+		}
+	}`))
+
+	metadata.SetUser(ctx, "username:test", influxdb.SpaceUid, "")
+	body := `{"query_list":[{"field_name":"container_cpu_usage_seconds_total","reference_name":"a"}],"metric_merge":"a","start_time":"1729602000","end_time":"1729609200","step":"1m"}`
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://127.0.0.1/query/ts", strings.NewReader(body))
+
+	w := &Writer{}
+	HandlerQueryTs(&gin.Context{Request: req, Writer: w})
+
+	assert.Equal(t, []string{fmt.Sprintf("%d", http.StatusUnprocessableEntity)}, w.Header()["code"])
+	assert.Equal(t, `{"error":"query cost exceeded: range 2h0m0s is greater than limit 1h0m0s","reason":"range","cost":{"series":0,"points":0,"range":7200000000000,"step":60000000000},"limit":{"max_series":0,"max_points":0,"max_range":3600000000000}}`, w.body())
+
+	// prometheus 兼容接口同样返回具体原因
+	values := url.Values{
+		"query": []string{`container_cpu_usage_seconds_total`},
+		"start": []string{"1729602000"},
+		"end":   []string{"1729609200"},
+		"step":  []string{"60"},
+	}
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, "http://127.0.0.1/api/v1/query_range?"+values.Encode(), nil)
+
+	w = &Writer{}
+	HandlerPromAPIQueryRange(&gin.Context{Request: req, Writer: w})
+
+	assert.Equal(t, []string{fmt.Sprintf("%d", http.StatusUnprocessableEntity)}, w.Header()["code"])
+	assert.Equal(t, `{"status":"error","data":{"reason":"range","cost":{"series":0,"points":0,"range":7200000000000,"step":60000000000},"limit":{"max_series":0,"max_points":0,"max_range":3600000000000}},"errorType":"execution","error":"query cost exceeded: range 2h0m0s is greater than limit 1h0m0s"}`, w.body())
+}
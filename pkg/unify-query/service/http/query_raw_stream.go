@@ -0,0 +1,398 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/elasticsearch"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/prometheus"
+)
+
+const (
+	// RawStreamContentType 流式导出的返回格式，每行一个 json
+	RawStreamContentType = "application/x-ndjson"
+
+	// 流式导出的控制行字段，每页数据之后返回 __scroll_id，最后一行额外返回 __total 以及 __error
+	RawStreamKeyScrollID = "__scroll_id"
+	RawStreamKeyTotal    = "__total"
+	RawStreamKeyError    = "__error"
+)
+
+// rawStreamCursor 流式导出的游标，记录当前查询的路由以及该路由已经导出的位置
+type rawStreamCursor struct {
+	Route       string `json:"route"`
+	From        int    `json:"from,omitempty"`
+	SearchAfter []any  `json:"search_after,omitempty"`
+}
+
+// encodeScrollID 游标编码为 scroll_id
+func encodeScrollID(cursor *rawStreamCursor) string {
+	s, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(s)
+}
+
+// decodeScrollID 解析 scroll_id，排序值使用 json.Number 避免大整数精度丢失
+func decodeScrollID(scrollID string) (*rawStreamCursor, error) {
+	if scrollID == "" {
+		return nil, nil
+	}
+
+	s, err := base64.RawURLEncoding.DecodeString(scrollID)
+	if err != nil {
+		return nil, fmt.Errorf("scroll_id is invalid: %s", err)
+	}
+
+	cursor := &rawStreamCursor{}
+	dec := json.NewDecoder(bytes.NewReader(s))
+	dec.UseNumber()
+	if err = dec.Decode(cursor); err != nil {
+		return nil, fmt.Errorf("scroll_id is invalid: %s", err)
+	}
+	if cursor.Route == "" {
+		return nil, fmt.Errorf("scroll_id is invalid: route is empty")
+	}
+	return cursor, nil
+}
+
+// rawStreamRoute 流式导出的单个查询路由
+type rawStreamRoute struct {
+	key string
+	qry *metadata.Query
+}
+
+// rawStreamRoutes 解析所有查询路由，路由顺序固定，用于 scroll_id 续传定位
+func rawStreamRoutes(ctx context.Context, queryTs *structured.QueryTs) ([]*rawStreamRoute, error) {
+	routes := make([]*rawStreamRoute, 0)
+	for idx, ql := range queryTs.QueryList {
+		// 时间复用
+		ql.Timezone = queryTs.Timezone
+		ql.Start = queryTs.Start
+		ql.End = queryTs.End
+
+		// 排序复用
+		ql.OrderBy = queryTs.OrderBy
+
+		// 如果 qry.Step 不存在去外部统一的 step
+		if ql.Step == "" {
+			ql.Step = queryTs.Step
+		}
+
+		qm, err := ql.ToQueryMetric(ctx, queryTs.SpaceUid)
+		if err != nil {
+			return nil, err
+		}
+
+		list := make([]*metadata.Query, len(qm.QueryList))
+		copy(list, qm.QueryList)
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].TableID != list[j].TableID {
+				return list[i].TableID < list[j].TableID
+			}
+			return list[i].StorageID < list[j].StorageID
+		})
+
+		for j, qry := range list {
+			routes = append(routes, &rawStreamRoute{
+				key: fmt.Sprintf("%d:%d:%s", idx, j, qry.TableID),
+				qry: qry,
+			})
+		}
+	}
+	return routes, nil
+}
+
+// rawStreamPageable 支持翻页的存储类型，es 使用 search_after，其余使用 offset
+func rawStreamPageable(instanceType string) bool {
+	switch instanceType {
+	case consul.ElasticsearchStorageType, consul.BkSqlStorageType, consul.ClickHouseStorageType:
+		return true
+	default:
+		return false
+	}
+}
+
+// rawStreamer 按路由顺序逐页查询原始数据，边查询边写入，避免全量数据驻留内存
+type rawStreamer struct {
+	enc   *json.Encoder
+	flush func()
+
+	start    time.Time
+	end      time.Time
+	pageSize int
+
+	total    int64
+	scrollID string
+}
+
+func (s *rawStreamer) run(ctx context.Context, routes []*rawStreamRoute, cursor *rawStreamCursor) error {
+	for _, route := range routes {
+		if cursor != nil && cursor.Route != route.key {
+			continue
+		}
+
+		if err := s.queryRoute(ctx, route, cursor); err != nil {
+			s.finish(err)
+			return err
+		}
+		cursor = nil
+	}
+
+	s.scrollID = ""
+	s.finish(nil)
+	return nil
+}
+
+func (s *rawStreamer) queryRoute(ctx context.Context, route *rawStreamRoute, cursor *rawStreamCursor) error {
+	if cursor == nil {
+		cursor = &rawStreamCursor{Route: route.key}
+	}
+
+	instance := prometheus.GetTsDbInstance(ctx, route.qry)
+	if instance == nil {
+		log.Warnf(ctx, "not instance in %s", route.qry.StorageID)
+		return nil
+	}
+
+	pageable := rawStreamPageable(instance.InstanceType())
+	// 路由指定了 limit 时，累计返回的条数达到 limit 后结束
+	limit := route.qry.Size
+
+	for {
+		size := s.pageSize
+		if limit > 0 {
+			if cursor.From >= limit {
+				return nil
+			}
+			if limit-cursor.From < size {
+				size = limit - cursor.From
+			}
+		}
+
+		// 查询结构体会被存储实例修改，每页使用副本
+		qry := *route.qry
+		if pageable {
+			qry.Cursor = true
+			qry.Size = size
+			qry.From = route.qry.From + cursor.From
+			qry.SearchAfter = cursor.SearchAfter
+		}
+
+		num, lastSort, err := s.queryPage(ctx, instance, &qry)
+		if err != nil {
+			return fmt.Errorf("query %s:%s is error: %s", qry.TableID, qry.Fields, err)
+		}
+		// 存储实例可能会限制单页条数，返回不足一页时不代表数据已经查完，以空页作为结束条件
+		if !pageable || num == 0 {
+			return nil
+		}
+
+		cursor.From += num
+		if len(lastSort) > 0 {
+			cursor.SearchAfter = lastSort
+		}
+		s.scrollID = encodeScrollID(cursor)
+		if err = s.enc.Encode(map[string]any{RawStreamKeyScrollID: s.scrollID}); err != nil {
+			return err
+		}
+		s.flush()
+	}
+}
+
+func (s *rawStreamer) queryPage(ctx context.Context, instance tsdb.Instance, qry *metadata.Query) (num int, lastSort []any, err error) {
+	var (
+		queryErr error
+		dataCh   = make(chan map[string]any)
+	)
+
+	go func() {
+		defer close(dataCh)
+		_, queryErr = instance.QueryRawData(ctx, qry, s.start, s.end, dataCh)
+	}()
+
+	for d := range dataCh {
+		if v, ok := d[elasticsearch.KeySort]; ok {
+			lastSort, _ = v.([]any)
+			delete(d, elasticsearch.KeySort)
+		}
+
+		// 写入失败后继续消费数据，避免查询协程阻塞
+		if err != nil {
+			continue
+		}
+		if err = s.write(d); err == nil {
+			num++
+		}
+	}
+
+	if queryErr != nil {
+		err = queryErr
+	}
+	return
+}
+
+func (s *rawStreamer) write(d map[string]any) error {
+	if err := s.enc.Encode(d); err != nil {
+		return err
+	}
+	s.total++
+	return nil
+}
+
+// finish 写入最后一行，异常时返回最近一次的 scroll_id 用于续传
+func (s *rawStreamer) finish(err error) {
+	end := map[string]any{
+		RawStreamKeyScrollID: s.scrollID,
+		RawStreamKeyTotal:    s.total,
+	}
+	if err != nil {
+		end[RawStreamKeyError] = err.Error()
+	}
+	_ = s.enc.Encode(end)
+	s.flush()
+}
+
+// queryRawStream 流式导出原始数据
+func queryRawStream(ctx context.Context, queryTs *structured.QueryTs, w io.Writer, flush func()) (err error) {
+	ctx, span := trace.NewSpan(ctx, "query-raw-stream")
+	defer span.End(&err)
+
+	start, end, err := queryTs.GetTime()
+	if err != nil {
+		return err
+	}
+
+	if queryTs.SpaceUid == "" {
+		queryTs.SpaceUid = metadata.GetUser(ctx).SpaceUid
+	}
+
+	cursor, err := decodeScrollID(queryTs.ScrollID)
+	if err != nil {
+		return err
+	}
+
+	routes, err := rawStreamRoutes(ctx, queryTs)
+	if err != nil {
+		return err
+	}
+
+	if cursor != nil {
+		var found bool
+		for _, route := range routes {
+			if route.key == cursor.Route {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("scroll_id is invalid: route %s is not exists", cursor.Route)
+		}
+	}
+
+	pageSize := viper.GetInt(TSQueryRawStreamPageSizeConfigPath)
+	if pageSize <= 0 {
+		pageSize = 1e3
+	}
+
+	s := &rawStreamer{
+		enc:      json.NewEncoder(w),
+		flush:    flush,
+		start:    start,
+		end:      end,
+		pageSize: pageSize,
+	}
+	span.Set("route-num", len(routes))
+	span.Set("page-size", pageSize)
+
+	err = s.run(ctx, routes, cursor)
+	span.Set("total", s.total)
+	metadata.GetAudit(ctx).AddResult(0, s.total)
+	return err
+}
+
+// HandlerQueryRawStream
+// @Summary query monitor by raw data with ndjson stream
+// @ID query_raw_stream
+// @Produce application/x-ndjson
+// @Param    traceparent            header    string                        false  "TraceID" default(00-3967ac0f1648bf0216b27631730d7eb9-8e3c31d5109e78dd-01)
+// @Param    Bk-Query-Source   		header    string                        false  "来源" default(username:goodman)
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param	 X-Bk-Scope-Skip-Space  header	  string						false  "是否跳过空间验证" default()
+// @Param    data                  	body      structured.QueryTs  			true   "json data"
+// @Success  200                   	{string}  string
+// @Failure  400                   	{object}  ErrResponse
+// @Router   /query/ts/raw/stream [post]
+func HandlerQueryRawStream(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &response{c: c}
+		user = metadata.GetUser(ctx)
+		err  error
+		span *trace.Span
+	)
+
+	ctx, span = trace.NewSpan(ctx, "handler-query-raw-stream")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+
+	span.Set("query-source", user.Key)
+	span.Set("query-space-uid", user.SpaceUid)
+
+	// 解析请求 body
+	queryTs := &structured.QueryTs{}
+	err = json.NewDecoder(c.Request.Body).Decode(queryTs)
+	if err != nil {
+		log.Errorf(ctx, err.Error())
+		resp.failed(ctx, err)
+		return
+	}
+
+	// metadata 中的 spaceUid 是从 header 头信息中获取
+	if user.SpaceUid != "" {
+		queryTs.SpaceUid = user.SpaceUid
+	}
+
+	queryStr, _ := json.Marshal(queryTs)
+	span.Set("query-body", string(queryStr))
+
+	// 参数异常在写入数据之前返回，写入数据之后的异常通过最后一行返回
+	if _, err = decodeScrollID(queryTs.ScrollID); err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	c.Header("Content-Type", RawStreamContentType)
+	c.Status(http.StatusOK)
+
+	err = queryRawStream(ctx, queryTs, c.Writer, c.Writer.Flush)
+	if err != nil {
+		log.Errorf(ctx, err.Error())
+		metadata.GetAudit(ctx).SetError(err)
+	}
+}
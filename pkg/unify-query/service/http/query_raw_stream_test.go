@@ -0,0 +1,115 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/promql"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+func TestQueryRawStream(t *testing.T) {
+	ctx := metadata.InitHashID(context.Background())
+
+	mock.Init()
+	influxdb.MockSpaceRouter(ctx)
+	promql.MockEngine()
+
+	viper.Set(TSQueryRawStreamPageSizeConfigPath, 2)
+	defer viper.Set(TSQueryRawStreamPageSizeConfigPath, 1e3)
+
+	query := `{"_source":{"includes":["__ext.container_id","dtEventTimeStamp"]},%s"query":{"bool":{"filter":{"range":{"dtEventTimeStamp":{"format":"epoch_second","from":1723594000,"include_lower":true,"include_upper":true,"to":1723595000}}}}},%s"size":2,"sort":[{"group":{"order":"asc"}},{"_id":{"order":"asc"}}]}`
+	mock.Es.Set(map[string]any{
+		fmt.Sprintf(query, `"from":0,`, ""):                                             `{"took":1,"timed_out":false,"hits":{"total":{"value":3,"relation":"eq"},"hits":[{"_index":"es_index","_id":"1","_source":{"dtEventTimeStamp":"1723594161000","__ext":{"container_id":"a"}},"sort":["a","1"]},{"_index":"es_index","_id":"2","_source":{"dtEventTimeStamp":"1723594162000","__ext":{"container_id":"b"}},"sort":["b","2"]}]}}`,
+		fmt.Sprintf(query, "", `"search_after":["b","2"],`):                             `{"took":1,"timed_out":false,"hits":{"total":{"value":3,"relation":"eq"},"hits":[{"_index":"es_index","_id":"3","_source":{"dtEventTimeStamp":"1723594163000","__ext":{"container_id":"c"}},"sort":["c","3"]}]}}`,
+		strings.Replace(fmt.Sprintf(query, `"from":0,`, ""), `"size":2`, `"size":1`, 1): `{"took":1,"timed_out":false,"hits":{"total":{"value":3,"relation":"eq"},"hits":[{"_index":"es_index","_id":"1","_source":{"dtEventTimeStamp":"1723594161000","__ext":{"container_id":"a"}},"sort":["a","1"]}]}}`,
+		// 第二页不足一页时继续查询，直到返回空页
+		fmt.Sprintf(query, "", `"search_after":["c","3"],`): `{"took":1,"timed_out":false,"hits":{"total":{"value":3,"relation":"eq"},"hits":[]}}`,
+	})
+
+	firstScrollID := encodeScrollID(&rawStreamCursor{Route: "0:0:" + influxdb.ResultTableBkBaseEs, From: 2, SearchAfter: []any{"b", "2"}})
+	lastScrollID := encodeScrollID(&rawStreamCursor{Route: "0:0:" + influxdb.ResultTableBkBaseEs, From: 3, SearchAfter: []any{"c", "3"}})
+
+	testCases := map[string]struct {
+		scrollID string
+		limit    int
+		err      error
+		expected string
+	}{
+		"stream all pages": {
+			expected: `{"__doc_id":"1","__ext.container_id":"a","dtEventTimeStamp":"1723594161000"}
+{"__doc_id":"2","__ext.container_id":"b","dtEventTimeStamp":"1723594162000"}
+{"__scroll_id":"` + firstScrollID + `"}
+{"__doc_id":"3","__ext.container_id":"c","dtEventTimeStamp":"1723594163000"}
+{"__scroll_id":"` + lastScrollID + `"}
+{"__scroll_id":"","__total":3}
+`,
+		},
+		"stop at limit": {
+			limit: 1,
+			expected: `{"__doc_id":"1","__ext.container_id":"a","dtEventTimeStamp":"1723594161000"}
+{"__scroll_id":"` + encodeScrollID(&rawStreamCursor{Route: "0:0:" + influxdb.ResultTableBkBaseEs, From: 1, SearchAfter: []any{"a", "1"}}) + `"}
+{"__scroll_id":"","__total":1}
+`,
+		},
+		"resume with scroll_id": {
+			scrollID: firstScrollID,
+			expected: `{"__doc_id":"3","__ext.container_id":"c","dtEventTimeStamp":"1723594163000"}
+{"__scroll_id":"` + lastScrollID + `"}
+{"__scroll_id":"","__total":1}
+`,
+		},
+		"invalid scroll_id": {
+			scrollID: "not a scroll id",
+			err:      fmt.Errorf("scroll_id is invalid: illegal base64 data at input byte 3"),
+		},
+		"scroll_id route not exists": {
+			scrollID: encodeScrollID(&rawStreamCursor{Route: "1:0:not_exists"}),
+			err:      fmt.Errorf("scroll_id is invalid: route 1:0:not_exists is not exists"),
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := metadata.InitHashID(ctx)
+			queryTs := &structured.QueryTs{
+				SpaceUid: influxdb.SpaceUid,
+				QueryList: []*structured.Query{
+					{
+						DataSource:  structured.BkLog,
+						TableID:     structured.TableID(influxdb.ResultTableBkBaseEs),
+						KeepColumns: []string{"__ext.container_id", "dtEventTimeStamp"},
+						Limit:       c.limit,
+					},
+				},
+				OrderBy:  structured.OrderBy{"group"},
+				Start:    "1723594000",
+				End:      "1723595000",
+				ScrollID: c.scrollID,
+			}
+
+			var buf bytes.Buffer
+			err := queryRawStream(ctx, queryTs, &buf, func() {})
+			assert.Equal(t, c.err, err)
+			assert.Equal(t, c.expected, buf.String())
+		})
+	}
+}
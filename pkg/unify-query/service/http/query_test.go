@@ -14,13 +14,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/common/model"
+	promPromql "github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/downsample"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/featureFlag"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb/decoder"
@@ -29,6 +33,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/promql"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/victoriaMetrics"
 )
 
 func TestQueryTsWithEs(t *testing.T) {
@@ -129,6 +134,69 @@ func TestQueryTsWithEs(t *testing.T) {
 	}
 }
 
+func TestQueryTsWithLogMetrics(t *testing.T) {
+	ctx := metadata.InitHashID(context.Background())
+
+	mock.Init()
+	influxdb.MockSpaceRouter(ctx)
+	promql.MockEngine()
+
+	bucket := func(ts int64, value string) string {
+		return fmt.Sprintf(`{"key_as_string":"%d","key":%d,"doc_count":1,"_value":%s}`, ts, ts, value)
+	}
+	containers := func(name string, buckets ...string) string {
+		return `{"took":1,"timed_out":false,"hits":{"total":{"value":1,"relation":"eq"},"hits":[]},"aggregations":{"__ext.container_name":{"buckets":[{"key":"` + name + `","doc_count":1,"dtEventTimeStamp":{"buckets":[` + strings.Join(buckets, ",") + `]}}]}}}`
+	}
+
+	mock.Es.Set(map[string]any{
+		`{"aggregations":{"__ext.container_name":{"aggregations":{"dtEventTimeStamp":{"aggregations":{"_value":{"value_count":{"field":"gseIndex"}}},"date_histogram":{"extended_bounds":{"max":1723594179000,"min":1723593900000},"field":"dtEventTimeStamp","fixed_interval":"1m","min_doc_count":0,"time_zone":"UTC"}}},"terms":{"field":"__ext.container_name","size":10000}}},"query":{"bool":{"filter":{"range":{"dtEventTimeStamp":{"format":"epoch_second","from":1723593900,"include_lower":true,"include_upper":true,"to":1723594179}}}}},"size":0}`: containers(
+			"unify-query",
+			bucket(1723593960000, `{"value":120}`),
+			bucket(1723594020000, `{"value":60}`),
+			bucket(1723594080000, `{"value":30}`),
+		),
+		`{"aggregations":{"__ext.container_name":{"aggregations":{"dtEventTimeStamp":{"aggregations":{"_value":{"percentiles":{"field":"gseIndex","percents":[90]}}},"date_histogram":{"extended_bounds":{"max":1723594179000,"min":1723593900000},"field":"dtEventTimeStamp","fixed_interval":"1m","min_doc_count":0,"time_zone":"UTC"}}},"terms":{"field":"__ext.container_name","size":10000}}},"query":{"bool":{"filter":{"range":{"dtEventTimeStamp":{"format":"epoch_second","from":1723593900,"include_lower":true,"include_upper":true,"to":1723594179}}}}},"size":0}`: containers(
+			"unify-query",
+			bucket(1723593960000, `{"values":{"90.0":10}}`),
+			bucket(1723594020000, `{"values":{"90.0":20}}`),
+			bucket(1723594080000, `{"values":{"90.0":30}}`),
+		),
+	})
+
+	for name, c := range map[string]struct {
+		promql   string
+		expected string
+	}{
+		"sum rate by container": {
+			promql:   `sum by (__ext__bk_46__container_name) (rate(bklog:result_table:bk_base_es:gseIndex[1m]))`,
+			expected: `{"series":[{"name":"_result0","metric_name":"","columns":["_time","_value"],"types":["float","float"],"group_keys":["__ext.container_name"],"group_values":["unify-query"],"values":[[1723593960000,2],[1723594020000,1],[1723594080000,0.5]]}]}`,
+		},
+		"quantile_over_time by container": {
+			promql:   `quantile by (__ext__bk_46__container_name) (0.9, quantile_over_time(0.9, bklog:result_table:bk_base_es:gseIndex[1m]))`,
+			expected: `{"series":[{"name":"_result0","metric_name":"","columns":["_time","_value"],"types":["float","float"],"group_keys":["__ext.container_name"],"group_values":["unify-query"],"values":[[1723593960000,10],[1723594020000,20],[1723594080000,30]]}]}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := metadata.InitHashID(ctx)
+			metadata.SetUser(ctx, "", influxdb.SpaceUid, "")
+
+			queryTs, err := promQLToStruct(ctx, &structured.QueryPromQL{
+				PromQL: c.promql,
+				Start:  "1723594000",
+				End:    "1723594120",
+				Step:   "1m",
+			})
+			assert.Nil(t, err)
+
+			res, err := queryTsWithPromEngine(ctx, queryTs)
+			assert.Nil(t, err)
+
+			actual, _ := json.Marshal(res)
+			assert.Equal(t, c.expected, string(actual))
+		})
+	}
+}
+
 func TestQueryReference(t *testing.T) {
 	ctx := metadata.InitHashID(context.Background())
 
@@ -1699,6 +1767,65 @@ func TestStructAndPromQLConvert(t *testing.T) {
 				MetricMerge: "a",
 			},
 		},
+		"struct to promql with moving_percentile": {
+			queryStruct: true,
+			promql: &structured.QueryPromQL{
+				PromQL: `max(moving_percentile(bkmonitor:metric[1h], 90))`,
+			},
+			query: &structured.QueryTs{
+				QueryList: []*structured.Query{
+					{
+						DataSource:    "bkmonitor",
+						FieldName:     "metric",
+						ReferenceName: "a",
+						TimeAggregation: structured.TimeAggregation{
+							Function:  promql.MovingPercentile,
+							Window:    "1h",
+							VargsList: []interface{}{90},
+						},
+						AggregateMethodList: []structured.AggregateMethod{
+							{
+								Method: "max",
+							},
+						},
+					},
+				},
+				MetricMerge: "a",
+			},
+		},
+		"promql to struct with mad_outlier": {
+			queryStruct: false,
+			promql: &structured.QueryPromQL{
+				PromQL: `sum(mad_outlier(bkmonitor:metric[30m], 3.5))`,
+			},
+			query: &structured.QueryTs{
+				QueryList: []*structured.Query{
+					{
+						DataSource: "bkmonitor",
+						FieldName:  "metric",
+						Conditions: structured.Conditions{
+							FieldList:     []structured.ConditionField{},
+							ConditionList: []string{},
+						},
+						ReferenceName: "a",
+						TimeAggregation: structured.TimeAggregation{
+							Function:  promql.MadOutlier,
+							Window:    "30m0s",
+							NodeIndex: 2,
+							VargsList: []interface{}{
+								3.5,
+							},
+						},
+						AggregateMethodList: []structured.AggregateMethod{
+							{
+								Method: "sum",
+							},
+						},
+					},
+				},
+				MetricMerge: "a",
+			},
+		},
 		"nodeIndex 3 with sum": {
 			queryStruct: false,
 			promql: &structured.QueryPromQL{
@@ -2120,6 +2247,11 @@ func TestQueryTsToInstanceAndStmt(t *testing.T) {
 			stmt:         `sum(count_over_time(a[1m] offset -59s999ms))`,
 			instanceType: consul.VictoriaMetricsStorageType,
 		},
+		"test_engine_function_with_vm": {
+			promql:       `max(moving_percentile(datasource:result_table:vm:container_cpu_usage_seconds_total{}[1h], 90))`,
+			stmt:         `max(moving_percentile(a[1h] offset -59s999ms, 90))`,
+			instanceType: consul.PrometheusStorageType,
+		},
 		"test_group_with_influxdb": {
 			promql:       `sum(count_over_time(datasource:result_table:influxdb:cpu_summary{}[1m]))`,
 			stmt:         `sum(last_over_time(a[1m] offset -59s999ms))`,
@@ -2172,3 +2304,195 @@ func TestQueryTsToInstanceAndStmt(t *testing.T) {
 		})
 	}
 }
+
+// TestQueryTsEngineFunctionWithVm vm 直查的空间使用扩展函数时，由内置引擎计算，原始数据通过 vm 查询
+func TestQueryTsEngineFunctionWithVm(t *testing.T) {
+	ctx := metadata.InitHashID(context.Background())
+
+	mock.Init()
+	influxdb.MockSpaceRouter(ctx)
+	promql.MockEngine()
+
+	err := featureFlag.MockFeatureFlag(ctx, `{
+	  	"must-vm-query": {
+	  		"variations": {
+	  			"true": true,
+	  			"false": false
+	  		},
+	  		"targeting": [{
+	  			"query": "tableID in [\"result_table.vm\"]",
+	  			"percentage": {
+	  				"true": 100,
+	  				"false":0 
+	  			}
+	  		}],
+	  		"defaultRule": {
+	  			"variation": "false"
+	  		}
+	  	}
+	  }`)
+	assert.Nil(t, err)
+
+	values := make([]victoriaMetrics.Value, 0)
+	for i := 0; i < 9; i++ {
+		values = append(values, victoriaMetrics.Value{1729859940 + i*30, strconv.Itoa(i + 1)})
+	}
+	mock.Vm.Set(map[string]any{
+		`query:1729860180{result_table_id="2_bcs_prom_computation_result_table", __name__="container_cpu_usage_seconds_total_value"}[241s]`: victoriaMetrics.Data{
+			ResultType: victoriaMetrics.MatrixType,
+			Result: []victoriaMetrics.Series{
+				{
+					Metric: map[string]string{
+						"__name__": "container_cpu_usage_seconds_total_value",
+						"pod":      "pod-1",
+					},
+					Values: values,
+				},
+			},
+		},
+	})
+
+	metadata.SetUser(ctx, "", influxdb.SpaceUid, "")
+	query, err := promQLToStruct(ctx, &structured.QueryPromQL{
+		PromQL: `max(moving_percentile(datasource:result_table:vm:container_cpu_usage_seconds_total{}[2m], 50))`,
+		Start:  "1729860000",
+		End:    "1729860120",
+		Step:   "60s",
+	})
+	assert.Nil(t, err)
+	query.SpaceUid = influxdb.SpaceUid
+
+	res, err := queryTsWithPromEngine(ctx, query)
+	assert.Nil(t, err)
+
+	actual, _ := json.Marshal(res)
+	assert.Equal(t, `{"series":[{"name":"_result0","metric_name":"","columns":["_time","_value"],"types":["float","float"],"group_keys":[],"group_values":[],"values":[[1729860000000,2.5],[1729860060000,4.5],[1729860120000,6.5]]}]}`, string(actual))
+}
+
+func TestPushDownSample(t *testing.T) {
+	testCases := map[string]struct {
+		query    *structured.QueryTs
+		step     string
+		function string
+		window   structured.Window
+	}{
+		"avg push down": {
+			query: &structured.QueryTs{
+				QueryList:        []*structured.Query{{FieldName: "usage"}},
+				Step:             "1m",
+				DownSampleRange:  "5m",
+				DownSampleMethod: downsample.MethodAvg,
+			},
+			step:     "5m",
+			function: structured.AvgOT,
+			window:   "5m",
+		},
+		"m4 not push down": {
+			query: &structured.QueryTs{
+				QueryList:        []*structured.Query{{FieldName: "usage"}},
+				Step:             "1m",
+				DownSampleRange:  "5m",
+				DownSampleMethod: downsample.MethodM4,
+			},
+			step: "1m",
+		},
+		"range less than step": {
+			query: &structured.QueryTs{
+				QueryList:        []*structured.Query{{FieldName: "usage"}},
+				Step:             "10m",
+				DownSampleRange:  "5m",
+				DownSampleMethod: downsample.MethodAvg,
+			},
+			step: "10m",
+		},
+		"time aggregation exists": {
+			query: &structured.QueryTs{
+				QueryList: []*structured.Query{{
+					FieldName: "usage",
+					TimeAggregation: structured.TimeAggregation{
+						Function: "max_over_time",
+						Window:   "1m",
+					},
+				}},
+				Step:             "1m",
+				DownSampleRange:  "5m",
+				DownSampleMethod: downsample.MethodAvg,
+			},
+			step:     "1m",
+			function: "max_over_time",
+			window:   "1m",
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			pushDownSample(c.query)
+			assert.Equal(t, c.step, c.query.Step)
+			assert.Equal(t, c.function, c.query.QueryList[0].TimeAggregation.Function)
+			assert.Equal(t, c.window, c.query.QueryList[0].TimeAggregation.Window)
+		})
+	}
+}
+
+// TestPushDownSampleConsistent avg 降采样下推到存储和内存降采样的结果需要一致
+func TestPushDownSampleConsistent(t *testing.T) {
+	query := &structured.QueryTs{
+		QueryList:        []*structured.Query{{FieldName: "usage"}},
+		Step:             "15s",
+		DownSampleRange:  "40s",
+		DownSampleMethod: downsample.MethodAvg,
+	}
+	pushDownSample(query)
+	assert.Equal(t, structured.AvgOT, query.QueryList[0].TimeAggregation.Function)
+
+	window, err := model.ParseDuration(string(query.QueryList[0].TimeAggregation.Window))
+	assert.NoError(t, err)
+	step, err := model.ParseDuration(query.Step)
+	assert.NoError(t, err)
+
+	// 原始数据每 15s 一个点，第一个点缺失，避免落在窗口边界上
+	test, err := promPromql.NewTest(t, `
+load 15s
+	usage _ 1 5 2 4 6 7
+`)
+	assert.NoError(t, err)
+	defer test.Close()
+	assert.NoError(t, test.Run())
+
+	// 下推路径：存储按 avg_over_time 计算
+	qry, err := test.QueryEngine().NewRangeQuery(
+		test.Queryable(), nil,
+		fmt.Sprintf("%s(usage[%s])", structured.AvgOT, window),
+		time.Unix(40, 0), time.Unix(120, 0), time.Duration(step),
+	)
+	assert.NoError(t, err)
+	res := qry.Exec(test.Context())
+	assert.NoError(t, res.Err)
+	matrix, err := res.Matrix()
+	assert.NoError(t, err)
+	assert.Len(t, matrix, 1)
+	if len(matrix) != 1 {
+		return
+	}
+
+	// 内存路径：原始数据查询后按 avg 降采样
+	raw := make([]promPromql.Point, 0)
+	for i, v := range []float64{1, 5, 2, 4, 6, 7} {
+		raw = append(raw, promPromql.Point{T: int64(i+1) * 15e3, V: v})
+	}
+	actual := downsample.DownsampleByMethod(query.DownSampleMethod, raw, 0, time.Duration(window).Milliseconds())
+
+	assert.Equal(t, matrix[0].Points, actual)
+}
+
+func TestEngineFunctionName(t *testing.T) {
+	for q, name := range map[string]string{
+		`sum(rate(metric[1m]))`:                             "",
+		`sum(zscore_outlier(metric[1h], 3)) > 0`:            promql.ZScoreOutlier,
+		`max(moving_percentile(rate(metric[1m])[1h:], 95))`: promql.MovingPercentile,
+	} {
+		expr, err := parser.ParseExpr(q)
+		assert.Nil(t, err)
+		assert.Equal(t, name, engineFunctionName(expr))
+	}
+}
@@ -72,6 +72,10 @@ func registerDefaultHandlers(ctx context.Context, g *gin.RouterGroup) {
 	handlerPath = viper.GetString(TSQueryRawQueryHandlePathConfigPath)
 	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryRaw)
 
+	// query/raw/stream
+	handlerPath = viper.GetString(TSQueryRawStreamHandlePathConfigPath)
+	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryRawStream)
+
 	// query/ts/exemplar
 	handlerPath = viper.GetString(TSQueryExemplarHandlePathConfigPath)
 	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryExemplar)
@@ -107,9 +111,25 @@ func registerDefaultHandlers(ctx context.Context, g *gin.RouterGroup) {
 	handlerPath = viper.GetString(TSQueryClusterMetricsPathConfigPath)
 	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryTsClusterMetrics)
 
+	// query/ts/explain
+	handlerPath = viper.GetString(TSQueryExplainPathConfigPath)
+	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryTsExplain)
+
 	// query/es/
 	handlerPath = viper.GetString(ESHandlePathConfigPath)
 	registerHandler.register(http.MethodPost, handlerPath, HandleESQueryRequest)
+
+	// api/v1 prometheus 兼容接口，空间从 header 中获取
+	handlerPath = viper.GetString(PromAPIPathConfigPath)
+	registerPromAPIHandlers(registerHandler, handlerPath)
+
+	// space/:space_uid/api/v1 prometheus 兼容接口，空间从路径前缀中获取
+	handlerPath = viper.GetString(PromAPISpacePathConfigPath)
+	registerPromAPIHandlers(registerHandler, handlerPath, promAPISpace)
+
+	// query/trace/jaeger/:table_id/api jaeger-query 兼容接口
+	handlerPath = viper.GetString(TraceJaegerPathConfigPath)
+	registerJaegerAPIHandlers(registerHandler, handlerPath)
 }
 
 func registerOtherHandlers(ctx context.Context, g *gin.RouterGroup) {
@@ -170,6 +190,10 @@ func registerOtherHandlers(ctx context.Context, g *gin.RouterGroup) {
 	handlerPath = viper.GetString(TsDBPrintHandlePathConfigPath)
 	registerHandler.register(http.MethodGet, handlerPath, HandleTsDBPrint)
 
+	// debug/slow_queries
+	handlerPath = viper.GetString(SlowQueriesPathConfigPath)
+	registerHandler.register(http.MethodGet, handlerPath, HandlerSlowQueries)
+
 	// HEAD
 	registerHandler.register(http.MethodHead, "", HandlerHealth)
 
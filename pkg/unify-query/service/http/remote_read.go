@@ -0,0 +1,421 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/prometheus"
+)
+
+// remoteReadQuerier 读取原始数据的 querier，通过 metadata 中的 queryReference 路由到各个存储的 QuerySeriesSet
+var remoteReadQuerier = func(ctx context.Context, start, end time.Time) storage.Querier {
+	return prometheus.NewQuerier(ctx, start, end, QueryMaxRouting, SingleflightTimeout)
+}
+
+// remoteReadError 带状态码的 remote read 错误
+type remoteReadError struct {
+	code int
+	err  error
+}
+
+func (e *remoteReadError) Error() string {
+	return e.err.Error()
+}
+
+// HandlerPromAPIRemoteRead
+// @Summary  prometheus remote read
+// @ID       prom_api_remote_read
+// @Accept   application/x-protobuf
+// @Produce  application/x-protobuf
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Success  200
+// @Failure  400
+// @Router   /api/v1/read [post]
+func HandlerPromAPIRemoteRead(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		user = metadata.GetUser(ctx)
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-prom-api-remote-read")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+
+	defer func() {
+		status := metric.StatusSuccess
+		if err != nil {
+			status = metric.StatusFailed
+			log.Errorf(ctx, "remote read error: %s", err)
+		}
+		metric.APIRequestInc(ctx, c.Request.URL.Path, status, user.SpaceUid, user.Source)
+	}()
+
+	req, err := remote.DecodeReadRequest(c.Request)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	responseType, err := remote.NegotiateResponseType(req.AcceptedResponseTypes)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	span.Set("query-num", len(req.Queries))
+	span.Set("response-type", responseType.String())
+
+	switch responseType {
+	case prompb.ReadRequest_STREAMED_XOR_CHUNKS:
+		err = remoteReadStreamedChunks(ctx, c, req)
+	default:
+		err = remoteReadSamples(ctx, c, req)
+	}
+	if err != nil {
+		code := http.StatusInternalServerError
+		var (
+			readErr *remoteReadError
+			httpErr remote.HTTPError
+		)
+		switch {
+		case errors.As(err, &readErr):
+			code = readErr.code
+		case errors.As(err, &httpErr):
+			code = httpErr.Status()
+		}
+		c.String(code, err.Error())
+	}
+}
+
+// remoteReadSamples 一次性返回全部数据点
+func remoteReadSamples(ctx context.Context, c *gin.Context, req *prompb.ReadRequest) error {
+	resp := &prompb.ReadResponse{
+		Results: make([]*prompb.QueryResult, len(req.Queries)),
+	}
+	sampleLimit := viper.GetInt(PromAPIRemoteReadSampleLimitConfigPath)
+
+	for i, query := range req.Queries {
+		ss, err := remoteReadSelect(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		result, ws, err := remote.ToQueryResult(ss, sampleLimit)
+		if err != nil {
+			return err
+		}
+		for _, w := range ws {
+			log.Warnf(ctx, "remote read query %d warning: %s", i, w)
+		}
+		resp.Results[i] = result
+	}
+
+	c.Header("Content-Type", "application/x-protobuf")
+	c.Header("Content-Encoding", "snappy")
+	return remote.EncodeReadResponse(resp, c.Writer)
+}
+
+// remoteReadStreamedChunks 按照 series 编码为 XOR chunk 流式返回
+func remoteReadStreamedChunks(ctx context.Context, c *gin.Context, req *prompb.ReadRequest) error {
+	c.Header("Content-Type", "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse")
+
+	var (
+		writer      = remote.NewChunkedWriter(c.Writer, c.Writer)
+		maxBytes    = viper.GetInt(PromAPIRemoteReadMaxBytesInFrameConfigPath)
+		marshalPool = &sync.Pool{}
+	)
+
+	for i, query := range req.Queries {
+		ss, err := remoteReadSelect(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		ws, err := remote.StreamChunkedReadResponses(
+			writer, int64(i), storage.NewSeriesSetToChunkSet(ss), nil, maxBytes, marshalPool,
+		)
+		if err != nil {
+			return err
+		}
+		for _, w := range ws {
+			log.Warnf(ctx, "remote read query %d warning: %s", i, w)
+		}
+	}
+	return nil
+}
+
+// remoteReadMetricNames 查询空间下的全部指标名，用于指标名为正则或未指定指标名的查询
+var remoteReadMetricNames = func(ctx context.Context, start, end time.Time) ([]string, error) {
+	queryRef, err := promSpaceQueryRef(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return queryReferenceLabelValues(ctx, queryRef, labels.MetricName, start, end), nil
+}
+
+// remoteReadSelector 拆分 remote read 的匹配条件，返回指标名匹配条件以及其余维度条件
+func remoteReadSelector(matchers []*labels.Matcher) ([]*labels.Matcher, []string) {
+	var (
+		nameMatchers = make([]*labels.Matcher, 0)
+		conds        = make([]string, 0, len(matchers))
+	)
+	for _, m := range matchers {
+		if m.Name == labels.MetricName {
+			nameMatchers = append(nameMatchers, m)
+			continue
+		}
+		conds = append(conds, m.String())
+	}
+	return nameMatchers, conds
+}
+
+// remoteReadNames 解析需要查询的指标名，只有一个等于匹配时直接使用，否则从空间指标中过滤
+func remoteReadNames(ctx context.Context, nameMatchers []*labels.Matcher, start, end time.Time) ([]string, error) {
+	if len(nameMatchers) == 1 && nameMatchers[0].Type == labels.MatchEqual && nameMatchers[0].Value != "" {
+		return []string{nameMatchers[0].Value}, nil
+	}
+
+	metrics, err := remoteReadMetricNames(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		matched := true
+		for _, m := range nameMatchers {
+			if !m.Matches(metric) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			names = append(names, metric)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// remoteReadSelect 通过空间路由解析匹配条件，按照指标名顺序逐个从存储读取原始数据
+func remoteReadSelect(ctx context.Context, query *prompb.Query) (ss storage.SeriesSet, err error) {
+	ctx, span := trace.NewSpan(ctx, "remote-read-select")
+	defer span.End(&err)
+
+	matchers, err := remote.FromLabelMatchers(query.Matchers)
+	if err != nil {
+		return nil, &remoteReadError{code: http.StatusBadRequest, err: err}
+	}
+	nameMatchers, conds := remoteReadSelector(matchers)
+
+	start := time.UnixMilli(query.StartTimestampMs)
+	end := time.UnixMilli(query.EndTimestampMs)
+
+	span.Set("matchers", fmt.Sprintf("%v", matchers))
+	span.Set("start", start)
+	span.Set("end", end)
+
+	// 多个查询串行执行，每次执行前覆盖 queryParams 以及 queryReference
+	metadata.GetQueryParams(ctx).SetTime(start.Unix(), end.Unix())
+
+	names, err := remoteReadNames(ctx, nameMatchers, start, end)
+	if err != nil {
+		return nil, err
+	}
+	span.Set("metric-num", len(names))
+
+	hints := &storage.SelectHints{
+		Start: query.StartTimestampMs,
+		End:   query.EndTimestampMs,
+	}
+	if query.Hints != nil {
+		hints.Step = query.Hints.StepMs
+		hints.Func = query.Hints.Func
+		hints.Grouping = query.Hints.Grouping
+		hints.Range = query.Hints.RangeMs
+		hints.By = query.Hints.By
+	}
+
+	querier := remoteReadQuerier(ctx, start, end)
+	return &remoteReadSeriesSet{
+		names:   names,
+		querier: querier,
+		open: func(name string) (storage.SeriesSet, error) {
+			return remoteReadMetricSelect(ctx, querier, name, conds, hints, start, end)
+		},
+	}, nil
+}
+
+// remoteReadMetricSelect 读取单个指标的数据，各个 queryReference 的结果按照 series 逐条合并返回
+func remoteReadMetricSelect(
+	ctx context.Context, querier storage.Querier, name string, conds []string,
+	hints *storage.SelectHints, start, end time.Time,
+) (storage.SeriesSet, error) {
+	selector := name
+	if len(conds) > 0 {
+		selector = fmt.Sprintf("%s{%s}", name, strings.Join(conds, ", "))
+	}
+
+	queryRef, err := promMatchersToQueryRef(ctx, selector, start, end)
+	if err != nil {
+		return nil, &remoteReadError{code: http.StatusBadRequest, err: err}
+	}
+	metadata.SetQueryReference(ctx, queryRef)
+
+	var (
+		decodeFunc = metadata.GetPromDataFormat(ctx).DecodeFunc()
+		sets       = make([]storage.SeriesSet, 0, len(queryRef))
+	)
+	relabel := func(lbs labels.Labels) labels.Labels {
+		builder := labels.NewBuilder(nil)
+		for _, lb := range lbs {
+			if lb.Name == influxdb.BKTaskIndex || lb.Name == labels.MetricName {
+				continue
+			}
+			builder.Set(decodeFunc(lb.Name), lb.Value)
+		}
+		builder.Set(labels.MetricName, name)
+		return builder.Labels(nil)
+	}
+	for referenceName := range queryRef {
+		sets = append(sets, &remoteReadRelabelSet{
+			set: querier.Select(
+				true, hints, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, referenceName),
+			),
+			relabel: relabel,
+		})
+	}
+
+	// 存储返回的 series 有序，维度相同的 series 在合并时逐条处理，不需要在内存中缓存全部数据
+	return storage.NewMergeSeriesSet(sets, storage.ChainedSeriesMerge), nil
+}
+
+// remoteReadSeriesSet 按照指标名顺序拼接各个指标的 series，当前指标读取完成后才开始查询下一个指标
+type remoteReadSeriesSet struct {
+	names   []string
+	querier storage.Querier
+	open    func(name string) (storage.SeriesSet, error)
+
+	cur storage.SeriesSet
+	ws  storage.Warnings
+	err error
+}
+
+func (s *remoteReadSeriesSet) Next() bool {
+	for s.err == nil {
+		if s.cur != nil {
+			if s.cur.Next() {
+				return true
+			}
+			s.ws = append(s.ws, s.cur.Warnings()...)
+			if err := s.cur.Err(); err != nil {
+				s.err = err
+				break
+			}
+		}
+		if len(s.names) == 0 {
+			break
+		}
+		s.cur, s.err = s.open(s.names[0])
+		s.names = s.names[1:]
+	}
+	if s.querier != nil {
+		_ = s.querier.Close()
+		s.querier = nil
+	}
+	return false
+}
+
+func (s *remoteReadSeriesSet) At() storage.Series {
+	return s.cur.At()
+}
+
+func (s *remoteReadSeriesSet) Err() error {
+	return s.err
+}
+
+func (s *remoteReadSeriesSet) Warnings() storage.Warnings {
+	return s.ws
+}
+
+// remoteReadRelabelSet 还原维度名并补充指标名，去掉维度后相邻且维度相同的 series 合并为一条
+type remoteReadRelabelSet struct {
+	set     storage.SeriesSet
+	relabel func(labels.Labels) labels.Labels
+
+	cur  storage.Series
+	peek storage.Series
+}
+
+func (s *remoteReadRelabelSet) wrap(series storage.Series) storage.Series {
+	return &storage.SeriesEntry{Lset: s.relabel(series.Labels()), SampleIteratorFn: series.Iterator}
+}
+
+func (s *remoteReadRelabelSet) Next() bool {
+	if s.peek == nil {
+		if !s.set.Next() {
+			return false
+		}
+		s.peek = s.wrap(s.set.At())
+	}
+
+	series := []storage.Series{s.peek}
+	s.peek = nil
+	for s.set.Next() {
+		next := s.wrap(s.set.At())
+		if labels.Equal(next.Labels(), series[0].Labels()) {
+			series = append(series, next)
+			continue
+		}
+		s.peek = next
+		break
+	}
+
+	s.cur = series[0]
+	if len(series) > 1 {
+		s.cur = storage.ChainedSeriesMerge(series...)
+	}
+	return true
+}
+
+func (s *remoteReadRelabelSet) At() storage.Series {
+	return s.cur
+}
+
+func (s *remoteReadRelabelSet) Err() error {
+	return s.set.Err()
+}
+
+func (s *remoteReadRelabelSet) Warnings() storage.Warnings {
+	return s.set.Warnings()
+}
@@ -0,0 +1,274 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
+)
+
+func TestHandlerPromAPIRemoteRead(t *testing.T) {
+	mock.Init()
+	ctx := metadata.InitHashID(context.Background())
+	influxdb.MockSpaceRouter(ctx)
+	metadata.SetUser(ctx, "username:test", influxdb.SpaceUid, "")
+
+	start := time.Unix(1729859485, 0)
+	end := time.Unix(1729863085, 0)
+
+	// 存储返回有序的 series，相邻维度相同的 series 需要合并，存储中的指标名为 reference name，需要还原为查询的指标名
+	defer func(fn func(ctx context.Context, start, end time.Time) storage.Querier) {
+		remoteReadQuerier = fn
+	}(remoteReadQuerier)
+	defer func(fn func(ctx context.Context, start, end time.Time) ([]string, error)) {
+		remoteReadMetricNames = fn
+	}(remoteReadMetricNames)
+	remoteReadMetricNames = func(ctx context.Context, start, end time.Time) ([]string, error) {
+		return []string{"container_cpu_usage_seconds_total", "container_memory_rss", "kube_pod_info"}, nil
+	}
+
+	var (
+		selected  []*labels.Matcher
+		selectNum int
+	)
+	remoteReadQuerier = func(ctx context.Context, start, end time.Time) storage.Querier {
+		return &storage.MockQuerier{
+			SelectMockFunction: func(_ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+				selected = matchers
+				selectNum++
+				return newTestSeriesSet(
+					promql.NewStorageSeries(promql.Series{
+						Metric: labels.FromStrings("__name__", "a", "pod", "a", influxdb.BKTaskIndex, "1"),
+						Points: []promql.Point{{T: start.UnixMilli(), V: 3}},
+					}),
+					promql.NewStorageSeries(promql.Series{
+						Metric: labels.FromStrings("__name__", "a", "pod", "b"),
+						Points: []promql.Point{{T: start.UnixMilli() + 60e3, V: 2}},
+					}),
+					promql.NewStorageSeries(promql.Series{
+						Metric: labels.FromStrings("__name__", "a", "pod", "b"),
+						Points: []promql.Point{{T: start.UnixMilli(), V: 1}},
+					}),
+				)
+			},
+		}
+	}
+
+	readRequest := func(t *testing.T, responseType prompb.ReadRequest_ResponseType, matchers ...*prompb.LabelMatcher) *httptest.ResponseRecorder {
+		body, err := (&prompb.ReadRequest{
+			Queries: []*prompb.Query{{
+				StartTimestampMs: start.UnixMilli(),
+				EndTimestampMs:   end.UnixMilli(),
+				Matchers:         matchers,
+			}},
+			AcceptedResponseTypes: []prompb.ReadRequest_ResponseType{responseType},
+		}).Marshal()
+		assert.NoError(t, err)
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://127.0.0.1/api/v1/read", bytes.NewReader(snappy.Encode(nil, body)))
+		req.Header.Set("Content-Encoding", "snappy")
+		req.Header.Set("Content-Type", "application/x-protobuf")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		HandlerPromAPIRemoteRead(c)
+		return w
+	}
+
+	matchers := []*prompb.LabelMatcher{
+		{Type: prompb.LabelMatcher_EQ, Name: labels.MetricName, Value: "container_cpu_usage_seconds_total"},
+		{Type: prompb.LabelMatcher_RE, Name: "pod", Value: "a|b"},
+	}
+	expected := []string{
+		`{__name__="container_cpu_usage_seconds_total", pod="a"} [3@1729859485000]`,
+		`{__name__="container_cpu_usage_seconds_total", pod="b"} [1@1729859485000 2@1729859545000]`,
+	}
+
+	t.Run("samples", func(t *testing.T) {
+		w := readRequest(t, prompb.ReadRequest_SAMPLES, matchers...)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "snappy", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "a")}, selected)
+
+		body, err := snappy.Decode(nil, w.Body.Bytes())
+		assert.NoError(t, err)
+		var resp prompb.ReadResponse
+		assert.NoError(t, resp.Unmarshal(body))
+		assert.Len(t, resp.Results, 1)
+
+		assert.Equal(t, expected, seriesSetStrings(remote.FromQueryResult(true, resp.Results[0])))
+	})
+
+	t.Run("streamed chunks", func(t *testing.T) {
+		w := readRequest(t, prompb.ReadRequest_STREAMED_XOR_CHUNKS, matchers...)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse", w.Header().Get("Content-Type"))
+
+		var (
+			reader = remote.NewChunkedReader(w.Body, 1<<20, nil)
+			result []string
+		)
+		for {
+			var resp prompb.ChunkedReadResponse
+			err := reader.NextProto(&resp)
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			for _, series := range resp.ChunkedSeries {
+				lbs := make([]string, 0)
+				for _, lb := range series.Labels {
+					lbs = append(lbs, lb.Name, lb.Value)
+				}
+				var samples []string
+				for _, chk := range series.Chunks {
+					c, err := chunkenc.FromData(chunkenc.EncXOR, chk.Data)
+					assert.NoError(t, err)
+					it := c.Iterator(nil)
+					for it.Next() == chunkenc.ValFloat {
+						ts, v := it.At()
+						samples = append(samples, sampleString(ts, v))
+					}
+				}
+				result = append(result, labels.FromStrings(lbs...).String()+" "+joinSamples(samples))
+			}
+		}
+		assert.Equal(t, expected, result)
+	})
+
+	readSamples := func(t *testing.T, matchers ...*prompb.LabelMatcher) []string {
+		w := readRequest(t, prompb.ReadRequest_SAMPLES, matchers...)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		body, err := snappy.Decode(nil, w.Body.Bytes())
+		assert.NoError(t, err)
+		var resp prompb.ReadResponse
+		assert.NoError(t, resp.Unmarshal(body))
+		assert.Len(t, resp.Results, 1)
+		return seriesSetStrings(remote.FromQueryResult(false, resp.Results[0]))
+	}
+
+	t.Run("regexp metric name", func(t *testing.T) {
+		selectNum = 0
+		result := readSamples(t,
+			&prompb.LabelMatcher{Type: prompb.LabelMatcher_RE, Name: labels.MetricName, Value: "container_.+"},
+			&prompb.LabelMatcher{Type: prompb.LabelMatcher_EQ, Name: "pod", Value: "a"},
+		)
+		assert.Equal(t, 2, selectNum)
+		assert.Equal(t, []string{
+			`{__name__="container_cpu_usage_seconds_total", pod="a"} [3@1729859485000]`,
+			`{__name__="container_cpu_usage_seconds_total", pod="b"} [1@1729859485000 2@1729859545000]`,
+			`{__name__="container_memory_rss", pod="a"} [3@1729859485000]`,
+			`{__name__="container_memory_rss", pod="b"} [1@1729859485000 2@1729859545000]`,
+		}, result)
+	})
+
+	t.Run("without metric name", func(t *testing.T) {
+		selectNum = 0
+		result := readSamples(t,
+			&prompb.LabelMatcher{Type: prompb.LabelMatcher_NEQ, Name: labels.MetricName, Value: "kube_pod_info"},
+			&prompb.LabelMatcher{Type: prompb.LabelMatcher_EQ, Name: "pod", Value: "a"},
+		)
+		assert.Equal(t, 2, selectNum)
+		assert.Len(t, result, 4)
+	})
+}
+
+func TestRemoteReadSeriesSetStreaming(t *testing.T) {
+	// 下一个指标只有在当前指标读取完成后才开始查询
+	var opened []string
+	ss := &remoteReadSeriesSet{
+		names: []string{"a", "b"},
+		open: func(name string) (storage.SeriesSet, error) {
+			opened = append(opened, name)
+			return newTestSeriesSet(
+				promql.NewStorageSeries(promql.Series{Metric: labels.FromStrings("__name__", name)}),
+			), nil
+		},
+	}
+
+	assert.True(t, ss.Next())
+	assert.Equal(t, []string{"a"}, opened)
+	assert.True(t, ss.Next())
+	assert.Equal(t, []string{"a", "b"}, opened)
+	assert.False(t, ss.Next())
+	assert.NoError(t, ss.Err())
+}
+
+func sampleString(t int64, v float64) string {
+	return fmt.Sprintf("%g@%d", v, t)
+}
+
+func joinSamples(samples []string) string {
+	return "[" + strings.Join(samples, " ") + "]"
+}
+
+func seriesSetStrings(ss storage.SeriesSet) []string {
+	var result []string
+	for ss.Next() {
+		series := ss.At()
+		var samples []string
+		it := series.Iterator(nil)
+		for it.Next() == chunkenc.ValFloat {
+			samples = append(samples, sampleString(it.At()))
+		}
+		result = append(result, series.Labels().String()+" "+joinSamples(samples))
+	}
+	return result
+}
+
+// testSeriesSet 测试使用的内存 series 集合
+type testSeriesSet struct {
+	series []storage.Series
+	index  int
+}
+
+func newTestSeriesSet(series ...storage.Series) storage.SeriesSet {
+	return &testSeriesSet{series: series, index: -1}
+}
+
+func (s *testSeriesSet) Next() bool {
+	s.index++
+	return s.index < len(s.series)
+}
+
+func (s *testSeriesSet) At() storage.Series {
+	return s.series[s.index]
+}
+
+func (s *testSeriesSet) Err() error {
+	return nil
+}
+
+func (s *testSeriesSet) Warnings() storage.Warnings {
+	return nil
+}
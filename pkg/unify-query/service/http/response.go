@@ -11,6 +11,7 @@ package http
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"unsafe"
@@ -28,8 +29,20 @@ type response struct {
 
 func (r *response) failed(ctx context.Context, err error) {
 	log.Errorf(ctx, err.Error())
+	metadata.GetAudit(ctx).SetError(err)
 	user := metadata.GetUser(ctx)
 	metric.APIRequestInc(ctx, r.c.Request.URL.Path, metric.StatusFailed, user.SpaceUid, user.Source)
+
+	// 查询开销超限返回 422 以及具体原因
+	var costErr *QueryCostError
+	if errors.As(err, &costErr) {
+		r.c.JSON(http.StatusUnprocessableEntity, QueryCostErrResponse{
+			Err:            err.Error(),
+			QueryCostError: costErr,
+		})
+		return
+	}
+
 	r.c.JSON(http.StatusBadRequest, ErrResponse{
 		Err: err.Error(),
 	})
@@ -39,9 +52,36 @@ func (r *response) success(ctx context.Context, data interface{}) {
 	log.Debugf(ctx, "query data size is %s", fmt.Sprint(unsafe.Sizeof(data)))
 	user := metadata.GetUser(ctx)
 	metric.APIRequestInc(ctx, r.c.Request.URL.Path, metric.StatusSuccess, user.SpaceUid, user.Source)
+	auditResult(ctx, data)
 	r.c.JSON(http.StatusOK, data)
 }
 
+// auditResult 记录返回的 series 数量以及数据行数
+func auditResult(ctx context.Context, data interface{}) {
+	audit := metadata.GetAudit(ctx)
+	if audit == nil {
+		return
+	}
+
+	switch d := data.(type) {
+	case *PromData:
+		if d == nil {
+			return
+		}
+		var rows int
+		for _, t := range d.Tables {
+			rows += len(t.Values)
+		}
+		audit.AddResult(int64(len(d.Tables)), int64(rows))
+	case ListData:
+		audit.AddResult(0, int64(len(d.List)))
+	case *ListData:
+		if d != nil {
+			audit.AddResult(0, int64(len(d.List)))
+		}
+	}
+}
+
 // ListData 数据返回格式
 type ListData struct {
 	Total  int64            `json:"total,omitempty"`
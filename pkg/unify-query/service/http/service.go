@@ -80,6 +80,7 @@ func (s *Service) Reload(ctx context.Context) {
 		middleware.Timer(&middleware.Params{
 			SlowQueryThreshold: SlowQueryThreshold,
 		}),
+		middleware.Audit(auditParams),
 	)
 	registerDefaultHandlers(ctx, public)
 	api.RegisterRelation(ctx, public)
@@ -28,6 +28,23 @@ const (
 	QueryContentTypeConfigPath     = "http.query.content_type"
 	QueryContentEncodingConfigPath = "http.query.content_encoding"
 
+	// 区间查询结果缓存配置
+	QueryCacheEnableConfigPath        = "http.query.cache.enable"
+	QueryCacheStoreConfigPath         = "http.query.cache.store"
+	QueryCacheSplitIntervalConfigPath = "http.query.cache.split_interval"
+	QueryCacheMaxFreshnessConfigPath  = "http.query.cache.max_freshness"
+	QueryCacheTTLConfigPath           = "http.query.cache.ttl"
+	QueryCacheRedisPrefixConfigPath   = "http.query.cache.redis_prefix"
+
+	// 查询开销限制配置，可以通过特性开关按照空间以及用户覆盖
+	QueryCostEnableConfigPath    = "http.query.cost.enable"
+	QueryCostMaxSeriesConfigPath = "http.query.cost.max_series"
+	QueryCostMaxPointsConfigPath = "http.query.cost.max_points"
+	QueryCostMaxRangeConfigPath  = "http.query.cost.max_range"
+
+	// 查询计划预估 series 时每个结果表最多查询的数量
+	QueryExplainSeriesLimitConfigPath = "http.query.explain.series_limit"
+
 	// 服务配置
 	EnablePrometheusConfigPath = "http.prometheus.enable"
 	PrometheusPathConfigPath   = "http.prometheus.path"
@@ -42,10 +59,13 @@ const (
 	TSQueryPromQLHandlePathConfigPath         = "http.path.ts_promql"
 	TSQueryReferenceQueryHandlePathConfigPath = "http.path.ts_reference"
 	TSQueryRawQueryHandlePathConfigPath       = "http.path.ts_raw"
+	TSQueryRawStreamHandlePathConfigPath      = "http.path.ts_raw_stream"
 	TSQueryStructToPromQLHandlePathConfigPath = "http.path.ts_struct_to_promql"
 	TSQueryPromQLToStructHandlePathConfigPath = "http.path.ts_promql_to_struct"
 	TSQueryLabelValuesPathConfigPath          = "http.path.ts_label_values"
 	TSQueryClusterMetricsPathConfigPath       = "http.path.ts_cluster_metrics"
+	TSQueryExplainPathConfigPath              = "http.path.ts_explain"
+	SlowQueriesPathConfigPath                 = "http.path.debug_slow_queries"
 	FluxHandlePromqlPathConfigPath            = "http.path.promql"
 	PrintHandlePathConfigPath                 = "http.path.print"
 	InfluxDBPrintHandlePathConfigPath         = "http.path.influxdb_print"
@@ -54,7 +74,11 @@ const (
 	TsDBPrintHandlePathConfigPath             = "http.path.tsdb_print"
 	FeatureFlagHandlePathConfigPath           = "http.path.feature_flag_path"
 	ESHandlePathConfigPath                    = "http.path.es"
+	PromAPIPathConfigPath                     = "http.path.prom_api"
+	PromAPISpacePathConfigPath                = "http.path.prom_api_space"
+	TraceJaegerPathConfigPath                 = "http.path.trace_jaeger"
 	TSQueryRawMAXLimitConfigPath              = "http.query.raw.max_limit"
+	TSQueryRawStreamPageSizeConfigPath        = "http.query.raw.stream_page_size"
 
 	CheckQueryTsConfigPath     = "http.path.check_query_ts"
 	CheckQueryPromQLConfigPath = "http.path.check_query_promql"
@@ -70,6 +94,28 @@ const (
 	// 集群指标查询配置
 	ClusterMetricQueryPrefixConfigPath  = "http.cluster_metric.prefix"
 	ClusterMetricQueryTimeoutConfigPath = "http.cluster_metric.timeout"
+
+	// 审计记录配置
+	AuditSinkConfigPath           = "http.audit.sink"
+	AuditPathConfigPath           = "http.audit.path"
+	AuditMaxSizeConfigPath        = "http.audit.max_size"
+	AuditMaxBackupsConfigPath     = "http.audit.max_backups"
+	AuditMaxQueryLengthConfigPath = "http.audit.max_query_length"
+
+	// 慢查询列表配置
+	SlowQueriesSizeConfigPath   = "http.slow_queries.size"
+	SlowQueriesWindowConfigPath = "http.slow_queries.window"
+
+	// trace 查询配置
+	TraceLookbackConfigPath = "http.trace.lookback"
+	TraceMaxSpansConfigPath = "http.trace.max_spans"
+
+	// prometheus 兼容接口配置
+	PromAPILabelValuesMaxLimitConfigPath = "http.prom_api.label_values_max_limit"
+
+	// prometheus remote read 配置
+	PromAPIRemoteReadSampleLimitConfigPath     = "http.prom_api.remote_read.sample_limit"
+	PromAPIRemoteReadMaxBytesInFrameConfigPath = "http.prom_api.remote_read.max_bytes_in_frame"
 )
 
 var (
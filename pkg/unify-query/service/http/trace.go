@@ -0,0 +1,749 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/common/model"
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+)
+
+// apm span 存储字段，与 collector 写入的结构保持一致，嵌套字段在 es 查询结果中会被展开
+const (
+	spanFieldTraceID       = "trace_id"
+	spanFieldSpanID        = "span_id"
+	spanFieldParentSpanID  = "parent_span_id"
+	spanFieldSpanName      = "span_name"
+	spanFieldKind          = "kind"
+	spanFieldStartTime     = "start_time"
+	spanFieldElapsedTime   = "elapsed_time"
+	spanFieldStatusCode    = "status.code"
+	spanFieldStatusMessage = "status.message"
+	spanFieldEvents        = "events"
+	spanFieldLinks         = "links"
+	spanFieldServiceName   = "resource.service.name"
+
+	spanAttributesPrefix = "attributes."
+	spanResourcePrefix   = "resource."
+
+	// spanStatusCodeError opentelemetry 的 STATUS_CODE_ERROR
+	spanStatusCodeError = 2
+
+	// jaegerDefaultLimit 与 jaeger-query 一致，默认返回 20 条 trace
+	jaegerDefaultLimit = 20
+)
+
+// spanKinds opentelemetry span kind 对应 jaeger 中的 span.kind 标签
+var spanKinds = map[int64]string{
+	1: "internal",
+	2: "server",
+	3: "client",
+	4: "producer",
+	5: "consumer",
+}
+
+// jaegerKeyValue jaeger 标签结构
+type jaegerKeyValue struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+type jaegerReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+type jaegerLog struct {
+	Timestamp int64            `json:"timestamp"`
+	Fields    []jaegerKeyValue `json:"fields"`
+}
+
+type jaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []jaegerReference `json:"references"`
+	StartTime     int64             `json:"startTime"`
+	Duration      int64             `json:"duration"`
+	Tags          []jaegerKeyValue  `json:"tags"`
+	Logs          []jaegerLog       `json:"logs"`
+	ProcessID     string            `json:"processID"`
+	Warnings      []string          `json:"warnings"`
+}
+
+type jaegerProcess struct {
+	ServiceName string           `json:"serviceName"`
+	Tags        []jaegerKeyValue `json:"tags"`
+}
+
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+	Warnings  []string                 `json:"warnings"`
+}
+
+type jaegerError struct {
+	Code    int    `json:"code,omitempty"`
+	Msg     string `json:"msg"`
+	TraceID string `json:"traceID,omitempty"`
+}
+
+// jaegerAPIResponse jaeger-query http api 返回结构
+type jaegerAPIResponse struct {
+	Data   any           `json:"data"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+	Errors []jaegerError `json:"errors"`
+}
+
+type jaegerResponse struct {
+	c *gin.Context
+}
+
+func (r *jaegerResponse) failed(ctx context.Context, code int, err error) {
+	user := metadata.GetUser(ctx)
+	metric.APIRequestInc(ctx, r.c.Request.URL.Path, metric.StatusFailed, user.SpaceUid, user.Source)
+	r.c.JSON(code, jaegerAPIResponse{
+		Errors: []jaegerError{{Code: code, Msg: err.Error()}},
+	})
+}
+
+func (r *jaegerResponse) success(ctx context.Context, data any, total int) {
+	user := metadata.GetUser(ctx)
+	metric.APIRequestInc(ctx, r.c.Request.URL.Path, metric.StatusSuccess, user.SpaceUid, user.Source)
+	r.c.JSON(http.StatusOK, jaegerAPIResponse{
+		Data:  data,
+		Total: total,
+	})
+}
+
+// jaegerTimeRange 解析 start、end 参数（微秒），未指定 start 时使用 lookback 参数或者默认的回溯时长
+func jaegerTimeRange(c *gin.Context) (start, end time.Time, err error) {
+	parseMicro := func(name string, defaultTime time.Time) (time.Time, error) {
+		v := c.Query(name)
+		if v == "" {
+			return defaultTime, nil
+		}
+		us, parseErr := strconv.ParseInt(v, 10, 64)
+		if parseErr != nil {
+			return time.Time{}, fmt.Errorf("cannot parse parameter '%s': %s", name, parseErr)
+		}
+		return time.UnixMicro(us), nil
+	}
+
+	end, err = parseMicro("end", time.Now())
+	if err != nil {
+		return
+	}
+
+	lookback := viper.GetDuration(TraceLookbackConfigPath)
+	if v := c.Query("lookback"); v != "" && v != "custom" {
+		d, parseErr := model.ParseDuration(v)
+		if parseErr != nil {
+			err = fmt.Errorf("cannot parse parameter 'lookback': %s", parseErr)
+			return
+		}
+		lookback = time.Duration(d)
+	}
+
+	start, err = parseMicro("start", end.Add(-lookback))
+	if err != nil {
+		return
+	}
+	if end.Before(start) {
+		err = fmt.Errorf("start time %d is after end time %d", start.UnixMicro(), end.UnixMicro())
+	}
+	return
+}
+
+// jaegerQueryTs 构造 trace 表的查询，时间单位为秒，结束时间向上取整避免漏掉最后一秒的 span
+func jaegerQueryTs(ctx context.Context, tableID, field string, conditions structured.Conditions, start, end time.Time) *structured.QueryTs {
+	endUnix := end.Unix()
+	if end.Nanosecond() > 0 {
+		endUnix++
+	}
+
+	return &structured.QueryTs{
+		SpaceUid: metadata.GetUser(ctx).SpaceUid,
+		QueryList: []*structured.Query{
+			{
+				DataSource:    structured.BkApm,
+				TableID:       structured.TableID(tableID),
+				FieldName:     field,
+				Conditions:    conditions,
+				Limit:         viper.GetInt(TraceMaxSpansConfigPath),
+				ReferenceName: "a",
+			},
+		},
+		MetricMerge: "a",
+		OrderBy:     structured.OrderBy{"-" + spanFieldStartTime},
+		Start:       strconv.FormatInt(start.Unix(), 10),
+		End:         strconv.FormatInt(endUnix, 10),
+	}
+}
+
+// jaegerTagConditions 转换 tags 查询条件，error=true 对应 span 状态为异常，其余默认查询 attributes 中的字段
+func jaegerTagConditions(c *gin.Context, conditions *structured.Conditions) error {
+	tags := make(map[string]string)
+	if v := c.Query("tags"); v != "" {
+		if err := json.Unmarshal([]byte(v), &tags); err != nil {
+			return fmt.Errorf("malformed 'tags' parameter: %s", err)
+		}
+	}
+	for _, tag := range c.QueryArray("tag") {
+		kv := strings.SplitN(tag, ":", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("malformed 'tag' parameter, expecting key:value, received: %s", tag)
+		}
+		tags[kv[0]] = kv[1]
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		field := structured.ConditionField{
+			DimensionName: k,
+			Value:         []string{tags[k]},
+			Operator:      structured.ConditionEqual,
+		}
+		switch {
+		case k == "error":
+			field.DimensionName = spanFieldStatusCode
+			field.Value = []string{strconv.Itoa(spanStatusCodeError)}
+			if tags[k] != "true" {
+				field.Operator = structured.ConditionNotEqual
+			}
+		case !strings.HasPrefix(k, spanResourcePrefix) && !strings.HasPrefix(k, spanAttributesPrefix):
+			field.DimensionName = spanAttributesPrefix + k
+		}
+		conditions.Append(field, structured.ConditionAnd)
+	}
+	return nil
+}
+
+// jaegerDurationConditions 转换 minDuration、maxDuration 查询条件，span 耗时的单位为微秒
+func jaegerDurationConditions(c *gin.Context, conditions *structured.Conditions) error {
+	for name, operator := range map[string]string{
+		"minDuration": structured.ConditionGte,
+		"maxDuration": structured.ConditionLte,
+	} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("cannot parse parameter '%s': %s", name, err)
+		}
+		conditions.Append(structured.ConditionField{
+			DimensionName: spanFieldElapsedTime,
+			Value:         []string{strconv.FormatInt(d.Microseconds(), 10)},
+			Operator:      operator,
+		}, structured.ConditionAnd)
+	}
+	return nil
+}
+
+func spanString(v any) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	default:
+		return fmt.Sprintf("%v", s)
+	}
+}
+
+func spanInt(v any) int64 {
+	switch n := v.(type) {
+	case float64:
+		return int64(n)
+	case json.Number:
+		i, _ := n.Int64()
+		return i
+	case string:
+		i, _ := strconv.ParseInt(n, 10, 64)
+		return i
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	default:
+		return 0
+	}
+}
+
+// toJaegerKeyValue 根据值类型转换为 jaeger 标签，复杂类型序列化为字符串
+func toJaegerKeyValue(key string, v any) jaegerKeyValue {
+	switch value := v.(type) {
+	case string:
+		return jaegerKeyValue{Key: key, Type: "string", Value: value}
+	case bool:
+		return jaegerKeyValue{Key: key, Type: "bool", Value: value}
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			return jaegerKeyValue{Key: key, Type: "int64", Value: int64(value)}
+		}
+		return jaegerKeyValue{Key: key, Type: "float64", Value: value}
+	case int64, int:
+		return jaegerKeyValue{Key: key, Type: "int64", Value: value}
+	default:
+		s, _ := json.Marshal(value)
+		return jaegerKeyValue{Key: key, Type: "string", Value: string(s)}
+	}
+}
+
+func sortJaegerKeyValues(kvs []jaegerKeyValue) {
+	sort.SliceStable(kvs, func(i, j int) bool {
+		return kvs[i].Key < kvs[j].Key
+	})
+}
+
+// toJaegerSpan 将 span 文档转换为 jaeger span 以及所属的 process
+func toJaegerSpan(doc map[string]any) (jaegerSpan, jaegerProcess) {
+	span := jaegerSpan{
+		TraceID:       spanString(doc[spanFieldTraceID]),
+		SpanID:        spanString(doc[spanFieldSpanID]),
+		OperationName: spanString(doc[spanFieldSpanName]),
+		References:    make([]jaegerReference, 0),
+		StartTime:     spanInt(doc[spanFieldStartTime]),
+		Duration:      spanInt(doc[spanFieldElapsedTime]),
+		Tags:          make([]jaegerKeyValue, 0),
+		Logs:          make([]jaegerLog, 0),
+	}
+	process := jaegerProcess{
+		ServiceName: spanString(doc[spanFieldServiceName]),
+		Tags:        make([]jaegerKeyValue, 0),
+	}
+
+	if parent := spanString(doc[spanFieldParentSpanID]); parent != "" {
+		span.References = append(span.References, jaegerReference{
+			RefType: "CHILD_OF",
+			TraceID: span.TraceID,
+			SpanID:  parent,
+		})
+	}
+	if links, ok := doc[spanFieldLinks].([]any); ok {
+		for _, l := range links {
+			link, ok := l.(map[string]any)
+			if !ok {
+				continue
+			}
+			span.References = append(span.References, jaegerReference{
+				RefType: "FOLLOWS_FROM",
+				TraceID: spanString(link[spanFieldTraceID]),
+				SpanID:  spanString(link[spanFieldSpanID]),
+			})
+		}
+	}
+
+	for k, v := range doc {
+		switch {
+		case strings.HasPrefix(k, spanAttributesPrefix):
+			span.Tags = append(span.Tags, toJaegerKeyValue(strings.TrimPrefix(k, spanAttributesPrefix), v))
+		case k == spanFieldServiceName:
+		case strings.HasPrefix(k, spanResourcePrefix):
+			process.Tags = append(process.Tags, toJaegerKeyValue(strings.TrimPrefix(k, spanResourcePrefix), v))
+		}
+	}
+
+	if kind, ok := spanKinds[spanInt(doc[spanFieldKind])]; ok {
+		span.Tags = append(span.Tags, toJaegerKeyValue("span.kind", kind))
+	}
+	if spanInt(doc[spanFieldStatusCode]) == spanStatusCodeError {
+		span.Tags = append(span.Tags, toJaegerKeyValue("error", true))
+	}
+	if msg := spanString(doc[spanFieldStatusMessage]); msg != "" {
+		span.Tags = append(span.Tags, toJaegerKeyValue("otel.status_description", msg))
+	}
+	sortJaegerKeyValues(span.Tags)
+	sortJaegerKeyValues(process.Tags)
+
+	if events, ok := doc[spanFieldEvents].([]any); ok {
+		for _, e := range events {
+			event, ok := e.(map[string]any)
+			if !ok {
+				continue
+			}
+			log := jaegerLog{
+				Timestamp: spanInt(event["timestamp"]),
+				Fields:    []jaegerKeyValue{toJaegerKeyValue("event", spanString(event["name"]))},
+			}
+			if attrs, ok := event["attributes"].(map[string]any); ok {
+				fields := make([]jaegerKeyValue, 0, len(attrs))
+				for k, v := range attrs {
+					fields = append(fields, toJaegerKeyValue(k, v))
+				}
+				sortJaegerKeyValues(fields)
+				log.Fields = append(log.Fields, fields...)
+			}
+			span.Logs = append(span.Logs, log)
+		}
+	}
+
+	return span, process
+}
+
+// toJaegerTraces 按 trace 分组，traceIDs 指定返回顺序，为空时按照 span 出现的顺序返回
+func toJaegerTraces(docs []map[string]any, traceIDs []string) []*jaegerTrace {
+	type spanWithProcess struct {
+		span    jaegerSpan
+		process jaegerProcess
+	}
+
+	var (
+		spans = make(map[string][]spanWithProcess)
+		order = traceIDs
+	)
+
+	for _, doc := range docs {
+		span, process := toJaegerSpan(doc)
+		if _, ok := spans[span.TraceID]; !ok && len(traceIDs) == 0 {
+			order = append(order, span.TraceID)
+		}
+		spans[span.TraceID] = append(spans[span.TraceID], spanWithProcess{span: span, process: process})
+	}
+
+	res := make([]*jaegerTrace, 0, len(order))
+	for _, id := range order {
+		list, ok := spans[id]
+		if !ok {
+			continue
+		}
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].span.StartTime < list[j].span.StartTime
+		})
+
+		t := &jaegerTrace{
+			TraceID:   id,
+			Spans:     make([]jaegerSpan, 0, len(list)),
+			Processes: make(map[string]jaegerProcess),
+		}
+		// 相同的服务以及资源标签复用同一个 process，按照 span 开始时间依次编号
+		processIDs := make(map[string]string)
+		for _, s := range list {
+			key, _ := json.Marshal(s.process)
+			processID, exists := processIDs[string(key)]
+			if !exists {
+				processID = fmt.Sprintf("p%d", len(processIDs)+1)
+				processIDs[string(key)] = processID
+				t.Processes[processID] = s.process
+			}
+			s.span.ProcessID = processID
+			t.Spans = append(t.Spans, s.span)
+		}
+		res = append(res, t)
+	}
+	return res
+}
+
+// queryTraceSpans 查询 trace 下的全部 span
+func queryTraceSpans(ctx context.Context, tableID string, traceIDs []string, start, end time.Time) ([]map[string]any, error) {
+	conditions := structured.Conditions{}
+	conditions.Append(structured.ConditionField{
+		DimensionName: spanFieldTraceID,
+		Value:         traceIDs,
+		Operator:      structured.ConditionEqual,
+	}, structured.ConditionAnd)
+
+	_, list, err := queryRawWithInstance(ctx, jaegerQueryTs(ctx, tableID, spanFieldTraceID, conditions, start, end))
+	return list, err
+}
+
+// searchTraceIDs 查询满足条件的 span，按开始时间倒序取前 limit 个不重复的 traceID，
+// es 按 traceID 折叠，每个 trace 只返回开始时间最新的一条 span，多个结果表的数据再合并去重
+func searchTraceIDs(ctx context.Context, tableID string, conditions structured.Conditions, start, end time.Time, limit int) ([]string, error) {
+	queryTs := jaegerQueryTs(ctx, tableID, spanFieldTraceID, conditions, start, end)
+	for _, q := range queryTs.QueryList {
+		q.Collapse = spanFieldTraceID
+		q.Limit = limit
+	}
+
+	_, list, err := queryRawWithInstance(ctx, queryTs)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return spanInt(list[i][spanFieldStartTime]) > spanInt(list[j][spanFieldStartTime])
+	})
+
+	traceIDs := make([]string, 0, limit)
+	exists := make(map[string]struct{})
+	for _, d := range list {
+		id := spanString(d[spanFieldTraceID])
+		if _, ok := exists[id]; ok || id == "" {
+			continue
+		}
+		exists[id] = struct{}{}
+		traceIDs = append(traceIDs, id)
+		if len(traceIDs) >= limit {
+			break
+		}
+	}
+	return traceIDs, nil
+}
+
+// queryTraceLabelValues 查询 trace 表中维度的值
+func queryTraceLabelValues(ctx context.Context, tableID, name string, conditions structured.Conditions, start, end time.Time) ([]string, error) {
+	queryTs := jaegerQueryTs(ctx, tableID, name, conditions, start, end)
+	metadata.GetQueryParams(ctx).SetTime(start.Unix(), end.Unix())
+	queryRef, err := queryTs.ToQueryReference(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return queryReferenceLabelValues(ctx, queryRef, name, start, end), nil
+}
+
+// HandlerJaegerServices
+// @Summary  jaeger query api services
+// @ID       jaeger_services
+// @Produce  json
+// @Param    X-Bk-Scope-Space-Uid   header    string   false  "空间UID" default(bkcc__2)
+// @Param    table_id               path      string   true   "trace 结果表"
+// @Success  200                    {object}  jaegerAPIResponse
+// @Router   /query/trace/jaeger/{table_id}/api/services [get]
+func HandlerJaegerServices(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &jaegerResponse{c: c}
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-jaeger-services")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+
+	start, end, err := jaegerTimeRange(c)
+	if err != nil {
+		resp.failed(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	data, err := queryTraceLabelValues(ctx, c.Param("table_id"), spanFieldServiceName, structured.Conditions{}, start, end)
+	if err != nil {
+		resp.failed(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	resp.success(ctx, data, len(data))
+}
+
+// HandlerJaegerOperations
+// @Summary  jaeger query api operations
+// @ID       jaeger_operations
+// @Produce  json
+// @Param    X-Bk-Scope-Space-Uid   header    string   false  "空间UID" default(bkcc__2)
+// @Param    table_id               path      string   true   "trace 结果表"
+// @Param    service                path      string   true   "服务名"
+// @Success  200                    {object}  jaegerAPIResponse
+// @Router   /query/trace/jaeger/{table_id}/api/services/{service}/operations [get]
+func HandlerJaegerOperations(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &jaegerResponse{c: c}
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-jaeger-operations")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+
+	start, end, err := jaegerTimeRange(c)
+	if err != nil {
+		resp.failed(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	conditions := structured.Conditions{}
+	conditions.Append(structured.ConditionField{
+		DimensionName: spanFieldServiceName,
+		Value:         []string{c.Param("service")},
+		Operator:      structured.ConditionEqual,
+	}, structured.ConditionAnd)
+
+	data, err := queryTraceLabelValues(ctx, c.Param("table_id"), spanFieldSpanName, conditions, start, end)
+	if err != nil {
+		resp.failed(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	resp.success(ctx, data, len(data))
+}
+
+// HandlerJaegerTrace
+// @Summary  jaeger query api get trace
+// @ID       jaeger_trace
+// @Produce  json
+// @Param    X-Bk-Scope-Space-Uid   header    string   false  "空间UID" default(bkcc__2)
+// @Param    table_id               path      string   true   "trace 结果表"
+// @Param    trace_id               path      string   true   "traceID"
+// @Success  200                    {object}  jaegerAPIResponse
+// @Failure  404                    {object}  jaegerAPIResponse
+// @Router   /query/trace/jaeger/{table_id}/api/traces/{trace_id} [get]
+func HandlerJaegerTrace(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &jaegerResponse{c: c}
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-jaeger-trace")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+
+	traceID := c.Param("trace_id")
+	start, end, err := jaegerTimeRange(c)
+	if err != nil {
+		resp.failed(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	docs, err := queryTraceSpans(ctx, c.Param("table_id"), []string{traceID}, start, end)
+	if err != nil {
+		resp.failed(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	traces := toJaegerTraces(docs, []string{traceID})
+	if len(traces) == 0 {
+		err = fmt.Errorf("trace not found")
+		resp.failed(ctx, http.StatusNotFound, err)
+		return
+	}
+	resp.success(ctx, traces, len(traces))
+}
+
+// HandlerJaegerFindTraces
+// @Summary  jaeger query api find traces
+// @ID       jaeger_find_traces
+// @Produce  json
+// @Param    X-Bk-Scope-Space-Uid   header    string   false  "空间UID" default(bkcc__2)
+// @Param    table_id               path      string   true   "trace 结果表"
+// @Param    service                query     string   true   "服务名"
+// @Param    operation              query     string   false  "接口名"
+// @Param    tags                   query     string   false  "标签过滤，json 格式"
+// @Param    minDuration            query     string   false  "最小耗时" default(100ms)
+// @Param    maxDuration            query     string   false  "最大耗时" default(1s)
+// @Param    limit                  query     int      false  "trace 数量" default(20)
+// @Success  200                    {object}  jaegerAPIResponse
+// @Failure  400                    {object}  jaegerAPIResponse
+// @Router   /query/trace/jaeger/{table_id}/api/traces [get]
+func HandlerJaegerFindTraces(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		resp = &jaegerResponse{c: c}
+		err  error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-jaeger-find-traces")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+
+	tableID := c.Param("table_id")
+	service := c.Query("service")
+	if service == "" {
+		err = fmt.Errorf("parameter 'service' is required")
+		resp.failed(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	limit := jaegerDefaultLimit
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			err = fmt.Errorf("parameter 'limit' must be a positive integer: %s", v)
+			resp.failed(ctx, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	start, end, err := jaegerTimeRange(c)
+	if err != nil {
+		resp.failed(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	conditions := structured.Conditions{}
+	conditions.Append(structured.ConditionField{
+		DimensionName: spanFieldServiceName,
+		Value:         []string{service},
+		Operator:      structured.ConditionEqual,
+	}, structured.ConditionAnd)
+	if operation := c.Query("operation"); operation != "" {
+		conditions.Append(structured.ConditionField{
+			DimensionName: spanFieldSpanName,
+			Value:         []string{operation},
+			Operator:      structured.ConditionEqual,
+		}, structured.ConditionAnd)
+	}
+	if err = jaegerTagConditions(c, &conditions); err != nil {
+		resp.failed(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err = jaegerDurationConditions(c, &conditions); err != nil {
+		resp.failed(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	traceIDs, err := searchTraceIDs(ctx, tableID, conditions, start, end, limit)
+	if err != nil {
+		resp.failed(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	span.Set("trace-ids", traceIDs)
+
+	traces := make([]*jaegerTrace, 0)
+	if len(traceIDs) > 0 {
+		docs, queryErr := queryTraceSpans(ctx, tableID, traceIDs, start, end)
+		if queryErr != nil {
+			err = queryErr
+			resp.failed(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		traces = toJaegerTraces(docs, traceIDs)
+	}
+	resp.success(ctx, traces, len(traces))
+}
+
+// registerJaegerAPIHandlers 注册 jaeger-query 兼容接口，prefix 中需要包含 :table_id 参数
+func registerJaegerAPIHandlers(registerHandler *RegisterHandlers, prefix string) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	registerHandler.register(http.MethodGet, prefix+"/api/services", HandlerJaegerServices)
+	registerHandler.register(http.MethodGet, prefix+"/api/services/:service/operations", HandlerJaegerOperations)
+	registerHandler.register(http.MethodGet, prefix+"/api/traces", HandlerJaegerFindTraces)
+	registerHandler.register(http.MethodGet, prefix+"/api/traces/:trace_id", HandlerJaegerTrace)
+}
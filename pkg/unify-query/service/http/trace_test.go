@@ -0,0 +1,131 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/promql"
+)
+
+func TestToJaegerTraces(t *testing.T) {
+	var docs []map[string]any
+	err := json.Unmarshal([]byte(`[
+{"trace_id":"t1","span_id":"s2","parent_span_id":"s1","span_name":"SELECT","kind":3,"start_time":1723594001000000,"elapsed_time":500,"status.code":2,"status.message":"timeout","resource.service.name":"api","resource.host":"h1","attributes.db.system":"mysql","attributes.retry":2,"events":[{"name":"exception","timestamp":1723594001000100,"attributes":{"exception.type":"Timeout"}}]},
+{"trace_id":"t1","span_id":"s1","span_name":"GET /users","kind":2,"start_time":1723594000000000,"elapsed_time":2000,"status.code":0,"resource.service.name":"api","resource.host":"h1","attributes.http.status_code":200,"links":[{"trace_id":"t0","span_id":"s0"}]},
+{"trace_id":"t2","span_id":"s3","span_name":"consume","kind":5,"start_time":1723594002000000,"elapsed_time":100,"resource.service.name":"worker","attributes.ratio":0.5}
+]`), &docs)
+	assert.Nil(t, err)
+
+	traces := toJaegerTraces(docs, []string{"t2", "t1", "t3"})
+	actual, err := json.Marshal(traces)
+	assert.Nil(t, err)
+
+	expected := `[{"traceID":"t2","spans":[{"traceID":"t2","spanID":"s3","operationName":"consume","references":[],"startTime":1723594002000000,"duration":100,"tags":[{"key":"ratio","type":"float64","value":0.5},{"key":"span.kind","type":"string","value":"consumer"}],"logs":[],"processID":"p1","warnings":null}],"processes":{"p1":{"serviceName":"worker","tags":[]}},"warnings":null},` +
+		`{"traceID":"t1","spans":[{"traceID":"t1","spanID":"s1","operationName":"GET /users","references":[{"refType":"FOLLOWS_FROM","traceID":"t0","spanID":"s0"}],"startTime":1723594000000000,"duration":2000,"tags":[{"key":"http.status_code","type":"int64","value":200},{"key":"span.kind","type":"string","value":"server"}],"logs":[],"processID":"p1","warnings":null},` +
+		`{"traceID":"t1","spanID":"s2","operationName":"SELECT","references":[{"refType":"CHILD_OF","traceID":"t1","spanID":"s1"}],"startTime":1723594001000000,"duration":500,"tags":[{"key":"db.system","type":"string","value":"mysql"},{"key":"error","type":"bool","value":true},{"key":"otel.status_description","type":"string","value":"timeout"},{"key":"retry","type":"int64","value":2},{"key":"span.kind","type":"string","value":"client"}],"logs":[{"timestamp":1723594001000100,"fields":[{"key":"event","type":"string","value":"exception"},{"key":"exception.type","type":"string","value":"Timeout"}]}],"processID":"p1","warnings":null}],"processes":{"p1":{"serviceName":"api","tags":[{"key":"host","type":"string","value":"h1"}]}},"warnings":null}]`
+	assert.Equal(t, expected, string(actual))
+}
+
+func TestJaegerAPIHandler(t *testing.T) {
+	ctx := metadata.InitHashID(context.Background())
+
+	mock.Init()
+	influxdb.MockSpaceRouter(ctx)
+	promql.MockEngine()
+
+	hit := func(id, source string) string {
+		return `{"_index":"es_index","_id":"` + id + `","_source":` + source + `}`
+	}
+	span1 := hit("1", `{"trace_id":"t1","span_id":"s1","span_name":"GET","kind":2,"start_time":1723594000000000,"elapsed_time":2000,"resource":{"service":{"name":"api"}},"attributes":{"http":{"method":"GET"}}}`)
+	span2 := hit("2", `{"trace_id":"t1","span_id":"s2","parent_span_id":"s1","span_name":"SELECT","kind":3,"start_time":1723594000001000,"elapsed_time":1000,"resource":{"service":{"name":"db"}}}`)
+	span3 := hit("3", `{"trace_id":"t2","span_id":"s3","span_name":"GET","kind":2,"start_time":1723594100000000,"elapsed_time":3000,"resource":{"service":{"name":"api"}},"attributes":{"http":{"method":"GET"}}}`)
+	hits := func(list ...string) string {
+		return `{"hits":{"total":{"value":` + strconv.Itoa(len(list)) + `,"relation":"eq"},"hits":[` + strings.Join(list, ",") + `]}}`
+	}
+
+	mock.Es.Set(map[string]any{
+		// 查询 traceID 时按 trace_id 折叠，每个 trace 只返回一条 span
+		`{"collapse":{"field":"trace_id"},"from":0,"query":{"bool":{"filter":[{"bool":{"must":[{"match_phrase":{"resource.service.name":{"query":"api"}}},{"match_phrase":{"span_name":{"query":"GET"}}},{"match_phrase":{"status.code":{"query":"2"}}},{"match_phrase":{"attributes.http.method":{"query":"GET"}}},{"range":{"elapsed_time":{"from":"1000","include_lower":true,"include_upper":true,"to":null}}}]}},{"range":{"dtEventTimeStamp":{"format":"epoch_second","from":1723594000,"include_lower":true,"include_upper":true,"to":1723595000}}}]}},"size":2}`: hits(span3, span1),
+		`{"from":0,"query":{"bool":{"filter":[{"match_phrase":{"trace_id":{"query":"t1"}}},{"range":{"dtEventTimeStamp":{"format":"epoch_second","from":1723594000,"include_lower":true,"include_upper":true,"to":1723595000}}}]}},"size":10000}`:                                                                                                                                                                                                                                                                                                                        hits(span2, span1),
+		`{"from":0,"query":{"bool":{"filter":[{"match_phrase":{"trace_id":{"query":"t9"}}},{"range":{"dtEventTimeStamp":{"format":"epoch_second","from":1723594000,"include_lower":true,"include_upper":true,"to":1723595000}}}]}},"size":10000}`:                                                                                                                                                                                                                                                                                                                        hits(),
+		`{"from":0,"query":{"bool":{"filter":[{"bool":{"should":[{"match_phrase":{"trace_id":{"query":"t2"}}},{"match_phrase":{"trace_id":{"query":"t1"}}}]}},{"range":{"dtEventTimeStamp":{"format":"epoch_second","from":1723594000,"include_lower":true,"include_upper":true,"to":1723595000}}}]}},"size":10000}`:                                                                                                                                                                                                                                                     hits(span1, span3, span2),
+	})
+
+	testCases := map[string]struct {
+		path     string
+		code     int
+		expected string
+	}{
+		"find traces without service": {
+			path:     "/api/traces?start=1723594000000000&end=1723595000000000",
+			code:     http.StatusBadRequest,
+			expected: `{"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":400,"msg":"parameter 'service' is required"}]}`,
+		},
+		"find traces with wrong duration": {
+			path:     "/api/traces?service=api&minDuration=1x&start=1723594000000000&end=1723595000000000",
+			code:     http.StatusBadRequest,
+			expected: `{"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":400,"msg":"cannot parse parameter 'minDuration': time: unknown unit \"x\" in duration \"1x\""}]}`,
+		},
+		"find traces with wrong tags": {
+			path:     "/api/traces?service=api&tag=http.method&start=1723594000000000&end=1723595000000000",
+			code:     http.StatusBadRequest,
+			expected: `{"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":400,"msg":"malformed 'tag' parameter, expecting key:value, received: http.method"}]}`,
+		},
+		"find traces": {
+			path:     "/api/traces?service=api&operation=GET&minDuration=1ms&tag=http.method:GET&tags=%7B%22error%22%3A%22true%22%7D&limit=2&start=1723594000000000&end=1723595000000000",
+			code:     http.StatusOK,
+			expected: `{"data":[{"traceID":"t2","spans":[{"traceID":"t2","spanID":"s3","operationName":"GET","references":[],"startTime":1723594100000000,"duration":3000,"tags":[{"key":"http.method","type":"string","value":"GET"},{"key":"span.kind","type":"string","value":"server"}],"logs":[],"processID":"p1","warnings":null}],"processes":{"p1":{"serviceName":"api","tags":[]}},"warnings":null},{"traceID":"t1","spans":[{"traceID":"t1","spanID":"s1","operationName":"GET","references":[],"startTime":1723594000000000,"duration":2000,"tags":[{"key":"http.method","type":"string","value":"GET"},{"key":"span.kind","type":"string","value":"server"}],"logs":[],"processID":"p1","warnings":null},{"traceID":"t1","spanID":"s2","operationName":"SELECT","references":[{"refType":"CHILD_OF","traceID":"t1","spanID":"s1"}],"startTime":1723594000001000,"duration":1000,"tags":[{"key":"span.kind","type":"string","value":"client"}],"logs":[],"processID":"p2","warnings":null}],"processes":{"p1":{"serviceName":"api","tags":[]},"p2":{"serviceName":"db","tags":[]}},"warnings":null}],"total":2,"limit":0,"offset":0,"errors":null}`,
+		},
+		"get trace": {
+			path:     "/api/traces/t1?start=1723594000000000&end=1723595000000000",
+			code:     http.StatusOK,
+			expected: `{"data":[{"traceID":"t1","spans":[{"traceID":"t1","spanID":"s1","operationName":"GET","references":[],"startTime":1723594000000000,"duration":2000,"tags":[{"key":"http.method","type":"string","value":"GET"},{"key":"span.kind","type":"string","value":"server"}],"logs":[],"processID":"p1","warnings":null},{"traceID":"t1","spanID":"s2","operationName":"SELECT","references":[{"refType":"CHILD_OF","traceID":"t1","spanID":"s1"}],"startTime":1723594000001000,"duration":1000,"tags":[{"key":"span.kind","type":"string","value":"client"}],"logs":[],"processID":"p2","warnings":null}],"processes":{"p1":{"serviceName":"api","tags":[]},"p2":{"serviceName":"db","tags":[]}},"warnings":null}],"total":1,"limit":0,"offset":0,"errors":null}`,
+		},
+		"get trace not found": {
+			path:     "/api/traces/t9?start=1723594000000000&end=1723595000000000",
+			code:     http.StatusNotFound,
+			expected: `{"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":404,"msg":"trace not found"}]}`,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Request = c.Request.WithContext(metadata.InitHashID(ctx))
+				metadata.SetUser(c.Request.Context(), "", influxdb.SpaceUid, "")
+			})
+			prefix := "/query/trace/jaeger/:table_id"
+			router.GET(prefix+"/api/traces", HandlerJaegerFindTraces)
+			router.GET(prefix+"/api/traces/:trace_id", HandlerJaegerTrace)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/query/trace/jaeger/"+influxdb.ResultTableBkBaseEs+c.path, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, c.code, w.Code)
+			assert.Equal(t, c.expected, w.Body.String())
+		})
+	}
+}
@@ -53,6 +53,10 @@ func setDefaultConfig() {
 	viper.SetDefault(BkSqlToleranceConfigPath, 5)
 	viper.SetDefault(BkSqlContentTypeConfigPath, "application/json")
 
+	viper.SetDefault(ClickHouseTimeoutConfigPath, "30s")
+	viper.SetDefault(ClickHouseLimitConfigPath, 2e6)
+	viper.SetDefault(ClickHouseToleranceConfigPath, 5)
+
 	viper.SetDefault(EsTimeoutConfigPath, "30s")
 	viper.SetDefault(EsMaxSizeConfigPath, 1e4)
 	viper.SetDefault(EsMaxRoutingConfigPath, 10)
@@ -88,6 +92,11 @@ func initConfig() {
 	BkSqlTolerance = viper.GetInt(BkSqlToleranceConfigPath)
 	BkSqlContentType = viper.GetString(BkSqlContentTypeConfigPath)
 
+	// clickhouse 配置
+	ClickHouseTimeout = viper.GetDuration(ClickHouseTimeoutConfigPath)
+	ClickHouseLimit = viper.GetInt(ClickHouseLimitConfigPath)
+	ClickHouseTolerance = viper.GetInt(ClickHouseToleranceConfigPath)
+
 	EsTimeout = viper.GetDuration(EsTimeoutConfigPath)
 	EsMaxRouting = viper.GetInt(EsMaxRoutingConfigPath)
 	EsMaxSize = viper.GetInt(EsMaxSizeConfigPath)
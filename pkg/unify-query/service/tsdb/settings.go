@@ -47,6 +47,11 @@ const (
 	BkSqlToleranceConfigPath   = "bk_sql.tolerance"
 	BkSqlContentTypeConfigPath = "bk_sql.content_type"
 
+	// ClickHouse 配置
+	ClickHouseTimeoutConfigPath   = "clickhouse.timeout"
+	ClickHouseLimitConfigPath     = "clickhouse.limit"
+	ClickHouseToleranceConfigPath = "clickhouse.tolerance"
+
 	EsTimeoutConfigPath    = "elasticsearch.timeout"
 	EsMaxRoutingConfigPath = "elasticsearch.max_routing"
 	EsMaxSizeConfigPath    = "elasticsearch.max_size"
@@ -75,6 +80,11 @@ var (
 	BkSqlTolerance   int
 	BkSqlContentType string
 
+	// clickhouse 配置
+	ClickHouseTimeout   time.Duration
+	ClickHouseLimit     int
+	ClickHouseTolerance int
+
 	// victoriaMetrics 配置
 	VmTimeout time.Duration
 
@@ -35,6 +35,9 @@ const (
 
 	FieldValue = "_value"
 	FieldTime  = "_time"
+
+	// aggRate 按照聚合周期计算每秒的数量
+	aggRate = "rate"
 )
 
 var (
@@ -112,7 +115,14 @@ func (f *QueryFactory) ParserQuery() (err error) {
 				f.groups = append(f.groups, dim)
 				f.selects = append(f.selects, dim)
 			}
-			f.selects = append(f.selects, fmt.Sprintf("%s(`%s`) AS `%s`", strings.ToUpper(agg.Name), f.query.Field, value))
+			if agg.Name == aggRate {
+				if agg.Window <= 0 {
+					return fmt.Errorf("aggregate %s window is empty", agg.Name)
+				}
+				f.selects = append(f.selects, fmt.Sprintf("COUNT(`%s`) / %g AS `%s`", f.query.Field, agg.Window.Seconds(), value))
+			} else {
+				f.selects = append(f.selects, fmt.Sprintf("%s(`%s`) AS `%s`", strings.ToUpper(agg.Name), f.query.Field, value))
+			}
 			if agg.Window > 0 {
 				timeField := fmt.Sprintf("(`%s` - (`%s` %% %d))", f.timeField, f.timeField, agg.Window.Milliseconds())
 				f.groups = append(f.groups, timeField)
@@ -161,6 +171,7 @@ func (f *QueryFactory) SQL() (sql string, err error) {
 	}
 
 	orders := make([]string, 0)
+	orderFields := make(map[string]struct{}, len(f.orders))
 	for key, asc := range f.orders {
 		var orderField string
 		switch key {
@@ -171,14 +182,31 @@ func (f *QueryFactory) SQL() (sql string, err error) {
 		default:
 			orderField = key
 		}
+		orderFields[orderField] = struct{}{}
 		ascName := "ASC"
 		if !asc {
 			ascName = "DESC"
 		}
 		orders = append(orders, fmt.Sprintf("`%s` %s", orderField, ascName))
 	}
+	sort.Strings(orders)
+
+	// OFFSET 翻页依赖稳定的排序，否则排序值相同的数据在多次查询之间顺序不一致，会导致翻页重复或者遗漏，
+	// 所以在指定的排序字段之后追加时间和值字段作为兜底排序
+	if len(f.groups) == 0 && (f.query.Cursor || f.query.From > 0) {
+		for _, field := range []string{timeStamp, f.query.Field} {
+			if field == "" {
+				continue
+			}
+			if _, ok := orderFields[field]; ok {
+				continue
+			}
+			orderFields[field] = struct{}{}
+			orders = append(orders, fmt.Sprintf("`%s` ASC", field))
+		}
+	}
+
 	if len(orders) > 0 {
-		sort.Strings(orders)
 		f.write("ORDER BY")
 		f.write(strings.Join(orders, ", "))
 	}
@@ -86,6 +86,24 @@ func TestNewSqlFactory(t *testing.T) {
 			},
 			expected: "SELECT `ip`, COUNT(`gseIndex`) AS `_value_`, MAX((`dtEventTimeStamp` - (`dtEventTimeStamp` % 60000))) AS `_timestamp_` FROM `100133_ieod_logsearch4_errorlog_p`.doris WHERE `dtEventTimeStamp` >= 1717144141000 AND `dtEventTimeStamp` < 1717147741000 AND (gseIndex > 0) GROUP BY `ip`, (`dtEventTimeStamp` - (`dtEventTimeStamp` % 60000)) ORDER BY `_timestamp_` ASC",
 		},
+		"sum-rate-with-promql-1": {
+			query: &metadata.Query{
+				DB:          "100133_ieod_logsearch4_errorlog_p",
+				Measurement: "doris",
+				Field:       "gseIndex",
+				Aggregates: metadata.Aggregates{
+					{
+						Name: "rate",
+						Dimensions: []string{
+							"ip",
+						},
+						Window: time.Minute * 5,
+					},
+				},
+				BkSqlCondition: "gseIndex > 0",
+			},
+			expected: "SELECT `ip`, COUNT(`gseIndex`) / 300 AS `_value_`, MAX((`dtEventTimeStamp` - (`dtEventTimeStamp` % 300000))) AS `_timestamp_` FROM `100133_ieod_logsearch4_errorlog_p`.doris WHERE `dtEventTimeStamp` >= 1717144141000 AND `dtEventTimeStamp` < 1717147741000 AND (gseIndex > 0) GROUP BY `ip`, (`dtEventTimeStamp` - (`dtEventTimeStamp` % 300000)) ORDER BY `_timestamp_` ASC",
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			ctx := metadata.InitHashID(context.Background())
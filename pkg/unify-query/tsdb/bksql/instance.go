@@ -273,9 +273,47 @@ func (i *Instance) table(query *metadata.Query) string {
 	return table
 }
 
-// QueryRawData 直接查询原始返回
+// QueryRawData 直接查询原始返回，通过 OFFSET 和 LIMIT 翻页
 func (i *Instance) QueryRawData(ctx context.Context, query *metadata.Query, start, end time.Time, dataCh chan<- map[string]any) (int64, error) {
-	return 0, nil
+	var (
+		err error
+	)
+	ctx, span := trace.NewSpan(ctx, "bk-sql-query-raw")
+	defer span.End(&err)
+
+	if start.UnixMilli() > end.UnixMilli() || start.UnixMilli() == 0 {
+		err = fmt.Errorf("range time is error, start: %s, end: %s ", start, end)
+		return 0, err
+	}
+
+	if i.maxLimit > 0 && (query.Size == 0 || query.Size > i.maxLimit) {
+		query.Size = i.maxLimit
+	}
+
+	// 游标翻页需要稳定的排序，默认按时间倒序
+	if query.Cursor && len(query.Orders) == 0 {
+		query.Orders = metadata.Orders{FieldTime: false}
+	}
+
+	sql, err := NewQueryFactory(ctx, query).WithRangeTime(start, end).SQL()
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := i.sqlQuery(ctx, sql, span)
+	if err != nil {
+		return 0, err
+	}
+	if data == nil {
+		return 0, nil
+	}
+
+	for _, d := range data.List {
+		dataCh <- d
+	}
+
+	span.Set("data-total-records", data.TotalRecords)
+	return int64(data.TotalRecords), nil
 }
 
 func (i *Instance) QuerySeriesSet(ctx context.Context, query *metadata.Query, start, end time.Time) storage.SeriesSet {
@@ -450,3 +488,21 @@ func getValue(k string, d map[string]interface{}) (string, error) {
 	}
 	return value, nil
 }
+
+// Explain 生成 bksql 查询语句
+func (i *Instance) Explain(ctx context.Context, query *metadata.Query, start, end time.Time) (*tsdb.QueryPlan, error) {
+	qry := *query
+	if i.maxLimit > 0 && (qry.Size == 0 || qry.Size > i.maxLimit) {
+		qry.Size = i.maxLimit + i.tolerance
+	}
+
+	sql, err := NewQueryFactory(ctx, &qry).WithRangeTime(start, end).SQL()
+	if err != nil {
+		return nil, err
+	}
+	return &tsdb.QueryPlan{
+		Language:   tsdb.LanguageSQL,
+		Statements: []string{sql},
+		Indexes:    []string{query.DB},
+	}, nil
+}
@@ -196,6 +196,21 @@ func TestInstance_bkSql(t *testing.T) {
 
 			expected: "SELECT `ip`, COUNT(`gseIndex`) AS `_value_` FROM `100133_ieod_logsearch4_errorlog_p`.doris WHERE `dtEventTimeStamp` >= 1718189940000 AND `dtEventTimeStamp` < 1718193555000 GROUP BY `ip` LIMIT 5",
 		},
+		{
+			query: &metadata.Query{
+				DB:          "100133_ieod_logsearch4_errorlog_p",
+				Measurement: "doris",
+				Field:       "gseIndex",
+				Orders: metadata.Orders{
+					"serverIp": true,
+				},
+				Cursor: true,
+				From:   10,
+				Size:   5,
+			},
+
+			expected: "SELECT *, `gseIndex` AS `_value_`, `dtEventTimeStamp` AS `_timestamp_` FROM `100133_ieod_logsearch4_errorlog_p`.doris WHERE `dtEventTimeStamp` >= 1718189940000 AND `dtEventTimeStamp` < 1718193555000 ORDER BY `serverIp` ASC, `_timestamp_` ASC, `gseIndex` ASC OFFSET 10 LIMIT 5",
+		},
 	}
 
 	for i, c := range testCases {
@@ -209,3 +224,50 @@ func TestInstance_bkSql(t *testing.T) {
 		})
 	}
 }
+
+func TestInstance_QueryRawData(t *testing.T) {
+	ctx := metadata.InitHashID(context.Background())
+	mock.Init()
+
+	ins, err := NewInstance(ctx, &Options{
+		Address:   mock.BkSQLUrl,
+		Timeout:   time.Minute,
+		MaxLimit:  1e4,
+		Tolerance: 5,
+		Curl:      &curl.HttpCurl{Log: log.DefaultLogger},
+	})
+	assert.Nil(t, err)
+
+	mock.BkSQL.Set(map[string]any{
+		"SELECT *, `login_rate` AS `_value_`, `dtEventTimeStamp` AS `_timestamp_` FROM `132_lol_new_login_queue_login_1min` WHERE `dtEventTimeStamp` >= 1730118589181 AND `dtEventTimeStamp` < 1730118889181 ORDER BY `_timestamp_` ASC, `login_rate` ASC OFFSET 2 LIMIT 2": "{\"result\":true,\"message\":\"成功\",\"code\":\"00\",\"data\":{\"totalRecords\":2,\"list\":[{\"namespace\":\"gz100\",\"login_rate\":267.0,\"_value_\":267.0,\"_timestamp_\":1730118600000},{\"namespace\":\"gz100\",\"login_rate\":274.0,\"_value_\":274.0,\"_timestamp_\":1730118660000}],\"select_fields_order\":[\"namespace\",\"login_rate\",\"_value_\",\"_timestamp_\"]},\"errors\":null}",
+	})
+
+	query := &metadata.Query{
+		DB:     "132_lol_new_login_queue_login_1min",
+		Field:  "login_rate",
+		From:   2,
+		Size:   2,
+		Orders: metadata.Orders{FieldTime: true},
+	}
+
+	dataCh := make(chan map[string]any)
+	list := make([]map[string]any, 0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for d := range dataCh {
+			list = append(list, d)
+		}
+	}()
+
+	total, err := ins.QueryRawData(ctx, query, time.UnixMilli(1730118589181), time.UnixMilli(1730118889181), dataCh)
+	close(dataCh)
+	<-done
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, list, 2)
+	if len(list) == 2 {
+		assert.Equal(t, float64(1730118660000), list[1]["_timestamp_"])
+	}
+}
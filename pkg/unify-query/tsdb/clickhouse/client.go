@@ -0,0 +1,103 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/curl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+)
+
+type Client struct {
+	url      string
+	username string
+	password string
+	headers  map[string]string
+
+	timeout time.Duration
+
+	curl curl.Curl
+}
+
+func (c *Client) WithCurl(cc curl.Curl) *Client {
+	c.curl = cc
+	return c
+}
+
+func (c *Client) WithUrl(address string) *Client {
+	// 通过 http 接口查询，统一使用 JSON 格式返回，并且 64 位整型不使用字符串返回
+	params := url.Values{}
+	params.Set("default_format", FormatJSON)
+	params.Set("output_format_json_quote_64bit_integers", "0")
+	c.url = fmt.Sprintf("%s/?%s", address, params.Encode())
+	return c
+}
+
+func (c *Client) WithAuth(username, password string) *Client {
+	c.username = username
+	c.password = password
+	return c
+}
+
+func (c *Client) WithHeader(headers map[string]string) *Client {
+	c.headers = headers
+	return c
+}
+
+func (c *Client) WithTimeout(timeout time.Duration) *Client {
+	c.timeout = timeout
+	return c
+}
+
+// Query 通过 http 接口执行 sql 查询
+func (c *Client) Query(ctx context.Context, sql string, span *trace.Span) (*Result, error) {
+	if sql == "" {
+		return nil, fmt.Errorf("query sql is empty")
+	}
+
+	res := &Result{}
+	startAnaylize := time.Now()
+	size, err := c.curl.Request(
+		ctx, curl.Post,
+		curl.Options{
+			UrlPath:  c.url,
+			Body:     []byte(sql),
+			Headers:  metadata.Headers(ctx, c.headers),
+			UserName: c.username,
+			Password: c.password,
+			Timeout:  c.timeout,
+		},
+		res,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user := metadata.GetUser(ctx)
+	metric.TsDBRequestBytes(ctx, size, user.SpaceUid, user.Source, consul.ClickHouseStorageType)
+
+	queryCost := time.Since(startAnaylize)
+	if span != nil {
+		span.Set("query-cost", queryCost.String())
+		span.Set("query-rows-read", res.Statistics.RowsRead)
+	}
+
+	metric.TsDBRequestSecond(
+		ctx, queryCost, user.SpaceUid, user.Source, consul.ClickHouseStorageType, c.url,
+	)
+	return res, nil
+}
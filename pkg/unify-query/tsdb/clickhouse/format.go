@@ -0,0 +1,467 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+var (
+	// aggregateFunc 支持下推的聚合方法
+	aggregateFunc = map[string]string{
+		structured.MIN:   "min",
+		structured.MAX:   "max",
+		structured.SUM:   "sum",
+		structured.AVG:   "avg",
+		structured.COUNT: "count",
+	}
+)
+
+type QueryFactory struct {
+	ctx context.Context
+
+	query *metadata.Query
+
+	start time.Time
+	end   time.Time
+
+	selects    []string
+	groups     []string
+	orders     metadata.Orders
+	dimensions []string
+
+	sql strings.Builder
+
+	timeField string
+}
+
+func NewQueryFactory(ctx context.Context, query *metadata.Query) *QueryFactory {
+	f := &QueryFactory{
+		ctx:     ctx,
+		query:   query,
+		selects: make([]string, 0),
+		groups:  make([]string, 0),
+		orders:  make(metadata.Orders),
+	}
+	for k, v := range query.Orders {
+		f.orders[k] = v
+	}
+
+	if query.TimeField.Name != "" {
+		f.timeField = query.TimeField.Name
+	} else {
+		f.timeField = DefaultTimeField
+	}
+	return f
+}
+
+func (f *QueryFactory) write(s string) {
+	f.sql.WriteString(s + " ")
+}
+
+func (f *QueryFactory) WithRangeTime(start, end time.Time) *QueryFactory {
+	f.start = start
+	f.end = end
+	return f
+}
+
+// WithDimensions 原始查询的维度列，用于按 series 及时间排序
+func (f *QueryFactory) WithDimensions(dimensions []string) *QueryFactory {
+	f.dimensions = dimensions
+	return f
+}
+
+// timestamp 毫秒时间戳表达式
+func (f *QueryFactory) timestamp() string {
+	return fmt.Sprintf("toUnixTimestamp64Milli(%s)", quote(f.timeField))
+}
+
+func (f *QueryFactory) parserQuery() error {
+	f.selects = f.selects[:0]
+	f.groups = f.groups[:0]
+
+	// 多个聚合存在相同的分组列时只输出一次
+	grouped := make(map[string]struct{})
+	for _, agg := range f.query.Aggregates {
+		name, ok := aggregateFunc[strings.ToLower(agg.Name)]
+		if !ok {
+			return fmt.Errorf("aggregate %s is not support in %s", agg.Name, f.query.TableID)
+		}
+
+		for _, dim := range agg.Dimensions {
+			dim = quote(dim)
+			if _, ok := grouped[dim]; ok {
+				continue
+			}
+			grouped[dim] = struct{}{}
+			f.groups = append(f.groups, dim)
+			f.selects = append(f.selects, dim)
+		}
+		f.selects = append(f.selects, fmt.Sprintf("%s(%s) AS %s", name, quote(f.query.Field), quote(value)))
+		if agg.Window > 0 {
+			if _, ok := grouped[quote(timeStamp)]; ok {
+				continue
+			}
+			grouped[quote(timeStamp)] = struct{}{}
+			window := agg.Window.Milliseconds()
+			f.selects = append(f.selects, fmt.Sprintf("intDiv(%s, %d) * %d AS %s", f.timestamp(), window, window, quote(timeStamp)))
+			f.groups = append(f.groups, quote(timeStamp))
+			f.orders[FieldTime] = true
+		}
+	}
+
+	if len(f.selects) == 0 {
+		f.selects = append(f.selects, "*")
+		f.selects = append(f.selects, fmt.Sprintf("%s AS %s", quote(f.query.Field), quote(value)))
+		f.selects = append(f.selects, fmt.Sprintf("%s AS %s", f.timestamp(), quote(timeStamp)))
+	}
+
+	return nil
+}
+
+// Table 查询的表名，db 对应 ClickHouse 的 database，measurement 对应表名
+func (f *QueryFactory) Table() string {
+	if f.query.Measurement == "" {
+		return quote(f.query.DB)
+	}
+	return fmt.Sprintf("%s.%s", quote(f.query.DB), quote(f.query.Measurement))
+}
+
+// Where 时间范围以及过滤条件
+func (f *QueryFactory) Where() (string, error) {
+	where := fmt.Sprintf(
+		"%s >= fromUnixTimestamp64Milli(toInt64(%d)) AND %s < fromUnixTimestamp64Milli(toInt64(%d))",
+		quote(f.timeField), f.start.UnixMilli(), quote(f.timeField), f.end.UnixMilli(),
+	)
+
+	condition, err := buildCondition(f.query.AllConditions)
+	if err != nil {
+		return "", err
+	}
+	if condition != "" {
+		where = fmt.Sprintf("%s AND (%s)", where, condition)
+	}
+	return where, nil
+}
+
+// SQL 生成查询语句
+func (f *QueryFactory) SQL() (string, error) {
+	f.sql.Reset()
+	if err := f.parserQuery(); err != nil {
+		return "", err
+	}
+
+	where, err := f.Where()
+	if err != nil {
+		return "", err
+	}
+
+	f.write("SELECT")
+	f.write(strings.Join(f.selects, ", "))
+	f.write("FROM")
+	f.write(f.Table())
+	f.write("WHERE")
+	f.write(where)
+	if len(f.groups) > 0 {
+		f.write("GROUP BY")
+		f.write(strings.Join(f.groups, ", "))
+	}
+
+	orders := f.orderBy()
+	if len(orders) > 0 {
+		f.write("ORDER BY")
+		f.write(strings.Join(orders, ", "))
+	}
+	if f.query.Size > 0 {
+		f.write("LIMIT")
+		f.write(fmt.Sprintf("%d", f.query.Size))
+	}
+	if f.query.From > 0 {
+		f.write("OFFSET")
+		f.write(fmt.Sprintf("%d", f.query.From))
+	}
+
+	return strings.Trim(f.sql.String(), " "), nil
+}
+
+// orderBy 排序语句，原始查询按维度及时间排序，保证同一 series 的样本按时间有序
+func (f *QueryFactory) orderBy() []string {
+	if len(f.query.Aggregates) == 0 {
+		orders := make([]string, 0, len(f.dimensions)+1)
+		for _, dim := range f.dimensions {
+			orders = append(orders, fmt.Sprintf("%s ASC", quote(dim)))
+		}
+		return append(orders, fmt.Sprintf("%s ASC", quote(timeStamp)))
+	}
+
+	orders := make([]string, 0, len(f.orders))
+	for key, asc := range f.orders {
+		var orderField string
+		switch key {
+		case FieldValue:
+			orderField = value
+		case FieldTime:
+			orderField = timeStamp
+		default:
+			orderField = key
+		}
+		ascName := "ASC"
+		if !asc {
+			ascName = "DESC"
+		}
+		orders = append(orders, fmt.Sprintf("%s %s", quote(orderField), ascName))
+	}
+	sort.Strings(orders)
+	return orders
+}
+
+// dims 获取维度列，聚合查询返回所有的分组列，原始查询只返回字符串类型的列
+func (f *QueryFactory) dims(meta []Column) []string {
+	dimensions := make([]string, 0, len(meta))
+	for _, m := range meta {
+		switch m.Name {
+		case value, timeStamp, f.timeField, f.query.Field:
+			continue
+		}
+
+		if len(f.query.Aggregates) == 0 && !isStringType(m.Type) {
+			continue
+		}
+		dimensions = append(dimensions, m.Name)
+	}
+	return dimensions
+}
+
+// FormatData 把查询结果转换为 prompb.QueryResult
+func (f *QueryFactory) FormatData(meta []Column, list []map[string]interface{}) (*prompb.QueryResult, error) {
+	res := &prompb.QueryResult{}
+
+	if len(list) == 0 {
+		return res, nil
+	}
+	if len(meta) == 0 {
+		return res, fmt.Errorf("result meta is empty")
+	}
+
+	dimensions := f.dims(meta)
+	metricLabel := f.query.MetricLabels(f.ctx)
+
+	tsMap := make(map[string]*prompb.TimeSeries)
+	keys := make([]string, 0)
+	for _, d := range list {
+		if d == nil {
+			continue
+		}
+
+		// 获取时间戳，单位是毫秒，没有时间聚合的情况下使用开始时间
+		var vt int64
+		if t, ok := d[timeStamp]; ok {
+			if t == nil {
+				continue
+			}
+			ts, err := toFloat(t)
+			if err != nil {
+				return res, fmt.Errorf("%s %s", timeStamp, err.Error())
+			}
+			vt = int64(ts)
+		} else {
+			vt = f.start.UnixMilli()
+		}
+
+		v, ok := d[value]
+		if !ok {
+			return res, fmt.Errorf("dimension %s is emtpy", value)
+		}
+		// 空值以及 NaN 在 JSON 中都返回 null
+		if v == nil {
+			continue
+		}
+		vv, err := toFloat(v)
+		if err != nil {
+			return res, fmt.Errorf("%s %s", value, err.Error())
+		}
+
+		lbl := make([]prompb.Label, 0, len(dimensions)+1)
+		for _, dimName := range dimensions {
+			val, err := getValue(dimName, d)
+			if err != nil {
+				return res, fmt.Errorf("dimensions %+v %s", dimensions, err.Error())
+			}
+			lbl = append(lbl, prompb.Label{
+				Name:  dimName,
+				Value: val,
+			})
+		}
+		if metricLabel != nil {
+			lbl = append(lbl, *metricLabel)
+		}
+
+		var buf strings.Builder
+		for _, l := range lbl {
+			buf.WriteString(l.String())
+		}
+
+		// 同一个 series 进行合并分组
+		key := buf.String()
+		if _, ok := tsMap[key]; !ok {
+			tsMap[key] = &prompb.TimeSeries{
+				Labels:  lbl,
+				Samples: make([]prompb.Sample, 0),
+			}
+			keys = append(keys, key)
+		}
+
+		tsMap[key].Samples = append(tsMap[key].Samples, prompb.Sample{
+			Value:     vv,
+			Timestamp: vt,
+		})
+	}
+
+	res.Timeseries = make([]*prompb.TimeSeries, 0, len(tsMap))
+	for _, key := range keys {
+		res.Timeseries = append(res.Timeseries, tsMap[key])
+	}
+
+	return res, nil
+}
+
+// buildCondition 把查询条件转换为 ClickHouse 的 where 语句，同一组内使用 AND 连接，不同组之间使用 OR 连接
+func buildCondition(allConditions metadata.AllConditions) (string, error) {
+	var orList []string
+	for _, conditions := range allConditions {
+		var andList []string
+		for _, c := range conditions {
+			s, err := conditionField(c)
+			if err != nil {
+				return "", err
+			}
+			if s != "" {
+				andList = append(andList, s)
+			}
+		}
+
+		switch len(andList) {
+		case 0:
+		case 1:
+			orList = append(orList, andList[0])
+		default:
+			orList = append(orList, fmt.Sprintf("(%s)", strings.Join(andList, " AND ")))
+		}
+	}
+
+	return strings.Join(orList, " OR "), nil
+}
+
+func conditionField(c metadata.ConditionField) (string, error) {
+	var (
+		key     = quote(c.DimensionName)
+		format  string
+		logical = " OR "
+	)
+
+	switch c.Operator {
+	case structured.ConditionEqual, structured.ConditionContains, structured.ConditionExact:
+		format = "%s = %s"
+	case structured.ConditionNotEqual, structured.ConditionNotContains:
+		format = "%s != %s"
+		logical = " AND "
+	case structured.ConditionRegEqual:
+		format = "match(%s, %s)"
+	case structured.ConditionNotRegEqual:
+		format = "NOT match(%s, %s)"
+		logical = " AND "
+	case structured.ConditionGt:
+		format = "%s > %s"
+	case structured.ConditionGte:
+		format = "%s >= %s"
+	case structured.ConditionLt:
+		format = "%s < %s"
+	case structured.ConditionLte:
+		format = "%s <= %s"
+	case structured.ConditionExisted:
+		return fmt.Sprintf("%s != ''", key), nil
+	case structured.ConditionNotExisted:
+		return fmt.Sprintf("%s = ''", key), nil
+	default:
+		return "", fmt.Errorf("condition operator %s is not support", c.Operator)
+	}
+
+	list := make([]string, 0, len(c.Value))
+	for _, v := range c.Value {
+		list = append(list, fmt.Sprintf(format, key, literal(v)))
+	}
+
+	switch len(list) {
+	case 0:
+		return "", nil
+	case 1:
+		return list[0], nil
+	default:
+		return fmt.Sprintf("(%s)", strings.Join(list, logical)), nil
+	}
+}
+
+// quote 标识符转义
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
+
+// literal 字符串常量转义
+func literal(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int64:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	default:
+		return 0, fmt.Errorf("type is error %T, %v", v, v)
+	}
+}
+
+func getValue(k string, d map[string]interface{}) (string, error) {
+	var val string
+	if v, ok := d[k]; ok {
+		// 增加 nil 判断，避免回传的数值为空
+		if v == nil {
+			return val, nil
+		}
+
+		switch v.(type) {
+		case string:
+			val = fmt.Sprintf("%s", v)
+		case float64, float32:
+			val = fmt.Sprintf("%.f", v)
+		case int64, int32, int:
+			val = fmt.Sprintf("%d", v)
+		case bool:
+			val = fmt.Sprintf("%t", v)
+		default:
+			return val, fmt.Errorf("get_value_error: type %T, %v in %s with %+v", v, v, k, d)
+		}
+	}
+	return val, nil
+}
@@ -0,0 +1,409 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/curl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb/decoder"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
+)
+
+type Instance struct {
+	ctx context.Context
+
+	timeout time.Duration
+
+	maxLimit  int
+	tolerance int
+
+	client *Client
+}
+
+var _ tsdb.Instance = (*Instance)(nil)
+
+type Options struct {
+	Address  string
+	Username string
+	Password string
+	Headers  map[string]string
+
+	Timeout   time.Duration
+	MaxLimit  int
+	Tolerance int
+
+	Curl curl.Curl
+}
+
+func NewInstance(ctx context.Context, opt *Options) (*Instance, error) {
+	if opt.Address == "" {
+		return nil, fmt.Errorf("address is empty")
+	}
+	instance := &Instance{
+		ctx:       ctx,
+		timeout:   opt.Timeout,
+		maxLimit:  opt.MaxLimit,
+		tolerance: opt.Tolerance,
+		client: (&Client{}).
+			WithUrl(strings.TrimRight(opt.Address, "/")).
+			WithAuth(opt.Username, opt.Password).
+			WithHeader(opt.Headers).
+			WithTimeout(opt.Timeout).
+			WithCurl(opt.Curl),
+	}
+	return instance, nil
+}
+
+func (i *Instance) Check(ctx context.Context, promql string, start, end time.Time, step time.Duration) string {
+	return ""
+}
+
+func (i *Instance) sqlQuery(ctx context.Context, sql string, span *trace.Span) (*Result, error) {
+	log.Infof(ctx, "%s: %s", i.InstanceType(), sql)
+	span.Set("query-sql", sql)
+	span.Set("query-timeout", i.timeout.String())
+
+	if i.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, i.timeout)
+		defer cancel()
+	}
+
+	return i.client.Query(ctx, sql, span)
+}
+
+// limit 根据配置补充查询的最大条数
+func (i *Instance) limit(query *metadata.Query) {
+	if i.maxLimit > 0 {
+		// 如果不传 size，则取最大的限制值
+		if query.Size == 0 || query.Size > i.maxLimit {
+			query.Size = i.maxLimit + i.tolerance
+		}
+	}
+}
+
+func (i *Instance) checkRange(start, end time.Time) error {
+	if start.UnixMilli() > end.UnixMilli() || start.UnixMilli() == 0 {
+		return fmt.Errorf("range time is error, start: %s, end: %s ", start, end)
+	}
+	return nil
+}
+
+// QueryRawData 直接查询原始返回
+func (i *Instance) QueryRawData(ctx context.Context, query *metadata.Query, start, end time.Time, dataCh chan<- map[string]any) (int64, error) {
+	var (
+		err error
+	)
+	ctx, span := trace.NewSpan(ctx, "clickhouse-query-raw")
+	defer span.End(&err)
+
+	if err = i.checkRange(start, end); err != nil {
+		return 0, err
+	}
+
+	i.limit(query)
+	qf := NewQueryFactory(ctx, query).WithRangeTime(start, end)
+	where, err := qf.Where()
+	if err != nil {
+		return 0, err
+	}
+
+	sql := fmt.Sprintf("SELECT * FROM %s WHERE %s", qf.Table(), where)
+	if len(query.Orders) > 0 {
+		orders := make([]string, 0, len(query.Orders))
+		for key, asc := range query.Orders {
+			if key == FieldTime {
+				key = qf.timeField
+			}
+			ascName := "ASC"
+			if !asc {
+				ascName = "DESC"
+			}
+			orders = append(orders, fmt.Sprintf("%s %s", quote(key), ascName))
+		}
+		sort.Strings(orders)
+		sql = fmt.Sprintf("%s ORDER BY %s", sql, strings.Join(orders, ", "))
+	}
+	if query.Size > 0 {
+		sql = fmt.Sprintf("%s LIMIT %d", sql, query.Size)
+	}
+	if query.From > 0 {
+		sql = fmt.Sprintf("%s OFFSET %d", sql, query.From)
+	}
+
+	data, err := i.sqlQuery(ctx, sql, span)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range data.Data {
+		dataCh <- d
+	}
+
+	total := int64(data.RowsBeforeLimitAtLeast)
+	if total < int64(data.Rows) {
+		total = int64(data.Rows)
+	}
+	span.Set("data-total-records", total)
+	return total, nil
+}
+
+func (i *Instance) QuerySeriesSet(ctx context.Context, query *metadata.Query, start, end time.Time) storage.SeriesSet {
+	var (
+		err error
+	)
+	ctx, span := trace.NewSpan(ctx, "clickhouse-query-series-set")
+	defer span.End(&err)
+
+	if err = i.checkRange(start, end); err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	i.limit(query)
+	qf, err := i.seriesQueryFactory(ctx, query, start, end, span)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+	sql, err := qf.SQL()
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	data, err := i.sqlQuery(ctx, sql, span)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	span.Set("data-total-records", data.Rows)
+	log.Infof(ctx, "total records: %d", data.Rows)
+
+	if i.maxLimit > 0 && data.Rows > i.maxLimit {
+		err = fmt.Errorf("记录数(%d)超过限制(%d)", data.Rows, i.maxLimit)
+		return storage.ErrSeriesSet(err)
+	}
+
+	qr, err := qf.FormatData(data.Meta, data.Data)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	return remote.FromQueryResult(true, qr)
+}
+
+// seriesQueryFactory 原始查询需要按维度排序，先通过表结构获取维度列
+func (i *Instance) seriesQueryFactory(ctx context.Context, query *metadata.Query, start, end time.Time, span *trace.Span) (*QueryFactory, error) {
+	qf := NewQueryFactory(ctx, query).WithRangeTime(start, end)
+	if len(query.Aggregates) > 0 {
+		return qf, nil
+	}
+
+	dimensions, err := i.labelNames(ctx, query, span)
+	if err != nil {
+		return nil, err
+	}
+	return qf.WithDimensions(dimensions), nil
+}
+
+func (i *Instance) DirectQueryRange(ctx context.Context, promql string, start, end time.Time, step time.Duration) (promql.Matrix, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (i *Instance) DirectQuery(ctx context.Context, qs string, end time.Time) (promql.Vector, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (i *Instance) QueryExemplar(ctx context.Context, fields []string, query *metadata.Query, start, end time.Time, matchers ...*labels.Matcher) (*decoder.Response, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+// labelNames 通过表结构获取维度列，字符串类型的列作为维度
+func (i *Instance) labelNames(ctx context.Context, query *metadata.Query, span *trace.Span) ([]string, error) {
+	qf := NewQueryFactory(ctx, query)
+	sql := fmt.Sprintf("DESCRIBE TABLE %s", qf.Table())
+	data, err := i.sqlQuery(ctx, sql, span)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]Column, 0, len(data.Data))
+	for _, d := range data.Data {
+		name, _ := d["name"].(string)
+		typ, _ := d["type"].(string)
+		if name == "" {
+			continue
+		}
+		columns = append(columns, Column{Name: name, Type: typ})
+	}
+	return qf.dims(columns), nil
+}
+
+func (i *Instance) QueryLabelNames(ctx context.Context, query *metadata.Query, start, end time.Time) ([]string, error) {
+	var (
+		err error
+	)
+	ctx, span := trace.NewSpan(ctx, "clickhouse-label-names")
+	defer span.End(&err)
+
+	lbs, err := i.labelNames(ctx, query, span)
+	return lbs, err
+}
+
+func (i *Instance) QueryLabelValues(ctx context.Context, query *metadata.Query, name string, start, end time.Time) ([]string, error) {
+	var (
+		err error
+	)
+	ctx, span := trace.NewSpan(ctx, "clickhouse-label-values")
+	defer span.End(&err)
+
+	if name == labels.MetricName {
+		err = fmt.Errorf("not support metric query with %s", name)
+		return nil, err
+	}
+
+	i.limit(query)
+	qf := NewQueryFactory(ctx, query).WithRangeTime(start, end)
+	where, err := qf.Where()
+	if err != nil {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s", quote(name), qf.Table(), where)
+	if query.Size > 0 {
+		sql = fmt.Sprintf("%s LIMIT %d", sql, query.Size)
+	}
+	data, err := i.sqlQuery(ctx, sql, span)
+	if err != nil {
+		return nil, err
+	}
+
+	lbs := make([]string, 0, len(data.Data))
+	for _, d := range data.Data {
+		var v string
+		v, err = getValue(name, d)
+		if err != nil {
+			return nil, err
+		}
+		if v != "" {
+			lbs = append(lbs, v)
+		}
+	}
+	sort.Strings(lbs)
+
+	return lbs, nil
+}
+
+func (i *Instance) QuerySeries(ctx context.Context, query *metadata.Query, start, end time.Time) ([]map[string]string, error) {
+	var (
+		err error
+	)
+	ctx, span := trace.NewSpan(ctx, "clickhouse-series")
+	defer span.End(&err)
+
+	names, err := i.labelNames(ctx, query, span)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	i.limit(query)
+	qf := NewQueryFactory(ctx, query).WithRangeTime(start, end)
+	where, err := qf.Where()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, 0, len(names))
+	for _, n := range names {
+		columns = append(columns, quote(n))
+	}
+	sql := fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s", strings.Join(columns, ", "), qf.Table(), where)
+	if query.Size > 0 {
+		sql = fmt.Sprintf("%s LIMIT %d", sql, query.Size)
+	}
+	data, err := i.sqlQuery(ctx, sql, span)
+	if err != nil {
+		return nil, err
+	}
+
+	series := make([]map[string]string, 0, len(data.Data))
+	for _, d := range data.Data {
+		s := make(map[string]string, len(names))
+		for _, n := range names {
+			var v string
+			v, err = getValue(n, d)
+			if err != nil {
+				return nil, err
+			}
+			s[n] = v
+		}
+		series = append(series, s)
+	}
+
+	return series, nil
+}
+
+func (i *Instance) DirectLabelNames(ctx context.Context, start, end time.Time, matchers ...*labels.Matcher) ([]string, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (i *Instance) DirectLabelValues(ctx context.Context, name string, start, end time.Time, limit int, matchers ...*labels.Matcher) ([]string, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (i *Instance) InstanceType() string {
+	return consul.ClickHouseStorageType
+}
+
+// Explain 生成 ClickHouse 查询语句
+func (i *Instance) Explain(ctx context.Context, query *metadata.Query, start, end time.Time) (*tsdb.QueryPlan, error) {
+	var (
+		err error
+	)
+	ctx, span := trace.NewSpan(ctx, "clickhouse-explain")
+	defer span.End(&err)
+
+	qry := *query
+	i.limit(&qry)
+
+	qf, err := i.seriesQueryFactory(ctx, &qry, start, end, span)
+	if err != nil {
+		return nil, err
+	}
+	sql, err := qf.SQL()
+	if err != nil {
+		return nil, err
+	}
+	return &tsdb.QueryPlan{
+		Language:   tsdb.LanguageSQL,
+		Statements: []string{sql},
+		Indexes:    []string{qf.Table()},
+	}, nil
+}
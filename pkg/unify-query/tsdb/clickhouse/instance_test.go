@@ -0,0 +1,259 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/curl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
+)
+
+const (
+	testDB    = "bkmonitor"
+	testTable = "container_cpu"
+	testField = "usage"
+
+	describeResponse = `{"meta":[{"name":"name","type":"String"},{"name":"type","type":"String"}],"data":[{"name":"time","type":"DateTime64(3)"},{"name":"namespace","type":"LowCardinality(String)"},{"name":"pod","type":"String"},{"name":"usage","type":"Float64"},{"name":"count","type":"UInt64"}],"rows":5}`
+)
+
+func newTestInstance(t *testing.T, data map[string]any) *Instance {
+	mock.Init()
+	mock.ClickHouse.Set(data)
+
+	ins, err := NewInstance(context.Background(), &Options{
+		Address:   mock.ClickHouseUrl,
+		Username:  "reader",
+		Password:  "secret",
+		Timeout:   time.Minute,
+		MaxLimit:  1e4,
+		Tolerance: 5,
+		Curl:      &curl.HttpCurl{Log: log.DefaultLogger},
+	})
+	require.NoError(t, err)
+	return ins
+}
+
+func TestInstance_QuerySeriesSet(t *testing.T) {
+	ins := newTestInstance(t, map[string]any{
+		"DESCRIBE TABLE `bkmonitor`.`container_cpu`": describeResponse,
+		"SELECT *, `usage` AS `_value_`, toUnixTimestamp64Milli(`time`) AS `_timestamp_` FROM `bkmonitor`.`container_cpu` WHERE `time` >= fromUnixTimestamp64Milli(toInt64(1730118589181)) AND `time` < fromUnixTimestamp64Milli(toInt64(1730118889181)) AND (`namespace` = 'gz100' OR (`namespace` = 'bgp2' AND `pod` != 'pod-\\'1')) ORDER BY `namespace` ASC, `pod` ASC, `_timestamp_` ASC LIMIT 10005":                        `{"meta":[{"name":"time","type":"DateTime64(3)"},{"name":"namespace","type":"LowCardinality(String)"},{"name":"pod","type":"String"},{"name":"usage","type":"Float64"},{"name":"count","type":"UInt64"},{"name":"_value_","type":"Float64"},{"name":"_timestamp_","type":"Int64"}],"data":[{"time":"2024-10-28 20:31:00.000","namespace":"gz100","pod":"pod-1","usage":1.5,"count":3,"_value_":1.5,"_timestamp_":1730118660000},{"time":"2024-10-28 20:32:00.000","namespace":"gz100","pod":"pod-1","usage":2,"count":3,"_value_":2,"_timestamp_":1730118720000},{"time":"2024-10-28 20:32:00.000","namespace":"bgp2","pod":"pod-2","usage":null,"count":3,"_value_":null,"_timestamp_":1730118720000}],"rows":3,"statistics":{"elapsed":0.001,"rows_read":3,"bytes_read":120}}`,
+		"SELECT `namespace`, sum(`usage`) AS `_value_`, intDiv(toUnixTimestamp64Milli(`time`), 60000) * 60000 AS `_timestamp_` FROM `bkmonitor`.`container_cpu` WHERE `time` >= fromUnixTimestamp64Milli(toInt64(1730118589181)) AND `time` < fromUnixTimestamp64Milli(toInt64(1730118889181)) GROUP BY `namespace`, `_timestamp_` ORDER BY `_timestamp_` ASC LIMIT 10005": `{"meta":[{"name":"namespace","type":"LowCardinality(String)"},{"name":"_value_","type":"Float64"},{"name":"_timestamp_","type":"Int64"}],"data":[{"namespace":"gz100","_value_":3,"_timestamp_":1730118600000},{"namespace":"bgp2","_value_":4,"_timestamp_":1730118600000},{"namespace":"gz100","_value_":5,"_timestamp_":1730118660000}],"rows":3}`,
+		"SELECT count(`usage`) AS `_value_` FROM `bkmonitor`.`container_cpu` WHERE `time` >= fromUnixTimestamp64Milli(toInt64(1730118589181)) AND `time` < fromUnixTimestamp64Milli(toInt64(1730118889181)) LIMIT 10005":                                                                                                                                                   `{"meta":[{"name":"_value_","type":"UInt64"}],"data":[{"_value_":11}],"rows":1}`,
+	})
+
+	start := time.UnixMilli(1730118589181)
+	end := time.UnixMilli(1730118889181)
+
+	for name, c := range map[string]struct {
+		query    *metadata.Query
+		expected string
+		err      string
+	}{
+		"raw with conditions": {
+			query: &metadata.Query{
+				AllConditions: metadata.AllConditions{
+					{
+						{DimensionName: "namespace", Value: []string{"gz100"}, Operator: "eq"},
+					},
+					{
+						{DimensionName: "namespace", Value: []string{"bgp2"}, Operator: "eq"},
+						{DimensionName: "pod", Value: []string{"pod-'1"}, Operator: "ne"},
+					},
+				},
+			},
+			expected: `[{"labels":[{"name":"__name__","value":"bkmonitor:bkmonitor:container_cpu:usage"},{"name":"namespace","value":"gz100"},{"name":"pod","value":"pod-1"}],"samples":[{"value":1.5,"timestamp":1730118660000},{"value":2,"timestamp":1730118720000}],"exemplars":null,"histograms":null}]`,
+		},
+		"sum by namespace with window": {
+			query: &metadata.Query{
+				Aggregates: metadata.Aggregates{
+					{Name: "sum", Dimensions: []string{"namespace"}, Window: time.Minute},
+				},
+			},
+			expected: `[{"labels":[{"name":"__name__","value":"bkmonitor:bkmonitor:container_cpu:usage"},{"name":"namespace","value":"bgp2"}],"samples":[{"value":4,"timestamp":1730118600000}],"exemplars":null,"histograms":null},{"labels":[{"name":"__name__","value":"bkmonitor:bkmonitor:container_cpu:usage"},{"name":"namespace","value":"gz100"}],"samples":[{"value":3,"timestamp":1730118600000},{"value":5,"timestamp":1730118660000}],"exemplars":null,"histograms":null}]`,
+		},
+		"count without window": {
+			query: &metadata.Query{
+				Aggregates: metadata.Aggregates{
+					{Name: "count"},
+				},
+			},
+			expected: `[{"labels":[{"name":"__name__","value":"bkmonitor:bkmonitor:container_cpu:usage"}],"samples":[{"value":11,"timestamp":1730118589181}],"exemplars":null,"histograms":null}]`,
+		},
+		"unsupported aggregate": {
+			query: &metadata.Query{
+				Aggregates: metadata.Aggregates{
+					{Name: "quantile"},
+				},
+			},
+			err: "aggregate quantile is not support",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := metadata.InitHashID(context.Background())
+			c.query.DataSource = "bkmonitor"
+			c.query.TableID = testDB + "." + testTable
+			c.query.MetricName = testField
+			c.query.DB = testDB
+			c.query.Measurement = testTable
+			c.query.Field = testField
+
+			ss := ins.QuerySeriesSet(ctx, c.query, start, end)
+			timeSeries, err := mock.SeriesSetToTimeSeries(ss)
+			if c.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), c.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, timeSeries.String())
+		})
+	}
+}
+
+func TestQueryFactory_SQL(t *testing.T) {
+	start := time.UnixMilli(1730118589181)
+	end := time.UnixMilli(1730118889181)
+	where := "WHERE `time` >= fromUnixTimestamp64Milli(toInt64(1730118589181)) AND `time` < fromUnixTimestamp64Milli(toInt64(1730118889181))"
+
+	for name, c := range map[string]struct {
+		query      *metadata.Query
+		dimensions []string
+		expected   string
+	}{
+		"raw series ordered by dimensions and time": {
+			query:      &metadata.Query{},
+			dimensions: []string{"namespace", "pod"},
+			expected:   "SELECT *, `usage` AS `_value_`, toUnixTimestamp64Milli(`time`) AS `_timestamp_` FROM `bkmonitor`.`container_cpu` " + where + " ORDER BY `namespace` ASC, `pod` ASC, `_timestamp_` ASC",
+		},
+		"multiple aggregates with same dimensions": {
+			query: &metadata.Query{
+				Aggregates: metadata.Aggregates{
+					{Name: "sum", Dimensions: []string{"namespace", "pod"}, Window: time.Minute},
+					{Name: "max", Dimensions: []string{"namespace"}, Window: time.Minute},
+				},
+			},
+			expected: "GROUP BY `namespace`, `pod`, `_timestamp_` ORDER BY `_timestamp_` ASC",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c.query.DB = testDB
+			c.query.Measurement = testTable
+			c.query.Field = testField
+
+			sql, err := NewQueryFactory(context.Background(), c.query).WithRangeTime(start, end).WithDimensions(c.dimensions).SQL()
+			require.NoError(t, err)
+			assert.True(t, strings.HasSuffix(sql, c.expected), sql)
+		})
+	}
+}
+
+func TestInstance_QueryRawData(t *testing.T) {
+	ins := newTestInstance(t, map[string]any{
+		"SELECT * FROM `bkmonitor`.`container_cpu` WHERE `time` >= fromUnixTimestamp64Milli(toInt64(1730118589181)) AND `time` < fromUnixTimestamp64Milli(toInt64(1730118889181)) AND (match(`pod`, '^pod-\\\\d$')) ORDER BY `time` DESC LIMIT 2 OFFSET 1": `{"meta":[{"name":"time","type":"DateTime64(3)"},{"name":"pod","type":"String"},{"name":"usage","type":"Float64"}],"data":[{"time":"2024-10-28 20:32:00.000","pod":"pod-2","usage":2},{"time":"2024-10-28 20:31:00.000","pod":"pod-1","usage":1.5}],"rows":2,"rows_before_limit_at_least":5}`,
+	})
+
+	ctx := metadata.InitHashID(context.Background())
+	query := &metadata.Query{
+		DB:          testDB,
+		Measurement: testTable,
+		Field:       testField,
+		From:        1,
+		Size:        2,
+		Orders:      metadata.Orders{FieldTime: false},
+		AllConditions: metadata.AllConditions{
+			{
+				{DimensionName: "pod", Value: []string{`^pod-\d$`}, Operator: "req"},
+			},
+		},
+	}
+
+	dataCh := make(chan map[string]any, 10)
+	total, err := ins.QueryRawData(ctx, query, time.UnixMilli(1730118589181), time.UnixMilli(1730118889181), dataCh)
+	close(dataCh)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+
+	list := make([]map[string]any, 0)
+	for d := range dataCh {
+		list = append(list, d)
+	}
+	assert.Equal(t, []map[string]any{
+		{"time": "2024-10-28 20:32:00.000", "pod": "pod-2", "usage": float64(2)},
+		{"time": "2024-10-28 20:31:00.000", "pod": "pod-1", "usage": 1.5},
+	}, list)
+}
+
+func TestInstance_QueryLabels(t *testing.T) {
+	ins := newTestInstance(t, map[string]any{
+		"DESCRIBE TABLE `bkmonitor`.`container_cpu`": describeResponse,
+		"SELECT DISTINCT `namespace` FROM `bkmonitor`.`container_cpu` WHERE `time` >= fromUnixTimestamp64Milli(toInt64(1730118589181)) AND `time` < fromUnixTimestamp64Milli(toInt64(1730118889181)) LIMIT 10005":        `{"meta":[{"name":"namespace","type":"LowCardinality(String)"}],"data":[{"namespace":"gz100"},{"namespace":"bgp2"},{"namespace":""}],"rows":3}`,
+		"SELECT DISTINCT `namespace`, `pod` FROM `bkmonitor`.`container_cpu` WHERE `time` >= fromUnixTimestamp64Milli(toInt64(1730118589181)) AND `time` < fromUnixTimestamp64Milli(toInt64(1730118889181)) LIMIT 10005": `{"meta":[{"name":"namespace","type":"LowCardinality(String)"},{"name":"pod","type":"String"}],"data":[{"namespace":"gz100","pod":"pod-1"},{"namespace":"bgp2","pod":"pod-2"}],"rows":2}`,
+	})
+
+	start := time.UnixMilli(1730118589181)
+	end := time.UnixMilli(1730118889181)
+	newQuery := func() *metadata.Query {
+		return &metadata.Query{DB: testDB, Measurement: testTable, Field: testField}
+	}
+
+	ctx := metadata.InitHashID(context.Background())
+	names, err := ins.QueryLabelNames(ctx, newQuery(), start, end)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"namespace", "pod"}, names)
+
+	values, err := ins.QueryLabelValues(ctx, newQuery(), "namespace", start, end)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bgp2", "gz100"}, values)
+
+	_, err = ins.QueryLabelValues(ctx, newQuery(), "__name__", start, end)
+	assert.Error(t, err)
+
+	series, err := ins.QuerySeries(ctx, newQuery(), start, end)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"namespace": "gz100", "pod": "pod-1"},
+		{"namespace": "bgp2", "pod": "pod-2"},
+	}, series)
+}
+
+func TestInstance_QueryError(t *testing.T) {
+	ins := newTestInstance(t, map[string]any{})
+
+	ctx := metadata.InitHashID(context.Background())
+	_, err := ins.QueryLabelNames(ctx, &metadata.Query{DB: testDB, Measurement: "not_exists"}, time.Now(), time.Now())
+	assert.Error(t, err)
+
+	ss := ins.QuerySeriesSet(ctx, &metadata.Query{DB: testDB, Measurement: testTable, Field: testField}, time.UnixMilli(0), time.Now())
+	assert.Error(t, ss.Err())
+}
+
+func TestUnwrapType(t *testing.T) {
+	for typ, expected := range map[string]bool{
+		"String":                           true,
+		"LowCardinality(String)":           true,
+		"Nullable(String)":                 true,
+		"LowCardinality(Nullable(String))": true,
+		"FixedString(16)":                  true,
+		"Float64":                          false,
+		"Nullable(Int64)":                  false,
+		"DateTime64(3)":                    false,
+	} {
+		assert.Equal(t, expected, isStringType(typ), typ)
+	}
+}
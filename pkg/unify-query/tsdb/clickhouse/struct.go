@@ -0,0 +1,91 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package clickhouse
+
+import "strings"
+
+// ClickHouse 结果表约定的表结构：
+//
+//	CREATE TABLE <db>.<measurement>
+//	(
+//	    `time`      DateTime64(3),  -- 数据时间，毫秒精度，可通过 TimeField 指定其它列名
+//	    `<field>`   Float64,        -- 指标值列，一张表可以有多个指标列
+//	    `<label>`   String,         -- 维度列，类型为 String 或者 LowCardinality(String)
+//	    ...
+//	)
+//	ENGINE = MergeTree
+//	ORDER BY (<labels...>, time)
+//
+// 其中 db 和 measurement 对应路由中结果表的 DB 和 Measurement，field 对应查询的指标字段，
+// 所有字符串类型的列都作为维度返回，其余列不作为维度处理。
+
+const (
+	// DefaultTimeField 默认时间列
+	DefaultTimeField = "time"
+
+	timeStamp = "_timestamp_"
+	value     = "_value_"
+
+	FieldValue = "_value"
+	FieldTime  = "_time"
+
+	// 返回的内置字段，用于原始数据查询
+	KeyTimeStamp = "_time"
+	KeyValue     = "_value"
+
+	FormatJSON = "JSON"
+)
+
+// Column 返回结果的列描述
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Statistics 查询统计信息
+type Statistics struct {
+	Elapsed   float64 `json:"elapsed"`
+	RowsRead  int64   `json:"rows_read"`
+	BytesRead int64   `json:"bytes_read"`
+}
+
+// Result ClickHouse FORMAT JSON 的返回结构
+type Result struct {
+	Meta                   []Column                 `json:"meta"`
+	Data                   []map[string]interface{} `json:"data"`
+	Rows                   int                      `json:"rows"`
+	RowsBeforeLimitAtLeast int                      `json:"rows_before_limit_at_least"`
+	Statistics             Statistics               `json:"statistics"`
+}
+
+// Keys 返回列名列表，保持 meta 中的顺序
+func (r *Result) Keys() []string {
+	keys := make([]string, 0, len(r.Meta))
+	for _, m := range r.Meta {
+		keys = append(keys, m.Name)
+	}
+	return keys
+}
+
+// isStringType 判断列类型是否为字符串，只有字符串类型的列才作为维度
+func isStringType(t string) bool {
+	t = unwrapType(t)
+	return t == "String" || strings.HasPrefix(t, "FixedString(")
+}
+
+// unwrapType 去掉 Nullable 和 LowCardinality 的类型包装
+func unwrapType(t string) string {
+	for _, wrapper := range []string{"LowCardinality(", "Nullable("} {
+		if strings.HasPrefix(t, wrapper) && strings.HasSuffix(t, ")") {
+			return unwrapType(strings.TrimSuffix(strings.TrimPrefix(t, wrapper), ")"))
+		}
+	}
+	return t
+}
@@ -196,7 +196,7 @@ func (a *aggFormat) ts(idx int, data elastic.Aggregations) error {
 				if percentMetric, ok := data.Percentiles(info.Name); ok && percentMetric != nil {
 					for k, v := range percentMetric.Values {
 						if !strings.Contains(k, "_as_string") {
-							a.addLabel("le", k)
+							a.addLabel("le", formatPercent(k))
 							a.item.value = v
 							a.reset()
 						}
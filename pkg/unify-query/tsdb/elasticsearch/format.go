@@ -14,9 +14,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/olivere/elastic/v7"
 	"github.com/prometheus/prometheus/model/labels"
@@ -45,6 +47,7 @@ const (
 	Count       = "count"
 	Avg         = "avg"
 	Cardinality = "cardinality"
+	Rate        = "rate"
 
 	DateHistogram = "date_histogram"
 	Percentiles   = "percentiles"
@@ -58,6 +61,10 @@ const (
 const (
 	KeyDocID     = "__doc_id"
 	KeyHighLight = "__highlight"
+	KeySort      = "__sort"
+
+	// FieldDocID 文档唯一 id，游标翻页时作为排序的最后一个字段，保证排序值唯一
+	FieldDocID = "_id"
 )
 
 const (
@@ -176,13 +183,18 @@ type FormatFactory struct {
 	aggInfoList aggInfoList
 	orders      metadata.Orders
 
-	from     int
-	size     int
-	timezone string
+	from        int
+	size        int
+	searchAfter []any
+	cursor      bool
+	timezone    string
 
 	start int64
 	end   int64
 
+	// rateWindow 不为 0 时，聚合结果为每秒的文档数量
+	rateWindow time.Duration
+
 	isReference bool
 }
 
@@ -263,6 +275,18 @@ func (f *FormatFactory) WithTransform(encode func(string) string, decode func(st
 	return f
 }
 
+// WithSearchAfter 使用上一页最后一条数据的排序值翻页，替代 from
+func (f *FormatFactory) WithSearchAfter(values []any) *FormatFactory {
+	f.searchAfter = values
+	return f
+}
+
+// WithCursor 开启游标翻页，排序字段会追加文档 id 作为唯一值
+func (f *FormatFactory) WithCursor(cursor bool) *FormatFactory {
+	f.cursor = cursor
+	return f
+}
+
 func (f *FormatFactory) WithOrders(orders map[string]bool) *FormatFactory {
 	f.orders = make(metadata.Orders, len(orders))
 	for k, ok := range orders {
@@ -421,9 +445,14 @@ func (f *FormatFactory) AggDataFormat(data elastic.Aggregations, metricLabel *pr
 			im.timestamp = f.start * 1e3
 		}
 
+		value := im.value
+		if f.rateWindow > 0 {
+			value /= f.rateWindow.Seconds()
+		}
+
 		timeSeriesMap[seriesKey].Labels = tsLabels
 		timeSeriesMap[seriesKey].Samples = append(timeSeriesMap[seriesKey].Samples, prompb.Sample{
-			Value:     im.value,
+			Value:     value,
 			Timestamp: im.timestamp,
 		})
 	}
@@ -507,7 +536,7 @@ func (f *FormatFactory) Agg() (name string, agg elastic.Aggregation, err error)
 					var percent float64
 					switch v := arg.(type) {
 					case float64:
-						percent = float64(int(v))
+						percent = roundPercent(v)
 					case int:
 						percent = float64(v)
 					case int32:
@@ -585,8 +614,18 @@ func (f *FormatFactory) EsAgg(aggregates metadata.Aggregates) (string, elastic.A
 		switch am.Name {
 		case DateHistogram:
 			f.timeAgg(f.timeField.Name, shortDur(am.Window), am.TimeZone)
-		case Max, Min, Avg, Sum, Count, Cardinality, Percentiles:
-			f.valueAgg(FieldValue, am.Name, am.Args...)
+		case Max, Min, Avg, Sum, Count, Cardinality, Percentiles, Rate:
+			funcType := am.Name
+			// rate 使用文档数量除以聚合周期计算
+			if am.Name == Rate {
+				if am.Window <= 0 {
+					err := fmt.Errorf("esAgg aggregation rate window is empty: %+v", am)
+					return "", nil, err
+				}
+				funcType = Count
+				f.rateWindow = am.Window
+			}
+			f.valueAgg(FieldValue, funcType, am.Args...)
 			f.nestedAgg(f.valueField)
 
 			if am.Window > 0 && !am.Without {
@@ -615,6 +654,10 @@ func (f *FormatFactory) EsAgg(aggregates metadata.Aggregates) (string, elastic.A
 }
 
 func (f *FormatFactory) Size(ss *elastic.SearchSource) {
+	if len(f.searchAfter) > 0 {
+		ss.SearchAfter(f.searchAfter...).Size(f.size)
+		return
+	}
 	ss.From(f.from).Size(f.size)
 }
 
@@ -904,3 +947,17 @@ func (f *FormatFactory) Labels() (lbs *prompb.Labels, err error) {
 
 	return
 }
+
+// roundPercent 百分位按 1e-6 精度取整，避免浮点误差，例如 28.999999999999996 取整为 29
+func roundPercent(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// formatPercent 格式化 es 返回的百分位 key 作为 le 维度，例如 50.0 格式化为 50
+func formatPercent(key string) string {
+	v, err := strconv.ParseFloat(key, 64)
+	if err != nil {
+		return key
+	}
+	return strconv.FormatFloat(roundPercent(v), 'f', -1, 64)
+}
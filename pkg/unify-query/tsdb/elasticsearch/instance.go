@@ -182,21 +182,15 @@ func (i *Instance) getMappings(ctx context.Context, aliases []string) ([]map[str
 	return mappings, nil
 }
 
-func (i *Instance) esQuery(ctx context.Context, qo *queryOption, fact *FormatFactory) (*elastic.SearchResult, error) {
-	var (
-		err  error
-		qb   = qo.query
-		user = metadata.GetUser(ctx)
-	)
-	ctx, span := trace.NewSpan(ctx, "elasticsearch-query")
-	defer span.End(&err)
-
+// searchSource 根据查询条件生成 es 查询结构体以及对应的查询语句
+func (i *Instance) searchSource(qo *queryOption, fact *FormatFactory) (*elastic.SearchSource, string, error) {
+	qb := qo.query
 	filterQueries := make([]elastic.Query, 0)
 
 	// 过滤条件生成 elastic.query
 	query, err := fact.Query(qb.AllConditions)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if query != nil {
 		filterQueries = append(filterQueries, query)
@@ -205,7 +199,7 @@ func (i *Instance) esQuery(ctx context.Context, qo *queryOption, fact *FormatFac
 	// 查询时间生成 elastic.query
 	rangeQuery, err := fact.RangeQuery()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	filterQueries = append(filterQueries, rangeQuery)
 
@@ -214,7 +208,7 @@ func (i *Instance) esQuery(ctx context.Context, qo *queryOption, fact *FormatFac
 		qs := NewQueryString(qb.QueryString, fact.NestedField)
 		q, qsErr := qs.Parser()
 		if qsErr != nil {
-			return nil, qsErr
+			return nil, "", qsErr
 		}
 		if q != nil {
 			filterQueries = append(filterQueries, q)
@@ -224,8 +218,18 @@ func (i *Instance) esQuery(ctx context.Context, qo *queryOption, fact *FormatFac
 	source := elastic.NewSearchSource()
 	order := fact.Order()
 
-	for key, asc := range order {
-		source.Sort(key, asc)
+	// 排序字段按名称排序，保证多次查询的排序顺序一致，search_after 的排序值才能对应
+	orderKeys := make([]string, 0, len(order))
+	for key := range order {
+		orderKeys = append(orderKeys, key)
+	}
+	sort.Strings(orderKeys)
+	for _, key := range orderKeys {
+		source.Sort(key, order[key])
+	}
+	// 相同排序值的文档在 search_after 翻页时会被跳过或重复，追加文档 id 保证排序值唯一
+	if fact.cursor {
+		source.Sort(FieldDocID, true)
 	}
 
 	if len(filterQueries) > 0 {
@@ -233,6 +237,10 @@ func (i *Instance) esQuery(ctx context.Context, qo *queryOption, fact *FormatFac
 		source.Query(esQuery)
 	}
 
+	if qb.Collapse != "" {
+		source.Collapse(elastic.NewCollapseBuilder(fact.encode(qb.Collapse)))
+	}
+
 	if len(qb.Source) > 0 {
 		fetchSource := elastic.NewFetchSourceContext(true)
 		fetchSource.Include(qb.Source...)
@@ -243,7 +251,7 @@ func (i *Instance) esQuery(ctx context.Context, qo *queryOption, fact *FormatFac
 	if len(qb.Aggregates) > 0 {
 		name, agg, aggErr := fact.EsAgg(qb.Aggregates)
 		if aggErr != nil {
-			return nil, aggErr
+			return nil, "", aggErr
 		}
 		source.Size(0)
 		source.Aggregation(name, agg)
@@ -252,16 +260,30 @@ func (i *Instance) esQuery(ctx context.Context, qo *queryOption, fact *FormatFac
 	}
 
 	if source == nil {
-		return nil, fmt.Errorf("empty es query source")
+		return nil, "", fmt.Errorf("empty es query source")
 	}
 
 	body, _ := source.Source()
 	if body == nil {
-		return nil, fmt.Errorf("empty query body")
+		return nil, "", fmt.Errorf("empty query body")
 	}
 
 	bodyJson, _ := json.Marshal(body)
-	bodyString := string(bodyJson)
+	return source, string(bodyJson), nil
+}
+
+func (i *Instance) esQuery(ctx context.Context, qo *queryOption, fact *FormatFactory) (*elastic.SearchResult, error) {
+	var (
+		err  error
+		user = metadata.GetUser(ctx)
+	)
+	ctx, span := trace.NewSpan(ctx, "elasticsearch-query")
+	defer span.End(&err)
+
+	source, bodyString, err := i.searchSource(qo, fact)
+	if err != nil {
+		return nil, err
+	}
 
 	span.Set("query-address", i.address)
 	span.Set("query-headers", i.headers)
@@ -580,6 +602,11 @@ func (i *Instance) QueryRawData(ctx context.Context, query *metadata.Query, star
 		query.Size = i.maxSize
 	}
 
+	// 游标翻页需要稳定的排序，默认按时间倒序
+	if query.Cursor && len(query.Orders) == 0 {
+		query.Orders = metadata.Orders{FieldTime: false}
+	}
+
 	qo := &queryOption{
 		indexes: aliases,
 		start:   start.Unix(),
@@ -590,9 +617,14 @@ func (i *Instance) QueryRawData(ctx context.Context, query *metadata.Query, star
 		WithIsReference(metadata.GetQueryParams(ctx).IsReference).
 		WithQuery(query.Field, query.TimeField, qo.start, qo.end, query.From, query.Size).
 		WithMappings(mappings...).
-		WithOrders(query.Orders)
+		WithOrders(query.Orders).
+		WithCursor(query.Cursor).
+		WithSearchAfter(query.SearchAfter)
 
 	sr, err := i.esQuery(ctx, qo, fact)
+	if err != nil {
+		return 0, err
+	}
 	for _, d := range sr.Hits.Hits {
 		data := make(map[string]any)
 		if err = json.Unmarshal(d.Source, &data); err != nil {
@@ -605,6 +637,9 @@ func (i *Instance) QueryRawData(ctx context.Context, query *metadata.Query, star
 		if len(d.Highlight) > 0 {
 			fact.data[KeyHighLight] = d.Highlight
 		}
+		if query.Cursor {
+			fact.data[KeySort] = d.Sort
+		}
 		dataCh <- fact.data
 	}
 
@@ -745,9 +780,83 @@ func (i *Instance) QueryLabelNames(ctx context.Context, query *metadata.Query, s
 	panic("implement me")
 }
 
-func (i *Instance) QueryLabelValues(ctx context.Context, query *metadata.Query, name string, start, end time.Time) ([]string, error) {
-	//TODO implement me
-	panic("implement me")
+// QueryLabelValues 通过 terms 聚合获取维度值，返回数量受 query.Size 以及 maxSize 限制
+func (i *Instance) QueryLabelValues(ctx context.Context, query *metadata.Query, name string, start, end time.Time) (list []string, err error) {
+	defer func() {
+		// es 查询有很多结构体无法判断的，会导致 panic
+		if r := recover(); r != nil {
+			err = fmt.Errorf("es query error: %s", r)
+		}
+	}()
+
+	ctx, span := trace.NewSpan(ctx, "elasticsearch-label-values")
+	defer span.End(&err)
+
+	if name == labels.MetricName {
+		return nil, fmt.Errorf("not support metric query with %s", name)
+	}
+
+	aliases, err := i.getAlias(ctx, query.DB, query.NeedAddTime, start, end, query.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	mappings, err := i.getMappings(ctx, aliases)
+	if err != nil {
+		return nil, err
+	}
+	// index 不存在，直接返回空
+	if len(mappings) == 0 {
+		log.Warnf(ctx, "index is empty with %v", aliases)
+		return nil, nil
+	}
+
+	size := i.maxSize
+	if query.Size > 0 && query.Size < i.maxSize {
+		size = query.Size
+	}
+
+	// 复制查询，按维度 count 聚合，不影响原始查询的聚合方法
+	qry := *query
+	if qry.Field == "" {
+		qry.Field = name
+	}
+	qry.Aggregates = metadata.Aggregates{
+		{
+			Name:       Count,
+			Dimensions: []string{name},
+		},
+	}
+
+	qo := &queryOption{
+		indexes: aliases,
+		start:   start.Unix(),
+		end:     end.Unix(),
+		query:   &qry,
+	}
+	fact := NewFormatFactory(ctx).
+		WithQuery(qry.Field, qry.TimeField, qo.start, qo.end, 0, size).
+		WithMappings(mappings...)
+
+	sr, err := i.esQuery(ctx, qo, fact)
+	if err != nil {
+		return nil, err
+	}
+
+	tsMap, err := fact.AggDataFormat(sr.Aggregations, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	list = make([]string, 0, len(tsMap))
+	for _, ts := range tsMap {
+		for _, lb := range ts.GetLabels() {
+			if lb.GetName() == name && lb.GetValue() != "" {
+				list = append(list, lb.GetValue())
+			}
+		}
+	}
+	sort.Strings(list)
+	return list, nil
 }
 
 func (i *Instance) QuerySeries(ctx context.Context, query *metadata.Query, start, end time.Time) ([]map[string]string, error) {
@@ -768,3 +877,78 @@ func (i *Instance) DirectLabelValues(ctx context.Context, name string, start, en
 func (i *Instance) InstanceType() string {
 	return consul.ElasticsearchStorageType
 }
+
+// Explain 生成 es 查询语句以及分段查询的时间窗口，mappings 以及索引信息获取失败时不影响语句生成
+func (i *Instance) Explain(ctx context.Context, query *metadata.Query, start, end time.Time) (plan *tsdb.QueryPlan, err error) {
+	defer func() {
+		// es 查询有很多结构体无法判断的，会导致 panic
+		if r := recover(); r != nil {
+			err = fmt.Errorf("es explain error: %s", r)
+		}
+	}()
+
+	aliases, err := i.getAlias(ctx, query.DB, query.NeedAddTime, start, end, query.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	plan = &tsdb.QueryPlan{
+		Language: tsdb.LanguageESDSL,
+		Indexes:  aliases,
+	}
+
+	mappings, err := i.getMappings(ctx, aliases)
+	if err != nil {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("get mappings error: %s", err))
+	}
+
+	size := i.maxSize
+	if query.Size > 0 && query.Size < i.maxSize {
+		size = query.Size
+	}
+
+	qo := &queryOption{
+		indexes: aliases,
+		start:   start.Unix(),
+		end:     end.Unix(),
+		query:   query,
+	}
+	fact := NewFormatFactory(ctx).
+		WithIsReference(metadata.GetQueryParams(ctx).IsReference).
+		WithQuery(query.Field, query.TimeField, qo.start, qo.end, query.From, size).
+		WithMappings(mappings...).
+		WithOrders(query.Orders)
+
+	_, body, err := i.searchSource(qo, fact)
+	if err != nil {
+		return nil, err
+	}
+	plan.Statements = []string{body}
+
+	// 根据索引的文档数以及存储大小计算分段查询的时间窗口
+	var docCount, storeSize int64
+	for _, alias := range aliases {
+		dc, ss, optErr := i.indexOption(ctx, alias)
+		if optErr != nil {
+			plan.Notes = append(plan.Notes, fmt.Sprintf("get index %s option error: %s", alias, optErr))
+			return plan, nil
+		}
+		docCount += dc
+		storeSize += ss
+	}
+
+	var interval int64
+	for _, agg := range query.Aggregates {
+		if agg.Window > 0 {
+			interval = agg.Window.Milliseconds()
+		}
+	}
+	plan.Segments, err = newRangeSegment(&querySegmentOption{
+		start:     start.UnixMilli(),
+		end:       end.UnixMilli(),
+		interval:  interval,
+		docCount:  docCount,
+		storeSize: storeSize,
+	})
+	return plan, err
+}
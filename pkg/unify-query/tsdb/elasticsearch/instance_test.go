@@ -13,10 +13,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
@@ -24,6 +26,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
 )
 
 func TestInstance_queryReference(t *testing.T) {
@@ -75,6 +78,7 @@ func TestInstance_queryReference(t *testing.T) {
 		`{"aggregations":{"_value":{"percentiles":{"field":"dtEventTimeStamp","percents":[50]}}},"query":{"bool":{"filter":{"range":{"dtEventTimeStamp":{"format":"epoch_second","from":1723593608,"include_lower":true,"include_upper":true,"to":1723679962}}}}},"size":0}`: `{"took":675,"timed_out":false,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},"hits":{"total":{"value":10000,"relation":"gte"},"max_score":null,"hits":[]},"aggregations":{"_value":{"values":{"50.0":1.7236371328063303E12,"50.0_as_string":"1723637132806"}}}}`,
 
 		// 获取 50, 90 分支值，同时按 6h 时间聚合
+		`{"aggregations":{"_value":{"percentiles":{"field":"dtEventTimeStamp","percents":[29,99.9]}}},"query":{"bool":{"filter":{"range":{"dtEventTimeStamp":{"format":"epoch_second","from":1723593608,"include_lower":true,"include_upper":true,"to":1723679962}}}}},"size":0}`: `{"took":675,"timed_out":false,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},"hits":{"total":{"value":10000,"relation":"gte"},"max_score":null,"hits":[]},"aggregations":{"_value":{"values":{"29.0":1.72361E12,"29.0_as_string":"1723610000000","99.9":1.723679E12,"99.9_as_string":"1723679000000"}}}}`,
 		`{"aggregations":{"dtEventTimeStamp":{"aggregations":{"_value":{"percentiles":{"field":"dtEventTimeStamp","percents":[50,90]}}},"date_histogram":{"extended_bounds":{"max":1723679962000,"min":1723593608000},"field":"dtEventTimeStamp","fixed_interval":"6h","min_doc_count":0}}},"query":{"bool":{"filter":{"range":{"dtEventTimeStamp":{"format":"epoch_second","from":1723593608,"include_lower":true,"include_upper":true,"to":1723679962}}}}},"size":0}`: `{"took":1338,"timed_out":false,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},"hits":{"total":{"value":10000,"relation":"gte"},"max_score":null,"hits":[]},"aggregations":{"dtEventTimeStamp":{"buckets":[{"key_as_string":"1723593600000","key":1723593600000,"doc_count":387467,"_value":{"values":{"50.0":1.7236043803502532E12,"50.0_as_string":"1723604380350","90.0":1.7236129561289934E12,"90.0_as_string":"1723612956128"}}},{"key_as_string":"1723615200000","key":1723615200000,"doc_count":368818,"_value":{"values":{"50.0":1.7236258380061033E12,"50.0_as_string":"1723625838006","90.0":1.7236346787215513E12,"90.0_as_string":"1723634678721"}}},{"key_as_string":"1723636800000","key":1723636800000,"doc_count":382721,"_value":{"values":{"50.0":1.7236475858829739E12,"50.0_as_string":"1723647585882","90.0":1.723656196499344E12,"90.0_as_string":"1723656196499"}}},{"key_as_string":"1723658400000","key":1723658400000,"doc_count":384296,"_value":{"values":{"50.0":1.7236691776407131E12,"50.0_as_string":"1723669177640","90.0":1.723677836133885E12,"90.0_as_string":"1723677836133"}}}]}}}`,
 
 		// 根据 field 字段聚合计算数量，同时根据值排序
@@ -283,6 +287,31 @@ func TestInstance_queryReference(t *testing.T) {
 			end:      defaultEnd,
 			expected: `[{"labels":[{"name":"__ext__bk_46__container_name","value":"sync-apigw"},{"name":"__ext__bk_46__io_kubernetes_pod","value":"bkmonitor-unify-query-apigw-sync-1178-cl8k8"},{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"}],"samples":[{"value":24,"timestamp":1723593600000},{"timestamp":1723636800000}],"exemplars":null,"histograms":null},{"labels":[{"name":"__ext__bk_46__container_name","value":"sync-apigw"},{"name":"__ext__bk_46__io_kubernetes_pod","value":"bkmonitor-unify-query-apigw-sync-1179-9h9xv"},{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"}],"samples":[{"value":24,"timestamp":1723593600000},{"timestamp":1723636800000}],"exemplars":null,"histograms":null},{"labels":[{"name":"__ext__bk_46__container_name","value":"unify-query"},{"name":"__ext__bk_46__io_kubernetes_pod","value":"bkmonitor-unify-query-64bd4f5df4-599f9"},{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"}],"samples":[{"value":375064,"timestamp":1723593600000},{"value":392679,"timestamp":1723636800000}],"exemplars":null,"histograms":null},{"labels":[{"name":"__ext__bk_46__container_name","value":"unify-query"},{"name":"__ext__bk_46__io_kubernetes_pod","value":"bkmonitor-unify-query-64bd4f5df4-llp94"},{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"}],"samples":[{"value":381173,"timestamp":1723593600000},{"value":374338,"timestamp":1723636800000}],"exemplars":null,"histograms":null}]`,
 		},
+		"使用 promql 计算每秒日志数量 sum(rate(field[12h]))": {
+			query: &metadata.Query{
+				DB:          db,
+				Field:       field,
+				From:        0,
+				Size:        20,
+				DataSource:  structured.BkLog,
+				TableID:     "bk_log_index_set_10",
+				MetricName:  "__ext.io_kubernetes_pod",
+				StorageType: consul.ElasticsearchStorageType,
+				Aggregates: metadata.Aggregates{
+					{
+						Name: Rate,
+						Dimensions: []string{
+							"__ext.io_kubernetes_pod",
+							"__ext.container_name",
+						},
+						Window: time.Hour * 12,
+					},
+				},
+			},
+			start:    defaultStart,
+			end:      defaultEnd,
+			expected: `[{"labels":[{"name":"__ext__bk_46__container_name","value":"sync-apigw"},{"name":"__ext__bk_46__io_kubernetes_pod","value":"bkmonitor-unify-query-apigw-sync-1178-cl8k8"},{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"}],"samples":[{"value":0.0005555555555555556,"timestamp":1723593600000},{"timestamp":1723636800000}],"exemplars":null,"histograms":null},{"labels":[{"name":"__ext__bk_46__container_name","value":"sync-apigw"},{"name":"__ext__bk_46__io_kubernetes_pod","value":"bkmonitor-unify-query-apigw-sync-1179-9h9xv"},{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"}],"samples":[{"value":0.0005555555555555556,"timestamp":1723593600000},{"timestamp":1723636800000}],"exemplars":null,"histograms":null},{"labels":[{"name":"__ext__bk_46__container_name","value":"unify-query"},{"name":"__ext__bk_46__io_kubernetes_pod","value":"bkmonitor-unify-query-64bd4f5df4-599f9"},{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"}],"samples":[{"value":8.682037037037038,"timestamp":1723593600000},{"value":9.089791666666667,"timestamp":1723636800000}],"exemplars":null,"histograms":null},{"labels":[{"name":"__ext__bk_46__container_name","value":"unify-query"},{"name":"__ext__bk_46__io_kubernetes_pod","value":"bkmonitor-unify-query-64bd4f5df4-llp94"},{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"}],"samples":[{"value":8.823449074074073,"timestamp":1723593600000},{"value":8.66523148148148,"timestamp":1723636800000}],"exemplars":null,"histograms":null}]`,
+		},
 		"使用非时间聚合统计数量": {
 			query: &metadata.Query{
 				DB:          db,
@@ -324,7 +353,7 @@ func TestInstance_queryReference(t *testing.T) {
 			},
 			start:    defaultStart,
 			end:      defaultEnd,
-			expected: `[{"labels":[{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"},{"name":"le","value":"50.0"}],"samples":[{"value":1723637132806.3303,"timestamp":1723593608000}],"exemplars":null,"histograms":null}]`,
+			expected: `[{"labels":[{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"},{"name":"le","value":"50"}],"samples":[{"value":1723637132806.3303,"timestamp":1723593608000}],"exemplars":null,"histograms":null}]`,
 		},
 		"获取 50, 90 分支值，同时按 6h 时间聚合": {
 			query: &metadata.Query{
@@ -351,7 +380,30 @@ func TestInstance_queryReference(t *testing.T) {
 			},
 			start:    defaultStart,
 			end:      defaultEnd,
-			expected: `[{"labels":[{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"},{"name":"le","value":"50.0"}],"samples":[{"value":1723604380350.2532,"timestamp":1723593600000},{"value":1723625838006.1033,"timestamp":1723615200000},{"value":1723647585882.9739,"timestamp":1723636800000},{"value":1723669177640.7131,"timestamp":1723658400000}],"exemplars":null,"histograms":null},{"labels":[{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"},{"name":"le","value":"90.0"}],"samples":[{"value":1723612956128.9934,"timestamp":1723593600000},{"value":1723634678721.5513,"timestamp":1723615200000},{"value":1723656196499.344,"timestamp":1723636800000},{"value":1723677836133.885,"timestamp":1723658400000}],"exemplars":null,"histograms":null}]`,
+			expected: `[{"labels":[{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"},{"name":"le","value":"50"}],"samples":[{"value":1723604380350.2532,"timestamp":1723593600000},{"value":1723625838006.1033,"timestamp":1723615200000},{"value":1723647585882.9739,"timestamp":1723636800000},{"value":1723669177640.7131,"timestamp":1723658400000}],"exemplars":null,"histograms":null},{"labels":[{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"},{"name":"le","value":"90"}],"samples":[{"value":1723612956128.9934,"timestamp":1723593600000},{"value":1723634678721.5513,"timestamp":1723615200000},{"value":1723656196499.344,"timestamp":1723636800000},{"value":1723677836133.885,"timestamp":1723658400000}],"exemplars":null,"histograms":null}]`,
+		},
+		"获取小数分位值，分位参数存在浮点误差": {
+			query: &metadata.Query{
+				DB:          db,
+				Field:       field,
+				From:        0,
+				Size:        20,
+				DataSource:  structured.BkLog,
+				TableID:     "bk_log_index_set_10",
+				MetricName:  "__ext.io_kubernetes_pod",
+				StorageType: consul.ElasticsearchStorageType,
+				Aggregates: metadata.Aggregates{
+					{
+						Name: Percentiles,
+						Args: []interface{}{
+							0.29 * 100, 99.9,
+						},
+					},
+				},
+			},
+			start:    defaultStart,
+			end:      defaultEnd,
+			expected: `[{"labels":[{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"},{"name":"le","value":"29"}],"samples":[{"value":1723610000000,"timestamp":1723593608000}],"exemplars":null,"histograms":null},{"labels":[{"name":"__name__","value":"bklog:bk_log_index_set_10:__ext__bk_46__io_kubernetes_pod"},{"name":"le","value":"99.9"}],"samples":[{"value":1723679000000,"timestamp":1723593608000}],"exemplars":null,"histograms":null}]`,
 		},
 		"根据 field 字段聚合计算数量，同时根据值排序": {
 			query: &metadata.Query{
@@ -525,3 +577,151 @@ func TestInstance_getAlias(t *testing.T) {
 		})
 	}
 }
+
+func TestInstance_QueryLabelValues(t *testing.T) {
+	mock.Init()
+	ctx := metadata.InitHashID(context.Background())
+
+	ins, err := NewInstance(ctx, &InstanceOption{
+		Address: mock.EsUrl,
+		Timeout: 3 * time.Second,
+		MaxSize: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	start := time.UnixMilli(1723593608000)
+	end := time.UnixMilli(1723679962000)
+
+	mock.Es.Set(map[string]any{
+		`{"aggregations":{"__ext.container_name":{"aggregations":{"_value":{"value_count":{"field":"__ext.container_name"}}},"terms":{"field":"__ext.container_name","size":10}}},"query":{"bool":{"filter":{"range":{"dtEventTimeStamp":{"format":"epoch_second","from":1723593608,"include_lower":true,"include_upper":true,"to":1723679962}}}}},"size":0}`: `{"took":10,"timed_out":false,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},"hits":{"total":{"value":10000,"relation":"gte"},"max_score":null,"hits":[]},"aggregations":{"__ext.container_name":{"doc_count_error_upper_bound":0,"sum_other_doc_count":0,"buckets":[{"key":"unify-query","doc_count":1523254,"_value":{"value":1523254}},{"key":"sync-apigw","doc_count":48,"_value":{"value":48}}]}}}`,
+	})
+
+	res, err := ins.QueryLabelValues(ctx, &metadata.Query{
+		DB:        "es_index",
+		TimeField: metadata.TimeField{Name: "dtEventTimeStamp", Type: TimeFieldTypeTime, Unit: Millisecond},
+	}, "__ext.container_name", start, end)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sync-apigw", "unify-query"}, res)
+
+	_, err = ins.QueryLabelValues(ctx, &metadata.Query{DB: "es_index"}, "__name__", start, end)
+	assert.NotNil(t, err)
+}
+
+func TestInstance_Explain(t *testing.T) {
+	mock.Init()
+	ctx := metadata.InitHashID(context.Background())
+
+	// 按照索引的文档数以及存储大小计算分段窗口，3 万条文档按照默认配置分为 3 段
+	httpmock.RegisterResponder(http.MethodGet, mock.EsUrl+"/_cat/indices/es_index", httpmock.NewStringResponder(
+		http.StatusOK, `[{"index":"es_index","docs.count":"30000","store.size":"10mb"}]`,
+	))
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	for name, c := range map[string]struct {
+		address  string
+		query    *metadata.Query
+		indexes  []string
+		segments [][2]int64
+	}{
+		"segmented by index stats": {
+			address: mock.EsUrl,
+			query:   &metadata.Query{DB: "es_index", Field: "gseIndex", Size: 10},
+			indexes: []string{"es_index"},
+			segments: [][2]int64{
+				{1704067200000, 1704096000000},
+				{1704096000000, 1704124800000},
+				{1704124800000, 1704153600000},
+			},
+		},
+		"index stats unavailable": {
+			address: "http://127.0.0.1:1",
+			query:   &metadata.Query{DB: "db_test", Field: "gseIndex", NeedAddTime: true, Size: 10},
+			indexes: []string{"db_test_20240101*", "db_test_20240102*"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ins, err := NewInstance(ctx, &InstanceOption{
+				Address: c.address,
+				MaxSize: 10000,
+				Timeout: time.Second,
+			})
+			assert.NoError(t, err)
+
+			plan, err := ins.Explain(ctx, c.query, start, end)
+			assert.NoError(t, err)
+			if plan == nil {
+				return
+			}
+
+			assert.Equal(t, tsdb.LanguageESDSL, plan.Language)
+			assert.Equal(t, c.indexes, plan.Indexes)
+			assert.Len(t, plan.Statements, 1)
+			assert.Equal(t, c.segments, plan.Segments)
+			if c.segments == nil {
+				assert.NotEmpty(t, plan.Notes)
+			}
+		})
+	}
+}
+
+func TestInstance_searchSourceCursor(t *testing.T) {
+	ctx := metadata.InitHashID(context.Background())
+	mappings := []map[string]any{
+		{
+			"properties": map[string]any{
+				"dtEventTimeStamp": map[string]any{
+					"type": "date",
+				},
+				"gseIndex": map[string]any{
+					"type": "long",
+				},
+			},
+		},
+	}
+
+	for name, c := range map[string]struct {
+		cursor      bool
+		searchAfter []any
+		expected    string
+	}{
+		"not cursor": {
+			expected: `[{"dtEventTimeStamp":{"order":"desc"}},{"gseIndex":{"order":"asc"}}]`,
+		},
+		"cursor first page": {
+			cursor:   true,
+			expected: `[{"dtEventTimeStamp":{"order":"desc"}},{"gseIndex":{"order":"asc"}},{"_id":{"order":"asc"}}]`,
+		},
+		"cursor with search after": {
+			cursor:      true,
+			searchAfter: []any{1704067200000, 1, "doc-1"},
+			expected:    `[{"dtEventTimeStamp":{"order":"desc"}},{"gseIndex":{"order":"asc"}},{"_id":{"order":"asc"}}]`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			qo := &queryOption{
+				query: &metadata.Query{},
+				start: 1704067200,
+				end:   1704153600,
+			}
+			fact := NewFormatFactory(ctx).
+				WithQuery("gseIndex", metadata.TimeField{}, qo.start, qo.end, 0, 10).
+				WithMappings(mappings...).
+				WithOrders(metadata.Orders{FieldTime: false, FieldValue: true}).
+				WithCursor(c.cursor).
+				WithSearchAfter(c.searchAfter)
+
+			source, _, err := (&Instance{}).searchSource(qo, fact)
+			assert.NoError(t, err)
+
+			body, err := source.Source()
+			assert.NoError(t, err)
+			sorts, err := json.Marshal(body.(map[string]any)["sort"])
+			assert.NoError(t, err)
+			assert.JSONEq(t, c.expected, string(sorts))
+		})
+	}
+}
@@ -59,8 +59,8 @@ func newRangeSegment(opt *querySegmentOption) ([][2]int64, error) {
 		segmentNum = timeMaxSegNum
 	}
 
-	// 如果分片数为 1 则无需分片
-	if segmentNum == 1 {
+	// 如果分片数不超过 1 则无需分片，空索引的文档数以及存储大小都为 0
+	if segmentNum <= 1 {
 		return [][2]int64{{opt.start, opt.end}}, nil
 	}
 
@@ -1154,3 +1154,35 @@ func (i *Instance) DirectLabelValues(ctx context.Context, name string, start, en
 	//TODO implement me
 	panic("implement me")
 }
+
+// Explain 生成 influxQL 查询语句，指标模糊匹配时按照 measurement 和 field 生成多条
+func (i *Instance) Explain(ctx context.Context, query *metadata.Query, start, end time.Time) (*tsdb.QueryPlan, error) {
+	plan := &tsdb.QueryPlan{
+		Language:   tsdb.LanguageInfluxQL,
+		Statements: make([]string, 0, len(query.Measurements)*len(query.Fields)),
+		Indexes:    []string{query.DB},
+	}
+	if len(query.Aggregates) == 0 && i.protocol == influxdb.GRPC {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("raw data is read by %s stream with the same condition", i.protocol))
+	}
+
+	for _, measurement := range query.Measurements {
+		for _, field := range query.Fields {
+			mq := &metadata.Query{
+				DB:          query.DB,
+				Measurement: measurement,
+				Field:       field,
+				Timezone:    query.Timezone,
+				Aggregates:  query.Aggregates,
+				Condition:   query.Condition,
+				OffsetInfo:  query.OffsetInfo,
+			}
+			sql, err := i.makeSQL(ctx, mq, start, end)
+			if err != nil {
+				return nil, err
+			}
+			plan.Statements = append(plan.Statements, sql)
+		}
+	}
+	return plan, nil
+}
@@ -38,3 +38,8 @@ type Instance interface {
 
 	InstanceType() string
 }
+
+// Explainer 生成存储实例的原生查询计划，只做语句转换不执行查询，未实现的存储实例在计划中只展示路由信息
+type Explainer interface {
+	Explain(ctx context.Context, query *metadata.Query, start, end time.Time) (*QueryPlan, error)
+}
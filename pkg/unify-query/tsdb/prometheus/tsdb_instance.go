@@ -24,6 +24,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/bksql"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/clickhouse"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/elasticsearch"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/influxdb"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/victoriaMetrics"
@@ -48,6 +49,8 @@ func GetTsDbInstance(ctx context.Context, qry *metadata.Query) tsdb.Instance {
 	span.Set("storage-id", qry.StorageID)
 
 	span.Set("storage-type", qry.StorageType)
+	metadata.GetAudit(ctx).AddBackend(qry.StorageType, qry.StorageID, qry.TableID)
+
 	curlGet := &curl.HttpCurl{Log: log.DefaultLogger}
 
 	switch qry.StorageType {
@@ -122,6 +125,21 @@ func GetTsDbInstance(ctx context.Context, qry *metadata.Query) tsdb.Instance {
 			Tolerance: tsDBService.BkSqlTolerance,
 			Curl:      curlGet,
 		})
+	case consul.ClickHouseStorageType:
+		stg, _ := tsdb.GetStorage(qry.StorageID)
+		if stg == nil {
+			err = fmt.Errorf("%s storage list is empty in %s", consul.ClickHouseStorageType, qry.StorageID)
+			return nil
+		}
+		instance, err = clickhouse.NewInstance(ctx, &clickhouse.Options{
+			Address:   stg.Address,
+			Username:  stg.Username,
+			Password:  stg.Password,
+			Timeout:   tsDBService.ClickHouseTimeout,
+			MaxLimit:  tsDBService.ClickHouseLimit,
+			Tolerance: tsDBService.ClickHouseTolerance,
+			Curl:      curlGet,
+		})
 	case consul.VictoriaMetricsStorageType:
 		instance, err = victoriaMetrics.NewInstance(ctx, &victoriaMetrics.Options{
 			Address: bkapi.GetBkDataAPI().QueryUrl(user.SpaceUid),
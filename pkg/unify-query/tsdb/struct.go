@@ -84,3 +84,24 @@ type InstanceConfig struct {
 
 	TimeOut time.Duration
 }
+
+const (
+	LanguageInfluxQL = "influxql"
+	LanguageSQL      = "sql"
+	LanguageESDSL    = "es_dsl"
+	LanguagePromQL   = "promql"
+)
+
+// QueryPlan 存储实例的查询计划，包含生成的原生查询语句，不执行查询
+type QueryPlan struct {
+	// Language 原生查询语言
+	Language string `json:"language"`
+	// Statements 原生查询语句，指标模糊匹配等情况下会生成多条
+	Statements []string `json:"statements"`
+	// Indexes 查询命中的索引或者表
+	Indexes []string `json:"indexes,omitempty"`
+	// Segments 分段查询的时间窗口，毫秒时间戳
+	Segments [][2]int64 `json:"segments,omitempty"`
+	// Notes 生成计划时的补充说明，例如元数据获取失败
+	Notes []string `json:"notes,omitempty"`
+}
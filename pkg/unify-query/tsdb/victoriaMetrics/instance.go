@@ -18,8 +18,10 @@ import (
 	"time"
 
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/bkapi"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
@@ -119,9 +121,92 @@ func (i *Instance) QueryRawData(ctx context.Context, query *metadata.Query, star
 	return 0, nil
 }
 
-// QuerySeriesSet 给 PromEngine 提供查询接口
+// QuerySeriesSet 给 PromEngine 提供原始数据，vm 不支持直接导出原始点，使用区间选择器的 instant 查询获取时间范围内的原始数据
 func (i *Instance) QuerySeriesSet(ctx context.Context, query *metadata.Query, start, end time.Time) storage.SeriesSet {
-	return storage.EmptySeriesSet()
+	var (
+		vmResp = &VmResponse{}
+		err    error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "victoria-metrics-query-series-set")
+	defer span.End(&err)
+
+	span.Set("query-info", query)
+	span.Set("query-start", start)
+	span.Set("query-end", end)
+
+	if query.VmRt == "" {
+		return storage.EmptySeriesSet()
+	}
+
+	// vm 的查询时间精度为秒，结束时间向上取整，避免丢失最后一秒内的数据
+	evalTime := end.Unix()
+	if end.UnixMilli()%1e3 != 0 {
+		evalTime++
+	}
+	window := evalTime - start.Unix()
+	if window <= 0 {
+		return storage.EmptySeriesSet()
+	}
+
+	paramsQuery := &ParamsQuery{
+		InfluxCompatible: i.influxCompatible,
+		APIType:          APIQuery,
+		APIParams: struct {
+			Query   string `json:"query"`
+			Time    int64  `json:"time"`
+			Timeout int64  `json:"timeout"`
+		}{
+			Query:   fmt.Sprintf("%s[%ds]", query.VmCondition.ToMatch(), window),
+			Time:    evalTime,
+			Timeout: int64(i.timeout.Seconds()),
+		},
+		UseNativeOr:     i.useNativeOr,
+		ResultTableList: []string{query.VmRt},
+		ClusterName:     query.StorageName,
+	}
+
+	sql, err := json.Marshal(paramsQuery)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	err = i.vmQuery(ctx, string(sql), vmResp, span)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	matrix, err := i.matrixFormat(ctx, vmResp, span)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	qr := &prompb.QueryResult{
+		Timeseries: make([]*prompb.TimeSeries, 0, len(matrix)),
+	}
+	for _, series := range matrix {
+		lbs := make([]prompb.Label, 0, len(series.Metric))
+		for _, lb := range series.Metric {
+			lbs = append(lbs, prompb.Label{Name: lb.Name, Value: lb.Value})
+		}
+		sort.Slice(lbs, func(a, b int) bool {
+			return lbs[a].Name < lbs[b].Name
+		})
+
+		samples := make([]prompb.Sample, 0, len(series.Points))
+		for _, p := range series.Points {
+			if p.T < start.UnixMilli() || p.T > end.UnixMilli() {
+				continue
+			}
+			samples = append(samples, prompb.Sample{Timestamp: p.T, Value: p.V})
+		}
+		qr.Timeseries = append(qr.Timeseries, &prompb.TimeSeries{
+			Labels:  lbs,
+			Samples: samples,
+		})
+	}
+
+	return remote.FromQueryResult(true, qr)
 }
 
 func (i *Instance) vectorFormat(ctx context.Context, resp *VmResponse, span *trace.Span) (promql.Vector, error) {
@@ -810,3 +895,15 @@ func (i *Instance) DirectLabelValues(ctx context.Context, name string, start, en
 func (i *Instance) QueryExemplar(ctx context.Context, fields []string, query *metadata.Query, start, end time.Time, matchers ...*labels.Matcher) (*decoder.Response, error) {
 	panic("implement me")
 }
+
+// Explain vm 通过 PromQL 直查，单个查询只展示对应的结果表以及过滤条件
+func (i *Instance) Explain(ctx context.Context, query *metadata.Query, start, end time.Time) (*tsdb.QueryPlan, error) {
+	plan := &tsdb.QueryPlan{
+		Language:   tsdb.LanguagePromQL,
+		Statements: []string{fmt.Sprintf("{%s}", query.VmCondition)},
+	}
+	if query.VmRt != "" {
+		plan.Indexes = []string{query.VmRt}
+	}
+	return plan, nil
+}